@@ -0,0 +1,72 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pitaya
+
+import (
+	"fmt"
+
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/logger"
+	mods "github.com/hnlxhzw/pitaya/modules"
+)
+
+// clusterModules are the modules that must be started before the server is
+// ready to receive requests in cluster mode
+var clusterModules = []string{"rpcServer", "rpcClient", "serviceDiscovery"}
+
+// adminStatus provides the application state to the admin module
+type adminStatus struct{}
+
+// Ready returns an error while the server is stopping or before the rpc and
+// service discovery modules are started
+func (adminStatus) Ready() error {
+	if IsStopping() {
+		return constants.ErrServerStopping
+	}
+	if app.serverMode != Cluster {
+		return nil
+	}
+	for _, name := range clusterModules {
+		if moduleState(name) != mods.ModuleStarted {
+			return fmt.Errorf("%s: %w", name, constants.ErrModuleNotStarted)
+		}
+	}
+	return nil
+}
+
+// Routes returns the handlers and remotes documentation
+func (adminStatus) Routes() (map[string]interface{}, error) {
+	return Documentation(false)
+}
+
+// Modules returns the state of the registered modules
+func (adminStatus) Modules() []*mods.ModuleState {
+	return ModulesStates()
+}
+
+func registerAdminModule() {
+	admin := mods.NewAdmin(app.config, app.server, app.serviceDiscovery, adminStatus{})
+	// registered before all other modules so the health endpoints are
+	// served while they are started and until they are stopped
+	if err := RegisterModuleBefore(admin, "admin"); err != nil {
+		logger.Log.Fatalf("failed to register admin module: %s", err.Error())
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pitaya
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+func TestAdminStatusReady(t *testing.T) {
+	tables := []struct {
+		name     string
+		mode     ServerMode
+		started  []string
+		stopping bool
+		err      error
+	}{
+		{"standalone", Standalone, []string{}, false, nil},
+		{"standalone_stopping", Standalone, []string{}, true, constants.ErrServerStopping},
+		{"cluster", Cluster, clusterModules, false, nil},
+		{"cluster_stopping", Cluster, clusterModules, true, constants.ErrServerStopping},
+		{"cluster_not_started", Cluster, []string{"rpcServer", "rpcClient"}, false, constants.ErrModuleNotStarted},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			resetModules()
+			defer resetModules()
+			initApp()
+			Configure(true, "testtype", table.mode, map[string]string{}, viper.New())
+			for _, name := range clusterModules {
+				RegisterModule(&MyMod{name: name}, name)
+			}
+			for _, name := range table.started {
+				setModuleState(name, "started")
+			}
+			app.stopping = table.stopping
+			defer func() { app.stopping = false }()
+
+			err := adminStatus{}.Ready()
+			if table.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, table.err))
+			}
+		})
+	}
+}
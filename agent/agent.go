@@ -26,6 +26,7 @@ import (
 	e "errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -52,8 +53,14 @@ var (
 	// hbd contains the heartbeat packet data
 	hbd []byte
 	// hrd contains the handshake response data
-	hrd  []byte
-	once sync.Once
+	hrd []byte
+	// hrdCached contains the handshake response data without the route
+	// dictionary, for clients that have its version
+	hrdCached []byte
+	// handshakeSys is the sys data of the handshake response
+	handshakeSys map[string]interface{}
+	dictVersion  string
+	once         sync.Once
 )
 
 const handlerType = "handler"
@@ -70,6 +77,7 @@ type (
 		closeMutex         sync.Mutex
 		conn               net.Conn            // low-level conn fd
 		decoder            codec.PacketDecoder // binary decoder
+		dispatchIndex      string              // index of the dispatch goroutine that processes the messages
 		encoder            codec.PacketEncoder // binary encoder
 		heartbeatTimeout   time.Duration
 		lastAt             int64 // last heartbeat unix time stamp
@@ -93,6 +101,7 @@ type (
 		ctx  context.Context
 		data []byte
 		err  error
+		done chan struct{} // closed once data is written, if set
 	}
 )
 
@@ -140,10 +149,11 @@ func NewAgent(
 	return a
 }
 
-// NewAgentForHttp create new agent instance
-func NewAgentForHttp() *Agent {
+// NewAgentForHttp create new agent instance, its session is bound to the
+// UID if one is given
+func NewAgentForHttp(UID ...string) *Agent {
 	a := &Agent{}
-	s := session.New(a, false)
+	s := session.New(a, false, UID...)
 	a.Session = s
 	return a
 }
@@ -186,6 +196,7 @@ func (a *Agent) packetEncodeMessage(m *message.Message) ([]byte, error) {
 func (a *Agent) send(pendingMsg pendingMessage) (err error) {
 	defer func() {
 		if e := recover(); e != nil {
+			a.reportSendSaturation(metrics.AgentSendDropped)
 			err = errors.NewError(constants.ErrBrokenPipe, errors.ErrClientClosedRequest.Desc, errors.ErrClientClosedRequest.ErrorCode)
 		}
 	}()
@@ -211,14 +222,28 @@ func (a *Agent) send(pendingMsg pendingMessage) (err error) {
 		pWrite.err = util.GetErrorFromPayload(a.serializer, m.Data)
 	}
 
+	select {
+	case a.chSend <- pWrite:
+		return
+	default:
+		a.reportSendSaturation(metrics.AgentSendBlocked)
+	}
+
 	// chSend is never closed so we need this to don't block if agent is already closed
 	select {
 	case a.chSend <- pWrite:
 	case <-a.chDie:
+		a.reportSendSaturation(metrics.AgentSendDropped)
 	}
 	return
 }
 
+// SetDispatchIndex sets the index of the dispatch goroutine that processes
+// the agent messages, it is used to tag the agent metrics
+func (a *Agent) SetDispatchIndex(index int) {
+	a.dispatchIndex = strconv.Itoa(index)
+}
+
 // Push implementation for session.NetworkEntity interface
 func (a *Agent) Push(route string, v interface{}) error {
 	if a.GetStatus() == constants.StatusClosed {
@@ -319,6 +344,48 @@ func (a *Agent) Kick(ctx context.Context) error {
 	return err
 }
 
+// Redirect pushes the address of another frontend and a resume token to the
+// client, blocking until the message is written so the agent can be closed safely
+func (a *Agent) Redirect(ctx context.Context, address, resumeToken string) error {
+	if a.GetStatus() == constants.StatusClosed {
+		return errors.NewError(constants.ErrBrokenPipe, errors.ErrClientClosedRequest.Desc, errors.ErrClientClosedRequest.ErrorCode)
+	}
+
+	data, err := gojson.Marshal(&session.Redirect{
+		Address:     address,
+		ResumeToken: resumeToken,
+	})
+	if err != nil {
+		return err
+	}
+
+	p, err := a.packetEncodeMessage(&message.Message{
+		Type:  message.Push,
+		Route: constants.RedirectRoute,
+		Data:  data,
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Debugf("Type=Redirect, ID=%d, UID=%s, Address=%s",
+		a.Session.ID(), a.Session.UID(), address)
+
+	done := make(chan struct{})
+	select {
+	case a.chSend <- pendingWrite{data: p, done: done}:
+	case <-a.chDie:
+		return constants.ErrBrokenPipe
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-a.chDie:
+		return constants.ErrBrokenPipe
+	}
+}
+
 // SetLastAt sets the last at to now
 func (a *Agent) SetLastAt() {
 	atomic.StoreInt64(&a.lastAt, time.Now().Unix())
@@ -407,9 +474,57 @@ func onSessionClosed(s *session.Session) {
 	}
 }
 
-// SendHandshakeResponse sends a handshake response
+// SendHandshakeResponse sends a handshake response, the route dictionary is
+// left out if the client sent its version and the serializer is the one
+// negotiated by the client, if any
 func (a *Agent) SendHandshakeResponse() error {
-	_, err := a.conn.Write(hrd)
+	hd := a.Session.GetHandshakeData()
+	cached := hd != nil && dictVersion != "" && hd.Sys.DictVersion == dictVersion
+	data := hrd
+	if cached {
+		data = hrdCached
+	}
+	if serializer := a.Session.Serializer(); serializer != nil {
+		sys := handshakeSysWithout(cached)
+		sys["serializer"] = serializer.GetName()
+		var err error
+		data, err = handshakeResponse(sys, a.encoder, a.messageEncoder.IsCompressionEnabled())
+		if err != nil {
+			return err
+		}
+	}
+	_, err := a.conn.Write(data)
+	return err
+}
+
+// SetSerializer sets the serializer negotiated by the client in the
+// handshake, it is kept in the session so backend servers use it too
+func (a *Agent) SetSerializer(serializer serialize.Serializer) error {
+	a.serializer = serializer
+	return a.Session.Set(constants.SessionSerializerKey, serializer.GetName())
+}
+
+// SendHandshakeErrorResponse sends a handshake response rejecting the
+// connection, with the code and message of the error
+func (a *Agent) SendHandshakeErrorResponse(err error) error {
+	pErr := errors.NewError(err, errors.ErrUnauthorizedCode.Desc, errors.ErrUnauthorizedCode.ErrorCode)
+	hData := map[string]interface{}{
+		"code":    pErr.ErrorCode,
+		"message": pErr.Message,
+	}
+	if len(pErr.Metadata) > 0 {
+		hData["metadata"] = pErr.Metadata
+	}
+	data, err := gojson.Marshal(hData)
+	if err != nil {
+		return err
+	}
+
+	p, err := a.encoder.Encode(packet.Handshake, data)
+	if err != nil {
+		return err
+	}
+	_, err = a.conn.Write(p)
 	return err
 }
 
@@ -429,6 +544,9 @@ func (a *Agent) write() {
 				logger.Log.Errorf("Failed to write in conn: %s", err.Error())
 				return
 			}
+			if pWrite.done != nil {
+				close(pWrite.done)
+			}
 			var e error
 			tracing.FinishSpan(pWrite.ctx, e)
 			metrics.ReportTimingFromCtx(pWrite.ctx, a.metricsReporters, handlerType, pWrite.err)
@@ -470,23 +588,58 @@ func (a *Agent) AnswerWithError(ctx context.Context, mid uint, err error) {
 }
 
 func hbdEncode(heartbeatTimeout time.Duration, packetEncoder codec.PacketEncoder, dataCompression bool, serializerName string) {
+	handshakeSys = map[string]interface{}{
+		"heartbeat":  heartbeatTimeout.Seconds(),
+		"dict":       message.GetDictionary(),
+		"serializer": serializerName,
+	}
+	dictVersion = message.DictionaryVersion()
+	if dictVersion != "" {
+		handshakeSys["dictVersion"] = dictVersion
+	}
+
+	var err error
+	hrd, err = handshakeResponse(handshakeSysWithout(false), packetEncoder, dataCompression)
+	if err != nil {
+		panic(err)
+	}
+	hrdCached, err = handshakeResponse(handshakeSysWithout(true), packetEncoder, dataCompression)
+	if err != nil {
+		panic(err)
+	}
+
+	hbd, err = packetEncoder.Encode(packet.Heartbeat, nil)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// handshakeSysWithout returns a copy of the handshake sys data, without the
+// route dictionary if the client has it cached
+func handshakeSysWithout(cachedDict bool) map[string]interface{} {
+	sys := make(map[string]interface{}, len(handshakeSys))
+	for k, v := range handshakeSys {
+		if k != "dict" || !cachedDict {
+			sys[k] = v
+		}
+	}
+	return sys
+}
+
+func handshakeResponse(sys map[string]interface{}, packetEncoder codec.PacketEncoder, dataCompression bool) ([]byte, error) {
 	hData := map[string]interface{}{
 		"code": 200,
-		"sys": map[string]interface{}{
-			"heartbeat":  heartbeatTimeout.Seconds(),
-			"dict":       message.GetDictionary(),
-			"serializer": serializerName,
-		},
+		"sys":  sys,
 	}
 	data, err := gojson.Marshal(hData)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	if dataCompression {
 		compressedData, err := compression.DeflateData(data)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 
 		if len(compressedData) < len(data) {
@@ -494,14 +647,14 @@ func hbdEncode(heartbeatTimeout time.Duration, packetEncoder codec.PacketEncoder
 		}
 	}
 
-	hrd, err = packetEncoder.Encode(packet.Handshake, data)
-	if err != nil {
-		panic(err)
-	}
+	return packetEncoder.Encode(packet.Handshake, data)
+}
 
-	hbd, err = packetEncoder.Encode(packet.Heartbeat, nil)
-	if err != nil {
-		panic(err)
+func (a *Agent) reportSendSaturation(metric string) {
+	for _, mr := range a.metricsReporters {
+		if err := mr.ReportCount(metric, map[string]string{"dispatch": a.dispatchIndex}, 1); err != nil {
+			logger.Log.Warnf("failed to report %s: %s", metric, err.Error())
+		}
 	}
 }
 
@@ -82,6 +82,9 @@ func NewRemote(
 		return nil, err
 	}
 	a.Session = s
+	if serializer := s.Serializer(); serializer != nil {
+		a.serializer = serializer
+	}
 
 	return a, nil
 }
@@ -101,6 +104,11 @@ func (a *Remote) Kick(ctx context.Context) error {
 	return err
 }
 
+// Redirect is not supported by remote agents, sessions must be handed off by their frontend
+func (a *Remote) Redirect(ctx context.Context, address, resumeToken string) error {
+	return constants.ErrNotImplemented
+}
+
 // Push pushes the message to the user
 func (a *Remote) Push(route string, v interface{}) error {
 	if (reflect.TypeOf(a.rpcClient) == reflect.TypeOf(&cluster.NatsRPCClient{}) &&
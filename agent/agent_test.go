@@ -22,6 +22,7 @@ package agent
 
 import (
 	"context"
+	gojson "encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -45,6 +46,7 @@ import (
 	metricsmocks "github.com/hnlxhzw/pitaya/metrics/mocks"
 	"github.com/hnlxhzw/pitaya/mocks"
 	"github.com/hnlxhzw/pitaya/protos"
+	"github.com/hnlxhzw/pitaya/serialize"
 	serializemocks "github.com/hnlxhzw/pitaya/serialize/mocks"
 	"github.com/hnlxhzw/pitaya/session"
 )
@@ -84,7 +86,7 @@ func TestNewAgent(t *testing.T) {
 		func(typ packet.Type, d []byte) {
 			// cannot compare inside the expect because they are equivalent but not equal
 			assert.EqualValues(t, packet.Handshake, typ)
-		})
+		}).Times(2) // with and without the route dictionary
 	mockEncoder.EXPECT().Encode(gomock.Any(), gomock.Nil()).Do(
 		func(typ packet.Type, d []byte) {
 			assert.EqualValues(t, packet.Heartbeat, typ)
@@ -144,6 +146,58 @@ func TestKick(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestAgentRedirect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSerializer := serializemocks.NewMockSerializer(ctrl)
+	mockEncoder := codecmocks.NewMockPacketEncoder(ctrl)
+	mockDecoder := codecmocks.NewMockPacketDecoder(ctrl)
+	heartbeatAndHandshakeMocks(mockEncoder)
+	dieChan := make(chan bool)
+	hbTime := time.Second
+
+	mockConn := mocks.NewMockPlayerConn(ctrl)
+	messageEncoder := message.NewMessagesEncoder(false)
+
+	mockSerializer.EXPECT().GetName()
+	ag := NewAgent(mockConn, mockDecoder, mockEncoder, mockSerializer, hbTime, 10, dieChan, messageEncoder, nil)
+
+	expectedData, err := gojson.Marshal(&session.Redirect{Address: "localhost:3250", ResumeToken: "token"})
+	assert.NoError(t, err)
+	expectedMsg, err := messageEncoder.Encode(&message.Message{
+		Type:  message.Push,
+		Route: constants.RedirectRoute,
+		Data:  expectedData,
+	})
+	assert.NoError(t, err)
+	expectedPacket := []byte("redirect")
+	mockEncoder.EXPECT().Encode(packet.Type(packet.Data), expectedMsg).Return(expectedPacket, nil)
+	mockConn.EXPECT().Write(expectedPacket).Return(0, nil)
+
+	go ag.write()
+	err = ag.Redirect(context.Background(), "localhost:3250", "token")
+	assert.NoError(t, err)
+}
+
+func TestAgentRedirectShouldFailIfClosed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSerializer := serializemocks.NewMockSerializer(ctrl)
+	mockEncoder := codecmocks.NewMockPacketEncoder(ctrl)
+	heartbeatAndHandshakeMocks(mockEncoder)
+	mockConn := mocks.NewMockPlayerConn(ctrl)
+	messageEncoder := message.NewMessagesEncoder(false)
+
+	mockSerializer.EXPECT().GetName()
+	ag := NewAgent(mockConn, nil, mockEncoder, mockSerializer, time.Second, 10, nil, messageEncoder, nil)
+	ag.SetStatus(constants.StatusClosed)
+
+	err := ag.Redirect(context.Background(), "localhost:3250", "token")
+	assert.Error(t, err)
+}
+
 func TestAgentSend(t *testing.T) {
 	tables := []struct {
 		name string
@@ -325,6 +379,7 @@ func TestAgentPushStruct(t *testing.T) {
 
 			if table.err != nil {
 				close(ag.chSend)
+				mockMetricsReporter.EXPECT().ReportCount(metrics.AgentSendDropped, gomock.Any(), float64(1))
 			}
 
 			mockMetricsReporter.EXPECT().ReportGauge(metrics.ChannelCapacity, gomock.Any(), float64(10))
@@ -382,6 +437,7 @@ func TestAgentPush(t *testing.T) {
 
 			if table.err != nil {
 				close(ag.chSend)
+				mockMetricsReporter.EXPECT().ReportCount(metrics.AgentSendDropped, gomock.Any(), float64(1))
 			}
 
 			mockMetricsReporter.EXPECT().ReportGauge(metrics.ChannelCapacity, gomock.Any(), float64(10))
@@ -416,6 +472,7 @@ func TestAgentPushFullChannel(t *testing.T) {
 	assert.NotNil(t, ag)
 
 	mockMetricsReporter.EXPECT().ReportGauge(metrics.ChannelCapacity, gomock.Any(), float64(0))
+	mockMetricsReporter.EXPECT().ReportCount(metrics.AgentSendBlocked, map[string]string{"dispatch": ""}, float64(1)).MaxTimes(1)
 
 	msg := &message.Message{
 		Route: "route",
@@ -433,6 +490,37 @@ func TestAgentPushFullChannel(t *testing.T) {
 	helpers.ShouldEventuallyReceive(t, ag.chSend)
 }
 
+func TestAgentSendReportsDroppedIfClosedWhileBlocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEncoder := codecmocks.NewMockPacketEncoder(ctrl)
+	messageEncoder := message.NewMessagesEncoder(false)
+	mockMetricsReporter := metricsmocks.NewMockReporter(ctrl)
+	ag := &Agent{ // avoid heartbeat and handshake to fully test send
+		chDie:            make(chan struct{}),
+		chSend:           make(chan pendingWrite),
+		encoder:          mockEncoder,
+		messageEncoder:   messageEncoder,
+		metricsReporters: []metrics.Reporter{mockMetricsReporter},
+		Session:          session.New(nil, true),
+	}
+	ag.SetDispatchIndex(3)
+
+	mockEncoder.EXPECT().Encode(packet.Type(packet.Data), gomock.Any())
+	mockMetricsReporter.EXPECT().ReportGauge(metrics.ChannelCapacity, gomock.Any(), float64(0))
+	mockMetricsReporter.EXPECT().ReportCount(metrics.AgentSendBlocked, map[string]string{"dispatch": "3"}, float64(1))
+	mockMetricsReporter.EXPECT().ReportCount(metrics.AgentSendDropped, map[string]string{"dispatch": "3"}, float64(1)).Do(
+		func(metric string, tags map[string]string, count float64) {
+			assert.Equal(t, 0, len(ag.chSend))
+		},
+	)
+
+	close(ag.chDie)
+	err := ag.send(pendingMessage{typ: message.Push, route: "route", payload: []byte("data")})
+	assert.NoError(t, err)
+}
+
 func TestAgentResponseMIDFailsIfClosedAgent(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -501,6 +589,7 @@ func TestAgentResponseMID(t *testing.T) {
 			if table.mid != 0 {
 				if table.err != nil {
 					close(ag.chSend)
+					mockMetricsReporter.EXPECT().ReportCount(metrics.AgentSendDropped, gomock.Any(), float64(1))
 				}
 			}
 			if reflect.TypeOf(table.data) != reflect.TypeOf([]byte{}) {
@@ -547,6 +636,7 @@ func TestAgentResponseMIDFullChannel(t *testing.T) {
 	ag := NewAgent(mockConn, mockDecoder, mockEncoder, mockSerializer, hbTime, 0, dieChan, messageEncoder, mockMetricsReporters)
 	assert.NotNil(t, ag)
 	mockMetricsReporters[0].(*metricsmocks.MockReporter).EXPECT().ReportGauge(metrics.ChannelCapacity, gomock.Any(), float64(0))
+	mockMetricsReporters[0].(*metricsmocks.MockReporter).EXPECT().ReportCount(metrics.AgentSendBlocked, gomock.Any(), float64(1)).MaxTimes(1)
 	go func() {
 		err := ag.ResponseMID(nil, 1, []byte("data"))
 		assert.NoError(t, err)
@@ -796,6 +886,109 @@ func TestAgentSendHandshakeResponse(t *testing.T) {
 	}
 }
 
+func TestAgentSendHandshakeResponseCachedDictionary(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockConn := mocks.NewMockPlayerConn(ctrl)
+	mockEncoder := codecmocks.NewMockPacketEncoder(ctrl)
+	heartbeatAndHandshakeMocks(mockEncoder)
+	mockSerializer := serializemocks.NewMockSerializer(ctrl)
+	mockSerializer.EXPECT().GetName()
+
+	ag := NewAgent(mockConn, nil, mockEncoder, mockSerializer, time.Second, 0, nil, messagemocks.NewMockEncoder(ctrl), nil)
+	assert.NotNil(t, ag)
+
+	oldHrd, oldHrdCached, oldDictVersion := hrd, hrdCached, dictVersion
+	defer func() { hrd, hrdCached, dictVersion = oldHrd, oldHrdCached, oldDictVersion }()
+	hrd, hrdCached, dictVersion = []byte("with dict"), []byte("without dict"), "v1"
+
+	ag.Session.SetHandshakeData(&session.HandshakeData{Sys: session.HandshakeClientData{DictVersion: "v0"}})
+	mockConn.EXPECT().Write(hrd)
+	assert.NoError(t, ag.SendHandshakeResponse())
+
+	ag.Session.SetHandshakeData(&session.HandshakeData{Sys: session.HandshakeClientData{DictVersion: "v1"}})
+	mockConn.EXPECT().Write(hrdCached)
+	assert.NoError(t, ag.SendHandshakeResponse())
+}
+
+func TestAgentSendHandshakeResponseSerializer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockConn := mocks.NewMockPlayerConn(ctrl)
+	mockEncoder := codecmocks.NewMockPacketEncoder(ctrl)
+	mockEncoder.EXPECT().Encode(packet.Type(packet.Handshake), gomock.Not(gomock.Nil())).DoAndReturn(
+		func(typ packet.Type, data []byte) ([]byte, error) { return data, nil }).AnyTimes()
+	mockEncoder.EXPECT().Encode(packet.Type(packet.Heartbeat), gomock.Nil()).AnyTimes()
+	mockSerializer := serializemocks.NewMockSerializer(ctrl)
+	mockSerializer.EXPECT().GetName().Return("custom").AnyTimes()
+	mockMessageEncoder := messagemocks.NewMockEncoder(ctrl)
+	mockMessageEncoder.EXPECT().IsCompressionEnabled().Return(false)
+
+	serialize.Register(mockSerializer)
+	defer serialize.Unregister("custom")
+	ag := NewAgent(mockConn, nil, mockEncoder, mockSerializer, time.Second, 0, nil, mockMessageEncoder, nil)
+	assert.NotNil(t, ag)
+	assert.Nil(t, ag.Session.Serializer())
+
+	assert.NoError(t, ag.SetSerializer(mockSerializer))
+	assert.Equal(t, mockSerializer, ag.Session.Serializer())
+	assert.Equal(t, "custom", ag.Session.String(constants.SessionSerializerKey))
+
+	mockConn.EXPECT().Write(gomock.Any()).Do(func(d []byte) {
+		var res map[string]interface{}
+		assert.NoError(t, gojson.Unmarshal(d, &res))
+		assert.Equal(t, "custom", res["sys"].(map[string]interface{})["serializer"])
+	})
+	assert.NoError(t, ag.SendHandshakeResponse())
+}
+
+func TestAgentSendHandshakeErrorResponse(t *testing.T) {
+	tables := []struct {
+		name     string
+		err      error
+		expected map[string]interface{}
+	}{
+		{"pitaya_error", e.NewError(errors.New("invalid token"), "PIT-403", 403, map[string]string{"reason": "expired"}), map[string]interface{}{
+			"code":     403,
+			"message":  "invalid token",
+			"metadata": map[string]string{"reason": "expired"},
+		}},
+		{"error", errors.New("not allowed"), map[string]interface{}{
+			"code":    e.ErrUnauthorizedCode.ErrorCode,
+			"message": "not allowed",
+		}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockConn := mocks.NewMockPlayerConn(ctrl)
+			mockEncoder := codecmocks.NewMockPacketEncoder(ctrl)
+			mockMessageEncoder := messagemocks.NewMockEncoder(ctrl)
+			mockSerializer := serializemocks.NewMockSerializer(ctrl)
+			mockSerializer.EXPECT().GetName()
+
+			expectedData, err := gojson.Marshal(table.expected)
+			assert.NoError(t, err)
+			expectedPacket := []byte("rejected")
+			mockEncoder.EXPECT().Encode(packet.Type(packet.Handshake), expectedData).Return(expectedPacket, nil)
+			heartbeatAndHandshakeMocks(mockEncoder)
+
+			ag := NewAgent(mockConn, nil, mockEncoder, mockSerializer, time.Second, 0, nil, mockMessageEncoder, nil)
+			assert.NotNil(t, ag)
+
+			mockConn.EXPECT().Write(expectedPacket).Return(len(expectedPacket), nil)
+
+			err = ag.SendHandshakeErrorResponse(table.err)
+			assert.NoError(t, err)
+		})
+	}
+}
+
 func TestAnswerWithError(t *testing.T) {
 	tables := []struct {
 		name          string
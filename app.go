@@ -22,9 +22,11 @@ package pitaya
 
 import (
 	"context"
+	"io"
 	"os"
 	"os/signal"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
@@ -138,6 +140,7 @@ func Configure(
 	app.serverMode = serverMode
 	app.server.Metadata = serverMetadata
 	app.messageEncoder = message.NewMessagesEncoder(app.config.GetBool("pitaya.handler.messages.compression"))
+	session.SetHandoffTimeout(app.config.GetDuration("pitaya.session.handoff.timeout"))
 	configureMetrics(serverType)
 	configureDefaultPipelines(app.config)
 	app.configured = true
@@ -147,6 +150,10 @@ func configureMetrics(serverType string) {
 	app.metricsReporters = make([]metrics.Reporter, 0)
 	constTags := app.config.GetStringMapString("pitaya.metrics.constTags")
 
+	if err := metrics.Configure(app.config); err != nil {
+		logger.Log.Errorf("failed to configure latency histograms and slos, skipping %v", err)
+	}
+
 	if app.config.GetBool("pitaya.metrics.prometheus.enabled") {
 		port := app.config.GetInt("pitaya.metrics.prometheus.port")
 		logger.Log.Infof("prometheus is enabled, configuring reporter on port %d", port)
@@ -177,6 +184,11 @@ func configureMetrics(serverType string) {
 			AddMetricsReporter(metricsReporter)
 		}
 	}
+
+	if app.config.GetBool("pitaya.metrics.otel.enabled") {
+		logger.Log.Info("opentelemetry is enabled, configuring the metrics reporter")
+		AddMetricsReporter(metrics.NewOTelReporter(serverType, constTags))
+	}
 }
 
 func configureDefaultPipelines(config *config.Config) {
@@ -202,6 +214,11 @@ func GetDieChan() chan bool {
 // SetDebug toggles debug on/off
 func SetDebug(debug bool) {
 	app.debug = debug
+	if debug {
+		session.SetPushValidator(validateSessionPush)
+	} else {
+		session.SetPushValidator(nil)
+	}
 }
 
 // SetPacketDecoder changes the decoder used to parse messages received
@@ -265,6 +282,13 @@ func GetSerializer() serialize.Serializer {
 	return app.serializer
 }
 
+// RegisterSerializer registers a serializer clients can request in the
+// handshake instead of the app one. Backend servers must register it too to
+// handle the requests and pushes of these sessions.
+func RegisterSerializer(seri serialize.Serializer) {
+	serialize.Register(seri)
+}
+
 // GetServer gets the local server instance
 func GetServer() *cluster.Server {
 	return app.server
@@ -322,7 +346,7 @@ func startDefaultRPCClient() {
 }
 
 func initSysRemotes() {
-	sys := &remote.Sys{}
+	sys := &remote.Sys{Worker: func() *worker.Worker { return app.worker }}
 	RegisterRemote(sys,
 		component.WithName("sys"),
 		component.WithNameFunc(strings.ToLower),
@@ -332,9 +356,10 @@ func initSysRemotes() {
 func periodicMetrics() {
 	period := app.config.GetDuration("pitaya.metrics.periodicMetrics.period")
 	go metrics.ReportSysMetrics(app.metricsReporters, period)
+	go handlerService.ReportSaturationMetrics(period)
 
 	if app.worker.Started() {
-		go worker.Report(app.metricsReporters, period)
+		go app.worker.Report(app.metricsReporters, period)
 	}
 }
 
@@ -404,6 +429,13 @@ func Start() {
 		initSysRemotes()
 	}
 
+	if app.config.GetBool("pitaya.modules.admin.enabled") {
+		registerAdminModule()
+	}
+
+	serialize.Register(app.serializer)
+	session.SetPushDecoder(decodePush)
+
 	handlerService = service.NewHandlerService(
 		app.dieChan,
 		app.packetDecoder,
@@ -448,6 +480,9 @@ func Start() {
 	shutdownCustomerModules()
 	shutdownModules()
 	shutdownComponents()
+	if app.worker.Started() {
+		app.worker.Stop()
+	}
 
 	logger.Log.Info("server is stopping done...")
 	<-time.After(time.Second * 1)
@@ -455,6 +490,12 @@ func Start() {
 
 func listen() {
 	startupComponents()
+	if app.serverMode == Cluster {
+		if err := publishHandlerRoutes(); err != nil {
+			logger.Log.Fatalf("failed to publish the handler routes: %s", err.Error())
+		}
+	}
+	autoDictionary := app.config.GetBool("pitaya.handler.dictionary.auto")
 	// create global ticker instance, timer precision could be customized
 	// by SetTimerPrecision
 	timer.GlobalTicker = time.NewTicker(timer.Precision)
@@ -468,19 +509,8 @@ func listen() {
 	}
 	wg.Wait()
 
-	for _, acc := range app.acceptors {
-		a := acc
-		go func() {
-			for conn := range a.GetConnChan() {
-				go handlerService.Handle(conn)
-			}
-		}()
-
-		go func() {
-			a.ListenAndServe()
-		}()
-
-		logger.Log.Infof("listening with acceptor %s on addr %s", reflect.TypeOf(a), a.GetAddr())
+	if !autoDictionary {
+		startAcceptors()
 	}
 
 	if app.serverMode == Cluster && app.server.Frontend && app.config.GetBool("pitaya.session.unique") {
@@ -494,9 +524,36 @@ func listen() {
 
 	logger.Log.Info("all modules started!")
 
+	if autoDictionary {
+		// the dictionary has the routes of the servers found by the service
+		// discovery, so it is built after it is started and before any client
+		// connects, since the dictionary can't change after it is sent
+		if err := addRoutesToDictionary(); err != nil {
+			logger.Log.Fatalf("failed to build the route dictionary: %s", err.Error())
+		}
+		startAcceptors()
+	}
+
 	app.running = true
 }
 
+func startAcceptors() {
+	for _, acc := range app.acceptors {
+		a := acc
+		go func() {
+			for conn := range a.GetConnChan() {
+				go handlerService.Handle(conn)
+			}
+		}()
+
+		go func() {
+			a.ListenAndServe()
+		}()
+
+		logger.Log.Infof("listening with acceptor %s on addr %s", reflect.TypeOf(a), a.GetAddr())
+	}
+}
+
 func IsRuning() bool {
 	return app.running
 }
@@ -513,6 +570,59 @@ func SetDictionary(dict map[string]uint16) error {
 	return message.SetDictionary(dict)
 }
 
+// publishHandlerRoutes adds the routes of the registered handlers to the
+// server metadata, so frontends can add them to their route dictionary
+func publishHandlerRoutes() error {
+	routes, err := handlerRoutes()
+	if err != nil || len(routes) == 0 {
+		return err
+	}
+	if app.server.Metadata == nil {
+		app.server.Metadata = map[string]string{}
+	}
+	app.server.Metadata[constants.HandlerRoutesKey] = strings.Join(routes, ",")
+	return nil
+}
+
+func handlerRoutes() ([]string, error) {
+	handlerDocs, err := handlerService.Docs(false)
+	if err != nil {
+		return nil, err
+	}
+	routes := make([]string, 0, len(handlerDocs))
+	for route := range handlerDocs {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	return routes, nil
+}
+
+// addRoutesToDictionary adds the routes of the registered handlers, of the
+// handlers of the servers found by the service discovery and of the declared
+// pushes to the dictionary, the ones set with SetDictionary keep their codes
+// and the others get deterministic codes
+func addRoutesToDictionary() error {
+	routes, err := handlerRoutes()
+	if err != nil {
+		return err
+	}
+	if app.serviceDiscovery != nil {
+		for _, sv := range app.serviceDiscovery.GetServers() {
+			if serverRoutes := sv.Metadata[constants.HandlerRoutesKey]; serverRoutes != "" {
+				routes = append(routes, strings.Split(serverRoutes, ",")...)
+			}
+		}
+	}
+	for route := range declaredPushes() {
+		routes = append(routes, route)
+	}
+	if err := message.AddRoutes(routes); err != nil {
+		return err
+	}
+	logger.Log.Infof("route dictionary has %d routes, version %s", len(message.GetDictionary()), message.DictionaryVersion())
+	return nil
+}
+
 // AddRoute adds a routing function to a server type
 func AddRoute(
 	serverType string,
@@ -589,7 +699,7 @@ func ExtractSpan(ctx context.Context) (opentracing.SpanContext, error) {
 	return tracing.ExtractSpan(ctx)
 }
 
-// Documentation returns handler and remotes documentacion
+// Documentation returns handler, remotes and declared pushes documentacion
 func Documentation(getPtrNames bool) (map[string]interface{}, error) {
 	handlerDocs, err := handlerService.Docs(getPtrNames)
 	if err != nil {
@@ -599,12 +709,49 @@ func Documentation(getPtrNames bool) (map[string]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	pushDocs, err := docgenerator.PushesDocs(declaredPushes(), getPtrNames)
+	if err != nil {
+		return nil, err
+	}
 	return map[string]interface{}{
 		"handlers": handlerDocs,
 		"remotes":  remoteDocs,
+		"pushes":   pushDocs,
 	}, nil
 }
 
+// OpenAPI returns an OpenAPI 3 document with an operation for each handler
+// route, protobuf messages are described from their descriptors
+func OpenAPI(info docgenerator.OpenAPIInfo) (*docgenerator.OpenAPIDocument, error) {
+	docs, err := Documentation(true)
+	if err != nil {
+		return nil, err
+	}
+	return docgenerator.OpenAPI(docs, info, docgenerator.ProtoDescriptors)
+}
+
+// JSONSchemas returns a JSON Schema for each type of the handlers and remotes
+func JSONSchemas() (map[string]*docgenerator.Schema, error) {
+	docs, err := Documentation(true)
+	if err != nil {
+		return nil, err
+	}
+	return docgenerator.JSONSchemas(docs, docgenerator.ProtoDescriptors)
+}
+
+// GenerateSDK writes a client sdk for the server handlers, using the route
+// dictionary set with SetDictionary if config has none
+func GenerateSDK(w io.Writer, config docgenerator.SDKConfig) error {
+	docs, err := Documentation(true)
+	if err != nil {
+		return err
+	}
+	if config.Dictionary == nil {
+		config.Dictionary = message.GetDictionary()
+	}
+	return docgenerator.GenerateSDK(w, docs, docgenerator.ProtoDescriptors, config)
+}
+
 // AddGRPCInfoToMetadata adds host, external host and
 // port into metadata
 func AddGRPCInfoToMetadata(
@@ -639,6 +786,13 @@ func StartWorker(config *config.Config) error {
 	return nil
 }
 
+// StartWorkerWithBackend starts and returns pitaya worker storing its jobs
+// in the given backend
+func StartWorkerWithBackend(config *config.Config, backend worker.Backend) {
+	app.worker = worker.NewWorkerWithBackend(config, backend)
+	app.worker.Start()
+}
+
 // RegisterRPCJob registers rpc job to execute jobs with retries
 func RegisterRPCJob(rpcJob worker.RPCJob) error {
 	err := app.worker.RegisterRPCJob(rpcJob)
@@ -27,10 +27,12 @@ import (
 	"net"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/coreos/etcd/integration"
+	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/sirupsen/logrus"
@@ -38,6 +40,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/hnlxhzw/pitaya/acceptor"
 	"github.com/hnlxhzw/pitaya/cluster"
+	clustermocks "github.com/hnlxhzw/pitaya/cluster/mocks"
+	"github.com/hnlxhzw/pitaya/component"
 	"github.com/hnlxhzw/pitaya/conn/codec"
 	"github.com/hnlxhzw/pitaya/conn/message"
 	"github.com/hnlxhzw/pitaya/constants"
@@ -46,11 +50,14 @@ import (
 	"github.com/hnlxhzw/pitaya/helpers"
 	"github.com/hnlxhzw/pitaya/logger"
 	"github.com/hnlxhzw/pitaya/metrics"
+	"github.com/hnlxhzw/pitaya/protos"
 	"github.com/hnlxhzw/pitaya/route"
+	"github.com/hnlxhzw/pitaya/service"
 	"github.com/hnlxhzw/pitaya/router"
 	"github.com/hnlxhzw/pitaya/serialize/json"
 	"github.com/hnlxhzw/pitaya/session"
 	"github.com/hnlxhzw/pitaya/timer"
+	"github.com/hnlxhzw/pitaya/worker"
 )
 
 var (
@@ -300,6 +307,58 @@ func TestSetDictionary(t *testing.T) {
 	assert.EqualError(t, constants.ErrChangeDictionaryWhileRunning, err.Error())
 }
 
+type RoutesComp struct {
+	component.Base
+}
+
+func (c *RoutesComp) Join(ctx context.Context) {}
+
+func (c *RoutesComp) Leave(ctx context.Context) {}
+
+func TestAddRoutesToDictionary(t *testing.T) {
+	defer func() { pushes = make(map[string]reflect.Type) }()
+	defer message.ClearDictionary()
+	message.ClearDictionary()
+	serviceDiscovery := app.serviceDiscovery
+	defer func() { app.serviceDiscovery = serviceDiscovery }()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSD := clustermocks.NewMockServiceDiscovery(ctrl)
+	mockSD.EXPECT().GetServers().Return([]*cluster.Server{
+		{Type: "room", Metadata: map[string]string{constants.HandlerRoutesKey: "room.room.leave,room.room.join"}},
+		{Type: "room", Metadata: map[string]string{constants.HandlerRoutesKey: "room.room.join,room.room.leave"}},
+		{Type: "chat", Metadata: map[string]string{}},
+	})
+	app.serviceDiscovery = mockSD
+
+	assert.NoError(t, message.SetDictionary(map[string]uint16{"someroute": 1}))
+	assert.NoError(t, RegisterPush("onB", []byte{}))
+	assert.NoError(t, RegisterPush("onA", []byte{}))
+	assert.NoError(t, addRoutesToDictionary())
+	assert.Equal(t, map[string]uint16{
+		"someroute":       1,
+		"onA":             2,
+		"onB":             3,
+		"room.room.join":  4,
+		"room.room.leave": 5,
+	}, message.GetDictionary())
+}
+
+func TestPublishHandlerRoutes(t *testing.T) {
+	initApp()
+	Configure(true, "connector", Cluster, map[string]string{}, viper.New())
+	defer func() { handlerService = nil }()
+
+	handlerService = service.NewHandlerService(nil, nil, nil, nil, 0, 0, 0, 0, app.server, nil, nil, nil, 1)
+	assert.NoError(t, publishHandlerRoutes())
+	assert.NotContains(t, app.server.Metadata, constants.HandlerRoutesKey)
+
+	assert.NoError(t, handlerService.Register(&RoutesComp{}, []component.Option{component.WithName("lobby"), component.WithNameFunc(strings.ToLower)}))
+	assert.NoError(t, publishHandlerRoutes())
+	assert.Equal(t, "connector.lobby.join,connector.lobby.leave", app.server.Metadata[constants.HandlerRoutesKey])
+}
+
 func TestAddRoute(t *testing.T) {
 	initApp()
 	Configure(true, "testtype", Cluster, map[string]string{}, viper.New())
@@ -414,6 +473,8 @@ func TestStartAndListenCluster(t *testing.T) {
 	cfg := viper.New()
 	cfg.Set("pitaya.cluster.rpc.client.nats.connect", fmt.Sprintf("nats://%s", nsAddr))
 	cfg.Set("pitaya.cluster.rpc.server.nats.connect", fmt.Sprintf("nats://%s", nsAddr))
+	cfg.Set("pitaya.handler.dictionary.auto", true)
+	defer message.ClearDictionary()
 
 	initApp()
 	Configure(true, "testtype", Cluster, map[string]string{}, cfg)
@@ -528,6 +589,7 @@ func TestDocumentation(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]interface{}{
 		"handlers": map[string]interface{}{},
+		"pushes":   map[string]interface{}{},
 		"remotes": map[string]interface{}{
 			"testtype.sys.bindsession": map[string]interface{}{
 				"input": map[string]interface{}{
@@ -647,6 +709,7 @@ func TestDocumentationTrue(t *testing.T) {
 			},
 		},
 		"handlers": map[string]interface{}{},
+		"pushes":   map[string]interface{}{},
 	}, doc)
 }
 
@@ -683,6 +746,53 @@ func TestStartWorker(t *testing.T) {
 	assert.True(t, app.worker.Started())
 }
 
+func TestStartWorkerWithBackend(t *testing.T) {
+	cfg := viper.New()
+	initApp()
+	Configure(true, "testtype", Cluster, map[string]string{}, cfg)
+
+	StartWorkerWithBackend(GetConfig(), worker.NewMemoryBackend())
+	assert.True(t, app.worker.Started())
+
+	jid, err := ReliableRPC("testtype.svc.method", nil, &protos.Response{}, &protos.Request{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, jid)
+}
+
+func TestCancelReliableRPC(t *testing.T) {
+	cfg := viper.New()
+	initApp()
+	Configure(true, "testtype", Cluster, map[string]string{}, cfg)
+	StartWorkerWithBackend(GetConfig(), worker.NewMemoryBackend())
+
+	jid, err := ReliableRPCWithOptions("testtype.svc.method", nil, &protos.Response{}, &protos.Request{},
+		&worker.EnqueueOpts{RunAfter: time.Hour})
+	assert.NoError(t, err)
+
+	err = CancelReliableRPC(jid)
+	assert.NoError(t, err)
+	err = CancelReliableRPC(jid)
+	assert.Equal(t, constants.ErrJobNotFound, err)
+}
+
+func TestDeadReliableRPCs(t *testing.T) {
+	cfg := viper.New()
+	initApp()
+	Configure(true, "testtype", Cluster, map[string]string{}, cfg)
+	StartWorkerWithBackend(GetConfig(), worker.NewMemoryBackend())
+
+	deadJobs, err := DeadReliableRPCs("")
+	assert.NoError(t, err)
+	assert.Empty(t, deadJobs)
+
+	_, err = DeadReliableRPC("jid")
+	assert.Equal(t, constants.ErrJobNotFound, err)
+	err = ReplayDeadReliableRPC("jid")
+	assert.Equal(t, constants.ErrJobNotFound, err)
+	err = PurgeDeadReliableRPC("jid")
+	assert.Equal(t, constants.ErrJobNotFound, err)
+}
+
 func TestRegisterRPCJob(t *testing.T) {
 	t.Run("register_once", func(t *testing.T) {
 		cfg := viper.New()
@@ -0,0 +1,221 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package auth
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hnlxhzw/pitaya/config"
+	"github.com/hnlxhzw/pitaya/constants"
+	e "github.com/hnlxhzw/pitaya/errors"
+	"github.com/hnlxhzw/pitaya/session"
+)
+
+// Identity is the identity of a client verified from a token
+type Identity struct {
+	// UID is the user id the session must be bound to, it is empty if
+	// binding is disabled or the token has no uid claim
+	UID string
+	// Data is the session data extracted from the token claims, it always
+	// has the token expiration time, in unix seconds, if the token expires
+	Data map[string]interface{}
+}
+
+// JWTAuthenticator verifies JSON Web Tokens signed with HMAC secrets or
+// RSA and EC keys, and authenticates sessions with their claims
+type JWTAuthenticator struct {
+	secret       []byte
+	keySet       *keySet
+	parser       *jwt.Parser
+	uidClaim     string
+	bind         bool
+	claims       map[string]string
+	handshakeKey string
+}
+
+// NewJWTAuthenticator creates a new jwt authenticator with the keys and
+// claims validation of the config
+func NewJWTAuthenticator(config *config.Config) (*JWTAuthenticator, error) {
+	a := &JWTAuthenticator{
+		uidClaim:     config.GetString("pitaya.auth.jwt.uidclaim"),
+		bind:         config.GetBool("pitaya.auth.jwt.bind"),
+		claims:       config.GetStringMapString("pitaya.auth.jwt.claims"),
+		handshakeKey: config.GetString("pitaya.auth.jwt.handshakekey"),
+	}
+
+	if secret := config.GetString("pitaya.auth.jwt.secret"); secret != "" {
+		a.secret = []byte(secret)
+	}
+	if path := config.GetString("pitaya.auth.jwt.keysfile"); path != "" {
+		ks, err := newKeySet(path, config.GetDuration("pitaya.auth.jwt.keysreloadinterval"))
+		if err != nil {
+			return nil, err
+		}
+		a.keySet = ks
+	}
+	if a.secret == nil && a.keySet == nil {
+		return nil, constants.ErrNoJWTKeys
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(config.GetStringSlice("pitaya.auth.jwt.algorithms")),
+		jwt.WithLeeway(config.GetDuration("pitaya.auth.jwt.leeway")),
+	}
+	if issuer := config.GetString("pitaya.auth.jwt.issuer"); issuer != "" {
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+	if audience := config.GetString("pitaya.auth.jwt.audience"); audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+	a.parser = jwt.NewParser(opts...)
+
+	return a, nil
+}
+
+// Verify verifies the token signature and claims and returns the identity
+// of its client. Failures are *errors.Error with the PIT-401 code
+func (a *JWTAuthenticator) Verify(token string) (*Identity, error) {
+	if token == "" {
+		return nil, unauthorized(constants.ErrMissingToken, "")
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(token, claims, a.keyFunc); err != nil {
+		return nil, unauthorized(constants.ErrInvalidToken, err.Error())
+	}
+
+	identity := &Identity{Data: map[string]interface{}{}}
+	if a.bind && a.uidClaim != "" {
+		if uid, ok := claims[a.uidClaim].(string); ok {
+			identity.UID = uid
+		}
+	}
+	for claim, key := range a.claims {
+		if value, ok := claims[claim]; ok {
+			identity.Data[key] = value
+		}
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		identity.Data[constants.SessionAuthExpiresAtKey] = exp.Unix()
+	} else {
+		identity.Data[constants.SessionAuthExpiresAtKey] = int64(0)
+	}
+	return identity, nil
+}
+
+// Authenticate verifies the token, sets the data extracted from its claims
+// in the session and binds the session to its uid, if binding is enabled
+// and the session is not bound yet. The token is rejected if the session
+// is bound to another uid
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, s *session.Session, token string) error {
+	identity, err := a.Verify(token)
+	if err != nil {
+		return err
+	}
+	if identity.UID != "" && s.UID() != "" && s.UID() != identity.UID {
+		return unauthorized(constants.ErrInvalidToken, "token uid does not match the session uid")
+	}
+
+	for key, value := range identity.Data {
+		if err := s.Set(key, value); err != nil {
+			return err
+		}
+	}
+	if identity.UID != "" && s.UID() == "" {
+		if err := s.Bind(ctx, identity.UID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandshakeValidator is a session.HandshakeValidator that authenticates
+// the session with the token sent in the handshake user data, rejecting
+// the connection if it is missing or invalid
+func (a *JWTAuthenticator) HandshakeValidator(ctx context.Context, s *session.Session, data *session.HandshakeData, remoteAddr net.Addr) error {
+	token := ""
+	if data != nil {
+		token, _ = data.User[a.handshakeKey].(string)
+	}
+	return a.Authenticate(ctx, s, token)
+}
+
+// BeforeHandler is a pipeline function that rejects the requests of sessions
+// not authenticated or whose token expired, it can be used for handlers
+// and remotes of both frontend and backend servers
+func (a *JWTAuthenticator) BeforeHandler(ctx context.Context, in interface{}) (context.Context, interface{}, error, int32) {
+	if err := CheckSession(ctx); err != nil {
+		pErr := err.(*e.Error)
+		return ctx, in, pErr, pErr.ErrorCode
+	}
+	return ctx, in, nil, 0
+}
+
+// CheckSession returns an *errors.Error with the PIT-401 code if the
+// session of the context was not authenticated or its token expired
+func CheckSession(ctx context.Context) error {
+	s, _ := ctx.Value(constants.SessionCtxKey).(*session.Session)
+	if s == nil || !s.HasKey(constants.SessionAuthExpiresAtKey) {
+		return unauthorized(constants.ErrUnauthenticatedSession, "")
+	}
+
+	// the expiration is a float64 if the session data came from another server
+	exp := s.Int64(constants.SessionAuthExpiresAtKey)
+	if f, ok := s.Get(constants.SessionAuthExpiresAtKey).(float64); ok {
+		exp = int64(f)
+	}
+	if exp != 0 && time.Now().Unix() >= exp {
+		return unauthorized(constants.ErrUnauthenticatedSession, jwt.ErrTokenExpired.Error())
+	}
+	return nil
+}
+
+func (a *JWTAuthenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	keys := []*verificationKey{}
+	if a.keySet != nil {
+		keys = a.keySet.get()
+	}
+	if a.secret != nil {
+		keys = append(keys, &verificationKey{key: a.secret})
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	set := jwt.VerificationKeySet{}
+	for _, k := range keys {
+		if (kid == "" || k.kid == "" || k.kid == kid) && k.matchesMethod(token.Method) {
+			set.Keys = append(set.Keys, k.key)
+		}
+	}
+	if len(set.Keys) == 0 {
+		return nil, constants.ErrUnknownJWTKey
+	}
+	return set, nil
+}
+
+func unauthorized(err error, reason string) *e.Error {
+	if reason == "" {
+		return e.NewError(err, e.ErrUnauthorizedCode.Desc, e.ErrUnauthorizedCode.ErrorCode)
+	}
+	return e.NewError(err, e.ErrUnauthorizedCode.Desc, e.ErrUnauthorizedCode.ErrorCode, map[string]string{"reason": reason})
+}
@@ -0,0 +1,243 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/config"
+	"github.com/hnlxhzw/pitaya/constants"
+	e "github.com/hnlxhzw/pitaya/errors"
+	"github.com/hnlxhzw/pitaya/session"
+)
+
+var testSecret = []byte("test-secret")
+
+func newTestAuthenticator(t *testing.T, values map[string]interface{}) *JWTAuthenticator {
+	t.Helper()
+	cfg := viper.New()
+	cfg.Set("pitaya.auth.jwt.secret", string(testSecret))
+	for key, value := range values {
+		cfg.Set(key, value)
+	}
+	a, err := NewJWTAuthenticator(config.NewConfig(cfg))
+	assert.NoError(t, err)
+	return a
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":  "user-1",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+		"role": "admin",
+	}
+}
+
+func assertUnauthorized(t *testing.T, err error, expected error) {
+	t.Helper()
+	pErr, ok := err.(*e.Error)
+	assert.True(t, ok)
+	assert.Equal(t, e.ErrUnauthorizedCode.Desc, pErr.Code)
+	assert.Equal(t, e.ErrUnauthorizedCode.ErrorCode, pErr.ErrorCode)
+	assert.Equal(t, expected.Error(), pErr.Message)
+}
+
+func TestNewJWTAuthenticatorWithoutKeys(t *testing.T) {
+	t.Parallel()
+	a, err := NewJWTAuthenticator(config.NewConfig())
+	assert.Nil(t, a)
+	assert.Equal(t, constants.ErrNoJWTKeys, err)
+}
+
+func TestJWTAuthenticatorVerify(t *testing.T) {
+	t.Parallel()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	writeKeySet(t, path, rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", &ecKey.PublicKey))
+	a := newTestAuthenticator(t, map[string]interface{}{
+		"pitaya.auth.jwt.keysfile": path,
+		"pitaya.auth.jwt.issuer":   "issuer",
+		"pitaya.auth.jwt.claims":   map[string]string{"role": "userRole"},
+	})
+
+	withIssuer := func(claims jwt.MapClaims) jwt.MapClaims {
+		claims["iss"] = "issuer"
+		return claims
+	}
+	expired := withIssuer(validClaims())
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+
+	tables := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"hmac", sign(t, jwt.SigningMethodHS256, testSecret, "", withIssuer(validClaims())), nil},
+		{"rsa", sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", withIssuer(validClaims())), nil},
+		{"rsa_without_kid", sign(t, jwt.SigningMethodRS512, rsaKey, "", withIssuer(validClaims())), nil},
+		{"ec", sign(t, jwt.SigningMethodES256, ecKey, "ec-1", withIssuer(validClaims())), nil},
+		{"missing", "", constants.ErrMissingToken},
+		{"malformed", "not-a-token", constants.ErrInvalidToken},
+		{"wrong_secret", sign(t, jwt.SigningMethodHS256, []byte("other"), "", withIssuer(validClaims())), constants.ErrInvalidToken},
+		{"unknown_signer", sign(t, jwt.SigningMethodRS256, otherKey, "", withIssuer(validClaims())), constants.ErrInvalidToken},
+		{"unknown_kid", sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-2", withIssuer(validClaims())), constants.ErrInvalidToken},
+		{"wrong_kid", sign(t, jwt.SigningMethodRS256, rsaKey, "ec-1", withIssuer(validClaims())), constants.ErrInvalidToken},
+		{"expired", sign(t, jwt.SigningMethodHS256, testSecret, "", expired), constants.ErrInvalidToken},
+		{"wrong_issuer", sign(t, jwt.SigningMethodHS256, testSecret, "", validClaims()), constants.ErrInvalidToken},
+		{"none", sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", withIssuer(validClaims())), constants.ErrInvalidToken},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			identity, err := a.Verify(table.token)
+			if table.err != nil {
+				assert.Nil(t, identity)
+				assertUnauthorized(t, err, table.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", identity.UID)
+			assert.Equal(t, "admin", identity.Data["userRole"])
+			assert.Greater(t, identity.Data[constants.SessionAuthExpiresAtKey], time.Now().Unix())
+		})
+	}
+}
+
+func TestJWTAuthenticatorVerifyWithoutBind(t *testing.T) {
+	t.Parallel()
+	a := newTestAuthenticator(t, map[string]interface{}{"pitaya.auth.jwt.bind": false})
+	identity, err := a.Verify(sign(t, jwt.SigningMethodHS256, testSecret, "", validClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, "", identity.UID)
+	assert.NotContains(t, identity.Data, "role")
+}
+
+func TestJWTAuthenticatorHandshakeValidator(t *testing.T) {
+	t.Parallel()
+	a := newTestAuthenticator(t, map[string]interface{}{
+		"pitaya.auth.jwt.claims": map[string]string{"role": "role"},
+	})
+	ctx := context.Background()
+
+	s := session.New(nil, true)
+	data := &session.HandshakeData{User: map[string]interface{}{
+		"token": sign(t, jwt.SigningMethodHS256, testSecret, "", validClaims()),
+	}}
+	assert.NoError(t, a.HandshakeValidator(ctx, s, data, nil))
+	assert.Equal(t, "user-1", s.UID())
+	assert.Equal(t, "admin", s.String("role"))
+	assert.True(t, s.HasKey(constants.SessionAuthExpiresAtKey))
+
+	s = session.New(nil, true)
+	err := a.HandshakeValidator(ctx, s, &session.HandshakeData{}, nil)
+	assertUnauthorized(t, err, constants.ErrMissingToken)
+	assert.Equal(t, "", s.UID())
+}
+
+func TestJWTAuthenticatorAuthenticateBoundSession(t *testing.T) {
+	t.Parallel()
+	a := newTestAuthenticator(t, map[string]interface{}{
+		"pitaya.auth.jwt.claims": map[string]string{"role": "role"},
+	})
+	ctx := context.Background()
+	token := sign(t, jwt.SigningMethodHS256, testSecret, "", validClaims())
+
+	s := session.New(nil, true, "user-1")
+	assert.NoError(t, a.Authenticate(ctx, s, token))
+	assert.Equal(t, "user-1", s.UID())
+	assert.Equal(t, "admin", s.String("role"))
+
+	s = session.New(nil, true, "user-2")
+	err := a.Authenticate(ctx, s, token)
+	assertUnauthorized(t, err, constants.ErrInvalidToken)
+	assert.Equal(t, "user-2", s.UID())
+	assert.False(t, s.HasKey("role"))
+	assert.False(t, s.HasKey(constants.SessionAuthExpiresAtKey))
+}
+
+func TestJWTAuthenticatorBeforeHandler(t *testing.T) {
+	t.Parallel()
+	a := newTestAuthenticator(t, nil)
+
+	authenticated := session.New(nil, false)
+	assert.NoError(t, authenticated.Set(constants.SessionAuthExpiresAtKey, time.Now().Add(time.Hour).Unix()))
+	remote := session.New(nil, false)
+	assert.NoError(t, remote.Set(constants.SessionAuthExpiresAtKey, float64(time.Now().Add(time.Hour).Unix())))
+	expired := session.New(nil, false)
+	assert.NoError(t, expired.Set(constants.SessionAuthExpiresAtKey, time.Now().Add(-time.Second).Unix()))
+
+	tables := []struct {
+		name    string
+		session *session.Session
+		err     bool
+	}{
+		{"authenticated", authenticated, false},
+		{"remote", remote, false},
+		{"expired", expired, true},
+		{"unauthenticated", session.New(nil, false), true},
+		{"no_session", nil, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			ctx := context.Background()
+			if table.session != nil {
+				ctx = context.WithValue(ctx, constants.SessionCtxKey, table.session)
+			}
+			_, out, err, code := a.BeforeHandler(ctx, "in")
+			assert.Equal(t, "in", out)
+			if table.err {
+				assertUnauthorized(t, err, constants.ErrUnauthenticatedSession)
+				assert.Equal(t, e.ErrUnauthorizedCode.ErrorCode, code)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, int32(0), code)
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/logger"
+)
+
+// verificationKey is a key used to verify the signature of tokens, either
+// a []byte HMAC secret, an *rsa.PublicKey or an *ecdsa.PublicKey
+type verificationKey struct {
+	kid string
+	key interface{}
+}
+
+// jsonWebKey is a key of a JSON Web Key Set, as defined by RFC 7517
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet holds the keys of a JSON Web Key Set file, reloading them when the
+// file is modified so keys can be rotated without restarting the server
+type keySet struct {
+	path           string
+	reloadInterval time.Duration
+
+	mutex     sync.RWMutex
+	keys      []*verificationKey
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func newKeySet(path string, reloadInterval time.Duration) (*keySet, error) {
+	ks := &keySet{
+		path:           path,
+		reloadInterval: reloadInterval,
+	}
+	if err := ks.reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// get returns the keys, reloading the file first if it was modified since
+// the last check and the reload interval has passed
+func (ks *keySet) get() []*verificationKey {
+	ks.mutex.RLock()
+	keys, checkedAt := ks.keys, ks.checkedAt
+	ks.mutex.RUnlock()
+
+	if ks.reloadInterval > 0 && time.Since(checkedAt) >= ks.reloadInterval {
+		if err := ks.reload(); err != nil {
+			logger.Log.Errorf("failed to reload jwt keys file %s, keeping the current keys: %s", ks.path, err.Error())
+		} else {
+			ks.mutex.RLock()
+			keys = ks.keys
+			ks.mutex.RUnlock()
+		}
+	}
+	return keys
+}
+
+func (ks *keySet) reload() error {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	ks.checkedAt = time.Now()
+
+	info, err := os.Stat(ks.path)
+	if err != nil {
+		return err
+	}
+	if ks.keys != nil && info.ModTime().Equal(ks.modTime) {
+		return nil
+	}
+
+	bts, err := os.ReadFile(ks.path)
+	if err != nil {
+		return err
+	}
+	keys, err := parseKeySet(bts)
+	if err != nil {
+		return err
+	}
+	ks.keys = keys
+	ks.modTime = info.ModTime()
+	logger.Log.Infof("loaded %d jwt keys from %s", len(keys), ks.path)
+	return nil
+}
+
+func parseKeySet(bts []byte) ([]*verificationKey, error) {
+	set := struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}{}
+	if err := json.Unmarshal(bts, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make([]*verificationKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.verificationKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: %w", jwk.Kid, err)
+		}
+		keys = append(keys, &verificationKey{kid: jwk.Kid, key: key})
+	}
+	return keys, nil
+}
+
+func (jwk *jsonWebKey) verificationKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "oct":
+		return decodeKeyParam(jwk.K)
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, constants.ErrUnsupportedJWK
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, constants.ErrUnsupportedJWK
+}
+
+func decodeKeyParam(param string) ([]byte, error) {
+	if param == "" {
+		return nil, constants.ErrUnsupportedJWK
+	}
+	return base64.RawURLEncoding.DecodeString(param)
+}
+
+func decodeBigInt(param string) (*big.Int, error) {
+	bts, err := decodeKeyParam(param)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bts), nil
+}
+
+// matchesMethod returns true if the key can verify tokens signed with method
+func (k *verificationKey) matchesMethod(method jwt.SigningMethod) bool {
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		_, ok := k.key.([]byte)
+		return ok
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok := k.key.(*rsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodECDSA:
+		_, ok := k.key.(*ecdsa.PublicKey)
+		return ok
+	}
+	return false
+}
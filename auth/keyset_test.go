@@ -0,0 +1,154 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+func b64(bts []byte) string {
+	return base64.RawURLEncoding.EncodeToString(bts)
+}
+
+func hmacJWK(kid string, secret []byte) map[string]string {
+	return map[string]string{"kty": "oct", "kid": kid, "use": "sig", "k": b64(secret)}
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"n":   b64(key.N.Bytes()),
+		"e":   b64(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": key.Curve.Params().Name,
+		"x":   b64(key.X.Bytes()),
+		"y":   b64(key.Y.Bytes()),
+	}
+}
+
+func writeKeySet(t *testing.T, path string, keys ...map[string]string) {
+	t.Helper()
+	bts, err := json.Marshal(map[string]interface{}{"keys": keys})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, bts, 0644))
+}
+
+func TestParseKeySet(t *testing.T) {
+	t.Parallel()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+
+	bts, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		hmacJWK("hmac", []byte("secret")),
+		rsaJWK("rsa", &rsaKey.PublicKey),
+		ecJWK("ec", &ecKey.PublicKey),
+		{"kty": "RSA", "kid": "enc", "use": "enc"},
+	}})
+	assert.NoError(t, err)
+
+	keys, err := parseKeySet(bts)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 3)
+	assert.Equal(t, "hmac", keys[0].kid)
+	assert.Equal(t, []byte("secret"), keys[0].key)
+	assert.Equal(t, "rsa", keys[1].kid)
+	assert.True(t, rsaKey.PublicKey.Equal(keys[1].key))
+	assert.Equal(t, "ec", keys[2].kid)
+	assert.True(t, ecKey.PublicKey.Equal(keys[2].key))
+}
+
+func TestParseKeySetErrors(t *testing.T) {
+	t.Parallel()
+	tables := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"unsupported_kty", `{"keys":[{"kty":"OKP","kid":"a"}]}`, constants.ErrUnsupportedJWK},
+		{"unsupported_curve", `{"keys":[{"kty":"EC","crv":"P-224","x":"AQ","y":"AQ"}]}`, constants.ErrUnsupportedJWK},
+		{"missing_secret", `{"keys":[{"kty":"oct"}]}`, constants.ErrUnsupportedJWK},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			_, err := parseKeySet([]byte(table.data))
+			assert.ErrorIs(t, err, table.err)
+		})
+	}
+
+	_, err := parseKeySet([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestKeySetRotation(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	writeKeySet(t, path, hmacJWK("v1", []byte("secret-v1")))
+
+	ks, err := newKeySet(path, time.Millisecond)
+	assert.NoError(t, err)
+	keys := ks.get()
+	assert.Len(t, keys, 1)
+	assert.Equal(t, "v1", keys[0].kid)
+
+	writeKeySet(t, path, hmacJWK("v1", []byte("secret-v1")), hmacJWK("v2", []byte("secret-v2")))
+	modTime := time.Now().Add(time.Second)
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	time.Sleep(2 * time.Millisecond)
+	keys = ks.get()
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "v2", keys[1].kid)
+
+	// a broken file keeps the current keys
+	assert.NoError(t, os.WriteFile(path, []byte("broken"), 0644))
+	modTime = modTime.Add(time.Second)
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	time.Sleep(2 * time.Millisecond)
+	assert.Len(t, ks.get(), 2)
+}
+
+func TestNewKeySetMissingFile(t *testing.T) {
+	t.Parallel()
+	_, err := newKeySet(filepath.Join(t.TempDir(), "missing.json"), time.Minute)
+	assert.Error(t, err)
+}
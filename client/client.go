@@ -22,6 +22,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -41,27 +42,64 @@ import (
 	"github.com/hnlxhzw/pitaya/conn/codec"
 	"github.com/hnlxhzw/pitaya/conn/message"
 	"github.com/hnlxhzw/pitaya/conn/packet"
+	"github.com/hnlxhzw/pitaya/constants"
+	e "github.com/hnlxhzw/pitaya/errors"
 	"github.com/hnlxhzw/pitaya/logger"
+	"github.com/hnlxhzw/pitaya/protos"
+	"github.com/hnlxhzw/pitaya/serialize"
+	jsonserializer "github.com/hnlxhzw/pitaya/serialize/json"
+	"github.com/hnlxhzw/pitaya/serialize/jsonpb"
+	"github.com/hnlxhzw/pitaya/serialize/msgpack"
+	"github.com/hnlxhzw/pitaya/serialize/protobuf"
 	"github.com/hnlxhzw/pitaya/session"
+	"github.com/hnlxhzw/pitaya/util"
 	"github.com/hnlxhzw/pitaya/util/compression"
 )
 
 // HandshakeSys struct
 type HandshakeSys struct {
-	Dict       map[string]uint16 `json:"dict"`
-	Heartbeat  int               `json:"heartbeat"`
-	Serializer string            `json:"serializer"`
+	Dict        map[string]uint16 `json:"dict"`
+	DictVersion string            `json:"dictVersion"`
+	Heartbeat   int               `json:"heartbeat"`
+	Serializer  string            `json:"serializer"`
 }
 
 // HandshakeData struct
 type HandshakeData struct {
-	Code int          `json:"code"`
-	Sys  HandshakeSys `json:"sys"`
+	Code     int               `json:"code"`
+	Message  string            `json:"message,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Sys      HandshakeSys      `json:"sys"`
+}
+
+// HandshakeError is returned when the server rejects the handshake
+type HandshakeError struct {
+	Code     int
+	Message  string
+	Metadata map[string]string
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("handshake rejected with code %d: %s", e.Code, e.Message)
+}
+
+// DefaultInflightWindow is the default limit of requests waiting for a response
+const DefaultInflightWindow = 30
+
+// PushHandler handles the pushes of a route, its data can be decoded with Decode
+type PushHandler func(data []byte)
+
+type requestResult struct {
+	data []byte
+	err  error
 }
 
 type pendingRequest struct {
 	msg    *message.Message
 	sentAt time.Time
+	// result receives the response of requests made with Request,
+	// instead of the incoming message channel
+	result chan *requestResult
 }
 
 // Client struct
@@ -77,10 +115,19 @@ type Client struct {
 	pendingReqMutex     sync.Mutex
 	requestTimeout      time.Duration
 	closeChan           chan struct{}
+	connMutex           sync.Mutex
+	dial                func(addr string) (net.Conn, error)
+	addr                string
 	nextID              uint32
 	messageEncoder      message.Encoder
 	clientHandshakeData *session.HandshakeData
 	writeRW             sync.RWMutex
+	serializer          serialize.Serializer
+	pushHandlers        map[string]PushHandler
+	pushHandlersMutex   sync.RWMutex
+	reconnectConfig     *ReconnectConfig
+	stopReconnect       chan struct{}
+	events              chan ConnectionEvent
 }
 
 // MsgChannel return the incoming message channel
@@ -113,10 +160,11 @@ func New(logLevel logrus.Level, requestTimeout ...time.Duration) *Client {
 		packetChan:      make(chan *packet.Packet, 10),
 		pendingRequests: make(map[uint]*pendingRequest),
 		requestTimeout:  reqTimeout,
-		// 30 here is the limit of inflight messages
-		// TODO this should probably be configurable
-		pendingChan:    make(chan bool, 30),
-		messageEncoder: message.NewMessagesEncoder(false),
+		pendingChan:     make(chan bool, DefaultInflightWindow),
+		messageEncoder:  message.NewMessagesEncoder(false),
+		serializer:      jsonserializer.NewSerializer(),
+		pushHandlers:    make(map[string]PushHandler),
+		events:          make(chan ConnectionEvent, eventsBufferSize),
 		clientHandshakeData: &session.HandshakeData{
 			Sys: session.HandshakeClientData{
 				Platform:    "mac",
@@ -131,13 +179,29 @@ func New(logLevel logrus.Level, requestTimeout ...time.Duration) *Client {
 	}
 }
 
+// SetInflightWindow sets the limit of requests waiting for a response, sending
+// more requests blocks until responses arrive. It must be called before connecting
+func (c *Client) SetInflightWindow(size int) {
+	c.pendingChan = make(chan bool, size)
+}
+
 // SetClientHandshakeData sets the data to send inside handshake
 func (c *Client) SetClientHandshakeData(data *session.HandshakeData) {
 	c.clientHandshakeData = data
 }
 
-func (c *Client) sendHandshakeRequest() error {
-	enc, err := json.Marshal(c.clientHandshakeData)
+// sendHandshakeRequest sends the client handshake data, with the version of
+// the route dictionary received before so the server does not send it again
+func (c *Client) sendHandshakeRequest(resumeToken string) error {
+	handshakeData := *c.clientHandshakeData
+	if resumeToken != "" {
+		handshakeData.Sys.ResumeToken = resumeToken
+	}
+	if version := message.DictionaryVersion(); version != "" {
+		handshakeData.Sys.DictVersion = version
+	}
+
+	enc, err := json.Marshal(&handshakeData)
 	if err != nil {
 		return err
 	}
@@ -153,9 +217,13 @@ func (c *Client) sendHandshakeRequest() error {
 
 func (c *Client) handleHandshakeResponse() error {
 	buf := bytes.NewBuffer(nil)
-	packets, err := c.readPackets(buf)
-	if err != nil {
-		return err
+	packets := []*packet.Packet{}
+	for len(packets) == 0 {
+		var err error
+		packets, err = c.readPackets(c.conn, buf)
+		if err != nil {
+			return err
+		}
 	}
 
 	handshakePacket := packets[0]
@@ -164,6 +232,7 @@ func (c *Client) handleHandshakeResponse() error {
 	}
 
 	handshake := &HandshakeData{}
+	var err error
 	if compression.IsCompressed(handshakePacket.Data) {
 		handshakePacket.Data, err = compression.InflateData(handshakePacket.Data)
 		if err != nil {
@@ -178,9 +247,22 @@ func (c *Client) handleHandshakeResponse() error {
 
 	logger.Log.Debug("got handshake from sv, data: %v", handshake)
 
+	if handshake.Code != 0 && handshake.Code != 200 {
+		return &HandshakeError{
+			Code:     handshake.Code,
+			Message:  handshake.Message,
+			Metadata: handshake.Metadata,
+		}
+	}
+
 	if handshake.Sys.Dict != nil {
+		// a dictionary with another version replaces the one received before
+		if handshake.Sys.DictVersion != "" && handshake.Sys.DictVersion != message.DictionaryVersion() {
+			message.ClearDictionary()
+		}
 		message.SetDictionary(handshake.Sys.Dict)
 	}
+	c.setSerializer(handshake.Sys.Serializer)
 	p, err := c.packetEncoder.Encode(packet.HandshakeAck, []byte{})
 	if err != nil {
 		return err
@@ -191,17 +273,18 @@ func (c *Client) handleHandshakeResponse() error {
 	}
 
 	c.Connected = true
+	c.emit(ConnectionEvent{Type: EventConnected})
 
-	go c.sendHeartbeats(handshake.Sys.Heartbeat)
-	go c.handleServerMessages()
-	go c.handlePackets()
-	go c.pendingRequestsReaper()
+	go c.sendHeartbeats(handshake.Sys.Heartbeat, c.closeChan)
+	go c.handleServerMessages(c.conn, c.closeChan)
+	go c.handlePackets(c.closeChan)
+	go c.pendingRequestsReaper(c.closeChan)
 
 	return nil
 }
 
 // pendingRequestsReaper delete timedout requests
-func (c *Client) pendingRequestsReaper() {
+func (c *Client) pendingRequestsReaper(closeChan chan struct{}) {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 	for {
@@ -215,6 +298,14 @@ func (c *Client) pendingRequestsReaper() {
 				}
 			}
 			for _, pendingReq := range toDelete {
+				delete(c.pendingRequests, pendingReq.msg.ID)
+				<-c.pendingChan
+				if pendingReq.result != nil {
+					pendingReq.result <- &requestResult{
+						err: e.NewError(constants.ErrRequestTimeout, "PIT-504", 504),
+					}
+					continue
+				}
 				err := pitaya.Error(errors.New("request timeout"), "PIT-504")
 				errMarshalled, _ := json.Marshal(err)
 				// send a timeout to incoming msg chan
@@ -225,18 +316,16 @@ func (c *Client) pendingRequestsReaper() {
 					Data:  errMarshalled,
 					Err:   true,
 				}
-				delete(c.pendingRequests, pendingReq.msg.ID)
-				<-c.pendingChan
 				c.IncomingMsgChan <- m
 			}
 			c.pendingReqMutex.Unlock()
-		case <-c.closeChan:
+		case <-closeChan:
 			return
 		}
 	}
 }
 
-func (c *Client) handlePackets() {
+func (c *Client) handlePackets(closeChan chan struct{}) {
 	for {
 		select {
 		case p := <-c.packetChan:
@@ -249,45 +338,52 @@ func (c *Client) handlePackets() {
 					logger.Log.Errorf("error decoding msg from sv: %s", string(m.Data))
 				}
 				if m.Type == message.Response {
-					c.pendingReqMutex.Lock()
-					if _, ok := c.pendingRequests[m.ID]; ok {
-						delete(c.pendingRequests, m.ID)
-						<-c.pendingChan
-					} else {
-						c.pendingReqMutex.Unlock()
+					pendingReq := c.removePendingRequest(m.ID)
+					if pendingReq == nil {
 						continue // do not process msg for already timedout request
 					}
-					c.pendingReqMutex.Unlock()
+					if pendingReq.result != nil {
+						pendingReq.result <- c.requestResult(pendingReq.msg.Route, m)
+						continue
+					}
+				}
+				if m.Type == message.Push && m.Route == constants.RedirectRoute {
+					redirect := &session.Redirect{}
+					if err := json.Unmarshal(m.Data, redirect); err != nil {
+						logger.Log.Errorf("error decoding redirect from sv: %s", err.Error())
+						continue
+					}
+					go c.followRedirect(redirect, closeChan)
+					continue
+				}
+				if m.Type == message.Push {
+					c.pushHandlersMutex.RLock()
+					handler, ok := c.pushHandlers[m.Route]
+					c.pushHandlersMutex.RUnlock()
+					if ok {
+						handler(m.Data)
+						continue
+					}
 				}
 				c.IncomingMsgChan <- m
 			case packet.Kick:
 				logger.Log.Warn("got kick packet from the server! disconnecting...")
 				c.Disconnect()
 			}
-		case <-c.closeChan:
+		case <-closeChan:
 			return
 		}
 	}
 }
 
-func (c *Client) readPackets(buf *bytes.Buffer) ([]*packet.Packet, error) {
-	// listen for sv messages
+func (c *Client) readPackets(conn net.Conn, buf *bytes.Buffer) ([]*packet.Packet, error) {
+	// a single read, callers read again while no whole packet has arrived
 	data := make([]byte, 2048)
-	//n := len(data)
-	var err error
-
-	//OuterLoop:
-	for {
-		n, err := c.conn.Read(data)
-		if err == io.EOF {
-			buf.Write(data[:n])
-			break
-		} else if err != nil {
-			return nil, err
-		}
-
-		buf.Write(data[:n])
+	n, err := conn.Read(data)
+	if err != nil && (err != io.EOF || n == 0) {
+		return nil, err
 	}
+	buf.Write(data[:n])
 
 	packets, err := c.packetDecoder.Decode(buf.Bytes())
 	if err != nil {
@@ -302,14 +398,24 @@ func (c *Client) readPackets(buf *bytes.Buffer) ([]*packet.Packet, error) {
 	return packets, nil
 }
 
-func (c *Client) handleServerMessages() {
+func (c *Client) handleServerMessages(conn net.Conn, closeChan chan struct{}) {
 	buf := bytes.NewBuffer(nil)
-	defer c.Disconnect()
-	for c.Connected {
-		packets, err := c.readPackets(buf)
-		if err != nil && c.Connected {
-			logger.Log.Error(err)
-			break
+	for {
+		select {
+		case <-closeChan:
+			return
+		default:
+		}
+
+		packets, err := c.readPackets(conn, buf)
+		if err != nil {
+			select {
+			case <-closeChan:
+			default:
+				logger.Log.Error(err)
+				c.connectionLost(closeChan, err)
+			}
+			return
 		}
 
 		for _, p := range packets {
@@ -318,12 +424,9 @@ func (c *Client) handleServerMessages() {
 	}
 }
 
-func (c *Client) sendHeartbeats(interval int) {
+func (c *Client) sendHeartbeats(interval int, closeChan chan struct{}) {
 	t := time.NewTicker(time.Duration(interval) * time.Second)
-	defer func() {
-		t.Stop()
-		c.Disconnect()
-	}()
+	defer t.Stop()
 	for {
 		select {
 		case <-t.C:
@@ -331,83 +434,153 @@ func (c *Client) sendHeartbeats(interval int) {
 			_, err := c.write(p)
 			if err != nil {
 				logger.Log.Errorf("error sending heartbeat to server: %s", err.Error())
+				c.connectionLost(closeChan, err)
 				return
 			}
-		case <-c.closeChan:
+		case <-closeChan:
 			return
 		}
 	}
 }
 
-// Disconnect disconnects the client
+// Disconnect disconnects the client, stopping any reconnection in progress
 func (c *Client) Disconnect() {
-	if c.Connected {
+	c.connMutex.Lock()
+	if c.stopReconnect != nil {
+		close(c.stopReconnect)
+		c.stopReconnect = nil
+	}
+	closeChan := c.closeChan
+	c.connMutex.Unlock()
+
+	if c.disconnect(closeChan) {
+		c.emit(ConnectionEvent{Type: EventDisconnected})
+	}
+	c.failPendingRequests(constants.ErrConnectionClosed)
+}
+
+// disconnect only closes the connection that owns closeChan, so goroutines
+// of a connection that was already replaced by a redirect don't close the new one.
+// It returns false if the connection was already closed
+func (c *Client) disconnect(closeChan chan struct{}) bool {
+	c.connMutex.Lock()
+	defer c.connMutex.Unlock()
+	if c.Connected && c.closeChan == closeChan {
 		c.Connected = false
 		close(c.closeChan)
 		c.conn.Close()
+		return true
+	}
+	return false
+}
+
+// connectionLost closes the connection that owns closeChan after a read or
+// write error, reconnecting if reconnection is enabled
+func (c *Client) connectionLost(closeChan chan struct{}, err error) {
+	if !c.disconnect(closeChan) {
+		return
+	}
+	c.emit(ConnectionEvent{Type: EventDisconnected, Err: err})
+
+	if c.reconnectConfig == nil {
+		c.failPendingRequests(constants.ErrConnectionClosed)
+		return
+	}
+	if c.reconnectConfig.PendingRequests == FailPendingRequests {
+		c.failPendingRequests(constants.ErrConnectionClosed)
+	}
+	c.connMutex.Lock()
+	stop := make(chan struct{})
+	c.stopReconnect = stop
+	c.connMutex.Unlock()
+	go c.reconnect(stop)
+}
+
+// followRedirect reconnects to the frontend the session was handed off to,
+// sending the resume token in the handshake so the session is restored
+func (c *Client) followRedirect(redirect *session.Redirect, closeChan chan struct{}) {
+	logger.Log.Debugf("redirected by the server to %s, reconnecting...", redirect.Address)
+	if c.disconnect(closeChan) {
+		c.emit(ConnectionEvent{Type: EventDisconnected})
+	}
+	c.failPendingRequests(constants.ErrConnectionClosed)
+
+	c.addr = redirect.Address
+	conn, err := c.dial(redirect.Address)
+	if err != nil {
+		logger.Log.Errorf("error connecting to redirect address %s: %s", redirect.Address, err.Error())
+		return
+	}
+
+	if err := c.connect(conn, redirect.ResumeToken); err != nil {
+		logger.Log.Errorf("error resuming session at %s: %s", redirect.Address, err.Error())
+		c.emit(ConnectionEvent{Type: EventGaveUp, Err: err})
 	}
 }
 
 // ConnectTo connects to the server at addr, for now the only supported protocol is tcp
 // if tlsConfig is sent, it connects using TLS
 func (c *Client) ConnectTo(addr string, tlsConfig ...*tls.Config) error {
-	var conn net.Conn
-	var err error
-	if len(tlsConfig) > 0 {
-		conn, err = tls.Dial("tcp", addr, tlsConfig[0])
-	} else {
-		conn, err = net.Dial("tcp", addr)
+	dial := func(addr string) (net.Conn, error) {
+		if len(tlsConfig) > 0 {
+			return tls.Dial("tcp", addr, tlsConfig[0])
+		}
+		return net.Dial("tcp", addr)
 	}
+
+	conn, err := dial(addr)
 	if err != nil {
 		return err
 	}
-	c.conn = conn
+	c.dial = dial
+	c.addr = addr
 	c.IncomingMsgChan = make(chan *message.Message, 10)
 
-	if err = c.handleHandshake(); err != nil {
-		return err
-	}
-
-	c.closeChan = make(chan struct{})
-
-	return nil
+	return c.connect(conn, "")
 }
 
 // ConnectToWS connects using webshocket protocol
 func (c *Client) ConnectToWS(addr string, path string, tlsConfig ...*tls.Config) error {
-	u := url.URL{Scheme: "ws", Host: addr, Path: path}
-	dialer := websocket.DefaultDialer
-	dialer.WriteBufferSize = 40960
-	dialer.ReadBufferSize = 40960
-
-	if len(tlsConfig) > 0 {
-		dialer.TLSClientConfig = tlsConfig[0]
-		u.Scheme = "wss"
-	}
+	dial := func(addr string) (net.Conn, error) {
+		u := url.URL{Scheme: "ws", Host: addr, Path: path}
+		dialer := websocket.DefaultDialer
+		dialer.WriteBufferSize = 40960
+		dialer.ReadBufferSize = 40960
+
+		if len(tlsConfig) > 0 {
+			dialer.TLSClientConfig = tlsConfig[0]
+			u.Scheme = "wss"
+		}
 
-	conn, _, err := dialer.Dial(u.String(), nil)
-	if err != nil {
-		return err
+		conn, _, err := dialer.Dial(u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		return acceptor.NewWSConn(conn)
 	}
 
-	c.conn, err = acceptor.NewWSConn(conn)
+	conn, err := dial(addr)
 	if err != nil {
 		return err
 	}
-
+	c.dial = dial
+	c.addr = addr
 	c.IncomingMsgChan = make(chan *message.Message, 10)
 
-	if err = c.handleHandshake(); err != nil {
-		return err
-	}
+	return c.connect(conn, "")
+}
 
+func (c *Client) connect(conn net.Conn, resumeToken string) error {
+	c.connMutex.Lock()
+	c.conn = conn
 	c.closeChan = make(chan struct{})
+	c.connMutex.Unlock()
 
-	return nil
+	return c.handleHandshake(resumeToken)
 }
 
-func (c *Client) handleHandshake() error {
-	if err := c.sendHandshakeRequest(); err != nil {
+func (c *Client) handleHandshake(resumeToken string) error {
+	if err := c.sendHandshakeRequest(resumeToken); err != nil {
 		return err
 	}
 
@@ -441,8 +614,140 @@ func (c *Client) buildPacket(msg message.Message) ([]byte, error) {
 	return p, nil
 }
 
+// Request sends a request to the server and waits for its response, or for
+// the context to be done. The request is serialized with the serializer
+// negotiated in the handshake, unless it is a []byte, and the response is
+// decoded into resp with Decode. Errors answered by the server and timeouts
+// are returned as *errors.Error
+func (c *Client) Request(ctx context.Context, route string, req interface{}, resp interface{}) error {
+	if !c.Connected {
+		return constants.ErrClientNotConnected
+	}
+
+	data, err := util.SerializeOrRaw(c.serializer, req)
+	if err != nil {
+		return err
+	}
+
+	result := make(chan *requestResult, 1)
+	id, err := c.sendMsgWithContext(ctx, message.Request, route, data, result)
+	if err != nil {
+		c.removePendingRequest(id)
+		return err
+	}
+
+	select {
+	case res := <-result:
+		if res.err != nil {
+			return res.err
+		}
+		if resp == nil {
+			return nil
+		}
+		return c.Decode(res.data, resp)
+	case <-ctx.Done():
+		c.removePendingRequest(id)
+		return ctx.Err()
+	}
+}
+
+// OnPush sets the handler of the pushes of route, which are not sent to the
+// message channel anymore. Handlers are called in the order pushes arrive and
+// must not block. A nil handler removes the route handler
+func (c *Client) OnPush(route string, handler PushHandler) {
+	c.pushHandlersMutex.Lock()
+	defer c.pushHandlersMutex.Unlock()
+	if handler == nil {
+		delete(c.pushHandlers, route)
+		return
+	}
+	c.pushHandlers[route] = handler
+}
+
+// Decode decodes data received from the server into v with the serializer
+// negotiated in the handshake, if v is a *[]byte the raw data is set
+func (c *Client) Decode(data []byte, v interface{}) error {
+	if raw, ok := v.(*[]byte); ok {
+		*raw = data
+		return nil
+	}
+	return c.serializer.Unmarshal(data, v)
+}
+
+func (c *Client) setSerializer(name string) {
+	switch name {
+	case "", "json":
+		c.serializer = jsonserializer.NewSerializer()
+	case "protobuf":
+		c.serializer = protobuf.NewSerializer()
+	case "msgpack":
+		c.serializer = msgpack.NewSerializer()
+	case "jsonpb":
+		c.serializer = jsonpb.NewSerializer()
+	default:
+		logger.Log.Warnf("unknown serializer %s negotiated with the server, using json", name)
+		c.serializer = jsonserializer.NewSerializer()
+	}
+}
+
+func (c *Client) requestResult(route string, m *message.Message) *requestResult {
+	if !m.Err {
+		return &requestResult{data: m.Data}
+	}
+	clientErr := &protos.ClientError{ErrorCode: e.ErrUnknownCode.ErrorCode}
+	if err := c.serializer.Unmarshal(m.Data, clientErr); err != nil {
+		logger.Log.Errorf("error decoding error response from sv: %s", err.Error())
+	}
+	return &requestResult{
+		err: &e.Error{
+			Code:      fmt.Sprintf("PIT-%d", clientErr.ErrorCode),
+			Message:   fmt.Sprintf("request to %s failed with error code %d", route, clientErr.ErrorCode),
+			ErrorCode: clientErr.ErrorCode,
+		},
+	}
+}
+
+// removePendingRequest removes the request from the pending ones, freeing
+// its place in the inflight window, it returns nil if it was not pending
+func (c *Client) removePendingRequest(id uint) *pendingRequest {
+	c.pendingReqMutex.Lock()
+	defer c.pendingReqMutex.Unlock()
+	pendingReq, ok := c.pendingRequests[id]
+	if !ok {
+		return nil
+	}
+	delete(c.pendingRequests, id)
+	<-c.pendingChan
+	return pendingReq
+}
+
+// failPendingRequests answers the requests made with Request with err, the
+// other pending requests are kept so they can still time out
+func (c *Client) failPendingRequests(err error) {
+	c.pendingReqMutex.Lock()
+	defer c.pendingReqMutex.Unlock()
+	for id, pendingReq := range c.pendingRequests {
+		if pendingReq.result == nil {
+			continue
+		}
+		delete(c.pendingRequests, id)
+		<-c.pendingChan
+		pendingReq.result <- &requestResult{err: err}
+	}
+}
+
 // sendMsg sends the request to the server
 func (c *Client) sendMsg(msgType message.Type, route string, data []byte) (uint, error) {
+	return c.sendMsgWithContext(context.Background(), msgType, route, data, nil)
+}
+
+func (c *Client) sendMsgWithContext(
+	ctx context.Context,
+	msgType message.Type,
+	route string,
+	data []byte,
+	result chan *requestResult,
+) (uint, error) {
 	// TODO mount msg and encode
 	m := message.Message{
 		Type:  msgType,
@@ -453,12 +758,17 @@ func (c *Client) sendMsg(msgType message.Type, route string, data []byte) (uint,
 	}
 	p, err := c.buildPacket(m)
 	if msgType == message.Request {
-		c.pendingChan <- true
+		select {
+		case c.pendingChan <- true:
+		case <-ctx.Done():
+			return m.ID, ctx.Err()
+		}
 		c.pendingReqMutex.Lock()
 		if _, ok := c.pendingRequests[m.ID]; !ok {
 			c.pendingRequests[m.ID] = &pendingRequest{
 				msg:    &m,
 				sentAt: time.Now(),
+				result: result,
 			}
 		}
 		c.pendingReqMutex.Unlock()
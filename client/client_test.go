@@ -1,15 +1,24 @@
 package client
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/conn/codec"
 	"github.com/hnlxhzw/pitaya/conn/message"
+	"github.com/hnlxhzw/pitaya/conn/packet"
+	"github.com/hnlxhzw/pitaya/constants"
+	e "github.com/hnlxhzw/pitaya/errors"
 	"github.com/hnlxhzw/pitaya/helpers"
 	"github.com/hnlxhzw/pitaya/mocks"
+	"github.com/hnlxhzw/pitaya/session"
 )
 
 func TestSendRequestShouldTimeout(t *testing.T) {
@@ -19,7 +28,7 @@ func TestSendRequestShouldTimeout(t *testing.T) {
 
 	mockConn := mocks.NewMockPlayerConn(ctrl)
 	c.conn = mockConn
-	go c.pendingRequestsReaper()
+	go c.pendingRequestsReaper(c.closeChan)
 
 	route := "com.sometest.route"
 	data := []byte{0x02, 0x03, 0x04}
@@ -46,3 +55,277 @@ func TestSendRequestShouldTimeout(t *testing.T) {
 
 	assert.Equal(t, true, msg.Err)
 }
+
+func TestHandleHandshakeResponseRejected(t *testing.T) {
+	c := New(logrus.InfoLevel)
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	c.conn = clientConn
+
+	p, err := codec.NewPomeloPacketEncoder().Encode(packet.Handshake, []byte(`{"code":401,"message":"invalid token","metadata":{"reason":"expired"}}`))
+	assert.NoError(t, err)
+	go func() {
+		serverConn.Write(p)
+		serverConn.Close()
+	}()
+
+	err = c.handleHandshakeResponse()
+	assert.Equal(t, &HandshakeError{
+		Code:     401,
+		Message:  "invalid token",
+		Metadata: map[string]string{"reason": "expired"},
+	}, err)
+	assert.False(t, c.Connected)
+}
+
+func TestHandshakeDictionary(t *testing.T) {
+	defer message.ClearDictionary()
+	message.ClearDictionary()
+	assert.NoError(t, message.SetDictionary(map[string]uint16{"old.route": 1}))
+	oldVersion := message.DictionaryVersion()
+
+	c := New(logrus.InfoLevel)
+	clientConn, serverConn := net.Pipe()
+	requests := make(chan *session.HandshakeData, 1)
+	go func() {
+		data := make([]byte, 2048)
+		n, err := serverConn.Read(data)
+		assert.NoError(t, err)
+		packets, err := codec.NewPomeloPacketDecoder().Decode(data[:n])
+		assert.NoError(t, err)
+		handshake := &session.HandshakeData{}
+		assert.NoError(t, json.Unmarshal(packets[0].Data, handshake))
+		requests <- handshake
+
+		p, err := codec.NewPomeloPacketEncoder().Encode(packet.Handshake,
+			[]byte(`{"code":200,"sys":{"heartbeat":60,"dict":{"new.route":1},"dictVersion":"v2","serializer":"json"}}`))
+		assert.NoError(t, err)
+		serverConn.Write(p)
+		for {
+			if _, err := serverConn.Read(data); err != nil {
+				return
+			}
+		}
+	}()
+
+	assert.NoError(t, c.connect(clientConn, ""))
+	defer c.Disconnect()
+
+	// the version of the dictionary received before is sent
+	request := helpers.ShouldEventuallyReceive(t, requests).(*session.HandshakeData)
+	assert.Equal(t, oldVersion, request.Sys.DictVersion)
+	// a dictionary of another version replaces it
+	assert.Equal(t, map[string]uint16{"new.route": 1}, message.GetDictionary())
+}
+
+// serveTestConn acts as a frontend on conn, answering the handshake and
+// writing the messages returned by handle for each message received
+func serveTestConn(t *testing.T, conn net.Conn, handle func(m *message.Message) []*message.Message) {
+	decoder := codec.NewPomeloPacketDecoder()
+	encoder := codec.NewPomeloPacketEncoder()
+	msgEncoder := message.NewMessagesEncoder(false)
+	buf := bytes.NewBuffer(nil)
+	data := make([]byte, 2048)
+	for {
+		n, err := conn.Read(data)
+		if err != nil {
+			return
+		}
+		buf.Write(data[:n])
+		packets, err := decoder.Decode(buf.Bytes())
+		assert.NoError(t, err)
+		for _, p := range packets {
+			buf.Next(codec.HeadLength + p.Length)
+			var out []byte
+			switch p.Type {
+			case packet.Handshake:
+				out, err = encoder.Encode(packet.Handshake, []byte(`{"code":200,"sys":{"heartbeat":60,"serializer":"json"}}`))
+				assert.NoError(t, err)
+			case packet.Data:
+				m, err := message.Decode(p.Data)
+				assert.NoError(t, err)
+				for _, res := range handle(m) {
+					encMsg, err := msgEncoder.Encode(res)
+					assert.NoError(t, err)
+					pkt, err := encoder.Encode(packet.Data, encMsg)
+					assert.NoError(t, err)
+					out = append(out, pkt...)
+				}
+			}
+			if len(out) > 0 {
+				if _, err := conn.Write(out); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func newConnectedTestClient(t *testing.T, handle func(m *message.Message) []*message.Message) *Client {
+	c := New(logrus.InfoLevel, time.Second)
+	c.IncomingMsgChan = make(chan *message.Message, 10)
+	clientConn, serverConn := net.Pipe()
+	go serveTestConn(t, serverConn, handle)
+	assert.NoError(t, c.connect(clientConn, ""))
+	t.Cleanup(c.Disconnect)
+	return c
+}
+
+func TestRequest(t *testing.T) {
+	type payload struct {
+		Value string `json:"value"`
+	}
+
+	c := newConnectedTestClient(t, func(m *message.Message) []*message.Message {
+		switch m.Route {
+		case "room.room.join":
+			assert.JSONEq(t, `{"value":"user"}`, string(m.Data))
+			// answering after a push checks responses are matched by id
+			return []*message.Message{
+				{Type: message.Push, Route: "room.onJoin", Data: []byte(`{"value":"push"}`)},
+				{Type: message.Response, ID: m.ID, Data: []byte(`{"value":"user-ok"}`)},
+			}
+		case "room.room.fail":
+			return []*message.Message{{Type: message.Response, ID: m.ID, Data: []byte(`{"ErrorCode":401}`), Err: true}}
+		}
+		return nil
+	})
+
+	resp := &payload{}
+	err := c.Request(context.Background(), "room.room.join", &payload{Value: "user"}, resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-ok", resp.Value)
+
+	push := helpers.ShouldEventuallyReceive(t, c.IncomingMsgChan).(*message.Message)
+	assert.Equal(t, "room.onJoin", push.Route)
+
+	err = c.Request(context.Background(), "room.room.fail", []byte(`{}`), resp)
+	assert.Equal(t, &e.Error{
+		Code:      "PIT-401",
+		Message:   "request to room.room.fail failed with error code 401",
+		ErrorCode: 401,
+	}, err)
+
+	c.pendingReqMutex.Lock()
+	assert.Empty(t, c.pendingRequests)
+	c.pendingReqMutex.Unlock()
+	assert.Empty(t, c.pendingChan)
+}
+
+func TestRequestContextDone(t *testing.T) {
+	c := newConnectedTestClient(t, func(m *message.Message) []*message.Message { return nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.Request(ctx, "room.room.slow", []byte(`{}`), nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Empty(t, c.pendingChan)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	c := newConnectedTestClient(t, func(m *message.Message) []*message.Message { return nil })
+	c.requestTimeout = 10 * time.Millisecond
+
+	err := c.Request(context.Background(), "room.room.slow", []byte(`{}`), nil)
+	pErr, ok := err.(*e.Error)
+	assert.True(t, ok)
+	assert.Equal(t, "PIT-504", pErr.Code)
+	assert.Equal(t, constants.ErrRequestTimeout.Error(), pErr.Message)
+}
+
+func TestRequestFailsOnDisconnect(t *testing.T) {
+	c := newConnectedTestClient(t, func(m *message.Message) []*message.Message { return nil })
+
+	errs := make(chan error)
+	go func() {
+		errs <- c.Request(context.Background(), "room.room.slow", []byte(`{}`), nil)
+	}()
+	helpers.ShouldEventuallyReturn(t, func() int {
+		c.pendingReqMutex.Lock()
+		defer c.pendingReqMutex.Unlock()
+		return len(c.pendingRequests)
+	}, 1)
+	c.Disconnect()
+	assert.Equal(t, constants.ErrConnectionClosed, helpers.ShouldEventuallyReceive(t, errs))
+
+	assert.Equal(t, constants.ErrClientNotConnected, c.Request(context.Background(), "room.room.slow", nil, nil))
+}
+
+func TestRequestInflightWindow(t *testing.T) {
+	c := New(logrus.InfoLevel)
+	c.SetInflightWindow(1)
+	c.Connected = true
+	c.pendingChan <- true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := c.Request(ctx, "room.room.join", []byte(`{}`), nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Len(t, c.pendingChan, 1)
+	assert.Empty(t, c.pendingRequests)
+}
+
+func TestOnPush(t *testing.T) {
+	c := newConnectedTestClient(t, func(m *message.Message) []*message.Message {
+		return []*message.Message{
+			{Type: message.Push, Route: "room.onMessage", Data: []byte(`{"msg":"hello"}`)},
+			{Type: message.Push, Route: "room.onOther", Data: []byte(`{}`)},
+		}
+	})
+
+	pushes := make(chan map[string]string, 1)
+	c.OnPush("room.onMessage", func(data []byte) {
+		push := map[string]string{}
+		assert.NoError(t, c.Decode(data, &push))
+		pushes <- push
+	})
+
+	assert.NoError(t, c.SendNotify("room.room.send", []byte(`{}`)))
+	assert.Equal(t, map[string]string{"msg": "hello"}, helpers.ShouldEventuallyReceive(t, pushes))
+	other := helpers.ShouldEventuallyReceive(t, c.IncomingMsgChan).(*message.Message)
+	assert.Equal(t, "room.onOther", other.Route)
+
+	c.OnPush("room.onMessage", nil)
+	assert.NoError(t, c.SendNotify("room.room.send", []byte(`{}`)))
+	msg := helpers.ShouldEventuallyReceive(t, c.IncomingMsgChan).(*message.Message)
+	assert.Equal(t, "room.onMessage", msg.Route)
+}
+
+func TestSetSerializer(t *testing.T) {
+	c := New(logrus.InfoLevel)
+	for _, name := range []string{"json", "protobuf", "msgpack", "jsonpb"} {
+		c.setSerializer(name)
+		assert.Equal(t, name, c.serializer.GetName())
+	}
+	c.setSerializer("unknown")
+	assert.Equal(t, "json", c.serializer.GetName())
+}
+
+func TestClientsImplementPitayaClient(t *testing.T) {
+	var _ PitayaClient = New(logrus.InfoLevel)
+	var _ PitayaClient = NewProto("docs", logrus.InfoLevel)
+}
+
+func TestReadPacketsFillingTheBuffer(t *testing.T) {
+	c := New(logrus.InfoLevel)
+	server, conn := net.Pipe()
+	defer server.Close()
+	defer conn.Close()
+
+	// a packet filling the read buffer exactly must not wait for more data
+	p, err := codec.NewPomeloPacketEncoder().Encode(packet.Data, bytes.Repeat([]byte{1}, 2048-codec.HeadLength))
+	assert.NoError(t, err)
+	go server.Write(p)
+
+	read := make(chan []*packet.Packet)
+	go func() {
+		buf := bytes.NewBuffer(nil)
+		packets, err := c.readPackets(conn, buf)
+		assert.NoError(t, err)
+		assert.Zero(t, buf.Len())
+		read <- packets
+	}()
+	packets := helpers.ShouldEventuallyReceive(t, read).([]*packet.Packet)
+	assert.Len(t, packets, 1)
+	assert.Equal(t, 2048-codec.HeadLength, packets[0].Length)
+}
@@ -21,6 +21,7 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
 
 	"github.com/hnlxhzw/pitaya/conn/message"
@@ -32,9 +33,13 @@ type PitayaClient interface {
 	ConnectTo(addr string, tlsConfig ...*tls.Config) error
 	ConnectToWS(addr string, path string, tlsConfig ...*tls.Config) error
 	ConnectedStatus() bool
+	Decode(data []byte, v interface{}) error
 	Disconnect()
 	MsgChannel() chan *message.Message
+	OnPush(route string, handler PushHandler)
+	Request(ctx context.Context, route string, req interface{}, resp interface{}) error
 	SendNotify(route string, data []byte) error
 	SendRequest(route string, data []byte) (uint, error)
 	SetClientHandshakeData(data *session.HandshakeData)
+	SetInflightWindow(size int)
 }
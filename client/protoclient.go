@@ -23,6 +23,7 @@ package client
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -239,6 +240,22 @@ func (pc *ProtoClient) getDescriptors(data string) error {
 		pc.info.Commands[k] = &command
 	}
 
+	// declared pushes have their payload as the output, older servers do
+	// not document them
+	pushes, _ := m["pushes"].(map[string]interface{})
+	for k, v := range pushes {
+		cmdInfo := v.(map[string]interface{})
+		_, out, err := getOutputInputNames(cmdInfo)
+		if err != nil {
+			return err
+		}
+
+		var command Command
+		command.output = out
+
+		pc.info.Commands[k] = &command
+	}
+
 	names := make([]string, 0, len(keysSet))
 	for key := range keysSet {
 		names = append(names, key)
@@ -421,23 +438,34 @@ func (pc *ProtoClient) waitForData() {
 }
 
 // ConnectTo connects to the server at addr, for now the only supported protocol is tcp
-// this methods blocks as it also handles the messages from the server
+// the server info is loaded first, if it was not loaded yet
 func (pc *ProtoClient) ConnectTo(addr string, tlsConfig ...*tls.Config) error {
-	err := pc.Client.ConnectTo(addr, tlsConfig...)
-	if err != nil {
+	if !pc.ready {
+		if err := pc.LoadServerInfo(addr); err != nil {
+			return err
+		}
+	}
+
+	if err := pc.Client.ConnectTo(addr, tlsConfig...); err != nil {
 		return err
 	}
+	go pc.waitForData()
+	return nil
+}
 
+// ConnectToWS connects using websockets, the server info is loaded first, if
+// it was not loaded yet
+func (pc *ProtoClient) ConnectToWS(addr string, path string, tlsConfig ...*tls.Config) error {
 	if !pc.ready {
-		err = pc.LoadServerInfo(addr)
-		if err != nil {
+		if err := pc.LoadServerInfo(addr); err != nil {
 			return err
 		}
 	}
 
-	if pc.ready {
-		go pc.waitForData()
+	if err := pc.Client.ConnectToWS(addr, path, tlsConfig...); err != nil {
+		return err
 	}
+	go pc.waitForData()
 	return nil
 }
 
@@ -498,6 +526,92 @@ func (pc *ProtoClient) SendRequest(route string, data []byte) (uint, error) {
 	return 0, errors.New("Invalid Route: " + route)
 }
 
+// Request sends a request with a json payload, converted to protobuf with the
+// route descriptors, and waits for its response, whose json is decoded into
+// resp with Decode
+func (pc *ProtoClient) Request(ctx context.Context, route string, req interface{}, resp interface{}) error {
+	if !pc.ready {
+		return pc.Client.Request(ctx, route, req, resp)
+	}
+
+	cmd, ok := pc.info.Commands[route]
+	if !ok {
+		return errors.New("Invalid Route: " + route)
+	}
+
+	data, ok := req.([]byte)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(req); err != nil {
+			return err
+		}
+	}
+	if len(data) == 0 || string(data) == "{}" || cmd.inputMsgDescriptor == nil {
+		data = []byte{}
+	} else {
+		inputMsg := dynamic.NewMessage(cmd.inputMsgDescriptor)
+		if err := inputMsg.UnmarshalJSON(data); err != nil {
+			return err
+		}
+		realdata, err := inputMsg.Marshal()
+		if err != nil {
+			return err
+		}
+		data = realdata
+	}
+
+	var response []byte
+	if err := pc.Client.Request(ctx, route, data, &response); err != nil {
+		return err
+	}
+	response, err := pc.protoToJSON(cmd.outputMsgDescriptor, response)
+	if err != nil {
+		return err
+	}
+	return pc.Decode(response, resp)
+}
+
+// OnPush sets the handler of the pushes of route, their data is converted to
+// json if the route output was added with AddPushResponse
+func (pc *ProtoClient) OnPush(route string, handler PushHandler) {
+	if handler == nil {
+		pc.Client.OnPush(route, nil)
+		return
+	}
+	pc.Client.OnPush(route, func(data []byte) {
+		if cmd, ok := pc.info.Commands[route]; ok {
+			converted, err := pc.protoToJSON(cmd.outputMsgDescriptor, data)
+			if err != nil {
+				logger.Log.Errorf("Erro decode push data: %s", string(data))
+				return
+			}
+			data = converted
+		}
+		handler(data)
+	})
+}
+
+// Decode decodes the json data of responses and pushes into v, if v is a
+// *[]byte the raw json is set
+func (pc *ProtoClient) Decode(data []byte, v interface{}) error {
+	if raw, ok := v.(*[]byte); ok {
+		*raw = data
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (pc *ProtoClient) protoToJSON(descriptor *desc.MessageDescriptor, data []byte) ([]byte, error) {
+	if descriptor == nil {
+		return data, nil
+	}
+	msg := dynamic.NewMessage(descriptor)
+	if err := msg.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return msg.MarshalJSON()
+}
+
 // SendNotify sends a notify to the server
 func (pc *ProtoClient) SendNotify(route string, data []byte) error {
 
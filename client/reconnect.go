@@ -0,0 +1,249 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package client
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/logger"
+)
+
+// eventsBufferSize is the number of connection events kept until they are
+// read, newer events are dropped if the buffer is full
+const eventsBufferSize = 32
+
+// ConnectionEventType is the type of a connection lifecycle event
+type ConnectionEventType int
+
+const (
+	// EventConnecting is emitted before each reconnection attempt
+	EventConnecting ConnectionEventType = iota
+	// EventConnected is emitted when the handshake with the server succeeds
+	EventConnected
+	// EventDisconnected is emitted when the connection is closed, its error
+	// is set if the connection was lost
+	EventDisconnected
+	// EventGaveUp is emitted when the client stops reconnecting, its error is
+	// the error of the last attempt
+	EventGaveUp
+)
+
+func (t ConnectionEventType) String() string {
+	switch t {
+	case EventConnecting:
+		return "connecting"
+	case EventConnected:
+		return "connected"
+	case EventDisconnected:
+		return "disconnected"
+	case EventGaveUp:
+		return "gave up"
+	}
+	return "unknown"
+}
+
+// ConnectionEvent is a connection lifecycle event
+type ConnectionEvent struct {
+	Type ConnectionEventType
+	// Attempt is the number of the reconnection attempt, starting at 1
+	Attempt int
+	Err     error
+}
+
+// PendingRequestsPolicy defines what happens to the requests waiting for a
+// response when the connection is lost and the client reconnects
+type PendingRequestsPolicy int
+
+const (
+	// FailPendingRequests answers the pending requests made with Request with
+	// constants.ErrConnectionClosed as soon as the connection is lost
+	FailPendingRequests PendingRequestsPolicy = iota
+	// RetryPendingRequests sends the pending requests again after reconnecting,
+	// they only fail if the client gives up
+	RetryPendingRequests
+)
+
+// ReconnectConfig configures the automatic reconnection of the client
+type ReconnectConfig struct {
+	// MinBackoff is the wait before the first attempt
+	MinBackoff time.Duration
+	// MaxBackoff is the maximum wait between attempts
+	MaxBackoff time.Duration
+	// Multiplier is the factor the wait grows by after each failed attempt
+	Multiplier float64
+	// Jitter is the fraction, between 0 and 1, of each wait that is random,
+	// so clients disconnected together don't reconnect together
+	Jitter float64
+	// MaxAttempts is the number of attempts before giving up, 0 never gives up
+	MaxAttempts int
+	// PendingRequests is the policy of the requests waiting for a response
+	PendingRequests PendingRequestsPolicy
+}
+
+// DefaultReconnectConfig returns the default reconnection config
+func DefaultReconnectConfig() ReconnectConfig {
+	return ReconnectConfig{
+		MinBackoff:      500 * time.Millisecond,
+		MaxBackoff:      30 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+		MaxAttempts:     0,
+		PendingRequests: FailPendingRequests,
+	}
+}
+
+// EnableReconnect makes the client reconnect when the connection is lost,
+// redoing the handshake with the same handshake data. It is not done when
+// the client is kicked or Disconnect is called. New requests fail while the
+// client is reconnecting. Unset backoff fields take the value of
+// DefaultReconnectConfig. It must be called before connecting
+func (c *Client) EnableReconnect(config ReconnectConfig) {
+	config.setDefaults()
+	c.reconnectConfig = &config
+}
+
+// Events returns the channel of connection lifecycle events. Events are
+// dropped if the channel is full, so it must be read if reconnection is used
+func (c *Client) Events() <-chan ConnectionEvent {
+	return c.events
+}
+
+func (c *Client) emit(event ConnectionEvent) {
+	select {
+	case c.events <- event:
+	default:
+	}
+}
+
+// setDefaults replaces the backoff fields that would make the client retry
+// without waiting with the ones of DefaultReconnectConfig
+func (config *ReconnectConfig) setDefaults() {
+	defaults := DefaultReconnectConfig()
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaults.MinBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaults.MaxBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = config.MinBackoff
+	}
+	if config.Multiplier < 1 {
+		config.Multiplier = defaults.Multiplier
+	}
+	if config.Jitter < 0 || config.Jitter > 1 {
+		config.Jitter = defaults.Jitter
+	}
+}
+
+// backoff returns the wait before the attempt, starting at 1
+func (config *ReconnectConfig) backoff(attempt int) time.Duration {
+	backoff := float64(config.MinBackoff)
+	for i := 1; i < attempt && backoff < float64(config.MaxBackoff); i++ {
+		backoff *= config.Multiplier
+	}
+	if config.MaxBackoff > 0 && backoff > float64(config.MaxBackoff) {
+		backoff = float64(config.MaxBackoff)
+	}
+	return time.Duration(backoff - backoff*config.Jitter*rand.Float64())
+}
+
+func (c *Client) reconnect(stop chan struct{}) {
+	config := c.reconnectConfig
+	var err error
+	for attempt := 1; config.MaxAttempts == 0 || attempt <= config.MaxAttempts; attempt++ {
+		timer := time.NewTimer(config.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			return
+		}
+
+		c.emit(ConnectionEvent{Type: EventConnecting, Attempt: attempt})
+		if err = c.reconnectOnce(); err == nil {
+			c.connMutex.Lock()
+			if c.stopReconnect == stop {
+				c.stopReconnect = nil
+			}
+			c.connMutex.Unlock()
+
+			select {
+			case <-stop:
+				// Disconnect was called while reconnecting
+				c.Disconnect()
+				return
+			default:
+			}
+			if config.PendingRequests == RetryPendingRequests {
+				c.resendPendingRequests()
+			}
+			return
+		}
+		logger.Log.Warnf("reconnection attempt %d to %s failed: %s", attempt, c.addr, err.Error())
+
+		// retrying won't help if the server rejected the handshake
+		var handshakeErr *HandshakeError
+		if errors.As(err, &handshakeErr) {
+			break
+		}
+	}
+
+	c.emit(ConnectionEvent{Type: EventGaveUp, Err: err})
+	c.failPendingRequests(constants.ErrConnectionClosed)
+}
+
+func (c *Client) reconnectOnce() error {
+	conn, err := c.dial(c.addr)
+	if err != nil {
+		return err
+	}
+	if err := c.connect(conn, ""); err != nil {
+		conn.Close()
+		return err
+	}
+	return nil
+}
+
+// resendPendingRequests sends the requests still waiting for a response
+// again, with the same ids, after reconnecting
+func (c *Client) resendPendingRequests() {
+	c.pendingReqMutex.Lock()
+	pendingReqs := make([]*pendingRequest, 0, len(c.pendingRequests))
+	for _, pendingReq := range c.pendingRequests {
+		pendingReq.sentAt = time.Now()
+		pendingReqs = append(pendingReqs, pendingReq)
+	}
+	c.pendingReqMutex.Unlock()
+
+	for _, pendingReq := range pendingReqs {
+		p, err := c.buildPacket(*pendingReq.msg)
+		if err == nil {
+			_, err = c.write(p)
+		}
+		if err != nil {
+			logger.Log.Errorf("error resending request %d to %s: %s", pendingReq.msg.ID, pendingReq.msg.Route, err.Error())
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/conn/message"
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+type testDialer struct {
+	mutex sync.Mutex
+	conns []net.Conn
+	err   error
+}
+
+func (d *testDialer) serverConn(i int) net.Conn {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.conns[i]
+}
+
+func (d *testDialer) setErr(err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.err = err
+}
+
+// newReconnectTestClient returns a client connected to a test server whose
+// handle receives the number of the connection, starting at 0
+func newReconnectTestClient(
+	t *testing.T,
+	config ReconnectConfig,
+	handle func(conn int, m *message.Message) []*message.Message,
+) (*Client, *testDialer) {
+	c := New(logrus.InfoLevel, time.Second)
+	c.EnableReconnect(config)
+	c.IncomingMsgChan = make(chan *message.Message, 10)
+
+	d := &testDialer{}
+	c.dial = func(addr string) (net.Conn, error) {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		if d.err != nil {
+			return nil, d.err
+		}
+		i := len(d.conns)
+		clientConn, serverConn := net.Pipe()
+		d.conns = append(d.conns, serverConn)
+		go serveTestConn(t, serverConn, func(m *message.Message) []*message.Message {
+			return handle(i, m)
+		})
+		return clientConn, nil
+	}
+
+	conn, err := c.dial("test")
+	assert.NoError(t, err)
+	assert.NoError(t, c.connect(conn, ""))
+	t.Cleanup(c.Disconnect)
+	return c, d
+}
+
+func testReconnectConfig() ReconnectConfig {
+	config := DefaultReconnectConfig()
+	config.MinBackoff = time.Millisecond
+	config.MaxBackoff = 5 * time.Millisecond
+	return config
+}
+
+func nextEvent(t *testing.T, c *Client) ConnectionEvent {
+	t.Helper()
+	select {
+	case event := <-c.Events():
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for connection event")
+	}
+	return ConnectionEvent{}
+}
+
+func answer(m *message.Message) []*message.Message {
+	return []*message.Message{{Type: message.Response, ID: m.ID, Data: []byte(`{"code":200}`)}}
+}
+
+func TestReconnect(t *testing.T) {
+	c, d := newReconnectTestClient(t, testReconnectConfig(), func(conn int, m *message.Message) []*message.Message {
+		return answer(m)
+	})
+	assert.Equal(t, EventConnected, nextEvent(t, c).Type)
+
+	d.serverConn(0).Close()
+	event := nextEvent(t, c)
+	assert.Equal(t, EventDisconnected, event.Type)
+	assert.Error(t, event.Err)
+	assert.Equal(t, ConnectionEvent{Type: EventConnecting, Attempt: 1}, nextEvent(t, c))
+	assert.Equal(t, EventConnected, nextEvent(t, c).Type)
+
+	assert.True(t, c.ConnectedStatus())
+	assert.NoError(t, c.Request(context.Background(), "room.room.join", []byte(`{}`), nil))
+}
+
+func TestReconnectRetryPendingRequests(t *testing.T) {
+	config := testReconnectConfig()
+	config.PendingRequests = RetryPendingRequests
+	var d *testDialer
+	c, d := newReconnectTestClient(t, config, func(conn int, m *message.Message) []*message.Message {
+		if conn == 0 {
+			// the connection is lost before answering
+			go d.serverConn(0).Close()
+			return nil
+		}
+		return answer(m)
+	})
+
+	resp := map[string]int{}
+	assert.NoError(t, c.Request(context.Background(), "room.room.join", []byte(`{}`), &resp))
+	assert.Equal(t, 200, resp["code"])
+}
+
+func TestReconnectFailPendingRequests(t *testing.T) {
+	var d *testDialer
+	c, d := newReconnectTestClient(t, testReconnectConfig(), func(conn int, m *message.Message) []*message.Message {
+		if conn == 0 {
+			go d.serverConn(0).Close()
+			return nil
+		}
+		return answer(m)
+	})
+
+	err := c.Request(context.Background(), "room.room.join", []byte(`{}`), nil)
+	assert.Equal(t, constants.ErrConnectionClosed, err)
+}
+
+func TestReconnectGivesUp(t *testing.T) {
+	config := testReconnectConfig()
+	config.MaxAttempts = 2
+	c, d := newReconnectTestClient(t, config, func(conn int, m *message.Message) []*message.Message {
+		return nil
+	})
+	assert.Equal(t, EventConnected, nextEvent(t, c).Type)
+
+	dialErr := errors.New("connection refused")
+	d.setErr(dialErr)
+	d.serverConn(0).Close()
+	assert.Equal(t, EventDisconnected, nextEvent(t, c).Type)
+	assert.Equal(t, ConnectionEvent{Type: EventConnecting, Attempt: 1}, nextEvent(t, c))
+	assert.Equal(t, ConnectionEvent{Type: EventConnecting, Attempt: 2}, nextEvent(t, c))
+	assert.Equal(t, ConnectionEvent{Type: EventGaveUp, Err: dialErr}, nextEvent(t, c))
+	assert.False(t, c.ConnectedStatus())
+}
+
+func TestDisconnectDoesNotReconnect(t *testing.T) {
+	c, _ := newReconnectTestClient(t, testReconnectConfig(), func(conn int, m *message.Message) []*message.Message {
+		return nil
+	})
+	assert.Equal(t, EventConnected, nextEvent(t, c).Type)
+
+	c.Disconnect()
+	assert.Equal(t, ConnectionEvent{Type: EventDisconnected}, nextEvent(t, c))
+	select {
+	case event := <-c.Events():
+		t.Fatalf("unexpected event %s", event.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReconnectConfigBackoff(t *testing.T) {
+	t.Parallel()
+	config := ReconnectConfig{
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: time.Second,
+		Multiplier: 2,
+	}
+	assert.Equal(t, 100*time.Millisecond, config.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, config.backoff(2))
+	assert.Equal(t, 800*time.Millisecond, config.backoff(4))
+	assert.Equal(t, time.Second, config.backoff(5))
+	assert.Equal(t, time.Second, config.backoff(100))
+
+	config.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		backoff := config.backoff(2)
+		assert.True(t, backoff > 100*time.Millisecond && backoff <= 200*time.Millisecond)
+	}
+}
+
+func TestReconnectConfigSetDefaults(t *testing.T) {
+	t.Parallel()
+	defaults := DefaultReconnectConfig()
+
+	config := ReconnectConfig{MaxAttempts: 3, PendingRequests: RetryPendingRequests}
+	config.setDefaults()
+	assert.Equal(t, defaults.MinBackoff, config.MinBackoff)
+	assert.Equal(t, defaults.MaxBackoff, config.MaxBackoff)
+	assert.Equal(t, defaults.Multiplier, config.Multiplier)
+	assert.Equal(t, 0.0, config.Jitter)
+	assert.Equal(t, 3, config.MaxAttempts)
+	assert.Equal(t, RetryPendingRequests, config.PendingRequests)
+	assert.True(t, config.backoff(1) > 0)
+
+	config = ReconnectConfig{MinBackoff: time.Minute, Multiplier: 0.5, Jitter: 2}
+	config.setDefaults()
+	assert.Equal(t, time.Minute, config.MaxBackoff)
+	assert.Equal(t, defaults.Multiplier, config.Multiplier)
+	assert.Equal(t, defaults.Jitter, config.Jitter)
+	assert.True(t, config.backoff(2) > 0 && config.backoff(2) <= time.Minute)
+}
@@ -192,6 +192,7 @@ func (gs *GRPCClient) Call2(ctx context.Context, routeKey string, req interface{
 		ctxT = pcontext.AddToPropagateCtx(ctxT, constants.RouteKey, routeKey)
 		defer metrics.ReportTimingFromCtx(ctxT, gs.metricsReporters, "rpc", err)
 	}
+	ctxT = tracing.InjectGRPCMetadata(ctxT)
 	err = c.(*grpcClient).call2(ctxT, routeKey, req, resp)
 	return err
 }
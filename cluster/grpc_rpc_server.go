@@ -29,6 +29,7 @@ import (
 	"github.com/hnlxhzw/pitaya/config"
 	"github.com/hnlxhzw/pitaya/metrics"
 	"github.com/hnlxhzw/pitaya/protos"
+	"github.com/hnlxhzw/pitaya/tracing"
 )
 
 // GRPCServer rpc server struct
@@ -57,7 +58,7 @@ func (gs *GRPCServer) Init() error {
 	if err != nil {
 		return err
 	}
-	gs.grpcSv = grpc.NewServer()
+	gs.grpcSv = grpc.NewServer(grpc.UnaryInterceptor(tracing.UnaryServerInterceptor))
 	protos.RegisterPitayaServer(gs.grpcSv, gs.pitayaServer)
 	go gs.grpcSv.Serve(lis)
 	return nil
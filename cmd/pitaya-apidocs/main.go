@@ -0,0 +1,244 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// pitaya-apidocs exports the documentation of a pitaya server as an OpenAPI 3
+// document, with an operation for each handler route, as a JSON Schema for
+// each type of the handlers and remotes, or as a TypeScript or C# client sdk.
+// The documentation is requested from the docs route of a running server, or
+// read from a file with the json returned by pitaya.Documentation(true).
+// Protobuf messages are described from the descriptors returned by the
+// descriptors route.
+//
+//	pitaya-apidocs -addr localhost:3250 -docs connector.docsHandler.docs -descriptors connector.docsHandler.protos -o openapi.json
+//	pitaya-apidocs -in docs.json -format jsonschema -o schemas
+//	pitaya-apidocs -in docs.json -dict dict.json -format csharp -namespace Game.Client -o Pitaya.cs
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/hnlxhzw/pitaya/client"
+	"github.com/hnlxhzw/pitaya/conn/message"
+	"github.com/hnlxhzw/pitaya/docgenerator"
+	"github.com/hnlxhzw/pitaya/logger"
+	"github.com/hnlxhzw/pitaya/protos"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:3250", "address of the frontend server")
+	ws := flag.Bool("ws", false, "connect using websockets")
+	path := flag.String("path", "", "websocket path")
+	useTLS := flag.Bool("tls", false, "connect using tls, without verifying the server certificate")
+	docsRoute := flag.String("docs", "", "route of the server docs")
+	descriptorsRoute := flag.String("descriptors", "", "route of the server protobuf descriptors")
+	in := flag.String("in", "", "file with the docs json, used instead of requesting them to the server")
+	format := flag.String("format", "openapi", "output format, openapi, jsonschema, typescript or csharp")
+	output := flag.String("o", "", "output file, or directory for jsonschema. The openapi document and sdks are written to stdout if empty")
+	title := flag.String("title", "pitaya", "title of the openapi document")
+	version := flag.String("version", "1.0.0", "version of the openapi document")
+	namespace := flag.String("namespace", "", "namespace of the c# sdk")
+	dict := flag.String("dict", "", "file with the json route dictionary of the sdks, by default the one sent by the server in the handshake")
+	timeout := flag.Duration("timeout", 5*time.Second, "request timeout")
+	flag.Parse()
+
+	switch *format {
+	case "openapi", "jsonschema", string(docgenerator.SDKTypeScript), string(docgenerator.SDKCSharp):
+	default:
+		fmt.Fprintf(os.Stderr, "invalid format %s, it must be openapi, jsonschema, typescript or csharp\n", *format)
+		os.Exit(2)
+	}
+	if *in == "" && *docsRoute == "" {
+		fmt.Fprintln(os.Stderr, "the docs route or a docs file is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format == "jsonschema" && *output == "" {
+		fmt.Fprintln(os.Stderr, "jsonschema requires an output directory")
+		os.Exit(2)
+	}
+
+	l := logrus.New()
+	l.SetLevel(logrus.FatalLevel)
+	logger.SetLogger(l)
+
+	var docs map[string]interface{}
+	var resolver docgenerator.DescriptorResolver
+	var dictionary map[string]uint16
+	if *dict != "" {
+		bts, err := ioutil.ReadFile(*dict)
+		if err != nil {
+			exit("failed to read dictionary", err)
+		}
+		if err := json.Unmarshal(bts, &dictionary); err != nil {
+			exit("failed to parse dictionary", err)
+		}
+	}
+
+	if *in != "" {
+		bts, err := ioutil.ReadFile(*in)
+		if err != nil {
+			exit("failed to read docs", err)
+		}
+		if err := json.Unmarshal(bts, &docs); err != nil {
+			exit("failed to parse docs", err)
+		}
+	} else {
+		c := client.New(logrus.FatalLevel, *timeout)
+		if err := connect(c, *addr, *ws, *path, *useTLS); err != nil {
+			exit("failed to connect", err)
+		}
+		defer c.Disconnect()
+
+		var err error
+		if docs, err = requestDocs(c, *docsRoute, *timeout); err != nil {
+			exit("failed to request docs", err)
+		}
+		if *descriptorsRoute != "" {
+			resolver = descriptorResolver(c, *descriptorsRoute, *timeout)
+		}
+		if dictionary == nil {
+			// set by the client with the one received in the handshake
+			dictionary = message.GetDictionary()
+		}
+	}
+
+	switch *format {
+	case string(docgenerator.SDKTypeScript), string(docgenerator.SDKCSharp):
+		config := docgenerator.SDKConfig{
+			Language:   docgenerator.SDKLanguage(*format),
+			Namespace:  *namespace,
+			Dictionary: dictionary,
+		}
+		var sdk bytes.Buffer
+		if err := docgenerator.GenerateSDK(&sdk, docs, resolver, config); err != nil {
+			exit("failed to generate sdk", err)
+		}
+		if err := writeOutput(*output, sdk.Bytes()); err != nil {
+			exit("failed to write sdk", err)
+		}
+		return
+	}
+
+	if *format == "jsonschema" {
+		schemas, err := docgenerator.JSONSchemas(docs, resolver)
+		if err != nil {
+			exit("failed to generate json schemas", err)
+		}
+		if err := writeJSONSchemas(*output, schemas); err != nil {
+			exit("failed to write json schemas", err)
+		}
+		return
+	}
+
+	document, err := docgenerator.OpenAPI(docs, docgenerator.OpenAPIInfo{Title: *title, Version: *version}, resolver)
+	if err != nil {
+		exit("failed to generate openapi document", err)
+	}
+	bts, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		exit("failed to encode openapi document", err)
+	}
+	if err := writeOutput(*output, append(bts, '\n')); err != nil {
+		exit("failed to write openapi document", err)
+	}
+}
+
+// writeOutput writes data to the output file, or to stdout if it is empty
+func writeOutput(output string, data []byte) error {
+	if output == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(output, data, 0644)
+}
+
+func exit(msg string, err error) {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", msg, err.Error())
+	os.Exit(1)
+}
+
+func connect(c *client.Client, addr string, ws bool, path string, useTLS bool) error {
+	var tlsConfig []*tls.Config
+	if useTLS {
+		tlsConfig = append(tlsConfig, &tls.Config{InsecureSkipVerify: true})
+	}
+	if ws {
+		return c.ConnectToWS(addr, path, tlsConfig...)
+	}
+	return c.ConnectTo(addr, tlsConfig...)
+}
+
+func requestDocs(c *client.Client, route string, timeout time.Duration) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	doc := &protos.Doc{}
+	if err := c.Request(ctx, route, &protos.Doc{}, doc); err != nil {
+		return nil, err
+	}
+	var docs map[string]interface{}
+	if err := json.Unmarshal([]byte(doc.Doc), &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// descriptorResolver resolves the protobuf messages with the descriptors
+// route of the server
+func descriptorResolver(c *client.Client, route string, timeout time.Duration) docgenerator.DescriptorResolver {
+	return func(protoName string) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		descriptors := &protos.ProtoDescriptors{}
+		if err := c.Request(ctx, route, &protos.ProtoNames{Name: []string{protoName}}, descriptors); err != nil {
+			return nil, err
+		}
+		if len(descriptors.Desc) == 0 {
+			return nil, fmt.Errorf("no descriptor for %s", protoName)
+		}
+		return descriptors.Desc[0], nil
+	}
+}
+
+// writeJSONSchemas writes each schema to a file named as its type in dir
+func writeJSONSchemas(dir string, schemas map[string]*docgenerator.Schema) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, schema := range schemas {
+		bts, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".json"), append(bts, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
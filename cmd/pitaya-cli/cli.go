@@ -0,0 +1,435 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/hnlxhzw/pitaya/client"
+	"github.com/hnlxhzw/pitaya/conn/message"
+	"github.com/hnlxhzw/pitaya/protos"
+	"github.com/hnlxhzw/pitaya/session"
+)
+
+var errQuit = errors.New("quit")
+
+type command struct {
+	name  string
+	usage string
+	help  string
+	run   func(args string) error
+}
+
+// cli holds the state of a pitaya-cli session, commands write their output,
+// and the pushes received, to out
+type cli struct {
+	out              io.Writer
+	outMutex         sync.Mutex
+	proto            bool
+	docsRoute        string
+	descriptorsRoute string
+	timeout          time.Duration
+	insecure         bool
+	handshake        *session.HandshakeData
+
+	client   client.PitayaClient
+	stop     chan struct{}
+	routes   []string
+	watchAll atomic.Bool
+	watched  map[string]bool
+	history  []string
+	commands []*command
+}
+
+func newCLI(out io.Writer) *cli {
+	c := &cli{
+		out:     out,
+		timeout: 5 * time.Second,
+		watched: map[string]bool{},
+	}
+	c.commands = []*command{
+		{"connect", "connect <addr>", "connects to addr, e.g. localhost:3250, tls://host:port, ws://host:port/path or wss://host:port/path", c.connect},
+		{"disconnect", "disconnect", "disconnects from the server", c.disconnect},
+		{"handshake", "handshake <json>", "sets the handshake data sent on the next connect, e.g. {\"user\":{\"token\":\"...\"}}", c.setHandshake},
+		{"request", "request <route> [json]", "sends a request and prints its response", c.request},
+		{"notify", "notify <route> [json]", "sends a notify", c.notify},
+		{"watch", "watch <route|*>", "prints the pushes of route, or of all routes with *", c.watch},
+		{"unwatch", "unwatch <route|*>", "stops printing the pushes of route", c.unwatch},
+		{"routes", "routes", "lists the routes of the server docs", c.listRoutes},
+		{"sleep", "sleep <duration>", "waits for duration, e.g. 500ms, useful in scripts to wait for pushes", c.sleep},
+		{"history", "history", "lists the commands run", c.printHistory},
+		{"help", "help", "lists the commands", c.help},
+		{"exit", "exit", "exits the cli", c.quit},
+	}
+	return c
+}
+
+func (c *cli) printf(format string, args ...interface{}) {
+	c.outMutex.Lock()
+	defer c.outMutex.Unlock()
+	fmt.Fprintf(c.out, format, args...)
+}
+
+// exec runs a command line, blank lines and comments starting with # are ignored
+func (c *cli) exec(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	c.history = append(c.history, line)
+
+	name, args := splitArg(line)
+	if name == "quit" {
+		name = "exit"
+	}
+	for _, cmd := range c.commands {
+		if cmd.name == name {
+			return cmd.run(args)
+		}
+	}
+	return fmt.Errorf("unknown command %s, run help to list the commands", name)
+}
+
+// splitArg splits the first word of s from the rest
+func splitArg(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		return s[:i], strings.TrimSpace(s[i+1:])
+	}
+	return s, ""
+}
+
+type target struct {
+	addr      string
+	path      string
+	websocket bool
+	tls       bool
+}
+
+func parseAddress(addr string) (*target, error) {
+	if addr == "" {
+		return nil, errors.New("an address is required")
+	}
+	if !strings.Contains(addr, "://") {
+		return &target{addr: addr}, nil
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	t := &target{addr: u.Host, path: u.Path}
+	switch u.Scheme {
+	case "tcp":
+	case "tls":
+		t.tls = true
+	case "ws":
+		t.websocket = true
+	case "wss":
+		t.websocket = true
+		t.tls = true
+	default:
+		return nil, fmt.Errorf("unsupported scheme %s, use tcp, tls, ws or wss", u.Scheme)
+	}
+	return t, nil
+}
+
+func (c *cli) connected() bool {
+	return c.client != nil && c.client.ConnectedStatus()
+}
+
+func (c *cli) connect(args string) error {
+	if c.connected() {
+		return errors.New("already connected, disconnect first")
+	}
+	t, err := parseAddress(args)
+	if err != nil {
+		return err
+	}
+
+	var pc client.PitayaClient
+	if c.proto {
+		if c.descriptorsRoute != "" {
+			pc = client.NewWithDescriptor(c.descriptorsRoute, c.docsRoute, logrus.ErrorLevel, c.timeout)
+		} else {
+			pc = client.NewProto(c.docsRoute, logrus.ErrorLevel, c.timeout)
+		}
+	} else {
+		pc = client.New(logrus.ErrorLevel, c.timeout)
+	}
+	if c.handshake != nil {
+		pc.SetClientHandshakeData(c.handshake)
+	}
+
+	var tlsConfig []*tls.Config
+	if t.tls {
+		tlsConfig = append(tlsConfig, &tls.Config{InsecureSkipVerify: c.insecure})
+	}
+	if t.websocket {
+		err = pc.ConnectToWS(t.addr, t.path, tlsConfig...)
+	} else {
+		err = pc.ConnectTo(t.addr, tlsConfig...)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.client = pc
+	c.stop = make(chan struct{})
+	for route := range c.watched {
+		c.watchRoute(route)
+	}
+	go c.receivePushes(pc.MsgChannel(), c.stop)
+	c.printf("connected to %s\n", t.addr)
+
+	if err := c.loadRoutes(); err != nil {
+		c.printf("failed to load routes from docs: %s\n", err.Error())
+	}
+	return nil
+}
+
+// loadRoutes loads the routes used for tab completion from the server docs
+func (c *cli) loadRoutes() error {
+	c.routes = nil
+	if pc, ok := c.client.(*client.ProtoClient); ok {
+		if info := pc.ExportInformation(); info != nil {
+			for route := range info.Commands {
+				c.routes = append(c.routes, route)
+			}
+		}
+		sort.Strings(c.routes)
+		return nil
+	}
+	if c.docsRoute == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	doc := &protos.Doc{}
+	if err := c.client.Request(ctx, c.docsRoute, &protos.Doc{}, doc); err != nil {
+		return err
+	}
+	docs := struct {
+		Handlers map[string]interface{} `json:"handlers"`
+	}{}
+	if err := json.Unmarshal([]byte(doc.Doc), &docs); err != nil {
+		return err
+	}
+	for route := range docs.Handlers {
+		c.routes = append(c.routes, route)
+	}
+	sort.Strings(c.routes)
+	return nil
+}
+
+// receivePushes prints the pushes of all routes, if they are watched, and
+// keeps the client from blocking on its message channel
+func (c *cli) receivePushes(msgs chan *message.Message, stop chan struct{}) {
+	for {
+		select {
+		case m := <-msgs:
+			if m.Type == message.Push && c.watchAll.Load() {
+				c.printf("push %s: %s\n", m.Route, string(m.Data))
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (c *cli) disconnect(args string) error {
+	if c.client == nil {
+		return errors.New("not connected")
+	}
+	close(c.stop)
+	c.client.Disconnect()
+	c.client = nil
+	c.printf("disconnected\n")
+	return nil
+}
+
+func (c *cli) setHandshake(args string) error {
+	handshake := &session.HandshakeData{}
+	if err := json.Unmarshal([]byte(args), handshake); err != nil {
+		return fmt.Errorf("invalid handshake data: %w", err)
+	}
+	c.handshake = handshake
+	return nil
+}
+
+func routeAndData(args string) (string, []byte, error) {
+	route, data := splitArg(args)
+	if route == "" {
+		return "", nil, errors.New("a route is required")
+	}
+	if data == "" {
+		data = "{}"
+	}
+	return route, []byte(data), nil
+}
+
+func (c *cli) request(args string) error {
+	if !c.connected() {
+		return errors.New("not connected")
+	}
+	route, data, err := routeAndData(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	var resp []byte
+	if err := c.client.Request(ctx, route, data, &resp); err != nil {
+		return err
+	}
+	c.printf("%s\n", string(resp))
+	return nil
+}
+
+func (c *cli) notify(args string) error {
+	if !c.connected() {
+		return errors.New("not connected")
+	}
+	route, data, err := routeAndData(args)
+	if err != nil {
+		return err
+	}
+	return c.client.SendNotify(route, data)
+}
+
+func (c *cli) watch(args string) error {
+	if args == "" {
+		return errors.New("a route is required")
+	}
+	c.watched[args] = true
+	if c.client != nil {
+		c.watchRoute(args)
+	}
+	return nil
+}
+
+func (c *cli) watchRoute(route string) {
+	if route == "*" {
+		c.watchAll.Store(true)
+		return
+	}
+	c.client.OnPush(route, func(data []byte) {
+		c.printf("push %s: %s\n", route, string(data))
+	})
+}
+
+func (c *cli) unwatch(args string) error {
+	if !c.watched[args] {
+		return fmt.Errorf("%s is not watched", args)
+	}
+	delete(c.watched, args)
+	if args == "*" {
+		c.watchAll.Store(false)
+	} else if c.client != nil {
+		c.client.OnPush(args, nil)
+	}
+	return nil
+}
+
+func (c *cli) listRoutes(args string) error {
+	if len(c.routes) == 0 {
+		return errors.New("no routes loaded, connect with the docs route set")
+	}
+	for _, route := range c.routes {
+		c.printf("%s\n", route)
+	}
+	return nil
+}
+
+func (c *cli) sleep(args string) error {
+	d, err := time.ParseDuration(args)
+	if err != nil {
+		return err
+	}
+	time.Sleep(d)
+	return nil
+}
+
+func (c *cli) printHistory(args string) error {
+	for i, line := range c.history {
+		c.printf("%4d  %s\n", i+1, line)
+	}
+	return nil
+}
+
+func (c *cli) help(args string) error {
+	for _, cmd := range c.commands {
+		c.printf("  %-28s %s\n", cmd.usage, cmd.help)
+	}
+	return nil
+}
+
+func (c *cli) quit(args string) error {
+	if c.client != nil {
+		c.disconnect("")
+	}
+	return errQuit
+}
+
+// completions returns the commands, or the routes for the commands that
+// receive one, completing line
+func (c *cli) completions(line string) []string {
+	name, args := splitArg(line)
+	if !strings.ContainsAny(line, " \t") {
+		completions := []string{}
+		for _, cmd := range c.commands {
+			if strings.HasPrefix(cmd.name, name) {
+				completions = append(completions, cmd.name)
+			}
+		}
+		return completions
+	}
+
+	switch name {
+	case "request", "notify", "watch", "unwatch":
+	default:
+		return nil
+	}
+	if strings.ContainsAny(args, " \t") {
+		return nil
+	}
+	completions := []string{}
+	for _, route := range c.routes {
+		if strings.HasPrefix(route, args) {
+			completions = append(completions, route)
+		}
+	}
+	return completions
+}
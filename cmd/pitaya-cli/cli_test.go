@@ -0,0 +1,239 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/conn/codec"
+	"github.com/hnlxhzw/pitaya/conn/message"
+	"github.com/hnlxhzw/pitaya/conn/packet"
+)
+
+const testDocsRoute = "connector.docsHandler.docs"
+
+// newTestServer starts a frontend that serves docs, answers requests to
+// room.room.join, fails requests to room.room.fail and pushes
+// room.onMessage on notifies
+func newTestServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestConn(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveTestConn(conn net.Conn) {
+	defer conn.Close()
+	decoder := codec.NewPomeloPacketDecoder()
+	encoder := codec.NewPomeloPacketEncoder()
+	msgEncoder := message.NewMessagesEncoder(false)
+	docs, _ := json.Marshal(map[string]string{
+		"doc": `{"handlers":{"room.room.join":{},"room.room.fail":{}},"remotes":{}}`,
+	})
+	buf := bytes.NewBuffer(nil)
+	data := make([]byte, 2048)
+	for {
+		n, err := conn.Read(data)
+		if err != nil {
+			return
+		}
+		buf.Write(data[:n])
+		packets, err := decoder.Decode(buf.Bytes())
+		if err != nil {
+			return
+		}
+		for _, p := range packets {
+			buf.Next(codec.HeadLength + p.Length)
+			var res *message.Message
+			switch p.Type {
+			case packet.Handshake:
+				out, _ := encoder.Encode(packet.Handshake, []byte(`{"code":200,"sys":{"heartbeat":60,"serializer":"json"}}`))
+				conn.Write(out)
+				continue
+			case packet.Data:
+				m, err := message.Decode(p.Data)
+				if err != nil {
+					return
+				}
+				switch m.Route {
+				case testDocsRoute:
+					res = &message.Message{Type: message.Response, ID: m.ID, Data: docs}
+				case "room.room.join":
+					res = &message.Message{Type: message.Response, ID: m.ID, Data: []byte(`{"code":200}`)}
+				case "room.room.fail":
+					res = &message.Message{Type: message.Response, ID: m.ID, Data: []byte(`{"ErrorCode":500}`), Err: true}
+				case "room.room.message":
+					res = &message.Message{Type: message.Push, Route: "room.onMessage", Data: m.Data}
+				}
+			}
+			if res != nil {
+				encMsg, _ := msgEncoder.Encode(res)
+				out, _ := encoder.Encode(packet.Data, encMsg)
+				conn.Write(out)
+			}
+		}
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	t.Parallel()
+	tables := []struct {
+		addr   string
+		target *target
+		err    bool
+	}{
+		{"localhost:3250", &target{addr: "localhost:3250"}, false},
+		{"tcp://localhost:3250", &target{addr: "localhost:3250"}, false},
+		{"tls://localhost:3250", &target{addr: "localhost:3250", tls: true}, false},
+		{"ws://localhost:3250/ws", &target{addr: "localhost:3250", path: "/ws", websocket: true}, false},
+		{"wss://localhost:3250", &target{addr: "localhost:3250", websocket: true, tls: true}, false},
+		{"http://localhost:3250", nil, true},
+		{"", nil, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.addr, func(t *testing.T) {
+			target, err := parseAddress(table.addr)
+			assert.Equal(t, table.target, target)
+			assert.Equal(t, table.err, err != nil)
+		})
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	addr := newTestServer(t)
+	out := &syncBuffer{}
+	c := newCLI(out)
+	c.docsRoute = testDocsRoute
+
+	script := strings.Join([]string{
+		"# smoke test",
+		`handshake {"user":{"token":"abc"}}`,
+		"watch room.onMessage",
+		"connect " + addr,
+		"routes",
+		`request room.room.join {"name": "john"}`,
+		`notify room.room.message {"content":"hello"}`,
+		"sleep 50ms",
+		"disconnect",
+	}, "\n")
+	assert.NoError(t, runScript(c, strings.NewReader(script)))
+
+	output := out.String()
+	assert.Contains(t, output, "connected to "+addr)
+	assert.Contains(t, output, "room.room.fail\nroom.room.join\n")
+	assert.Contains(t, output, "{\"code\":200}\n")
+	assert.Contains(t, output, "push room.onMessage: {\"content\":\"hello\"}\n")
+	assert.Contains(t, output, "disconnected\n")
+	assert.NotContains(t, output, "smoke test")
+	assert.Equal(t, "abc", c.handshake.User["token"])
+	assert.Len(t, c.history, 8)
+}
+
+func TestRunScriptStopsOnError(t *testing.T) {
+	addr := newTestServer(t)
+	out := &syncBuffer{}
+	c := newCLI(out)
+
+	script := strings.Join([]string{
+		"connect " + addr,
+		"request room.room.fail",
+		"request room.room.join",
+	}, "\n")
+	err := runScript(c, strings.NewReader(script))
+	assert.EqualError(t, err, "line 2: request room.room.fail: request to room.room.fail failed with error code 500")
+	assert.NotContains(t, out.String(), "room.room.join")
+	assert.Nil(t, c.client)
+}
+
+func TestRunScriptErrors(t *testing.T) {
+	t.Parallel()
+	tables := []struct {
+		line string
+		err  string
+	}{
+		{"unknown", "line 1: unknown: unknown command unknown, run help to list the commands"},
+		{"request room.room.join", "line 1: request room.room.join: not connected"},
+		{"handshake {", "line 1: handshake {: invalid handshake data: unexpected end of JSON input"},
+		{"sleep soon", `line 1: sleep soon: time: invalid duration "soon"`},
+		{"routes", "line 1: routes: no routes loaded, connect with the docs route set"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.line, func(t *testing.T) {
+			err := runScript(newCLI(&syncBuffer{}), strings.NewReader(table.line))
+			assert.EqualError(t, err, table.err)
+		})
+	}
+
+	assert.NoError(t, runScript(newCLI(&syncBuffer{}), strings.NewReader("exit\nunknown")))
+}
+
+func TestCompletions(t *testing.T) {
+	t.Parallel()
+	c := newCLI(&syncBuffer{})
+	c.routes = []string{"room.room.join", "room.room.leave", "connector.entry.enter"}
+
+	assert.Equal(t, []string{"request", "routes"}, c.completions("r"))
+	assert.Equal(t, []string{"room.room.join", "room.room.leave"}, c.completions("request room"))
+	assert.Equal(t, []string{"connector.entry.enter"}, c.completions("watch con"))
+	assert.Empty(t, c.completions("request room.room.join {"))
+	assert.Empty(t, c.completions("sleep 1"))
+
+	completer := &completer{cli: c}
+	line := []rune("request room.room.j")
+	completions, length := completer.Do(line, len(line))
+	assert.Equal(t, [][]rune{[]rune("oin ")}, completions)
+	assert.Equal(t, len("room.room.j"), length)
+}
+
+// syncBuffer is a buffer safe to be written by the pushes goroutine
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
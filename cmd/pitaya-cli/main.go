@@ -0,0 +1,187 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// pitaya-cli is a REPL client for pitaya servers, it connects using tcp or
+// websockets, sends requests and notifies and prints the pushes received.
+// Routes are completed with tab from the server docs. With -f it runs the
+// commands of a file instead, exiting with an error on the first that fails,
+// which is useful for smoke tests.
+//
+//	pitaya-cli -docs connector.docsHandler.docs
+//	pitaya> connect localhost:3250
+//	pitaya> request room.room.join {"name":"john"}
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+	"github.com/sirupsen/logrus"
+	"github.com/hnlxhzw/pitaya/logger"
+)
+
+func main() {
+	script := flag.String("f", "", "file with the commands to run, one per line, instead of starting the REPL")
+	docs := flag.String("docs", "", "route of the server docs, used to complete routes")
+	descriptors := flag.String("descriptors", "", "route of the server protobuf descriptors")
+	proto := flag.Bool("proto", false, "convert the json payloads to and from protobuf with the server descriptors, requires -docs")
+	timeout := flag.Duration("timeout", 5*time.Second, "request timeout")
+	insecure := flag.Bool("insecure", false, "skip the verification of the server certificate on tls connections")
+	history := flag.String("history", defaultHistoryFile(), "file to save the REPL history")
+	verbose := flag.Bool("v", false, "log the client errors")
+	flag.Parse()
+
+	if *proto && *docs == "" {
+		fmt.Fprintln(os.Stderr, "-proto requires the docs route")
+		os.Exit(2)
+	}
+
+	l := logrus.New()
+	l.SetLevel(logrus.FatalLevel)
+	if *verbose {
+		l.SetLevel(logrus.ErrorLevel)
+	}
+	logger.SetLogger(l)
+
+	c := newCLI(os.Stdout)
+	c.proto = *proto
+	c.docsRoute = *docs
+	c.descriptorsRoute = *descriptors
+	c.timeout = *timeout
+	c.insecure = *insecure
+
+	if *script != "" {
+		f, err := os.Open(*script)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer f.Close()
+		if err := runScript(c, f); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := repl(c, *history); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".pitaya_cli_history")
+}
+
+// runScript runs the commands of r, printing each before running it, and
+// stops at the first that fails
+func runScript(c *cli, r io.Reader) error {
+	defer func() {
+		if c.client != nil {
+			c.disconnect("")
+		}
+	}()
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		c.printf("> %s\n", line)
+		if err := c.exec(line); err == errQuit {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("line %d: %s: %w", lineNumber, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func repl(c *cli, historyFile string) error {
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          "pitaya> ",
+		HistoryFile:     historyFile,
+		AutoComplete:    &completer{cli: c},
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		return err
+	}
+	defer rl.Close()
+	c.out = rl.Stdout()
+
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			if line == "" {
+				break
+			}
+			continue
+		} else if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if err := c.exec(line); err == errQuit {
+			return nil
+		} else if err != nil {
+			c.printf("error: %s\n", err.Error())
+		}
+	}
+	c.quit("")
+	return nil
+}
+
+// completer completes the commands and routes of the cli
+type completer struct {
+	cli *cli
+}
+
+// Do returns the suffixes completing the word before pos
+func (c *completer) Do(line []rune, pos int) ([][]rune, int) {
+	text := string(line[:pos])
+	word := text
+	if i := strings.LastIndexAny(text, " \t"); i >= 0 {
+		word = text[i+1:]
+	}
+
+	completions := [][]rune{}
+	for _, completion := range c.cli.completions(text) {
+		completions = append(completions, []rune(completion[len(word):]+" "))
+	}
+	return completions, len([]rune(word))
+}
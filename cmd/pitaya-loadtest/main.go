@@ -0,0 +1,113 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// pitaya-loadtest runs simulated players against a pitaya frontend server,
+// following a yaml scenario, and reports the latency percentiles, errors and
+// throughput of each route.
+//
+//	pitaya-loadtest -addr localhost:3250 -scenario scenario.yaml -players 1000 -rampup 1m -duration 5m
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"github.com/hnlxhzw/pitaya/loadtest"
+	"github.com/hnlxhzw/pitaya/logger"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:3250", "address of the frontend server")
+	ws := flag.Bool("ws", false, "connect using websockets")
+	path := flag.String("path", "", "websocket path")
+	useTLS := flag.Bool("tls", false, "connect using tls, without verifying the server certificate")
+	scenarioPath := flag.String("scenario", "", "path of the yaml scenario")
+	players := flag.Int("players", 100, "number of simulated players")
+	rampUp := flag.Duration("rampup", 0, "time taken to start all players")
+	duration := flag.Duration("duration", 0, "duration of the test, players repeat the scenario until it ends. If 0 each player runs it once")
+	timeout := flag.Duration("timeout", 0, "request timeout, 5s if 0")
+	window := flag.Int("window", 0, "limit of requests of a player waiting for a response")
+	output := flag.String("output", "text", "report format, text or json")
+	verbose := flag.Bool("v", false, "log the clients errors")
+	flag.Parse()
+
+	if *scenarioPath == "" {
+		fmt.Fprintln(os.Stderr, "a scenario is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "invalid output %s, it must be text or json\n", *output)
+		os.Exit(2)
+	}
+
+	l := logrus.New()
+	l.SetLevel(logrus.FatalLevel)
+	if *verbose {
+		l.SetLevel(logrus.ErrorLevel)
+	}
+	logger.SetLogger(l)
+
+	scenario, err := loadtest.LoadYAMLScenario(*scenarioPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load scenario: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	config := loadtest.Config{
+		Address:        *addr,
+		WebSocket:      *ws,
+		Path:           *path,
+		Players:        *players,
+		RampUp:         *rampUp,
+		Duration:       *duration,
+		RequestTimeout: *timeout,
+		InflightWindow: *window,
+		HandshakeData:  scenario.HandshakeData,
+	}
+	if *useTLS {
+		config.TLS = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	report, err := loadtest.Run(ctx, config, scenario)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to run load test: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if *output == "json" {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
@@ -20,10 +20,16 @@
 
 package component
 
+import "github.com/hnlxhzw/pitaya/pipeline"
+
 type (
 	options struct {
-		name     string              // component name
-		nameFunc func(string) string // rename handler name
+		name            string                     // component name
+		nameFunc        func(string) string        // rename handler name
+		pipeline        pipeline.Chain             // pipeline of all methods
+		methodPipelines map[string]*pipeline.Chain // pipelines of each method
+		requireBound    bool                       // all handlers require a bound session
+		requireBoundFor map[string]bool            // handlers that require a bound session
 	}
 
 	// Option used to customize handler
@@ -44,3 +50,80 @@ func WithNameFunc(fn func(string) string) Option {
 		opt.nameFunc = fn
 	}
 }
+
+// WithBeforeHandler adds a function to be called before each method of the
+// component, after the global and route pipelines
+func WithBeforeHandler(h pipeline.HandlerTempl) Option {
+	return func(opt *options) {
+		opt.pipeline.PushBefore(h)
+	}
+}
+
+// WithAfterHandler adds a function to be called after each method of the
+// component, before the route and global pipelines
+func WithAfterHandler(h pipeline.AfterHandlerTempl) Option {
+	return func(opt *options) {
+		opt.pipeline.PushAfter(h)
+	}
+}
+
+// WithMethodBeforeHandler adds a function to be called before the method of
+// the component, after the component pipeline. The method name is the one
+// in the route, after applying the name function
+func WithMethodBeforeHandler(method string, h pipeline.HandlerTempl) Option {
+	return func(opt *options) {
+		opt.methodPipeline(method).PushBefore(h)
+	}
+}
+
+// WithMethodAfterHandler adds a function to be called after the method of
+// the component, before the component pipeline. The method name is the one
+// in the route, after applying the name function
+func WithMethodAfterHandler(method string, h pipeline.AfterHandlerTempl) Option {
+	return func(opt *options) {
+		opt.methodPipeline(method).PushAfter(h)
+	}
+}
+
+// WithRequireBoundSession makes the given handler methods, or all handler
+// methods of the component if none is given, require a session bound to an
+// UID. Requests from sessions not bound are rejected before the pipelines
+// are executed. The method names are the ones in the route, after applying
+// the name function
+func WithRequireBoundSession(methods ...string) Option {
+	return func(opt *options) {
+		if len(methods) == 0 {
+			opt.requireBound = true
+			return
+		}
+		if opt.requireBoundFor == nil {
+			opt.requireBoundFor = map[string]bool{}
+		}
+		for _, method := range methods {
+			opt.requireBoundFor[method] = true
+		}
+	}
+}
+
+func (opt *options) requiresBoundSession(method string) bool {
+	return opt.requireBound || opt.requireBoundFor[method]
+}
+
+func (opt *options) methodPipeline(method string) *pipeline.Chain {
+	if opt.methodPipelines == nil {
+		opt.methodPipelines = map[string]*pipeline.Chain{}
+	}
+	if _, ok := opt.methodPipelines[method]; !ok {
+		opt.methodPipelines[method] = &pipeline.Chain{}
+	}
+	return opt.methodPipelines[method]
+}
+
+// pipelineOf returns the pipeline of a method, the component pipeline
+// wrapping the method one
+func (opt *options) pipelineOf(method string) pipeline.Chain {
+	if chain, ok := opt.methodPipelines[method]; ok {
+		return opt.pipeline.Then(*chain)
+	}
+	return opt.pipeline.Then(pipeline.Chain{})
+}
@@ -21,6 +21,7 @@
 package component
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -43,3 +44,39 @@ func TestWithNameFunc(t *testing.T) {
 	WithNameFunc(nameFunc)(opt)
 	assert.Equal(t, opt.nameFunc(name), strings.ToUpper(name))
 }
+
+func TestWithPipelines(t *testing.T) {
+	before := func(ctx context.Context, in interface{}) (context.Context, interface{}, error, int32) {
+		return ctx, in, nil, 0
+	}
+	after := func(ctx context.Context, out interface{}, err error) (interface{}, error, int32) {
+		return out, err, 0
+	}
+
+	opt := &options{}
+	WithBeforeHandler(before)(opt)
+	WithAfterHandler(after)(opt)
+	WithMethodBeforeHandler("join", before)(opt)
+	WithMethodBeforeHandler("join", before)(opt)
+	WithMethodAfterHandler("leave", after)(opt)
+
+	assert.Len(t, opt.pipeline.Before, 1)
+	assert.Len(t, opt.pipeline.After, 1)
+	assert.Len(t, opt.pipelineOf("join").Before, 3)
+	assert.Len(t, opt.pipelineOf("join").After, 1)
+	assert.Len(t, opt.pipelineOf("leave").Before, 1)
+	assert.Len(t, opt.pipelineOf("leave").After, 2)
+	assert.Len(t, opt.pipelineOf("other").Before, 1)
+	assert.Len(t, opt.pipelineOf("other").After, 1)
+}
+
+func TestWithRequireBoundSession(t *testing.T) {
+	opt := &options{}
+	WithRequireBoundSession("join", "leave")(opt)
+	assert.True(t, opt.requiresBoundSession("join"))
+	assert.True(t, opt.requiresBoundSession("leave"))
+	assert.False(t, opt.requiresBoundSession("enter"))
+
+	WithRequireBoundSession()(opt)
+	assert.True(t, opt.requiresBoundSession("enter"))
+}
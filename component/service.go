@@ -26,16 +26,19 @@ import (
 
 	"github.com/hnlxhzw/pitaya/conn/message"
 	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/pipeline"
 )
 
 type (
 	//Handler represents a message.Message's handler's meta information.
 	Handler struct {
-		Receiver    reflect.Value  // receiver of method
-		Method      reflect.Method // method stub
-		Type        reflect.Type   // low-level type of method
-		IsRawArg    bool           // whether the data need to serialize
-		MessageType message.Type   // handler allowed message type (either request or notify)
+		Receiver            reflect.Value  // receiver of method
+		Method              reflect.Method // method stub
+		Type                reflect.Type   // low-level type of method
+		IsRawArg            bool           // whether the data need to serialize
+		MessageType         message.Type   // handler allowed message type (either request or notify)
+		Pipeline            pipeline.Chain // pipeline of the component and method options
+		RequireBoundSession bool           // whether the session must be bound to an UID
 	}
 
 	//Remote represents remote's meta information.
@@ -44,6 +47,7 @@ type (
 		Method   reflect.Method // method stub
 		HasArgs  bool           // if remote has no args we won't try to serialize received data into arguments
 		Type     reflect.Type   // low-level type of method
+		Pipeline pipeline.Chain // pipeline of the component and method options
 	}
 
 	// Service implements a specific service, some of it's methods will be
@@ -114,6 +118,8 @@ func (s *Service) ExtractHandler() error {
 
 	for i := range s.Handlers {
 		s.Handlers[i].Receiver = s.Receiver
+		s.Handlers[i].Pipeline = s.Options.pipelineOf(i)
+		s.Handlers[i].RequireBoundSession = s.Options.requiresBoundSession(i)
 	}
 
 	return nil
@@ -151,6 +157,7 @@ func (s *Service) ExtractRemote() error {
 
 	for i := range s.Remotes {
 		s.Remotes[i].Receiver = s.Receiver
+		s.Remotes[i].Pipeline = s.Options.pipelineOf(i)
 	}
 	return nil
 }
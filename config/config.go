@@ -50,7 +50,18 @@ func NewConfig(cfgs ...*viper.Viper) *Config {
 
 func (c *Config) fillDefaultValues() {
 	defaultsMap := map[string]interface{}{
-		"pitaya.buffer.agent.messages": 100,
+		"pitaya.auth.jwt.algorithms":         []string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "ES256", "ES384", "ES512"},
+		"pitaya.auth.jwt.audience":           "",
+		"pitaya.auth.jwt.bind":               true,
+		"pitaya.auth.jwt.claims":             map[string]string{},
+		"pitaya.auth.jwt.handshakekey":       "token",
+		"pitaya.auth.jwt.issuer":             "",
+		"pitaya.auth.jwt.keysfile":           "",
+		"pitaya.auth.jwt.keysreloadinterval": "1m",
+		"pitaya.auth.jwt.leeway":             "0s",
+		"pitaya.auth.jwt.secret":             "",
+		"pitaya.auth.jwt.uidclaim":           "sub",
+		"pitaya.buffer.agent.messages":       100,
 		// the max buffer size that nats will accept, if this buffer overflows, messages will begin to be dropped
 		"pitaya.buffer.cluster.rpc.server.nats.messages":        75,
 		"pitaya.buffer.cluster.rpc.server.nats.push":            100,
@@ -96,18 +107,36 @@ func (c *Config) fillDefaultValues() {
 		"pitaya.groups.etcd.prefix":                        "pitaya/",
 		"pitaya.groups.etcd.transactiontimeout":            "5s",
 		"pitaya.groups.memory.tickduration":                "30s",
+		"pitaya.groups.redis.db":                           0,
+		"pitaya.groups.redis.dialtimeout":                  "5s",
+		"pitaya.groups.redis.password":                     "",
+		"pitaya.groups.redis.poolsize":                     10,
+		"pitaya.groups.redis.prefix":                       "pitaya/",
+		"pitaya.groups.redis.transactiontimeout":           "5s",
+		"pitaya.groups.redis.url":                          "localhost:6379",
+		"pitaya.handler.dictionary.auto":                   false,
 		"pitaya.handler.messages.compression":              true,
 		"pitaya.heartbeat.interval":                        "30s",
 		"pitaya.metrics.additionalTags":                    map[string]string{},
 		"pitaya.metrics.constTags":                         map[string]string{},
 		"pitaya.metrics.custom":                            map[string]interface{}{},
+		"pitaya.metrics.histograms.buckets":                []string{"1ms", "5ms", "10ms", "25ms", "50ms", "100ms", "250ms", "500ms", "1s", "2.5s", "5s", "10s"},
+		"pitaya.metrics.histograms.enabled":                false,
+		"pitaya.metrics.histograms.routes":                 []interface{}{},
+		"pitaya.metrics.otel.enabled":                      false,
 		"pitaya.metrics.periodicMetrics.period":            "15s",
 		"pitaya.metrics.prometheus.enabled":                false,
 		"pitaya.metrics.prometheus.port":                   9090,
+		"pitaya.metrics.slos":                              []interface{}{},
 		"pitaya.metrics.statsd.enabled":                    false,
 		"pitaya.metrics.statsd.host":                       "localhost:9125",
 		"pitaya.metrics.statsd.prefix":                     "pitaya.",
 		"pitaya.metrics.statsd.rate":                       1,
+		"pitaya.modules.admin.enabled":                     false,
+		"pitaya.modules.admin.port":                        9091,
+		"pitaya.modules.admin.pprof":                       false,
+		"pitaya.modules.admin.shutdowntimeout":             "5s",
+		"pitaya.modules.admin.token":                       "",
 		"pitaya.modules.bindingstorage.etcd.dialtimeout":   "5s",
 		"pitaya.modules.bindingstorage.etcd.endpoints":     "localhost:2379",
 		"pitaya.modules.bindingstorage.etcd.leasettl":      "1h",
@@ -115,8 +144,12 @@ func (c *Config) fillDefaultValues() {
 		"pitaya.conn.ratelimiting.limit":                   20,
 		"pitaya.conn.ratelimiting.interval":                "1s",
 		"pitaya.conn.ratelimiting.forcedisable":            false,
+		"pitaya.session.handoff.timeout":                   "1m",
 		"pitaya.session.unique":                            true,
+		"pitaya.worker.backend":                            "redis",
 		"pitaya.worker.concurrency":                        1,
+		"pitaya.worker.idempotency.window":                 "24h",
+		"pitaya.worker.memory.maxDeadJobs":                 10000,
 		"pitaya.worker.redis.pool":                         "10",
 		"pitaya.worker.redis.url":                          "localhost:6379",
 		"pitaya.worker.retry.enabled":                      true,
@@ -21,8 +21,12 @@
 package message
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 )
 
@@ -63,6 +67,7 @@ var (
 	ErrWrongMessageType  = errors.New("wrong message type")
 	ErrInvalidMessage    = errors.New("invalid message")
 	ErrRouteInfoNotFound = errors.New("route info not found in dictionary")
+	ErrDictionaryFull    = errors.New("no route codes left in dictionary")
 )
 
 // Message represents a unmarshaled message or a message which to be marshaled
@@ -136,6 +141,63 @@ func GetDictionary() map[string]uint16 {
 	return routes
 }
 
+// AddRoutes adds the routes that are not in the dictionary yet, in their
+// sorted order, with the lowest codes not in use. The same routes added to
+// the same dictionary always get the same codes.
+func AddRoutes(newRoutes []string) error {
+	added := map[string]bool{}
+	sorted := make([]string, 0, len(newRoutes))
+	for _, route := range newRoutes {
+		r := strings.TrimSpace(route)
+		if _, ok := routes[r]; ok || r == "" || added[r] {
+			continue
+		}
+		added[r] = true
+		sorted = append(sorted, r)
+	}
+	sort.Strings(sorted)
+	if len(codes)+len(sorted) > math.MaxUint16 {
+		return ErrDictionaryFull
+	}
+
+	code := 1
+	for _, r := range sorted {
+		for ; codes[uint16(code)] != ""; code++ {
+		}
+		routes[r] = uint16(code)
+		codes[uint16(code)] = r
+	}
+
+	return nil
+}
+
+// ClearDictionary removes all the routes of the dictionary
+func ClearDictionary() {
+	routes = make(map[string]uint16)
+	codes = make(map[uint16]string)
+}
+
+// DictionaryVersion returns a hash of the routes and codes of the dictionary,
+// clients that have it can send it in the handshake to not receive the
+// dictionary again. It is empty if the dictionary is.
+func DictionaryVersion() string {
+	if len(routes) == 0 {
+		return ""
+	}
+
+	sorted := make([]string, 0, len(routes))
+	for route := range routes {
+		sorted = append(sorted, route)
+	}
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	for _, route := range sorted {
+		fmt.Fprintf(h, "%s:%d\n", route, routes[route])
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
 func (t *Type) String() string {
 	return types[*t]
 }
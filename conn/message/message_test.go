@@ -170,3 +170,29 @@ func TestSetDictionaty(t *testing.T) {
 		})
 	}
 }
+
+func TestAddRoutes(t *testing.T) {
+	defer resetDicts(t)
+	resetDicts(t)
+
+	assert.NoError(t, SetDictionary(map[string]uint16{"a.b.c": 2}))
+	assert.NoError(t, AddRoutes([]string{"z.z.z", "a.b.c", "b.b.b", " b.b.b ", ""}))
+	assert.Equal(t, map[string]uint16{"a.b.c": 2, "b.b.b": 1, "z.z.z": 3}, routes)
+	assert.Equal(t, map[uint16]string{1: "b.b.b", 2: "a.b.c", 3: "z.z.z"}, codes)
+
+	version := DictionaryVersion()
+	assert.Len(t, version, 16)
+
+	// the same routes always get the same codes
+	resetDicts(t)
+	assert.NoError(t, SetDictionary(map[string]uint16{"a.b.c": 2}))
+	assert.NoError(t, AddRoutes([]string{"b.b.b", "z.z.z"}))
+	assert.Equal(t, version, DictionaryVersion())
+
+	assert.NoError(t, AddRoutes([]string{"c.c.c"}))
+	assert.NotEqual(t, version, DictionaryVersion())
+
+	ClearDictionary()
+	assert.Empty(t, GetDictionary())
+	assert.Equal(t, "", DictionaryVersion())
+}
@@ -41,11 +41,24 @@ const (
 
 	// KickRoute is the route used for kicking an user
 	KickRoute = "sys.kick"
+
+	// SessionHandoffRoute is the route used for transferring a session to another frontend
+	SessionHandoffRoute = "sys.handoffsession"
+
+	// GroupBroadcastRoute is the route used for broadcasting to the local sessions of a group
+	GroupBroadcastRoute = "sys.groupbroadcast"
+
+	// RedirectRoute is the push route used for telling a client to reconnect to another frontend
+	RedirectRoute = "sys.redirect"
 )
 
 // SessionCtxKey is the context key where the session will be set
 var SessionCtxKey = "session"
 
+// SessionAuthExpiresAtKey is the session data key holding the expiration
+// time, in unix seconds, of the token the session was authenticated with
+var SessionAuthExpiresAtKey = "pitaya.auth.exp"
+
 // LoggerCtxKey is the context key where the default logger will be set
 var LoggerCtxKey = "default-logger"
 
@@ -59,6 +72,10 @@ var PropagateCtxKey = propagateKey{}
 // the propagate key
 var SpanPropagateCtxKey = "opentracing-span"
 
+// TraceContextPropagateCtxKey is the key holding the W3C trace context of
+// the OpenTelemetry spans inside the propagate key
+var TraceContextPropagateCtxKey = "trace-context"
+
 // PeerIDKey is the key holding the peer id to be sent over the context
 var PeerIDKey = "peer.id"
 
@@ -93,6 +110,18 @@ var GRPCExternalPortKey = "grpc-external-port"
 // RegionKey is the key to save the region server is on
 var RegionKey = "region"
 
+// SessionGroupsKey is the session data key holding the groups joined through the session
+var SessionGroupsKey = "pitaya.groups"
+
+// SessionSerializerKey is the session data key holding the serializer negotiated by the client
+var SessionSerializerKey = "pitaya.serializer"
+
+// ClientAddressKey is the key for the address clients use to reach a frontend on server metadata
+var ClientAddressKey = "client-address"
+
+// HandlerRoutesKey is the key for the comma separated routes of the handlers of a server on server metadata
+var HandlerRoutesKey = "handler-routes"
+
 // IP constants
 const (
 	IPVersionKey = "ipversion"
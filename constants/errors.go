@@ -24,62 +24,95 @@ import "errors"
 
 // Errors that can occur during message handling.
 var (
+	ErrAdminUnauthorized              = errors.New("missing or invalid admin token")
 	ErrBindingNotFound                = errors.New("binding for this user was not found in etcd")
 	ErrBrokenPipe                     = errors.New("broken low-level pipe")
 	ErrBufferExceed                   = errors.New("session send buffer exceed")
 	ErrChangeDictionaryWhileRunning   = errors.New("you shouldn't change the dictionary while the app is already running")
 	ErrChangeRouteWhileRunning        = errors.New("you shouldn't change routes while app is already running")
+	ErrClientNotConnected             = errors.New("client is not connected")
 	ErrCloseClosedGroup               = errors.New("close closed group")
 	ErrCloseClosedSession             = errors.New("close closed session")
 	ErrClosedGroup                    = errors.New("group closed")
+	ErrEmptyPushRoute                 = errors.New("push route must not be empty")
 	ErrEmptyUID                       = errors.New("empty uid")
 	ErrEtcdGrantLeaseTimeout          = errors.New("timed out waiting for etcd lease grant")
 	ErrEtcdLeaseNotFound              = errors.New("etcd lease not found in group")
 	ErrFrontSessionCantPushToFront    = errors.New("frontend session can't push to front")
 	ErrFrontendTypeNotSpecified       = errors.New("for using SendPushToUsers from a backend server you have to specify a valid frontendType")
 	ErrGroupAlreadyExists             = errors.New("group already exists")
+	ErrGroupMetadataNotSupported      = errors.New("the group service does not support member metadata")
 	ErrGroupNotFound                  = errors.New("group not found")
+	ErrHandingOffSessions             = errors.New("failed to hand off sessions, check array with failed session ids")
+	ErrHandoffNotFound                = errors.New("session handoff not found or expired")
+	ErrHandoffOnBackend               = errors.New("sessions can only be handed off from a frontend server")
+	ErrHandoffTargetNotFrontend       = errors.New("sessions can only be handed off to a frontend server")
+	ErrHandoffUIDMismatch             = errors.New("session handoff belongs to another uid")
 	ErrIllegalUID                     = errors.New("illegal uid")
 	ErrInvalidCertificates            = errors.New("certificates must be exactly two")
+	ErrInvalidDocs                    = errors.New("invalid docs, they must be the handlers and remotes documentation")
+	ErrInvalidToken                   = errors.New("invalid token")
+	ErrInvalidLoadTestConfig          = errors.New("invalid load test config, it must have an address and at least one player")
+	ErrInvalidSLO                     = errors.New("invalid slo, it must have a route and objectives between 0 and 1")
 	ErrInvalidSpanCarrier             = errors.New("tracing: invalid span carrier")
+	ErrInvalidWorkerBackend           = errors.New("invalid worker backend, it must be redis or memory")
+	ErrJobNotFound                    = errors.New("job not found or not scheduled")
 	ErrKickingUsers                   = errors.New("failed to kick users, check array with failed uids")
 	ErrMemberAlreadyExists            = errors.New("member already exists in group")
 	ErrMemberNotFound                 = errors.New("member not found in the group")
 	ErrMemoryTTLNotFound              = errors.New("memory group TTL not found")
 	ErrMetricNotKnown                 = errors.New("the provided metric does not exist")
+	ErrModuleNotStarted               = errors.New("module was not started yet")
 	ErrNatsMessagesBufferSizeZero     = errors.New("pitaya.buffer.cluster.rpc.server.nats.messages cant be zero")
 	ErrNatsNoRequestTimeout           = errors.New("pitaya.cluster.rpc.client.nats.requesttimeout cant be empty")
 	ErrNatsPushBufferSizeZero         = errors.New("pitaya.buffer.cluster.rpc.server.nats.push cant be zero")
 	ErrNilCondition                   = errors.New("pitaya/timer: nil condition")
+	ErrNilPushPayload                 = errors.New("push payload must not be nil")
+	ErrMissingToken                   = errors.New("missing token")
 	ErrNoBindingStorageModule         = errors.New("for sending remote pushes or using unique session module while using grpc you need to pass it a BindingStorage")
+	ErrNoClientAddress                = errors.New("target server has no client address specified in metadata")
 	ErrNoConnectionToServer           = errors.New("rpc client has no connection to the chosen server")
 	ErrNoContextFound                 = errors.New("no context found")
+	ErrNoJWTKeys                      = errors.New("no jwt keys configured, set a secret or a keys file")
 	ErrNoNatsConnectionString         = errors.New("you have to provide a nats url")
 	ErrNoServerTypeChosenForRPC       = errors.New("no server type chosen for sending RPC, send a full route in the format server.service.component")
 	ErrNoServerWithID                 = errors.New("can't find any server with the provided ID")
 	ErrNoServersAvailableOfType       = errors.New("no servers available of this type")
+	ErrNoServersToHandoff             = errors.New("no other frontend servers of this type available to hand off sessions")
 	ErrNoUIDBind                      = errors.New("you have to bind an UID to the session to do that")
 	ErrNonsenseRPC                    = errors.New("you are making a rpc that may be processed locally, either specify a different server type or specify a server id")
 	ErrNotImplemented                 = errors.New("method not implemented")
 	ErrNotifyOnRequest                = errors.New("tried to notify a request route")
 	ErrOnCloseBackend                 = errors.New("onclose callbacks are not allowed on backend servers")
 	ErrProtodescriptor                = errors.New("failed to get protobuf message descriptor")
+	ErrPushNotDecoded                 = errors.New("push cannot be decoded for the session serializer")
+	ErrPushNotDeclared                = errors.New("push route was not declared")
+	ErrPushPayloadMismatch            = errors.New("push payload type does not match the declared one")
 	ErrPushingToUsers                 = errors.New("failed to push message to users, check array with failed uids")
+	ErrRedisTTLNotFound               = errors.New("redis group TTL not found")
 	ErrRPCClientNotInitialized        = errors.New("RPC client is not running")
 	ErrRPCJobAlreadyRegistered        = errors.New("rpc job was already registered")
 	ErrRPCLocal                       = errors.New("RPC must be to a different server type")
 	ErrRPCServerNotInitialized        = errors.New("RPC server is not running")
 	ErrReplyShouldBeNotNull           = errors.New("reply must not be null")
 	ErrReplyShouldBePtr               = errors.New("reply must be a pointer")
+	ErrRequestTimeout                 = errors.New("request timeout")
 	ErrRequestOnNotify                = errors.New("tried to request a notify route")
 	ErrRouterNotInitialized           = errors.New("router is not initialized")
 	ErrServerNotFound                 = errors.New("server not found")
+	ErrServerStopping                 = errors.New("server is stopping")
 	ErrServiceDiscoveryNotInitialized = errors.New("service discovery client is not initialized")
 	ErrSessionAlreadyBound            = errors.New("session is already bound to an uid")
 	ErrSessionDuplication             = errors.New("session exists in the current group")
 	ErrSessionNotFound                = errors.New("session not found")
 	ErrSessionOnNotify                = errors.New("current session working on notify mode")
 	ErrTimeoutTerminatingBinaryModule = errors.New("timeout waiting to binary module to die")
+	ErrUnauthenticatedSession         = errors.New("session is not authenticated or its token expired")
+	ErrUnknownJWTKey                  = errors.New("no jwt key matches the token key id and algorithm")
+	ErrUnsupportedJWK                 = errors.New("unsupported json web key")
+	ErrUnsupportedSDKLanguage         = errors.New("unsupported sdk language, it must be typescript or csharp")
+	ErrUnsupportedSerializer          = errors.New("serializer is not registered on the server")
+	ErrWorkerNotStarted               = errors.New("worker was not started")
 	ErrWrongValueType                 = errors.New("protobuf: convert on wrong type value")
 	ErrRateLimitExceeded              = errors.New("rate limit exceeded")
 	ErrReceivedMsgSmallerThanExpected = errors.New("received less data than expected, EOF?")
@@ -33,6 +33,7 @@ import (
 type docs struct {
 	Handlers docMap `json:"handlers"`
 	Remotes  docMap `json:"remotes"`
+	Pushes   docMap `json:"pushes"`
 }
 
 type docMap map[string]*doc
@@ -74,6 +75,22 @@ func RemotesDocs(serverType string, services map[string]*component.Service, getP
 	return docs.Remotes.toMap()
 }
 
+// PushesDocs returns a map from push route to its payload as the only output
+func PushesDocs(pushes map[string]reflect.Type, getPtrNames bool) (map[string]interface{}, error) {
+	docs := &docs{
+		Pushes: map[string]*doc{},
+	}
+
+	for route, typ := range pushes {
+		isOutput := true
+		docs.Pushes[route] = &doc{
+			Output: []interface{}{docForType(typ, isOutput, getPtrNames)},
+		}
+	}
+
+	return docs.Pushes.toMap()
+}
+
 func (d docMap) toMap() (map[string]interface{}, error) {
 	var m map[string]interface{}
 	bts, err := json.Marshal(d)
@@ -22,6 +22,7 @@ package docgenerator
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
@@ -307,3 +308,40 @@ func TestRemotesDocTrue(t *testing.T) {
 		},
 	}, doc)
 }
+
+func TestPushesDoc(t *testing.T) {
+	t.Parallel()
+	pushes := map[string]reflect.Type{
+		"onStruct": reflect.TypeOf(&test.SomeStruct{}),
+		"onRaw":    reflect.TypeOf([]byte{}),
+	}
+
+	doc, err := PushesDocs(pushes, false)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"onStruct": map[string]interface{}{
+			"input": nil,
+			"output": []interface{}{
+				map[string]interface{}{
+					"A": "int32",
+					"B": "string",
+				},
+			},
+		},
+		"onRaw": map[string]interface{}{
+			"input":  nil,
+			"output": []interface{}{"[]byte"},
+		},
+	}, doc)
+
+	doc, err = PushesDocs(pushes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"*test.SomeStruct": map[string]interface{}{
+				"A": "int32",
+				"B": "string",
+			},
+		},
+	}, doc["onStruct"].(map[string]interface{})["output"])
+}
@@ -0,0 +1,295 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package docgenerator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+const (
+	openAPIVersion    = "3.0.3"
+	jsonSchemaVersion = "https://json-schema.org/draft/2020-12/schema"
+	errorSchemaName   = "pitaya.Error"
+)
+
+// OpenAPIDocument is an OpenAPI 3 document, the messages pushed by the
+// server are described in the x-pushes extension
+type OpenAPIDocument struct {
+	OpenAPI    string                      `json:"openapi"`
+	Info       OpenAPIInfo                 `json:"info"`
+	Paths      map[string]*OpenAPIPathItem `json:"paths"`
+	Pushes     map[string]*OpenAPIResponse `json:"x-pushes,omitempty"`
+	Components OpenAPIComponents           `json:"components"`
+}
+
+// OpenAPIInfo is the metadata of an OpenAPI document
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenAPIPathItem has the operations of a path
+type OpenAPIPathItem struct {
+	Post *OpenAPIOperation `json:"post,omitempty"`
+}
+
+// OpenAPIOperation is an operation of a path, a handler route
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Tags        []string                    `json:"tags,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIRequestBody is the request body of an operation
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIResponse is a response of an operation
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIMediaType is the schema of a request or response content type
+type OpenAPIMediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// OpenAPIComponents are the schemas referenced by the operations
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+var errorSchema = &Schema{
+	Title: errorSchemaName,
+	Type:  "object",
+	Properties: map[string]*Schema{
+		"code":      {Type: "string"},
+		"msg":       {Type: "string"},
+		"metadata":  {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+		"ErrorCode": {Type: "integer", Format: "int32"},
+	},
+}
+
+// OpenAPI returns an OpenAPI 3 document with a post operation for each
+// handler route of docs, as returned by pitaya.Documentation, to be served by
+// an http gateway, and the payload of each declared push. Types documented
+// with their names, and all the protobuf messages known by resolver, which may
+// be nil, are added to the components.
+func OpenAPI(docs map[string]interface{}, info OpenAPIInfo, resolver DescriptorResolver) (*OpenAPIDocument, error) {
+	handlers, err := docsSection(docs, "handlers")
+	if err != nil {
+		return nil, err
+	}
+	pushes, err := docsSection(docs, "pushes")
+	if err != nil {
+		return nil, err
+	}
+
+	b := newSchemaBuilder("#/components/schemas/", resolver)
+	b.definitions[errorSchemaName] = errorSchema
+	document := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    info,
+		Paths:   map[string]*OpenAPIPathItem{},
+	}
+	for _, route := range sortedKeys(handlers) {
+		input, outputs, err := routeDoc(handlers[route])
+		if err != nil {
+			return nil, err
+		}
+		operation, err := b.operation(route, input, outputs)
+		if err != nil {
+			return nil, err
+		}
+		document.Paths["/"+route] = &OpenAPIPathItem{Post: operation}
+	}
+	for _, route := range sortedKeys(pushes) {
+		_, outputs, err := routeDoc(pushes[route])
+		if err != nil {
+			return nil, err
+		}
+		push := &OpenAPIResponse{Description: "push"}
+		if len(outputs) > 0 {
+			if push.Content, err = b.content(outputs[0]); err != nil {
+				return nil, err
+			}
+		}
+		if document.Pushes == nil {
+			document.Pushes = map[string]*OpenAPIResponse{}
+		}
+		document.Pushes[route] = push
+	}
+	document.Components.Schemas = b.definitions
+	return document, nil
+}
+
+func (b *schemaBuilder) operation(route string, input interface{}, outputs []interface{}) (*OpenAPIOperation, error) {
+	operation := &OpenAPIOperation{
+		OperationID: route,
+		Responses: map[string]*OpenAPIResponse{
+			"200": {Description: "handler response"},
+			"default": {
+				Description: "handler error",
+				Content: map[string]*OpenAPIMediaType{
+					"application/json": {Schema: b.ref(errorSchemaName)},
+				},
+			},
+		},
+	}
+	if i := strings.LastIndex(route, "."); i > 0 {
+		operation.Tags = []string{route[:i]}
+	}
+
+	if input != nil {
+		content, err := b.content(input)
+		if err != nil {
+			return nil, err
+		}
+		operation.RequestBody = &OpenAPIRequestBody{Required: true, Content: content}
+	}
+	if len(outputs) > 0 && outputs[0] != "error" {
+		content, err := b.content(outputs[0])
+		if err != nil {
+			return nil, err
+		}
+		operation.Responses["200"].Content = content
+	}
+	return operation, nil
+}
+
+// content returns the content of a request or response, raw handlers
+// receive and answer the bytes as they are
+func (b *schemaBuilder) content(doc interface{}) (map[string]*OpenAPIMediaType, error) {
+	if doc == "[]byte" {
+		return map[string]*OpenAPIMediaType{
+			"application/octet-stream": {Schema: &Schema{Type: "string", Format: "binary"}},
+		}, nil
+	}
+	schema, err := b.fromDoc(doc)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]*OpenAPIMediaType{"application/json": {Schema: schema}}, nil
+}
+
+// JSONSchemas returns a standalone JSON Schema for each type of the handlers,
+// remotes and pushes of docs, which must be generated with the pointer names for the
+// types to be named. The schemas are indexed by the type names, protobuf
+// messages known by resolver, which may be nil, are built from their
+// descriptors.
+func JSONSchemas(docs map[string]interface{}, resolver DescriptorResolver) (map[string]*Schema, error) {
+	b := newSchemaBuilder("#/$defs/", resolver)
+	for _, section := range []string{"handlers", "remotes", "pushes"} {
+		routes, err := docsSection(docs, section)
+		if err != nil {
+			return nil, err
+		}
+		for _, route := range sortedKeys(routes) {
+			input, outputs, err := routeDoc(routes[route])
+			if err != nil {
+				return nil, err
+			}
+			for _, doc := range append([]interface{}{input}, outputs...) {
+				if _, err := b.fromDoc(doc); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	schemas := map[string]*Schema{}
+	for name, definition := range b.definitions {
+		schema := *definition
+		schema.Schema = jsonSchemaVersion
+		defs := map[string]*Schema{}
+		b.references(definition, defs)
+		if len(defs) > 0 {
+			schema.Defs = defs
+		}
+		schemas[name] = &schema
+	}
+	return schemas, nil
+}
+
+// references adds the definitions referenced by schema, and the ones they
+// reference, to defs
+func (b *schemaBuilder) references(schema *Schema, defs map[string]*Schema) {
+	if schema == nil {
+		return
+	}
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, b.refPrefix)
+		if _, ok := defs[name]; !ok {
+			defs[name] = b.definitions[name]
+			b.references(b.definitions[name], defs)
+		}
+		return
+	}
+	for _, property := range schema.Properties {
+		b.references(property, defs)
+	}
+	for _, oneOf := range schema.OneOf {
+		b.references(oneOf, defs)
+	}
+	b.references(schema.Items, defs)
+	b.references(schema.AdditionalProperties, defs)
+}
+
+func docsSection(docs map[string]interface{}, section string) (map[string]interface{}, error) {
+	v, ok := docs[section]
+	if !ok || v == nil {
+		return map[string]interface{}{}, nil
+	}
+	routes, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, constants.ErrInvalidDocs
+	}
+	return routes, nil
+}
+
+func routeDoc(v interface{}) (interface{}, []interface{}, error) {
+	doc, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, nil, constants.ErrInvalidDocs
+	}
+	outputs, ok := doc["output"].([]interface{})
+	if !ok && doc["output"] != nil {
+		return nil, nil, constants.ErrInvalidDocs
+	}
+	return doc["input"], outputs, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
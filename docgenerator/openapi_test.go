@@ -0,0 +1,214 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package docgenerator
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/component"
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/protos"
+)
+
+type ProtoComp struct {
+	component.Base
+}
+
+func (p *ProtoComp) Handler(ctx context.Context, req *protos.Request) (*protos.Error, error) {
+	return nil, nil
+}
+
+func testDocs(t *testing.T) map[string]interface{} {
+	t.Helper()
+	services := map[string]*component.Service{}
+	for _, c := range []component.Component{&MyComp{}, &ProtoComp{}} {
+		s := component.NewService(c, []component.Option{})
+		assert.NoError(t, s.ExtractHandler())
+		assert.NoError(t, s.ExtractRemote())
+		services[s.Name] = s
+	}
+
+	handlers, err := HandlersDocs("metagame", services, true)
+	assert.NoError(t, err)
+	remotes, err := RemotesDocs("metagame", services, true)
+	assert.NoError(t, err)
+	pushes, err := PushesDocs(map[string]reflect.Type{
+		"onKick": reflect.TypeOf(&protos.KickMsg{}),
+		"onRaw":  reflect.TypeOf([]byte{}),
+	}, true)
+	assert.NoError(t, err)
+	return map[string]interface{}{"handlers": handlers, "remotes": remotes, "pushes": pushes}
+}
+
+func TestOpenAPI(t *testing.T) {
+	t.Parallel()
+	info := OpenAPIInfo{Title: "metagame", Version: "1.0.0"}
+	doc, err := OpenAPI(testDocs(t), info, ProtoDescriptors)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, info, doc.Info)
+	assert.Len(t, doc.Paths, 5)
+
+	empty := doc.Paths["/metagame.MyComp.HandlerEmpty"].Post
+	assert.Equal(t, "metagame.MyComp.HandlerEmpty", empty.OperationID)
+	assert.Equal(t, []string{"metagame.MyComp"}, empty.Tags)
+	assert.Nil(t, empty.RequestBody)
+	assert.Nil(t, empty.Responses["200"].Content)
+	assert.Equal(t, "#/components/schemas/pitaya.Error", empty.Responses["default"].Content["application/json"].Schema.Ref)
+
+	raw := doc.Paths["/metagame.MyComp.HandlerRaw"].Post
+	binary := &Schema{Type: "string", Format: "binary"}
+	assert.Equal(t, binary, raw.RequestBody.Content["application/octet-stream"].Schema)
+	assert.Equal(t, binary, raw.Responses["200"].Content["application/octet-stream"].Schema)
+
+	handler := doc.Paths["/metagame.ProtoComp.Handler"].Post
+	assert.Equal(t, "#/components/schemas/protos.Request", handler.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/protos.Error", handler.Responses["200"].Content["application/json"].Schema.Ref)
+
+	assert.Len(t, doc.Pushes, 2)
+	assert.Equal(t, "#/components/schemas/protos.KickMsg", doc.Pushes["onKick"].Content["application/json"].Schema.Ref)
+	assert.Equal(t, binary, doc.Pushes["onRaw"].Content["application/octet-stream"].Schema)
+
+	schemas := doc.Components.Schemas
+	myStruct := schemas["docgenerator.MyStruct"]
+	if assert.NotNil(t, myStruct) {
+		assert.Equal(t, &Schema{Type: "integer", Format: "int64"}, myStruct.Properties["int"])
+		assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, myStruct.Properties["time"])
+		assert.Equal(t, &Schema{Type: "string", Format: "byte"}, myStruct.Properties["bytes"])
+		assert.Equal(t, "array", myStruct.Properties["slice"].Type)
+		assert.Equal(t, "object", myStruct.Properties["slice"].Items.Type)
+		assert.Equal(t, "object", myStruct.Properties["struct"].Type)
+	}
+
+	request := schemas["protos.Request"]
+	if assert.NotNil(t, request) {
+		assert.Equal(t, "#/components/schemas/protos.RPCType", request.Properties["type"].Ref)
+		assert.Equal(t, "#/components/schemas/protos.Session", request.Properties["session"].Ref)
+		assert.Equal(t, "#/components/schemas/protos.Msg", request.Properties["msg"].Ref)
+		assert.Equal(t, &Schema{Type: "string"}, request.Properties["frontendID"])
+	}
+	assert.Equal(t, &Schema{
+		Title:       "protos.RPCType",
+		Description: "Sys = 0, User = 1",
+		Type:        "integer",
+		Format:      "int32",
+		Enum:        []interface{}{int32(0), int32(1)},
+		EnumNames:   []string{"Sys", "User"},
+	}, schemas["protos.RPCType"])
+	if assert.NotNil(t, schemas["protos.Msg"]) {
+		assert.Equal(t, &Schema{Type: "integer", Format: "int64"}, schemas["protos.Msg"].Properties["id"])
+	}
+	if assert.NotNil(t, schemas["protos.Error"]) {
+		assert.Equal(t, &Schema{
+			Type:                 "object",
+			AdditionalProperties: &Schema{Type: "string"},
+		}, schemas["protos.Error"].Properties["metadata"])
+	}
+
+	_, err = json.Marshal(doc)
+	assert.NoError(t, err)
+}
+
+func TestOpenAPIWithoutResolver(t *testing.T) {
+	t.Parallel()
+	doc, err := OpenAPI(testDocs(t), OpenAPIInfo{}, nil)
+	assert.NoError(t, err)
+
+	request := doc.Components.Schemas["protos.Request"]
+	if assert.NotNil(t, request) {
+		// built from the go struct, enums are not known
+		assert.Equal(t, &Schema{Description: "protos.RPCType"}, request.Properties["type"])
+		assert.Equal(t, "#/components/schemas/protos.Session", request.Properties["session"].Ref)
+	}
+}
+
+func TestOpenAPIInvalidDocs(t *testing.T) {
+	t.Parallel()
+	tables := []struct {
+		name string
+		docs map[string]interface{}
+	}{
+		{"handlers", map[string]interface{}{"handlers": "invalid"}},
+		{"route", map[string]interface{}{"handlers": map[string]interface{}{"sv.svc.route": 1}}},
+		{"output", map[string]interface{}{"handlers": map[string]interface{}{
+			"sv.svc.route": map[string]interface{}{"output": 1},
+		}}},
+		{"input", map[string]interface{}{"handlers": map[string]interface{}{
+			"sv.svc.route": map[string]interface{}{"input": 1},
+		}}},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			_, err := OpenAPI(table.docs, OpenAPIInfo{}, nil)
+			assert.ErrorIs(t, err, constants.ErrInvalidDocs)
+		})
+	}
+}
+
+func TestJSONSchemas(t *testing.T) {
+	t.Parallel()
+	schemas, err := JSONSchemas(testDocs(t), ProtoDescriptors)
+	assert.NoError(t, err)
+
+	for _, name := range []string{"docgenerator.MyStruct", "test.SomeStruct", "protos.Request", "protos.Error", "protos.KickMsg"} {
+		assert.Contains(t, schemas, name)
+	}
+	assert.NotContains(t, schemas, "pitaya.Error")
+
+	someStruct := schemas["test.SomeStruct"]
+	assert.Equal(t, &Schema{
+		Schema: "https://json-schema.org/draft/2020-12/schema",
+		Title:  "test.SomeStruct",
+		Type:   "object",
+		Properties: map[string]*Schema{
+			"A": {Type: "integer", Format: "int32"},
+			"B": {Type: "string"},
+		},
+	}, someStruct)
+
+	request := schemas["protos.Request"]
+	assert.Equal(t, "#/$defs/protos.Session", request.Properties["session"].Ref)
+	assert.Contains(t, request.Defs, "protos.Session")
+	assert.Contains(t, request.Defs, "protos.Msg")
+	assert.Contains(t, request.Defs, "protos.MsgType")
+	assert.Contains(t, request.Defs, "protos.RPCType")
+	assert.NotContains(t, request.Defs, "protos.Error")
+}
+
+func TestGoCamelCase(t *testing.T) {
+	t.Parallel()
+	tables := map[string]string{
+		"name":       "Name",
+		"first_name": "FirstName",
+		"a_b_c":      "ABC",
+		"value2x":    "Value2X",
+		"_private":   "XPrivate",
+		"Already":    "Already",
+	}
+	for name, expected := range tables {
+		assert.Equal(t, expected, goCamelCase(name), name)
+	}
+}
@@ -0,0 +1,413 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package docgenerator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+// Schema is a JSON Schema, as used by OpenAPI 3 documents
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	EnumNames            []string           `json:"x-enum-varnames,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// DescriptorResolver returns the gzipped file descriptor of a protobuf
+// message, like ProtoDescriptors does
+type DescriptorResolver func(protoName string) ([]byte, error)
+
+var invalidDefinitionChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// schemaBuilder converts the documentation of the handlers and remotes into
+// schemas, the named types are added to definitions and referenced with
+// refPrefix. Types that resolver knows are built from their protobuf
+// descriptors instead of from the documentation.
+type schemaBuilder struct {
+	refPrefix   string
+	resolver    DescriptorResolver
+	definitions map[string]*Schema
+	messages    map[string]*descriptor.DescriptorProto
+	enums       map[string]*descriptor.EnumDescriptorProto
+	resolved    map[string]bool
+}
+
+func newSchemaBuilder(refPrefix string, resolver DescriptorResolver) *schemaBuilder {
+	return &schemaBuilder{
+		refPrefix:   refPrefix,
+		resolver:    resolver,
+		definitions: map[string]*Schema{},
+		messages:    map[string]*descriptor.DescriptorProto{},
+		enums:       map[string]*descriptor.EnumDescriptorProto{},
+		resolved:    map[string]bool{},
+	}
+}
+
+func (b *schemaBuilder) ref(name string) *Schema {
+	return &Schema{Ref: b.refPrefix + definitionName(name)}
+}
+
+func definitionName(name string) string {
+	return invalidDefinitionChars.ReplaceAllString(name, "_")
+}
+
+// fromDoc returns the schema of a type of the documentation, which is the go
+// type of scalars, a list with the type of the elements of slices and the
+// fields of structs, wrapped in a map from the type name when the docs are
+// generated with the pointer names
+func (b *schemaBuilder) fromDoc(doc interface{}) (*Schema, error) {
+	switch d := doc.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return b.scalar(d), nil
+	case []interface{}:
+		if len(d) != 1 {
+			return nil, fmt.Errorf("%w: slice with %d element types", constants.ErrInvalidDocs, len(d))
+		}
+		items, err := b.fromDoc(d[0])
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case map[string]interface{}:
+		if name, fields, ok := typeName(d); ok {
+			return b.named(name, fields)
+		}
+		return b.object(d)
+	default:
+		return nil, fmt.Errorf("%w: unexpected %T", constants.ErrInvalidDocs, doc)
+	}
+}
+
+// typeName returns the name and fields of a struct documented with its name
+func typeName(doc map[string]interface{}) (string, map[string]interface{}, bool) {
+	if len(doc) != 1 {
+		return "", nil, false
+	}
+	for name, v := range doc {
+		fields, ok := v.(map[string]interface{})
+		if !ok || !(strings.Contains(name, ".") || strings.HasPrefix(name, "*") || strings.Contains(name, "struct {")) {
+			return "", nil, false
+		}
+		return strings.TrimPrefix(name, "*"), fields, true
+	}
+	return "", nil, false
+}
+
+func (b *schemaBuilder) named(name string, fields map[string]interface{}) (*Schema, error) {
+	if strings.HasPrefix(name, "struct {") {
+		return b.object(fields)
+	}
+	if _, ok := b.definitions[definitionName(name)]; ok {
+		return b.ref(name), nil
+	}
+
+	ok, err := b.resolveMessage(name)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return b.message(name)
+	}
+
+	// added before the fields are built so recursive types reference it
+	definition := &Schema{}
+	b.definitions[definitionName(name)] = definition
+	object, err := b.object(fields)
+	if err != nil {
+		return nil, err
+	}
+	*definition = *object
+	definition.Title = name
+	return b.ref(name), nil
+}
+
+func (b *schemaBuilder) object(fields map[string]interface{}) (*Schema, error) {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for name, field := range fields {
+		property, err := b.fromDoc(field)
+		if err != nil {
+			return nil, err
+		}
+		schema.Properties[name] = property
+	}
+	return schema, nil
+}
+
+func (b *schemaBuilder) scalar(goType string) *Schema {
+	switch goType {
+	case "string":
+		return &Schema{Type: "string"}
+	case "bool":
+		return &Schema{Type: "boolean"}
+	case "int8", "int16", "int32", "uint8", "uint16", "uint32":
+		return &Schema{Type: "integer", Format: "int32"}
+	case "int", "int64", "uint", "uint64", "uintptr":
+		return &Schema{Type: "integer", Format: "int64"}
+	case "float32":
+		return &Schema{Type: "number", Format: "float"}
+	case "float64":
+		return &Schema{Type: "number", Format: "double"}
+	case "[]byte":
+		return &Schema{Type: "string", Format: "byte"}
+	case "time.Time":
+		return &Schema{Type: "string", Format: "date-time"}
+	case "interface {}":
+		return &Schema{}
+	}
+	if strings.HasPrefix(goType, "map[") {
+		return &Schema{Type: "object"}
+	}
+	if _, ok := b.enums[goType]; ok {
+		return b.enum(goType)
+	}
+	return &Schema{Description: goType}
+}
+
+// resolveMessage loads the descriptors of the protobuf message name, it
+// returns false if it is not a protobuf message
+func (b *schemaBuilder) resolveMessage(name string) (bool, error) {
+	if _, ok := b.messages[name]; ok {
+		return true, nil
+	}
+	if b.resolver == nil || b.resolved[name] {
+		return false, nil
+	}
+	b.resolved[name] = true
+
+	compressed, err := b.resolver(name)
+	if err != nil {
+		// not a protobuf message
+		return false, nil
+	}
+	file, err := unpackDescriptor(compressed)
+	if err != nil {
+		return false, err
+	}
+	prefix := file.GetPackage()
+	if prefix != "" {
+		prefix += "."
+	}
+	for _, message := range file.MessageType {
+		b.addMessage(prefix, message)
+	}
+	for _, enum := range file.EnumType {
+		b.enums[prefix+enum.GetName()] = enum
+	}
+
+	_, ok := b.messages[name]
+	return ok, nil
+}
+
+func (b *schemaBuilder) addMessage(prefix string, message *descriptor.DescriptorProto) {
+	name := prefix + message.GetName()
+	b.messages[name] = message
+	for _, nested := range message.NestedType {
+		b.addMessage(name+".", nested)
+	}
+	for _, enum := range message.EnumType {
+		b.enums[name+"."+enum.GetName()] = enum
+	}
+}
+
+func unpackDescriptor(compressed []byte) (*descriptor.FileDescriptorProto, error) {
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &descriptor.FileDescriptorProto{}
+	if err := proto.Unmarshal(b, file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// message returns a reference to the definition of a protobuf message, whose
+// properties are named as in the json tags of the generated go structs
+func (b *schemaBuilder) message(name string) (*Schema, error) {
+	if _, ok := b.definitions[definitionName(name)]; ok {
+		return b.ref(name), nil
+	}
+
+	message := b.messages[name]
+	definition := &Schema{Title: name, Type: "object", Properties: map[string]*Schema{}}
+	b.definitions[definitionName(name)] = definition
+
+	oneofs := map[int32]*Schema{}
+	for _, field := range message.Field {
+		property, err := b.field(field)
+		if err != nil {
+			return nil, err
+		}
+		if field.OneofIndex == nil || field.GetProto3Optional() {
+			definition.Properties[field.GetName()] = property
+			continue
+		}
+
+		// oneofs are an interface field of the go struct, holding a struct
+		// with the field that is set
+		oneof, ok := oneofs[field.GetOneofIndex()]
+		if !ok {
+			oneof = &Schema{}
+			oneofs[field.GetOneofIndex()] = oneof
+			oneofName := message.OneofDecl[field.GetOneofIndex()].GetName()
+			definition.Properties[goCamelCase(oneofName)] = oneof
+		}
+		oneof.OneOf = append(oneof.OneOf, &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{goCamelCase(field.GetName()): property},
+		})
+	}
+	return b.ref(name), nil
+}
+
+func (b *schemaBuilder) field(field *descriptor.FieldDescriptorProto) (*Schema, error) {
+	var schema *Schema
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		schema = &Schema{Type: "number", Format: "double"}
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		schema = &Schema{Type: "number", Format: "float"}
+	case descriptor.FieldDescriptorProto_TYPE_INT64,
+		descriptor.FieldDescriptorProto_TYPE_UINT64,
+		descriptor.FieldDescriptorProto_TYPE_SINT64,
+		descriptor.FieldDescriptorProto_TYPE_FIXED64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		schema = &Schema{Type: "integer", Format: "int64"}
+	case descriptor.FieldDescriptorProto_TYPE_INT32,
+		descriptor.FieldDescriptorProto_TYPE_UINT32,
+		descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		schema = &Schema{Type: "integer", Format: "int32"}
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		schema = &Schema{Type: "boolean"}
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		schema = &Schema{Type: "string"}
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		schema = &Schema{Type: "string", Format: "byte"}
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		name := strings.TrimPrefix(field.GetTypeName(), ".")
+		if _, ok := b.enums[name]; !ok {
+			return &Schema{Type: "integer", Format: "int32", Description: name}, nil
+		}
+		schema = b.enum(name)
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		name := strings.TrimPrefix(field.GetTypeName(), ".")
+		ok, err := b.resolveMessage(name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return &Schema{Type: "object", Description: name}, nil
+		}
+		if entry := b.messages[name]; entry.GetOptions().GetMapEntry() {
+			value, err := b.field(entry.Field[1])
+			if err != nil {
+				return nil, err
+			}
+			return &Schema{Type: "object", AdditionalProperties: value}, nil
+		}
+		if schema, err = b.message(name); err != nil {
+			return nil, err
+		}
+	default:
+		schema = &Schema{}
+	}
+
+	if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return &Schema{Type: "array", Items: schema}, nil
+	}
+	return schema, nil
+}
+
+// enum returns a reference to the definition of a protobuf enum, which is
+// serialized as its number
+func (b *schemaBuilder) enum(name string) *Schema {
+	if _, ok := b.definitions[definitionName(name)]; ok {
+		return b.ref(name)
+	}
+
+	enum := b.enums[name]
+	definition := &Schema{Title: name, Type: "integer", Format: "int32"}
+	values := make([]string, 0, len(enum.Value))
+	for _, value := range enum.Value {
+		definition.Enum = append(definition.Enum, value.GetNumber())
+		definition.EnumNames = append(definition.EnumNames, value.GetName())
+		values = append(values, fmt.Sprintf("%s = %d", value.GetName(), value.GetNumber()))
+	}
+	definition.Description = strings.Join(values, ", ")
+	b.definitions[definitionName(name)] = definition
+	return b.ref(name)
+}
+
+// goCamelCase returns the name of the go struct field generated for a
+// protobuf field or oneof name, following protoc-gen-go
+func goCamelCase(name string) string {
+	isLower := func(c byte) bool { return 'a' <= c && c <= 'z' }
+	var out []byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_' && i == 0:
+			out = append(out, 'X')
+		case c == '_' && i+1 < len(name) && isLower(name[i+1]):
+		case '0' <= c && c <= '9':
+			out = append(out, c)
+		default:
+			if isLower(c) {
+				c -= 'a' - 'A'
+			}
+			out = append(out, c)
+			for ; i+1 < len(name) && isLower(name[i+1]); i++ {
+				out = append(out, name[i+1])
+			}
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,375 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package docgenerator
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+// SDKLanguage is a language of the generated client sdks
+type SDKLanguage string
+
+// Languages of the generated client sdks
+const (
+	// SDKTypeScript generates a TypeScript client using websockets
+	SDKTypeScript SDKLanguage = "typescript"
+	// SDKCSharp generates a C# client using tcp
+	SDKCSharp SDKLanguage = "csharp"
+)
+
+//go:embed templates
+var sdkTemplates embed.FS
+
+// SDKConfig configures the generated client sdk
+type SDKConfig struct {
+	Language SDKLanguage
+	// Namespace of the generated C# code
+	Namespace string
+	// Dictionary are the compressed route codes, as set with
+	// message.SetDictionary on the server
+	Dictionary map[string]uint16
+}
+
+// sdkRoute is a handler route of the generated sdk, Input and Output are
+// empty for handlers without argument or response
+type sdkRoute struct {
+	Route     string
+	Constant  string
+	Name      string
+	Input     string
+	Output    string
+	RawInput  bool
+	RawOutput bool
+}
+
+// Notify returns whether the handler has no response
+func (r *sdkRoute) Notify() bool {
+	return r.Output == "" && !r.RawOutput
+}
+
+// sdkPush is a declared push route of the generated sdk, Raw pushes have
+// their bytes as payload
+type sdkPush struct {
+	Route    string
+	Constant string
+	Handler  string
+	Payload  string
+	Raw      bool
+}
+
+type sdkType struct {
+	Name   string
+	Fields []*sdkField
+	Enum   []*sdkEnumValue
+}
+
+type sdkField struct {
+	JSONName string
+	Name     string
+	Type     string
+}
+
+type sdkEnumValue struct {
+	Name   string
+	Number interface{}
+}
+
+type sdkDictionaryEntry struct {
+	Route string
+	Code  uint16
+}
+
+type sdkModel struct {
+	Namespace  string
+	Routes     []*sdkRoute
+	Pushes     []*sdkPush
+	Types      []*sdkType
+	Dictionary []*sdkDictionaryEntry
+}
+
+// sdkLanguage writes the types of a language
+type sdkLanguage interface {
+	// typeOf returns the type of a schema, owner and field name the inline
+	// objects
+	typeOf(g *sdkGenerator, schema *Schema, owner, field string) string
+	fieldName(owner *sdkType, jsonName string) string
+	methodName(route string) string
+	// reserved returns whether a type name is used by the runtime
+	reserved(name string) bool
+}
+
+type sdkGenerator struct {
+	language  sdkLanguage
+	builder   *schemaBuilder
+	names     map[string]string
+	types     []*sdkType
+	typeNames map[string]bool
+}
+
+// GenerateSDK writes a client sdk for the handlers of docs, as returned by
+// pitaya.Documentation, with the route constants, the request and response
+// types, a method for each handler and the registration of push handlers,
+// typed for the declared pushes.
+// Protobuf messages known by resolver, which may be nil, are built from their
+// descriptors. The generated clients use json and compress the routes of
+// config.Dictionary, along with the ones sent by the server in the handshake.
+func GenerateSDK(w io.Writer, docs map[string]interface{}, resolver DescriptorResolver, config SDKConfig) error {
+	var language sdkLanguage
+	switch config.Language {
+	case SDKTypeScript:
+		language = typeScript{}
+	case SDKCSharp:
+		language = cSharp{}
+	default:
+		return fmt.Errorf("%w: %s", constants.ErrUnsupportedSDKLanguage, config.Language)
+	}
+
+	handlers, err := docsSection(docs, "handlers")
+	if err != nil {
+		return err
+	}
+	pushes, err := docsSection(docs, "pushes")
+	if err != nil {
+		return err
+	}
+
+	g := &sdkGenerator{
+		language:  language,
+		builder:   newSchemaBuilder("#/", resolver),
+		names:     map[string]string{},
+		typeNames: map[string]bool{},
+	}
+	model := &sdkModel{Namespace: config.Namespace}
+	if model.Namespace == "" {
+		model.Namespace = "Pitaya.Client"
+	}
+
+	type routeSchemas struct {
+		route  *sdkRoute
+		input  *Schema
+		output *Schema
+	}
+	var routes []*routeSchemas
+	for _, route := range sortedKeys(handlers) {
+		input, outputs, err := routeDoc(handlers[route])
+		if err != nil {
+			return err
+		}
+		r := &routeSchemas{route: &sdkRoute{
+			Route:    route,
+			Constant: pascalCase(route),
+			Name:     language.methodName(route),
+		}}
+		if input == "[]byte" {
+			r.route.RawInput = true
+		} else if r.input, err = g.builder.fromDoc(input); err != nil {
+			return err
+		}
+		if len(outputs) > 0 && outputs[0] == "[]byte" {
+			r.route.RawOutput = true
+		} else if len(outputs) > 0 && outputs[0] != "error" {
+			if r.output, err = g.builder.fromDoc(outputs[0]); err != nil {
+				return err
+			}
+		}
+		routes = append(routes, r)
+	}
+
+	type pushSchema struct {
+		push    *sdkPush
+		payload *Schema
+	}
+	var declared []*pushSchema
+	for _, route := range sortedKeys(pushes) {
+		_, outputs, err := routeDoc(pushes[route])
+		if err != nil {
+			return err
+		}
+		p := &pushSchema{push: &sdkPush{
+			Route:    route,
+			Constant: pascalCase(route),
+			Handler:  language.methodName(pushHandlerName(route)),
+		}}
+		if len(outputs) == 0 || outputs[0] == "[]byte" {
+			p.push.Raw = true
+		} else if p.payload, err = g.builder.fromDoc(outputs[0]); err != nil {
+			return err
+		}
+		declared = append(declared, p)
+	}
+
+	g.nameDefinitions()
+	for _, name := range sortedDefinitions(g.builder.definitions) {
+		g.addType(g.names[name], g.builder.definitions[name])
+	}
+	for _, r := range routes {
+		if r.input != nil {
+			r.route.Input = language.typeOf(g, r.input, r.route.Name, "Request")
+		}
+		if r.output != nil {
+			r.route.Output = language.typeOf(g, r.output, r.route.Name, "Response")
+		}
+		model.Routes = append(model.Routes, r.route)
+	}
+	for _, p := range declared {
+		if p.payload != nil {
+			p.push.Payload = language.typeOf(g, p.payload, pushHandlerName(p.push.Route), "Push")
+		}
+		model.Pushes = append(model.Pushes, p.push)
+	}
+	model.Types = g.types
+
+	for route, code := range config.Dictionary {
+		model.Dictionary = append(model.Dictionary, &sdkDictionaryEntry{Route: route, Code: code})
+	}
+	sort.Slice(model.Dictionary, func(i, j int) bool {
+		return model.Dictionary[i].Code < model.Dictionary[j].Code
+	})
+
+	tmpl, err := template.New(string(config.Language)).
+		Funcs(template.FuncMap{"quote": func(s string) string { return fmt.Sprintf("%q", s) }}).
+		ParseFS(sdkTemplates, fmt.Sprintf("templates/%s.tmpl", config.Language))
+	if err != nil {
+		return err
+	}
+	return tmpl.ExecuteTemplate(w, fmt.Sprintf("%s.tmpl", config.Language), model)
+}
+
+// nameDefinitions names the types of the definitions after the last part of
+// their names, or the whole name if it is taken
+func (g *sdkGenerator) nameDefinitions() {
+	count := map[string]int{}
+	for name := range g.builder.definitions {
+		count[shortTypeName(name)]++
+	}
+	for name := range g.builder.definitions {
+		short := shortTypeName(name)
+		if count[short] > 1 || g.language.reserved(short) {
+			short = pascalCase(name)
+		}
+		g.names[name] = short
+		g.typeNames[short] = true
+	}
+}
+
+func shortTypeName(name string) string {
+	return pascalCase(name[strings.LastIndex(name, ".")+1:])
+}
+
+func sortedDefinitions(definitions map[string]*Schema) []string {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// definitionType returns the type name of a reference
+func (g *sdkGenerator) definitionType(ref string) string {
+	return g.names[definitionName(strings.TrimPrefix(ref, g.builder.refPrefix))]
+}
+
+// addType adds a type for an object or enum schema
+func (g *sdkGenerator) addType(name string, schema *Schema) *sdkType {
+	t := &sdkType{Name: name}
+	g.typeNames[name] = true
+	g.types = append(g.types, t)
+	for i, number := range schema.Enum {
+		value := &sdkEnumValue{Number: number, Name: fmt.Sprint(number)}
+		if i < len(schema.EnumNames) {
+			value.Name = schema.EnumNames[i]
+		}
+		t.Enum = append(t.Enum, value)
+	}
+	properties := make([]string, 0, len(schema.Properties))
+	for property := range schema.Properties {
+		properties = append(properties, property)
+	}
+	sort.Strings(properties)
+	for _, property := range properties {
+		t.Fields = append(t.Fields, &sdkField{
+			JSONName: property,
+			Name:     g.language.fieldName(t, property),
+			Type:     g.language.typeOf(g, schema.Properties[property], name, property),
+		})
+	}
+	return t
+}
+
+// inlineType adds a type for an inline object, named after its owner and
+// field
+func (g *sdkGenerator) inlineType(schema *Schema, owner, field string) string {
+	name := owner + pascalCase(field)
+	for g.typeNames[name] || g.language.reserved(name) {
+		name += "_"
+	}
+	return g.addType(name, schema).Name
+}
+
+// pushHandlerName names the registration of a push handler after its route,
+// prefixed with On unless the route already is
+func pushHandlerName(route string) string {
+	name := pascalCase(route)
+	if len(name) > 2 && strings.HasPrefix(name, "On") && unicode.IsUpper(rune(name[2])) {
+		return name
+	}
+	return "On" + name
+}
+
+// pascalCase joins the words of name, separated by non alphanumeric
+// characters, capitalized
+func pascalCase(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		s = "_" + s
+	}
+	return s
+}
+
+func camelCase(name string) string {
+	s := pascalCase(name)
+	if s[0] == '_' {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,194 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package docgenerator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var typeScriptIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// sdkRuntimeNames are the names declared by the runtimes of all languages
+var sdkRuntimeNames = map[string]bool{
+	"Api": true, "HandshakeData": true, "HandshakeSys": true, "PitayaClient": true,
+	"PitayaError": true, "RouteDictionary": true, "Routes": true,
+}
+
+// typeScriptGlobals are the global names used by the typescript runtime
+var typeScriptGlobals = map[string]bool{
+	"Array": true, "Blob": true, "DecompressionStream": true, "Error": true, "HandshakeResponse": true,
+	"JSON": true, "Map": true, "MessageType": true, "Object": true, "PacketType": true,
+	"PendingRequest": true, "Promise": true, "Request": true, "Response": true, "TextDecoder": true,
+	"TextEncoder": true, "Uint8Array": true, "WebSocket": true,
+}
+
+// cSharpTypes are the types used by the c# runtime
+var cSharpTypes = map[string]bool{
+	"Action": true, "Array": true, "Buffer": true, "CancellationToken": true,
+	"CancellationTokenSource": true, "ConcurrentDictionary": true, "DateTime": true,
+	"DeflateStream": true, "Dictionary": true, "Encoding": true, "EndOfStreamException": true,
+	"Exception": true, "GZipStream": true, "IDisposable": true, "IOException": true,
+	"Interlocked": true, "JsonElement": true, "JsonSerializer": true, "List": true,
+	"MemoryStream": true, "ObjectDisposedException": true, "SemaphoreSlim": true, "Stream": true,
+	"Task": true, "TaskCompletionSource": true, "TcpClient": true, "TimeSpan": true, "Timer": true,
+}
+
+// typeScript writes interfaces for objects and inline object literals
+type typeScript struct{}
+
+func (typeScript) typeOf(g *sdkGenerator, schema *Schema, owner, field string) string {
+	switch {
+	case schema.Ref != "":
+		return g.definitionType(schema.Ref)
+	case len(schema.OneOf) > 0:
+		types := make([]string, 0, len(schema.OneOf))
+		for _, oneOf := range schema.OneOf {
+			types = append(types, typeScript{}.typeOf(g, oneOf, owner, field))
+		}
+		return strings.Join(types, " | ")
+	}
+
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		items := typeScript{}.typeOf(g, schema.Items, owner, field)
+		if strings.ContainsAny(items, " |") {
+			return fmt.Sprintf("Array<%s>", items)
+		}
+		return items + "[]"
+	case "object":
+		if schema.AdditionalProperties != nil {
+			return fmt.Sprintf("{ [key: string]: %s }", typeScript{}.typeOf(g, schema.AdditionalProperties, owner, field))
+		}
+		if len(schema.Properties) == 0 {
+			return "{ [key: string]: unknown }"
+		}
+		properties := make([]string, 0, len(schema.Properties))
+		for property := range schema.Properties {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+		fields := make([]string, 0, len(properties))
+		for _, property := range properties {
+			fields = append(fields, fmt.Sprintf("%s?: %s",
+				typeScript{}.fieldName(nil, property),
+				typeScript{}.typeOf(g, schema.Properties[property], owner, property),
+			))
+		}
+		return fmt.Sprintf("{ %s }", strings.Join(fields, "; "))
+	default:
+		return "unknown"
+	}
+}
+
+func (typeScript) fieldName(owner *sdkType, jsonName string) string {
+	if typeScriptIdentifier.MatchString(jsonName) {
+		return jsonName
+	}
+	return fmt.Sprintf("%q", jsonName)
+}
+
+func (typeScript) methodName(route string) string {
+	return camelCase(route)
+}
+
+func (typeScript) reserved(name string) bool {
+	return sdkRuntimeNames[name] || typeScriptGlobals[name]
+}
+
+// cSharp writes classes for objects, including the inline ones
+type cSharp struct{}
+
+func (cSharp) typeOf(g *sdkGenerator, schema *Schema, owner, field string) string {
+	switch {
+	case schema.Ref != "":
+		return g.definitionType(schema.Ref)
+	case len(schema.OneOf) > 0:
+		return "JsonElement"
+	}
+
+	switch schema.Type {
+	case "string":
+		switch schema.Format {
+		case "byte":
+			return "byte[]"
+		case "date-time":
+			return "DateTime"
+		}
+		return "string"
+	case "integer":
+		if schema.Format == "int32" {
+			return "int"
+		}
+		return "long"
+	case "number":
+		if schema.Format == "float" {
+			return "float"
+		}
+		return "double"
+	case "boolean":
+		return "bool"
+	case "array":
+		return fmt.Sprintf("List<%s>", cSharp{}.typeOf(g, schema.Items, owner, field))
+	case "object":
+		if schema.AdditionalProperties != nil {
+			return fmt.Sprintf("Dictionary<string, %s>", cSharp{}.typeOf(g, schema.AdditionalProperties, owner, field))
+		}
+		if len(schema.Properties) == 0 {
+			return "Dictionary<string, JsonElement>"
+		}
+		return g.inlineType(schema, owner, field)
+	default:
+		return "JsonElement"
+	}
+}
+
+// fieldName returns the property name, which can't be the name of the class
+// or of another property
+func (cSharp) fieldName(owner *sdkType, jsonName string) string {
+	name := pascalCase(jsonName)
+	for taken := true; taken; {
+		taken = name == owner.Name
+		for _, field := range owner.Fields {
+			taken = taken || field.Name == name
+		}
+		if taken {
+			name += "_"
+		}
+	}
+	return name
+}
+
+func (cSharp) methodName(route string) string {
+	return pascalCase(route)
+}
+
+func (cSharp) reserved(name string) bool {
+	return sdkRuntimeNames[name] || cSharpTypes[name]
+}
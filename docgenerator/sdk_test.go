@@ -0,0 +1,126 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package docgenerator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+func generateSDK(t *testing.T, language SDKLanguage) string {
+	t.Helper()
+	var sdk bytes.Buffer
+	err := GenerateSDK(&sdk, testDocs(t), ProtoDescriptors, SDKConfig{
+		Language:   language,
+		Namespace:  "Game.Client",
+		Dictionary: map[string]uint16{"metagame.ProtoComp.Handler": 2, "metagame.MyComp.HandlerRaw": 1},
+	})
+	assert.NoError(t, err)
+	return sdk.String()
+}
+
+func TestGenerateSDKTypeScript(t *testing.T) {
+	t.Parallel()
+	sdk := generateSDK(t, SDKTypeScript)
+
+	for _, expected := range []string{
+		`  MetagameProtoCompHandler: "metagame.ProtoComp.Handler",`,
+		"  \"metagame.MyComp.HandlerRaw\": 1,\n  \"metagame.ProtoComp.Handler\": 2,\n};",
+		"export interface SomeStruct {\n  A?: number;\n  B?: string;\n}",
+		"export enum RPCType {\n  Sys = 0,\n  User = 1,\n}",
+		"  type?: RPCType;",
+		"  slice?: Array<{ int?: number; str?: string }>;",
+		"  metadata?: { [key: string]: string };",
+		// Request and Error are runtime globals
+		"export interface ProtosRequest {",
+		"export interface ProtosError {",
+		"  metagameProtoCompHandler(req: ProtosRequest): Promise<ProtosError> {",
+		"  metagameMyCompHandlerEmpty(): void {\n    this.client.notifyBytes(Routes.MetagameMyCompHandlerEmpty, new Uint8Array(0));",
+		"  metagameMyCompHandlerRaw(req: Uint8Array): Promise<Uint8Array> {\n    return this.client.requestBytes(Routes.MetagameMyCompHandlerRaw, req);",
+		"export const Pushes = {\n  OnKick: \"onKick\",\n  OnRaw: \"onRaw\",\n} as const;",
+		"  onKick(handler: (data: KickMsg) => void): void {\n    this.client.onPush(Pushes.OnKick, handler);",
+		"  onRaw(handler: (data: Uint8Array) => void): void {\n    this.client.onPushBytes(Pushes.OnRaw, handler);",
+		"export class PitayaClient {",
+	} {
+		assert.Contains(t, sdk, expected)
+	}
+}
+
+func TestGenerateSDKCSharp(t *testing.T) {
+	t.Parallel()
+	sdk := generateSDK(t, SDKCSharp)
+
+	for _, expected := range []string{
+		"namespace Game.Client\n{",
+		`        public const string MetagameProtoCompHandler = "metagame.ProtoComp.Handler";`,
+		`            { "metagame.MyComp.HandlerRaw", 1 },`,
+		"    public enum RPCType\n    {\n        Sys = 0,\n        User = 1,\n    }",
+		"        [JsonPropertyName(\"A\")]\n        public int A { get; set; }",
+		"        [JsonPropertyName(\"id\")]\n        public long Id { get; set; }",
+		"        public Dictionary<string, string> Metadata { get; set; }",
+		"        public DateTime Time { get; set; }",
+		"        public byte[] Bytes { get; set; }",
+		// inline structs are named after their owner and field
+		"        public List<MyStructSlice> Slice { get; set; }",
+		"    public class MyStructStructNotPointer\n",
+		"    public class Request\n",
+		"        public async Task<Error> MetagameProtoCompHandlerAsync(Request request, CancellationToken cancellationToken = default)",
+		"        public Task MetagameMyCompHandlerEmptyAsync()\n        {\n            return Client.NotifyBytesAsync(Routes.MetagameMyCompHandlerEmpty, Array.Empty<byte>());",
+		"        public Task<byte[]> MetagameMyCompHandlerRawAsync(byte[] request, CancellationToken cancellationToken = default)",
+		`        public const string OnKick = "onKick";`,
+		"        public void OnKick(Action<KickMsg> handler)\n        {\n            Client.OnPush(Pushes.OnKick, handler);",
+		"        public void OnRaw(Action<byte[]> handler)\n        {\n            Client.OnPushBytes(Pushes.OnRaw, handler);",
+		"    public class PitayaClient : IDisposable",
+	} {
+		assert.Contains(t, sdk, expected)
+	}
+}
+
+func TestGenerateSDKUnsupportedLanguage(t *testing.T) {
+	t.Parallel()
+	err := GenerateSDK(&bytes.Buffer{}, testDocs(t), nil, SDKConfig{Language: "java"})
+	assert.ErrorIs(t, err, constants.ErrUnsupportedSDKLanguage)
+}
+
+func TestCSharpFieldName(t *testing.T) {
+	t.Parallel()
+	owner := &sdkType{Name: "Player", Fields: []*sdkField{{Name: "Name"}}}
+	assert.Equal(t, "Name_", cSharp{}.fieldName(owner, "name"))
+	assert.Equal(t, "Player_", cSharp{}.fieldName(owner, "player"))
+	assert.Equal(t, "FirstName", cSharp{}.fieldName(owner, "first_name"))
+	assert.Equal(t, "_1st", cSharp{}.fieldName(owner, "1st"))
+}
+
+func TestSDKNames(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "RoomRoomJoin", pascalCase("room.room.join"))
+	assert.Equal(t, "roomRoomJoin", camelCase("room.room.join"))
+	assert.Equal(t, "ProtosRequest", pascalCase("protos.Request"))
+	assert.Equal(t, "Request", shortTypeName("protos.Request"))
+	assert.Equal(t, "OnKick", pushHandlerName("onKick"))
+	assert.Equal(t, "OnRoomMessage", pushHandlerName("room.message"))
+	assert.Equal(t, "OnOnline", pushHandlerName("online"))
+	assert.Equal(t, "name", typeScript{}.fieldName(nil, "name"))
+	assert.Equal(t, `"first-name"`, typeScript{}.fieldName(nil, "first-name"))
+}
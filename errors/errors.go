@@ -50,6 +50,13 @@ var ErrBadRequestCode = S_Code{
 	ErrorCode: 400,
 }
 
+// ErrUnauthorizedCode is a string code representing an unauthenticated or
+// unauthorized related error
+var ErrUnauthorizedCode = S_Code{
+	Desc:      "PIT-401",
+	ErrorCode: 401,
+}
+
 // ErrClientClosedRequest is a string code representing the client closed request error
 var ErrClientClosedRequest = S_Code{
 	Desc:      "PIT-499",
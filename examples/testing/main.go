@@ -41,6 +41,8 @@ import (
 	"github.com/hnlxhzw/pitaya/modules"
 	"github.com/hnlxhzw/pitaya/protos/test"
 	"github.com/hnlxhzw/pitaya/serialize/json"
+	"github.com/hnlxhzw/pitaya/serialize/jsonpb"
+	"github.com/hnlxhzw/pitaya/serialize/msgpack"
 	"github.com/hnlxhzw/pitaya/serialize/protobuf"
 	"github.com/hnlxhzw/pitaya/session"
 )
@@ -247,7 +249,7 @@ func main() {
 	port := flag.Int("port", 32222, "the port to listen")
 	svType := flag.String("type", "connector", "the server type")
 	isFrontend := flag.Bool("frontend", true, "if server is frontend")
-	serializer := flag.String("serializer", "json", "json or protobuf")
+	serializer := flag.String("serializer", "json", "json, protobuf, msgpack or jsonpb")
 	sdPrefix := flag.String("sdprefix", "pitaya/", "prefix to discover other servers")
 	debug := flag.Bool("debug", false, "turn on debug logging")
 	grpc := flag.Bool("grpc", false, "turn on grpc")
@@ -278,12 +280,17 @@ func main() {
 		component.WithNameFunc(strings.ToLower),
 	)
 
-	if *serializer == "json" {
+	switch *serializer {
+	case "json":
 		pitaya.SetSerializer(json.NewSerializer())
-	} else if *serializer == "protobuf" {
+	case "protobuf":
 		pitaya.SetSerializer(protobuf.NewSerializer())
-	} else {
-		panic("serializer should be either json or protobuf")
+	case "msgpack":
+		pitaya.SetSerializer(msgpack.NewSerializer())
+	case "jsonpb":
+		pitaya.SetSerializer(jsonpb.NewSerializer())
+	default:
+		panic("serializer should be json, protobuf, msgpack or jsonpb")
 	}
 
 	if *isFrontend {
@@ -22,12 +22,17 @@ package pitaya
 
 import (
 	"context"
+	"fmt"
+	"path"
 	"sync"
 	"time"
 
 	"github.com/hnlxhzw/pitaya/constants"
 	"github.com/hnlxhzw/pitaya/groups"
 	"github.com/hnlxhzw/pitaya/logger"
+	"github.com/hnlxhzw/pitaya/protos"
+	"github.com/hnlxhzw/pitaya/session"
+	"github.com/hnlxhzw/pitaya/util"
 )
 
 // Group represents an agglomeration of UIDs which is used to manage
@@ -36,12 +41,16 @@ import (
 var (
 	groupServiceInstance groups.GroupService
 	groupsOnce           sync.Once
+	sessionGroupsMu      sync.Mutex
+	indexedGroups        []string
+	indexedGroupsMu      sync.RWMutex
 )
 
 // InitGroups should be called once at the beginning of the application to setup the service type that will manage the groups
 func InitGroups(groupService groups.GroupService) {
 	groupsOnce.Do(func() {
 		groupServiceInstance = groupService
+		session.OnSessionClose(leaveSessionGroups)
 	})
 }
 
@@ -60,10 +69,44 @@ func GroupMembers(ctx context.Context, groupName string) ([]string, error) {
 	return groupServiceInstance.GroupMembers(ctx, groupName)
 }
 
+// IndexGroups enables frontend-local broadcasts for the groups whose names
+// match one of the patterns (as in path.Match, e.g. "chat.*"). Broadcasts to
+// these groups are sent once to each frontend, which pushes the message to
+// its sessions in the group, so their members must be added with
+// GroupAddSession. It should be called with the same patterns on every server
+func IndexGroups(patterns ...string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return err
+		}
+	}
+
+	indexedGroupsMu.Lock()
+	defer indexedGroupsMu.Unlock()
+	indexedGroups = append(indexedGroups, patterns...)
+	return nil
+}
+
+func isIndexedGroup(groupName string) bool {
+	indexedGroupsMu.RLock()
+	defer indexedGroupsMu.RUnlock()
+
+	for _, pattern := range indexedGroups {
+		if matched, _ := path.Match(pattern, groupName); matched {
+			return true
+		}
+	}
+	return false
+}
+
 // GroupBroadcast pushes the message to all members inside group
 func GroupBroadcast(ctx context.Context, frontendType, groupName, route string, v interface{}) error {
 	logger.Log.Debugf("Type=Broadcast Route=%s, Data=%+v", route, v)
 
+	if isIndexedGroup(groupName) {
+		return broadcastToIndexedGroup(ctx, frontendType, groupName, route, v)
+	}
+
 	members, err := GroupMembers(ctx, groupName)
 	if err != nil {
 		return err
@@ -71,6 +114,66 @@ func GroupBroadcast(ctx context.Context, frontendType, groupName, route string,
 	return sendDataToMembers(members, frontendType, route, v)
 }
 
+// broadcastToIndexedGroup sends the message once to each frontend of
+// frontendType, which fans it out to its local sessions in the group
+func broadcastToIndexedGroup(ctx context.Context, frontendType, groupName, route string, v interface{}) error {
+	if err := validatePush(route, v); err != nil {
+		return err
+	}
+
+	data, err := util.SerializeOrRaw(app.serializer, v)
+	if err != nil {
+		return err
+	}
+
+	if !app.server.Frontend && frontendType == "" {
+		return constants.ErrFrontendTypeNotSpecified
+	}
+	if frontendType == "" {
+		frontendType = app.server.Type
+	}
+
+	if app.server.Frontend && app.server.Type == frontendType {
+		for _, s := range session.GetSessionsByGroup(groupName) {
+			if err := s.Push(route, sessionPushData(s, v, data)); err != nil {
+				logger.Log.Warnf("Session push message error, ID=%d, UID=%s, Error=%s",
+					s.ID(), s.UID(), err.Error())
+			}
+		}
+	}
+
+	if app.serverMode != Cluster || app.serviceDiscovery == nil {
+		return nil
+	}
+
+	servers, err := app.serviceDiscovery.GetServersByType(frontendType)
+	if err != nil {
+		return err
+	}
+
+	push := &protos.GroupPush{Route: route, Group: groupName, Data: data}
+	sysRoute := fmt.Sprintf("%s.%s", frontendType, constants.GroupBroadcastRoute)
+	errs := make(chan error, len(servers))
+	var wg sync.WaitGroup
+	for _, sv := range servers {
+		if sv.ID == app.server.ID {
+			continue
+		}
+		wg.Add(1)
+		go func(serverID string) {
+			defer wg.Done()
+			if err := RPCTo(ctx, serverID, sysRoute, &protos.Response{}, push); err != nil {
+				logger.Log.Errorf("Group broadcast error, Group=%s, SvID=%s, Error=%s", groupName, serverID, err.Error())
+				errs <- err
+			}
+		}(sv.ID)
+	}
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
 func sendDataToMembers(uids []string, frontendType, route string, v interface{}) error {
 	errUids, err := SendPushToUsers(route, v, uids, frontendType)
 	if err != nil {
@@ -97,6 +200,156 @@ func GroupAddMember(ctx context.Context, groupName, uid string) error {
 	return groupServiceInstance.GroupAddMember(ctx, groupName, uid)
 }
 
+// GroupAddMemberWithMetadata adds a member with its metadata to group, the
+// group service must implement groups.MetadataGroupService
+func GroupAddMemberWithMetadata(ctx context.Context, groupName string, member *groups.GroupMember) error {
+	if member == nil || member.UID == "" {
+		return constants.ErrEmptyUID
+	}
+	gs, err := metadataGroupService()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debugf("Add user to group %s, UID=%s, Role=%s", groupName, member.UID, member.Role)
+	return gs.GroupAddMemberWithMetadata(ctx, groupName, member)
+}
+
+// GroupMember returns the member with the given UID and its metadata
+func GroupMember(ctx context.Context, groupName, uid string) (*groups.GroupMember, error) {
+	if uid == "" {
+		return nil, constants.ErrEmptyUID
+	}
+	gs, err := metadataGroupService()
+	if err != nil {
+		return nil, err
+	}
+	return gs.GroupMember(ctx, groupName, uid)
+}
+
+// GroupMembersPage returns up to limit members ordered by UID, starting after
+// the cursor, and the cursor of the next page, which is empty on the last page
+func GroupMembersPage(ctx context.Context, groupName, cursor string, limit int) ([]*groups.GroupMember, string, error) {
+	gs, err := metadataGroupService()
+	if err != nil {
+		return nil, "", err
+	}
+	return gs.GroupMembersPage(ctx, groupName, cursor, limit)
+}
+
+// GroupSubscribe calls handler whenever a member joins or leaves the group, until ctx is done
+func GroupSubscribe(ctx context.Context, groupName string, handler func(*groups.GroupEvent)) error {
+	gs, err := metadataGroupService()
+	if err != nil {
+		return err
+	}
+	return gs.GroupSubscribe(ctx, groupName, handler)
+}
+
+func metadataGroupService() (groups.MetadataGroupService, error) {
+	gs, ok := groupServiceInstance.(groups.MetadataGroupService)
+	if !ok {
+		return nil, constants.ErrGroupMetadataNotSupported
+	}
+	return gs, nil
+}
+
+// GroupAddSession adds the UID bound to the session to group, tying the
+// membership to the session: the UID leaves the group automatically when its
+// last session on the frontend closes. When called from a backend server the
+// membership is pushed to the frontend, which leaves the group on close
+func GroupAddSession(ctx context.Context, groupName string, s *session.Session) error {
+	uid := s.UID()
+	if uid == "" {
+		return constants.ErrNoUIDBind
+	}
+	if err := GroupAddMember(ctx, groupName, uid); err != nil {
+		return err
+	}
+	if err := updateSessionGroups(s, groupName, true); err != nil {
+		return err
+	}
+	if !s.IsFrontend {
+		return s.PushToFront(ctx)
+	}
+	return nil
+}
+
+// GroupRemoveSession removes the UID bound to the session from a group joined
+// with GroupAddSession
+func GroupRemoveSession(ctx context.Context, groupName string, s *session.Session) error {
+	uid := s.UID()
+	if uid == "" {
+		return constants.ErrNoUIDBind
+	}
+	if err := GroupRemoveMember(ctx, groupName, uid); err != nil {
+		return err
+	}
+	if err := updateSessionGroups(s, groupName, false); err != nil {
+		return err
+	}
+	if !s.IsFrontend {
+		return s.PushToFront(ctx)
+	}
+	return nil
+}
+
+// SessionGroups returns the groups joined through the session with GroupAddSession
+func SessionGroups(s *session.Session) []string {
+	return s.Groups()
+}
+
+func updateSessionGroups(s *session.Session, groupName string, add bool) error {
+	sessionGroupsMu.Lock()
+	defer sessionGroupsMu.Unlock()
+
+	groupNames := SessionGroups(s)
+	updated := make([]string, 0, len(groupNames)+1)
+	for _, name := range groupNames {
+		if name != groupName {
+			updated = append(updated, name)
+		}
+	}
+	if add {
+		updated = append(updated, groupName)
+	}
+	if len(updated) == 0 {
+		return s.Remove(constants.SessionGroupsKey)
+	}
+	return s.Set(constants.SessionGroupsKey, updated)
+}
+
+// leaveSessionGroups removes the UID of a closed session from the groups it
+// joined, except for groups also joined by another session of the same UID.
+// Sessions handed off to another frontend keep their groups
+func leaveSessionGroups(s *session.Session) {
+	uid := s.UID()
+	groupNames := SessionGroups(s)
+	if uid == "" || len(groupNames) == 0 || s.Redirected() {
+		return
+	}
+
+	kept := map[string]bool{}
+	session.Range(func(other *session.Session) bool {
+		if other != s && other.UID() == uid {
+			for _, name := range SessionGroups(other) {
+				kept[name] = true
+			}
+		}
+		return true
+	})
+
+	ctx := context.Background()
+	for _, groupName := range groupNames {
+		if kept[groupName] {
+			continue
+		}
+		err := groupServiceInstance.GroupRemoveMember(ctx, groupName, uid)
+		if err != nil && err != constants.ErrMemberNotFound && err != constants.ErrGroupNotFound {
+			logger.Log.Errorf("failed to leave group %s on session close, UID=%s: %s", groupName, uid, err.Error())
+		}
+	}
+}
+
 // GroupRemoveMember removes specified UID from group
 func GroupRemoveMember(ctx context.Context, groupName, uid string) error {
 	logger.Log.Debugf("Remove user from group %s, UID=%s", groupName, uid)
@@ -28,6 +28,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/groups"
 	"github.com/hnlxhzw/pitaya/session"
 	"github.com/hnlxhzw/pitaya/session/mocks"
 )
@@ -351,3 +352,116 @@ func TestBroadcast(t *testing.T) {
 	err = GroupBroadcast(ctx, "testtype", "testBroadcast", route, data)
 	assert.NoError(t, err)
 }
+
+func TestBroadcastIndexedGroup(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	err := IndexGroups("testBroadcastIndexed*")
+	assert.NoError(t, err)
+	err = GroupCreate(ctx, "testBroadcastIndexedGroup")
+	assert.NoError(t, err)
+
+	mockNetworkEntity := mocks.NewMockNetworkEntity(ctrl)
+	s := session.New(mockNetworkEntity, true, "indexedBroadcastUid")
+	err = GroupAddSession(ctx, "testBroadcastIndexedGroup", s)
+	assert.NoError(t, err)
+
+	route := "some.route.bla"
+	data := []byte("hellow")
+	mockNetworkEntity.EXPECT().Push(route, data)
+	err = GroupBroadcast(ctx, "testtype", "testBroadcastIndexedGroup", route, data)
+	assert.NoError(t, err)
+
+	mockNetworkEntity.EXPECT().Close()
+	s.Close()
+}
+
+func TestIndexGroupsShouldFailIfInvalidPattern(t *testing.T) {
+	t.Parallel()
+	err := IndexGroups("[")
+	assert.Error(t, err)
+	assert.False(t, isIndexedGroup("["))
+}
+
+func TestGroupAddMemberWithMetadata(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+	err := GroupCreate(ctx, "testGroupAddMemberWithMetadata")
+	assert.NoError(t, err)
+
+	err = GroupAddMemberWithMetadata(ctx, "testGroupAddMemberWithMetadata", &groups.GroupMember{UID: ""})
+	assert.Equal(t, constants.ErrEmptyUID, err)
+	err = GroupAddMemberWithMetadata(ctx, "testGroupAddMemberWithMetadata", &groups.GroupMember{
+		UID:      "metadataUid",
+		Role:     "owner",
+		Metadata: map[string]string{"team": "blue"},
+	})
+	assert.NoError(t, err)
+
+	member, err := GroupMember(ctx, "testGroupAddMemberWithMetadata", "metadataUid")
+	assert.NoError(t, err)
+	assert.Equal(t, "owner", member.Role)
+	assert.Equal(t, map[string]string{"team": "blue"}, member.Metadata)
+
+	members, next, err := GroupMembersPage(ctx, "testGroupAddMemberWithMetadata", "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, members, 1)
+	assert.Equal(t, "", next)
+}
+
+func TestGroupAddSession(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	err := GroupCreate(ctx, "testGroupAddSession")
+	assert.NoError(t, err)
+	err = GroupCreate(ctx, "testGroupAddSession2")
+	assert.NoError(t, err)
+
+	mockNetworkEntity := mocks.NewMockNetworkEntity(ctrl)
+	err = GroupAddSession(ctx, "testGroupAddSession", session.New(mockNetworkEntity, true))
+	assert.Equal(t, constants.ErrNoUIDBind, err)
+
+	s := session.New(mockNetworkEntity, true, "addSessionUid")
+	err = GroupAddSession(ctx, "testGroupAddSession", s)
+	assert.NoError(t, err)
+	err = GroupAddSession(ctx, "testGroupAddSession2", s)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"testGroupAddSession", "testGroupAddSession2"}, SessionGroups(s))
+
+	err = GroupRemoveSession(ctx, "testGroupAddSession2", s)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"testGroupAddSession"}, SessionGroups(s))
+
+	leaveSessionGroups(s)
+	res, err := GroupContainsMember(ctx, "testGroupAddSession", "addSessionUid")
+	assert.NoError(t, err)
+	assert.False(t, res)
+}
+
+func TestLeaveSessionGroupsShouldKeepGroupsOfRedirectedSessions(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	err := GroupCreate(ctx, "testLeaveSessionGroupsRedirected")
+	assert.NoError(t, err)
+
+	mockNetworkEntity := mocks.NewMockNetworkEntity(ctrl)
+	s := session.New(mockNetworkEntity, true, "redirectedUid")
+	err = GroupAddSession(ctx, "testLeaveSessionGroupsRedirected", s)
+	assert.NoError(t, err)
+
+	mockNetworkEntity.EXPECT().Redirect(ctx, "localhost:3250", "token")
+	mockNetworkEntity.EXPECT().Close()
+	err = s.Redirect(ctx, "localhost:3250", "token")
+	assert.NoError(t, err)
+
+	leaveSessionGroups(s)
+	res, err := GroupContainsMember(ctx, "testLeaveSessionGroupsRedirected", "redirectedUid")
+	assert.NoError(t, err)
+	assert.True(t, res)
+}
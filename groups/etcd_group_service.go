@@ -2,6 +2,7 @@ package groups
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -59,6 +60,7 @@ func createBaseClient(config *config.Config) (*clientv3.Client, error) {
 		return nil, err
 	}
 	cli.KV = namespace.NewKV(cli.KV, config.GetString("pitaya.groups.etcd.prefix"))
+	cli.Watcher = namespace.NewWatcher(cli.Watcher, config.GetString("pitaya.groups.etcd.prefix"))
 	return cli, nil
 }
 
@@ -70,6 +72,15 @@ func memberKey(groupName, uid string) string {
 	return fmt.Sprintf("%s/uids/%s", groupKey(groupName), uid)
 }
 
+func decodeMember(uid string, value []byte) *GroupMember {
+	member := &GroupMember{}
+	if len(value) == 0 || json.Unmarshal(value, member) != nil {
+		return &GroupMember{UID: uid}
+	}
+	member.UID = uid
+	return member
+}
+
 func getGroupKV(ctx context.Context, groupName string) (*mvccpb.KeyValue, error) {
 	ctxT, cancel := context.WithTimeout(ctx, transactionTimeout)
 	defer cancel()
@@ -171,25 +182,41 @@ func (c *EtcdGroupService) GroupContainsMember(ctx context.Context, groupName, u
 
 // GroupAddMember adds UID to group
 func (c *EtcdGroupService) GroupAddMember(ctx context.Context, groupName, uid string) error {
+	return c.GroupAddMemberWithMetadata(ctx, groupName, &GroupMember{UID: uid})
+}
+
+// GroupAddMemberWithMetadata adds a member with its metadata to group, if the
+// join time is not set the current time is used
+func (c *EtcdGroupService) GroupAddMemberWithMetadata(ctx context.Context, groupName string, member *GroupMember) error {
 	var etcdRes *clientv3.TxnResponse
 	kv, err := getGroupKV(ctx, groupName)
 	if err != nil {
 		return err
 	}
 
+	uid := member.UID
+	member = member.copy()
+	if member.JoinedAt.IsZero() {
+		member.JoinedAt = time.Now()
+	}
+	value, err := json.Marshal(member)
+	if err != nil {
+		return err
+	}
+
 	ctxT, cancel := context.WithTimeout(ctx, transactionTimeout)
 	defer cancel()
 	if kv.Lease != 0 {
 		etcdRes, err = clientInstance.Txn(ctxT).
 			If(clientv3.Compare(clientv3.CreateRevision(groupKey(groupName)), ">", 0),
 				clientv3.Compare(clientv3.CreateRevision(memberKey(groupName, uid)), "=", 0)).
-			Then(clientv3.OpPut(memberKey(groupName, uid), "", clientv3.WithLease(clientv3.LeaseID(kv.Lease)))).
+			Then(clientv3.OpPut(memberKey(groupName, uid), string(value), clientv3.WithLease(clientv3.LeaseID(kv.Lease)))).
 			Commit()
 	} else {
 		etcdRes, err = clientInstance.Txn(ctxT).
 			If(clientv3.Compare(clientv3.CreateRevision(groupKey(groupName)), ">", 0),
 				clientv3.Compare(clientv3.CreateRevision(memberKey(groupName, uid)), "=", 0)).
-			Then(clientv3.OpPut(memberKey(groupName, uid), "")).
+			Then(clientv3.OpPut(memberKey(groupName, uid), string(value))).
 			Commit()
 	}
 
@@ -202,6 +229,109 @@ func (c *EtcdGroupService) GroupAddMember(ctx context.Context, groupName, uid st
 	return nil
 }
 
+// GroupMember returns the member with the given UID and its metadata
+func (c *EtcdGroupService) GroupMember(ctx context.Context, groupName, uid string) (*GroupMember, error) {
+	ctxT, cancel := context.WithTimeout(ctx, transactionTimeout)
+	defer cancel()
+	etcdRes, err := clientInstance.Txn(ctxT).
+		If(clientv3.Compare(clientv3.CreateRevision(groupKey(groupName)), ">", 0)).
+		Then(clientv3.OpGet(memberKey(groupName, uid))).
+		Commit()
+
+	if err != nil {
+		return nil, err
+	}
+	if !etcdRes.Succeeded {
+		return nil, constants.ErrGroupNotFound
+	}
+	kvs := etcdRes.Responses[0].GetResponseRange().GetKvs()
+	if len(kvs) == 0 {
+		return nil, constants.ErrMemberNotFound
+	}
+	return decodeMember(uid, kvs[0].Value), nil
+}
+
+// GroupMembersPage returns up to limit members ordered by UID, starting after
+// the cursor, and the cursor of the next page, which is empty on the last page.
+// A limit lower than one returns all members after the cursor
+func (c *EtcdGroupService) GroupMembersPage(ctx context.Context, groupName, cursor string, limit int) ([]*GroupMember, string, error) {
+	prefix := memberKey(groupName, "")
+	start := prefix
+	if cursor != "" {
+		start = memberKey(groupName, cursor) + "\x00"
+	}
+	opts := []clientv3.OpOption{clientv3.WithRange(clientv3.GetPrefixRangeEnd(prefix))}
+	if limit > 0 {
+		opts = append(opts, clientv3.WithLimit(int64(limit)))
+	}
+
+	ctxT, cancel := context.WithTimeout(ctx, transactionTimeout)
+	defer cancel()
+	etcdRes, err := clientInstance.Txn(ctxT).
+		If(clientv3.Compare(clientv3.CreateRevision(groupKey(groupName)), ">", 0)).
+		Then(clientv3.OpGet(start, opts...)).
+		Commit()
+
+	if err != nil {
+		return nil, "", err
+	}
+	if !etcdRes.Succeeded {
+		return nil, "", constants.ErrGroupNotFound
+	}
+
+	getRes := etcdRes.Responses[0].GetResponseRange()
+	members := make([]*GroupMember, len(getRes.GetKvs()))
+	for i, kv := range getRes.GetKvs() {
+		members[i] = decodeMember(string(kv.Key)[len(prefix):], kv.Value)
+	}
+
+	next := ""
+	if getRes.GetMore() && len(members) > 0 {
+		next = members[len(members)-1].UID
+	}
+	return members, next, nil
+}
+
+// GroupSubscribe calls handler whenever a member joins or leaves the group,
+// until ctx is done. Changes made by any server sharing the etcd cluster
+// are notified, including members removed by lease expiration
+func (c *EtcdGroupService) GroupSubscribe(ctx context.Context, groupName string, handler func(*GroupEvent)) error {
+	prefix := memberKey(groupName, "")
+	ctxT, cancel := context.WithTimeout(ctx, transactionTimeout)
+	defer cancel()
+	// watching from the current revision makes sure no change made after
+	// this method returns is missed
+	etcdRes, err := clientInstance.Get(ctxT, prefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return err
+	}
+	watchChan := clientInstance.Watch(ctx, prefix, clientv3.WithPrefix(), clientv3.WithPrevKV(),
+		clientv3.WithRev(etcdRes.Header.Revision+1))
+
+	go func() {
+		for res := range watchChan {
+			if err := res.Err(); err != nil {
+				logger.Log.Errorf("error watching group %s: %s", groupName, err.Error())
+				continue
+			}
+			for _, ev := range res.Events {
+				uid := string(ev.Kv.Key)[len(prefix):]
+				switch {
+				case ev.Type == clientv3.EventTypePut && ev.IsCreate():
+					handler(&GroupEvent{Type: MemberJoined, GroupName: groupName, Member: decodeMember(uid, ev.Kv.Value)})
+				case ev.Type == clientv3.EventTypeDelete:
+					var value []byte
+					if ev.PrevKv != nil {
+						value = ev.PrevKv.Value
+					}
+					handler(&GroupEvent{Type: MemberLeft, GroupName: groupName, Member: decodeMember(uid, value)})
+				}
+			}
+		}
+	}()
+	return nil
+}
+
 // GroupRemoveMember removes specified UID from group
 func (c *EtcdGroupService) GroupRemoveMember(ctx context.Context, groupName, uid string) error {
 	ctxT, cancel := context.WithTimeout(ctx, transactionTimeout)
@@ -65,3 +65,15 @@ func TestEtcdCount(t *testing.T) {
 func TestEtcdMembers(t *testing.T) {
 	testMembers(etcdGroupService, t)
 }
+
+func TestEtcdAddMemberWithMetadata(t *testing.T) {
+	testAddMemberWithMetadata(etcdGroupService, t)
+}
+
+func TestEtcdMembersPage(t *testing.T) {
+	testMembersPage(etcdGroupService, t)
+}
+
+func TestEtcdSubscribe(t *testing.T) {
+	testSubscribe(etcdGroupService, t)
+}
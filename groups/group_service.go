@@ -19,8 +19,66 @@ type (
 		GroupRemoveMember(ctx context.Context, groupName, uid string) error
 		GroupRenewTTL(ctx context.Context, groupName string) error
 	}
+
+	// MetadataGroupService is a GroupService whose members hold metadata,
+	// which can be listed in pages and notify when members join or leave a group
+	MetadataGroupService interface {
+		GroupService
+		GroupAddMemberWithMetadata(ctx context.Context, groupName string, member *GroupMember) error
+		GroupMember(ctx context.Context, groupName, uid string) (*GroupMember, error)
+		GroupMembersPage(ctx context.Context, groupName, cursor string, limit int) ([]*GroupMember, string, error)
+		GroupSubscribe(ctx context.Context, groupName string, handler func(*GroupEvent)) error
+	}
+
+	// GroupMember is a member of a group with its metadata
+	GroupMember struct {
+		UID      string            `json:"uid"`
+		Role     string            `json:"role,omitempty"`
+		JoinedAt time.Time         `json:"joinedAt"`
+		Metadata map[string]string `json:"metadata,omitempty"`
+	}
+
+	// GroupEventType is the type of a group member event
+	GroupEventType int
+
+	// GroupEvent is sent to group subscribers when a member joins or leaves the group
+	GroupEvent struct {
+		Type      GroupEventType
+		GroupName string
+		Member    *GroupMember
+	}
+)
+
+const (
+	// MemberJoined is the event sent when a member is added to a group
+	MemberJoined GroupEventType = iota
+	// MemberLeft is the event sent when a member is removed from a group,
+	// including when the group is cleared, deleted or expires
+	MemberLeft
 )
 
+// String returns the name of the event type
+func (t GroupEventType) String() string {
+	switch t {
+	case MemberJoined:
+		return "MemberJoined"
+	case MemberLeft:
+		return "MemberLeft"
+	}
+	return "Unknown"
+}
+
+func (m *GroupMember) copy() *GroupMember {
+	c := *m
+	if m.Metadata != nil {
+		c.Metadata = make(map[string]string, len(m.Metadata))
+		for k, v := range m.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	return &c
+}
+
 func elementIndex(slice []string, element string) (int, bool) {
 	for i, sliceElement := range slice {
 		if element == sliceElement {
@@ -24,18 +24,24 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/coreos/etcd/integration"
+	"github.com/go-redis/redis"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/hnlxhzw/pitaya/config"
 	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/helpers"
 	"github.com/hnlxhzw/pitaya/session"
 	"github.com/hnlxhzw/pitaya/session/mocks"
 )
 
 var etcdGroupService *EtcdGroupService
 var memoryGroupService *MemoryGroupService
+var redisGroupService *RedisGroupService
+var redisServer *miniredis.Miniredis
 
 func TestMain(m *testing.M) {
 	setup()
@@ -53,6 +59,14 @@ func setup() {
 		panic(err)
 	}
 	memoryGroupService = NewMemoryGroupService(conf)
+	redisServer, err = miniredis.Run()
+	if err != nil {
+		panic(err)
+	}
+	redisGroupService, err = NewRedisGroupService(conf, redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+	if err != nil {
+		panic(err)
+	}
 }
 
 func testCreateDuplicatedGroup(gs GroupService, t *testing.T) {
@@ -329,3 +343,125 @@ func testMembers(gs GroupService, t *testing.T) {
 	assert.NoError(t, err)
 	assert.ElementsMatch(t, []string{"someid1", "someid2"}, res)
 }
+
+func testAddMemberWithMetadata(gs MetadataGroupService, t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+	err := gs.GroupCreate(ctx, "testAddMemberWithMetadata")
+	assert.NoError(t, err)
+
+	joinedAt := time.Unix(1500000000, 0).UTC()
+	err = gs.GroupAddMemberWithMetadata(ctx, "testAddMemberWithMetadata", &GroupMember{
+		UID:      "metadataUid",
+		Role:     "owner",
+		JoinedAt: joinedAt,
+		Metadata: map[string]string{"team": "blue"},
+	})
+	assert.NoError(t, err)
+	err = gs.GroupAddMember(ctx, "testAddMemberWithMetadata", "plainUid")
+	assert.NoError(t, err)
+	err = gs.GroupAddMemberWithMetadata(ctx, "testAddMemberWithMetadata", &GroupMember{UID: "plainUid"})
+	assert.Equal(t, constants.ErrMemberAlreadyExists, err)
+
+	member, err := gs.GroupMember(ctx, "testAddMemberWithMetadata", "metadataUid")
+	assert.NoError(t, err)
+	assert.Equal(t, "metadataUid", member.UID)
+	assert.Equal(t, "owner", member.Role)
+	assert.True(t, joinedAt.Equal(member.JoinedAt))
+	assert.Equal(t, map[string]string{"team": "blue"}, member.Metadata)
+
+	member, err = gs.GroupMember(ctx, "testAddMemberWithMetadata", "plainUid")
+	assert.NoError(t, err)
+	assert.Equal(t, "plainUid", member.UID)
+	assert.False(t, member.JoinedAt.IsZero())
+
+	_, err = gs.GroupMember(ctx, "testAddMemberWithMetadata", "notAMember")
+	assert.Equal(t, constants.ErrMemberNotFound, err)
+	_, err = gs.GroupMember(ctx, "testAddMemberWithMetadataNotFound", "metadataUid")
+	assert.Equal(t, constants.ErrGroupNotFound, err)
+	err = gs.GroupAddMemberWithMetadata(ctx, "testAddMemberWithMetadataNotFound", &GroupMember{UID: "metadataUid"})
+	assert.Equal(t, constants.ErrGroupNotFound, err)
+}
+
+func testMembersPage(gs MetadataGroupService, t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+	err := gs.GroupCreate(ctx, "testMembersPage")
+	assert.NoError(t, err)
+	for _, uid := range []string{"uid5", "uid3", "uid1", "uid4", "uid2"} {
+		err = gs.GroupAddMember(ctx, "testMembersPage", uid)
+		assert.NoError(t, err)
+	}
+
+	tables := []struct {
+		name     string
+		cursor   string
+		limit    int
+		expected []string
+		next     string
+	}{
+		{"first_page", "", 2, []string{"uid1", "uid2"}, "uid2"},
+		{"middle_page", "uid2", 2, []string{"uid3", "uid4"}, "uid4"},
+		{"last_page", "uid4", 2, []string{"uid5"}, ""},
+		{"exact_last_page", "uid3", 2, []string{"uid4", "uid5"}, ""},
+		{"no_limit", "uid1", 0, []string{"uid2", "uid3", "uid4", "uid5"}, ""},
+		{"after_last", "uid5", 2, []string{}, ""},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			members, next, err := gs.GroupMembersPage(ctx, "testMembersPage", table.cursor, table.limit)
+			assert.NoError(t, err)
+			uids := make([]string, len(members))
+			for i, member := range members {
+				uids[i] = member.UID
+			}
+			assert.Equal(t, table.expected, uids)
+			assert.Equal(t, table.next, next)
+		})
+	}
+
+	_, _, err = gs.GroupMembersPage(ctx, "testMembersPageNotFound", "", 2)
+	assert.Equal(t, constants.ErrGroupNotFound, err)
+}
+
+func testSubscribe(gs MetadataGroupService, t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	t.Parallel()
+	err := gs.GroupCreate(ctx, "testSubscribe")
+	assert.NoError(t, err)
+
+	events := make(chan *GroupEvent, 10)
+	err = gs.GroupSubscribe(ctx, "testSubscribe", func(ev *GroupEvent) {
+		events <- ev
+	})
+	assert.NoError(t, err)
+
+	err = gs.GroupAddMemberWithMetadata(ctx, "testSubscribe", &GroupMember{UID: "subscribeUid1", Role: "owner"})
+	assert.NoError(t, err)
+	err = gs.GroupAddMember(ctx, "testSubscribe", "subscribeUid2")
+	assert.NoError(t, err)
+	err = gs.GroupRemoveMember(ctx, "testSubscribe", "subscribeUid1")
+	assert.NoError(t, err)
+	err = gs.GroupDelete(ctx, "testSubscribe")
+	assert.NoError(t, err)
+
+	expected := []struct {
+		typ  GroupEventType
+		uid  string
+		role string
+	}{
+		{MemberJoined, "subscribeUid1", "owner"},
+		{MemberJoined, "subscribeUid2", ""},
+		{MemberLeft, "subscribeUid1", "owner"},
+		{MemberLeft, "subscribeUid2", ""},
+	}
+	for _, e := range expected {
+		ev := helpers.ShouldEventuallyReceive(t, events).(*GroupEvent)
+		assert.Equal(t, e.typ, ev.Type)
+		assert.Equal(t, "testSubscribe", ev.GroupName)
+		assert.Equal(t, e.uid, ev.Member.UID)
+		assert.Equal(t, e.role, ev.Member.Role)
+	}
+}
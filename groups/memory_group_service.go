@@ -2,6 +2,7 @@ package groups
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,8 +14,15 @@ var (
 	memoryGroupsMu sync.RWMutex
 	memoryGroups   map[string]*MemoryGroup
 	memoryOnce     sync.Once
+
+	memorySubscribersMu sync.RWMutex
+	memorySubscribers   = make(map[string]map[*memorySubscriber]struct{})
 )
 
+type memorySubscriber struct {
+	handler func(*GroupEvent)
+}
+
 // MemoryGroupService base in server memory solution
 type MemoryGroupService struct {
 }
@@ -22,6 +30,7 @@ type MemoryGroupService struct {
 // MemoryGroup is the struct stored in each group key(which is the name of the group)
 type MemoryGroup struct {
 	Uids        []string
+	Members     map[string]*GroupMember
 	LastRefresh int64
 	TTL         int64
 }
@@ -37,13 +46,52 @@ func NewMemoryGroupService(conf *config.Config) *MemoryGroupService {
 
 func groupTTLCleanup(conf *config.Config) {
 	for now := range time.Tick(conf.GetDuration("pitaya.groups.memory.tickduration")) {
+		var events []*GroupEvent
 		memoryGroupsMu.Lock()
 		for groupName, mg := range memoryGroups {
 			if mg.TTL != 0 && now.UnixNano()-mg.LastRefresh > mg.TTL {
+				events = append(events, mg.leaveEvents(groupName)...)
 				delete(memoryGroups, groupName)
 			}
 		}
 		memoryGroupsMu.Unlock()
+		publishMemoryEvents(events)
+	}
+}
+
+func (mg *MemoryGroup) member(uid string) *GroupMember {
+	if member, ok := mg.Members[uid]; ok {
+		return member.copy()
+	}
+	return &GroupMember{UID: uid}
+}
+
+func (mg *MemoryGroup) leaveEvents(groupName string) []*GroupEvent {
+	events := make([]*GroupEvent, len(mg.Uids))
+	for i, uid := range mg.Uids {
+		events[i] = &GroupEvent{Type: MemberLeft, GroupName: groupName, Member: mg.member(uid)}
+	}
+	return events
+}
+
+func publishMemoryEvents(events []*GroupEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	memorySubscribersMu.RLock()
+	handlers := make([][]func(*GroupEvent), len(events))
+	for i, event := range events {
+		for sub := range memorySubscribers[event.GroupName] {
+			handlers[i] = append(handlers[i], sub.handler)
+		}
+	}
+	memorySubscribersMu.RUnlock()
+
+	for i, event := range events {
+		for _, handler := range handlers[i] {
+			handler(event)
+		}
 	}
 }
 
@@ -105,69 +153,181 @@ func (c *MemoryGroupService) GroupContainsMember(ctx context.Context, groupName,
 
 // GroupAddMember adds UID to group
 func (c *MemoryGroupService) GroupAddMember(ctx context.Context, groupName, uid string) error {
+	return c.GroupAddMemberWithMetadata(ctx, groupName, &GroupMember{UID: uid})
+}
+
+// GroupAddMemberWithMetadata adds a member with its metadata to group, if the
+// join time is not set the current time is used
+func (c *MemoryGroupService) GroupAddMemberWithMetadata(ctx context.Context, groupName string, member *GroupMember) error {
 	memoryGroupsMu.Lock()
-	defer memoryGroupsMu.Unlock()
 
 	mg, ok := memoryGroups[groupName]
 	if !ok {
+		memoryGroupsMu.Unlock()
 		return constants.ErrGroupNotFound
 	}
 
-	_, contains := elementIndex(mg.Uids, uid)
+	_, contains := elementIndex(mg.Uids, member.UID)
 	if contains {
+		memoryGroupsMu.Unlock()
 		return constants.ErrMemberAlreadyExists
 	}
 
-	mg.Uids = append(mg.Uids, uid)
+	member = member.copy()
+	if member.JoinedAt.IsZero() {
+		member.JoinedAt = time.Now()
+	}
+	if mg.Members == nil {
+		mg.Members = make(map[string]*GroupMember)
+	}
+	mg.Uids = append(mg.Uids, member.UID)
+	mg.Members[member.UID] = member
 	memoryGroups[groupName] = mg
+	event := &GroupEvent{Type: MemberJoined, GroupName: groupName, Member: member.copy()}
+	memoryGroupsMu.Unlock()
+
+	publishMemoryEvents([]*GroupEvent{event})
+	return nil
+}
+
+// GroupMember returns the member with the given UID and its metadata
+func (c *MemoryGroupService) GroupMember(ctx context.Context, groupName, uid string) (*GroupMember, error) {
+	memoryGroupsMu.Lock()
+	defer memoryGroupsMu.Unlock()
+
+	mg, ok := memoryGroups[groupName]
+	if !ok {
+		return nil, constants.ErrGroupNotFound
+	}
+
+	if _, contains := elementIndex(mg.Uids, uid); !contains {
+		return nil, constants.ErrMemberNotFound
+	}
+	return mg.member(uid), nil
+}
+
+// GroupMembersPage returns up to limit members ordered by UID, starting after
+// the cursor, and the cursor of the next page, which is empty on the last page.
+// A limit lower than one returns all members after the cursor
+func (c *MemoryGroupService) GroupMembersPage(ctx context.Context, groupName, cursor string, limit int) ([]*GroupMember, string, error) {
+	memoryGroupsMu.Lock()
+	defer memoryGroupsMu.Unlock()
+
+	mg, ok := memoryGroups[groupName]
+	if !ok {
+		return nil, "", constants.ErrGroupNotFound
+	}
+
+	uids := make([]string, len(mg.Uids))
+	copy(uids, mg.Uids)
+	sort.Strings(uids)
+
+	start := sort.SearchStrings(uids, cursor)
+	if start < len(uids) && cursor != "" && uids[start] == cursor {
+		start++
+	}
+	end := len(uids)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+
+	members := make([]*GroupMember, 0, end-start)
+	for _, uid := range uids[start:end] {
+		members = append(members, mg.member(uid))
+	}
+
+	next := ""
+	if end < len(uids) {
+		next = uids[end-1]
+	}
+	return members, next, nil
+}
+
+// GroupSubscribe calls handler whenever a member joins or leaves the group,
+// until ctx is done. Memory groups are local to this server, so only
+// changes made on this server are notified
+func (c *MemoryGroupService) GroupSubscribe(ctx context.Context, groupName string, handler func(*GroupEvent)) error {
+	sub := &memorySubscriber{handler: handler}
+
+	memorySubscribersMu.Lock()
+	if memorySubscribers[groupName] == nil {
+		memorySubscribers[groupName] = make(map[*memorySubscriber]struct{})
+	}
+	memorySubscribers[groupName][sub] = struct{}{}
+	memorySubscribersMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		memorySubscribersMu.Lock()
+		defer memorySubscribersMu.Unlock()
+		delete(memorySubscribers[groupName], sub)
+		if len(memorySubscribers[groupName]) == 0 {
+			delete(memorySubscribers, groupName)
+		}
+	}()
 	return nil
 }
 
 // GroupRemoveMember removes specific UID from group
 func (c *MemoryGroupService) GroupRemoveMember(ctx context.Context, groupName, uid string) error {
 	memoryGroupsMu.Lock()
-	defer memoryGroupsMu.Unlock()
 
 	mg, ok := memoryGroups[groupName]
 	if !ok {
+		memoryGroupsMu.Unlock()
 		return constants.ErrGroupNotFound
 	}
 	index, contains := elementIndex(mg.Uids, uid)
-	if contains {
-		mg.Uids[index] = mg.Uids[len(mg.Uids)-1]
-		mg.Uids = mg.Uids[:len(mg.Uids)-1]
-		memoryGroups[groupName] = mg
-		return nil
+	if !contains {
+		memoryGroupsMu.Unlock()
+		return constants.ErrMemberNotFound
 	}
 
-	return constants.ErrMemberNotFound
+	event := &GroupEvent{Type: MemberLeft, GroupName: groupName, Member: mg.member(uid)}
+	mg.Uids[index] = mg.Uids[len(mg.Uids)-1]
+	mg.Uids = mg.Uids[:len(mg.Uids)-1]
+	delete(mg.Members, uid)
+	memoryGroups[groupName] = mg
+	memoryGroupsMu.Unlock()
+
+	publishMemoryEvents([]*GroupEvent{event})
+	return nil
 }
 
 // GroupRemoveAll clears all UIDs from group
 func (c *MemoryGroupService) GroupRemoveAll(ctx context.Context, groupName string) error {
 	memoryGroupsMu.Lock()
-	defer memoryGroupsMu.Unlock()
 
 	mg, ok := memoryGroups[groupName]
 	if !ok {
+		memoryGroupsMu.Unlock()
 		return constants.ErrGroupNotFound
 	}
 
+	events := mg.leaveEvents(groupName)
 	mg.Uids = []string{}
+	mg.Members = nil
+	memoryGroupsMu.Unlock()
+
+	publishMemoryEvents(events)
 	return nil
 }
 
 // GroupDelete deletes the whole group, including members and base group
 func (c *MemoryGroupService) GroupDelete(ctx context.Context, groupName string) error {
 	memoryGroupsMu.Lock()
-	defer memoryGroupsMu.Unlock()
 
-	_, ok := memoryGroups[groupName]
+	mg, ok := memoryGroups[groupName]
 	if !ok {
+		memoryGroupsMu.Unlock()
 		return constants.ErrGroupNotFound
 	}
 
+	events := mg.leaveEvents(groupName)
 	delete(memoryGroups, groupName)
+	memoryGroupsMu.Unlock()
+
+	publishMemoryEvents(events)
 	return nil
 }
 
@@ -65,3 +65,15 @@ func TestMemoryCount(t *testing.T) {
 func TestMemoryMembers(t *testing.T) {
 	testMembers(memoryGroupService, t)
 }
+
+func TestMemoryAddMemberWithMetadata(t *testing.T) {
+	testAddMemberWithMetadata(memoryGroupService, t)
+}
+
+func TestMemoryMembersPage(t *testing.T) {
+	testMembersPage(memoryGroupService, t)
+}
+
+func TestMemorySubscribe(t *testing.T) {
+	testSubscribe(memoryGroupService, t)
+}
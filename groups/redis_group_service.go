@@ -0,0 +1,271 @@
+package groups
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/hnlxhzw/pitaya/config"
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+// the group key holds the group TTL in milliseconds (0 when the group has no TTL)
+// and the members are stored in a set that expires together with the group key
+var (
+	redisCreateGroupScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+redis.call('DEL', KEYS[2])
+local ttl = tonumber(ARGV[1])
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return 1`)
+
+	redisAddMemberScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+if redis.call('SADD', KEYS[2], ARGV[1]) == 0 then
+	return 0
+end
+local pttl = redis.call('PTTL', KEYS[1])
+if pttl > 0 then
+	redis.call('PEXPIRE', KEYS[2], pttl)
+end
+return 1`)
+
+	redisRenewTTLScript = redis.NewScript(`
+local ttl = redis.call('GET', KEYS[1])
+if not ttl then
+	return -1
+end
+if tonumber(ttl) == 0 then
+	return 0
+end
+redis.call('PEXPIRE', KEYS[1], ttl)
+redis.call('PEXPIRE', KEYS[2], ttl)
+return 1`)
+)
+
+// RedisGroupService base Redis struct solution
+type RedisGroupService struct {
+	client             *redis.Client
+	prefix             string
+	transactionTimeout time.Duration
+}
+
+// NewRedisGroupService returns a new group instance, if no client is given
+// one is created using the pitaya.groups.redis configurations
+func NewRedisGroupService(conf *config.Config, clientOrNil *redis.Client) (*RedisGroupService, error) {
+	client := clientOrNil
+	if client == nil {
+		client = redis.NewClient(&redis.Options{
+			Addr:        conf.GetString("pitaya.groups.redis.url"),
+			Password:    conf.GetString("pitaya.groups.redis.password"),
+			DB:          conf.GetInt("pitaya.groups.redis.db"),
+			PoolSize:    conf.GetInt("pitaya.groups.redis.poolsize"),
+			DialTimeout: conf.GetDuration("pitaya.groups.redis.dialtimeout"),
+		})
+	}
+	if err := client.Ping().Err(); err != nil {
+		return nil, err
+	}
+	return &RedisGroupService{
+		client:             client,
+		prefix:             conf.GetString("pitaya.groups.redis.prefix"),
+		transactionTimeout: conf.GetDuration("pitaya.groups.redis.transactiontimeout"),
+	}, nil
+}
+
+func (c *RedisGroupService) groupKey(groupName string) string {
+	return fmt.Sprintf("%sgroups/%s", c.prefix, groupName)
+}
+
+func (c *RedisGroupService) membersKey(groupName string) string {
+	return fmt.Sprintf("%s/uids", c.groupKey(groupName))
+}
+
+func (c *RedisGroupService) withTimeout(ctx context.Context) (*redis.Client, context.CancelFunc) {
+	ctxT, cancel := context.WithTimeout(ctx, c.transactionTimeout)
+	return c.client.WithContext(ctxT), cancel
+}
+
+func (c *RedisGroupService) createGroup(ctx context.Context, groupName string, ttl time.Duration) error {
+	client, cancel := c.withTimeout(ctx)
+	defer cancel()
+	keys := []string{c.groupKey(groupName), c.membersKey(groupName)}
+	created, err := redisCreateGroupScript.Run(client, keys, int64(ttl/time.Millisecond)).Int()
+	if err != nil {
+		return err
+	}
+	if created == 0 {
+		return constants.ErrGroupAlreadyExists
+	}
+	return nil
+}
+
+// GroupCreate creates a group inside Redis, without TTL
+func (c *RedisGroupService) GroupCreate(ctx context.Context, groupName string) error {
+	return c.createGroup(ctx, groupName, 0)
+}
+
+// GroupCreateWithTTL creates a group inside Redis with TTL, the TTL has a
+// minimum of one second, as etcd leases do
+func (c *RedisGroupService) GroupCreateWithTTL(ctx context.Context, groupName string, ttlTime time.Duration) error {
+	if ttlTime < time.Second {
+		ttlTime = time.Second
+	}
+	return c.createGroup(ctx, groupName, ttlTime)
+}
+
+// GroupMembers returns all member's UIDs
+func (c *RedisGroupService) GroupMembers(ctx context.Context, groupName string) ([]string, error) {
+	client, cancel := c.withTimeout(ctx)
+	defer cancel()
+	var exists *redis.IntCmd
+	var members *redis.StringSliceCmd
+	_, err := client.TxPipelined(func(pipe redis.Pipeliner) error {
+		exists = pipe.Exists(c.groupKey(groupName))
+		members = pipe.SMembers(c.membersKey(groupName))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if exists.Val() == 0 {
+		return nil, constants.ErrGroupNotFound
+	}
+	return members.Val(), nil
+}
+
+// GroupContainsMember checks whether a UID is contained in current group or not
+func (c *RedisGroupService) GroupContainsMember(ctx context.Context, groupName, uid string) (bool, error) {
+	client, cancel := c.withTimeout(ctx)
+	defer cancel()
+	var exists *redis.IntCmd
+	var isMember *redis.BoolCmd
+	_, err := client.TxPipelined(func(pipe redis.Pipeliner) error {
+		exists = pipe.Exists(c.groupKey(groupName))
+		isMember = pipe.SIsMember(c.membersKey(groupName), uid)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if exists.Val() == 0 {
+		return false, constants.ErrGroupNotFound
+	}
+	return isMember.Val(), nil
+}
+
+// GroupAddMember adds UID to group
+func (c *RedisGroupService) GroupAddMember(ctx context.Context, groupName, uid string) error {
+	client, cancel := c.withTimeout(ctx)
+	defer cancel()
+	keys := []string{c.groupKey(groupName), c.membersKey(groupName)}
+	added, err := redisAddMemberScript.Run(client, keys, uid).Int()
+	if err != nil {
+		return err
+	}
+	switch added {
+	case -1:
+		return constants.ErrGroupNotFound
+	case 0:
+		return constants.ErrMemberAlreadyExists
+	}
+	return nil
+}
+
+// GroupRemoveMember removes specified UID from group
+func (c *RedisGroupService) GroupRemoveMember(ctx context.Context, groupName, uid string) error {
+	client, cancel := c.withTimeout(ctx)
+	defer cancel()
+	removed, err := client.SRem(c.membersKey(groupName), uid).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return constants.ErrMemberNotFound
+	}
+	return nil
+}
+
+// GroupRemoveAll clears all UIDs in the group
+func (c *RedisGroupService) GroupRemoveAll(ctx context.Context, groupName string) error {
+	client, cancel := c.withTimeout(ctx)
+	defer cancel()
+	var exists *redis.IntCmd
+	_, err := client.TxPipelined(func(pipe redis.Pipeliner) error {
+		exists = pipe.Exists(c.groupKey(groupName))
+		pipe.Del(c.membersKey(groupName))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if exists.Val() == 0 {
+		return constants.ErrGroupNotFound
+	}
+	return nil
+}
+
+// GroupDelete deletes the whole group, including members and base group
+func (c *RedisGroupService) GroupDelete(ctx context.Context, groupName string) error {
+	client, cancel := c.withTimeout(ctx)
+	defer cancel()
+	var deleted *redis.IntCmd
+	_, err := client.TxPipelined(func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(c.groupKey(groupName))
+		pipe.Del(c.membersKey(groupName))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if deleted.Val() == 0 {
+		return constants.ErrGroupNotFound
+	}
+	return nil
+}
+
+// GroupCountMembers get current member amount in group
+func (c *RedisGroupService) GroupCountMembers(ctx context.Context, groupName string) (int, error) {
+	client, cancel := c.withTimeout(ctx)
+	defer cancel()
+	var exists, count *redis.IntCmd
+	_, err := client.TxPipelined(func(pipe redis.Pipeliner) error {
+		exists = pipe.Exists(c.groupKey(groupName))
+		count = pipe.SCard(c.membersKey(groupName))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if exists.Val() == 0 {
+		return 0, constants.ErrGroupNotFound
+	}
+	return int(count.Val()), nil
+}
+
+// GroupRenewTTL will renew the TTL of the group and its members
+func (c *RedisGroupService) GroupRenewTTL(ctx context.Context, groupName string) error {
+	client, cancel := c.withTimeout(ctx)
+	defer cancel()
+	keys := []string{c.groupKey(groupName), c.membersKey(groupName)}
+	renewed, err := redisRenewTTLScript.Run(client, keys).Int()
+	if err != nil {
+		return err
+	}
+	switch renewed {
+	case -1:
+		return constants.ErrGroupNotFound
+	case 0:
+		return constants.ErrRedisTTLNotFound
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package groups
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+func TestRedisCreateDuplicatedGroup(t *testing.T) {
+	testCreateDuplicatedGroup(redisGroupService, t)
+}
+
+func TestRedisCreateGroup(t *testing.T) {
+	testCreateGroup(redisGroupService, t)
+}
+
+func TestRedisCreateGroupWithTTL(t *testing.T) {
+	testCreateGroupWithTTL(redisGroupService, t)
+}
+
+func TestRedisGroupAddMember(t *testing.T) {
+	testGroupAddMember(redisGroupService, t)
+}
+
+func TestRedisGroupAddDuplicatedMember(t *testing.T) {
+	testGroupAddDuplicatedMember(redisGroupService, t)
+}
+
+func TestRedisGroupContainsMember(t *testing.T) {
+	testGroupContainsMember(redisGroupService, t)
+}
+
+func TestRedisRemove(t *testing.T) {
+	testRemove(redisGroupService, t)
+}
+
+func TestRedisDelete(t *testing.T) {
+	testDelete(redisGroupService, t)
+}
+
+func TestRedisRemoveAll(t *testing.T) {
+	testRemoveAll(redisGroupService, t)
+}
+
+func TestRedisCount(t *testing.T) {
+	testCount(redisGroupService, t)
+}
+
+func TestRedisMembers(t *testing.T) {
+	testMembers(redisGroupService, t)
+}
+
+func TestRedisGroupExpiresWithTTL(t *testing.T) {
+	ctx := context.Background()
+	err := redisGroupService.GroupCreateWithTTL(ctx, "testRedisGroupExpires", 10*time.Second)
+	assert.NoError(t, err)
+	err = redisGroupService.GroupAddMember(ctx, "testRedisGroupExpires", "expiresUid")
+	assert.NoError(t, err)
+
+	redisServer.FastForward(6 * time.Second)
+	err = redisGroupService.GroupRenewTTL(ctx, "testRedisGroupExpires")
+	assert.NoError(t, err)
+	redisServer.FastForward(6 * time.Second)
+	res, err := redisGroupService.GroupContainsMember(ctx, "testRedisGroupExpires", "expiresUid")
+	assert.NoError(t, err)
+	assert.True(t, res)
+
+	redisServer.FastForward(5 * time.Second)
+	_, err = redisGroupService.GroupContainsMember(ctx, "testRedisGroupExpires", "expiresUid")
+	assert.Equal(t, constants.ErrGroupNotFound, err)
+	assert.False(t, redisServer.Exists(redisGroupService.membersKey("testRedisGroupExpires")))
+}
+
+func TestRedisAddMemberToNonexistentGroup(t *testing.T) {
+	err := redisGroupService.GroupAddMember(context.Background(), "testRedisNonexistentGroup", "someuid")
+	assert.Equal(t, constants.ErrGroupNotFound, err)
+}
@@ -0,0 +1,123 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package pitaya
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/hnlxhzw/pitaya/cluster"
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/logger"
+	"github.com/hnlxhzw/pitaya/protos"
+	"github.com/hnlxhzw/pitaya/session"
+)
+
+// HandoffSession transfers a session to the frontend server with the given id.
+// The session UID, data and handshake data are sent to the target server and the
+// client is told to reconnect to the address in the target metadata
+// (constants.ClientAddressKey) using a resume token, after that the local
+// connection is closed
+func HandoffSession(ctx context.Context, s *session.Session, serverID string) error {
+	if !app.server.Frontend {
+		return constants.ErrHandoffOnBackend
+	}
+	if app.serviceDiscovery == nil {
+		return constants.ErrServiceDiscoveryNotInitialized
+	}
+	target, err := app.serviceDiscovery.GetServer(serverID)
+	if err != nil {
+		return err
+	}
+	return handoffSession(ctx, s, target)
+}
+
+// HandoffAllSessions hands off every session of this frontend, spreading them
+// among the other frontend servers of the same type, it's meant to be called
+// before scaling down or redeploying a frontend server
+func HandoffAllSessions(ctx context.Context) ([]int64, error) {
+	if !app.server.Frontend {
+		return nil, constants.ErrHandoffOnBackend
+	}
+	if app.serviceDiscovery == nil {
+		return nil, constants.ErrServiceDiscoveryNotInitialized
+	}
+	servers, err := app.serviceDiscovery.GetServersByType(app.server.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]*cluster.Server, 0, len(servers))
+	for _, sv := range servers {
+		if sv.ID == app.server.ID || sv.Metadata[constants.ClientAddressKey] == "" {
+			continue
+		}
+		targets = append(targets, sv)
+	}
+	if len(targets) == 0 {
+		return nil, constants.ErrNoServersToHandoff
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ID < targets[j].ID })
+
+	var notHandedOff []int64
+	i := 0
+	session.Range(func(s *session.Session) bool {
+		target := targets[i%len(targets)]
+		i++
+		if err := handoffSession(ctx, s, target); err != nil {
+			notHandedOff = append(notHandedOff, s.ID())
+			logger.Log.Errorf("Session handoff error, ID=%d, UID=%s, SvID=%s, ERROR=%s", s.ID(), s.UID(), target.ID, err.Error())
+		}
+		return true
+	})
+
+	if len(notHandedOff) != 0 {
+		return notHandedOff, constants.ErrHandingOffSessions
+	}
+
+	return nil, nil
+}
+
+func handoffSession(ctx context.Context, s *session.Session, target *cluster.Server) error {
+	if !target.Frontend {
+		return constants.ErrHandoffTargetNotFrontend
+	}
+	address := target.Metadata[constants.ClientAddressKey]
+	if address == "" {
+		return constants.ErrNoClientAddress
+	}
+
+	resumeToken := uuid.New().String()
+	data, err := json.Marshal(s.GetHandoffData(resumeToken))
+	if err != nil {
+		return err
+	}
+
+	route := fmt.Sprintf("%s.%s", target.Type, constants.SessionHandoffRoute)
+	msg := &protos.Session{Id: s.ID(), Uid: s.UID(), Data: data}
+	if err := RPCTo(ctx, target.ID, route, &protos.Response{}, msg); err != nil {
+		return err
+	}
+
+	return s.Redirect(ctx, address, resumeToken)
+}
@@ -0,0 +1,167 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package loadtest
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/hnlxhzw/pitaya/client"
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/session"
+)
+
+// Scenario is what each simulated player does once connected
+type Scenario interface {
+	Run(ctx context.Context, p *Player) error
+}
+
+// ScenarioFunc is a function that implements Scenario
+type ScenarioFunc func(ctx context.Context, p *Player) error
+
+// Run runs the scenario function
+func (f ScenarioFunc) Run(ctx context.Context, p *Player) error {
+	return f(ctx, p)
+}
+
+// Config configures a load test
+type Config struct {
+	// Address of the frontend server the players connect to
+	Address string
+	// WebSocket makes the players connect using websockets on Path
+	WebSocket bool
+	Path      string
+	// TLS is used to connect if set
+	TLS *tls.Config
+	// Players is the number of simulated players
+	Players int
+	// RampUp is the time taken to start all players, they are started at
+	// even intervals. If 0 all players are started at once
+	RampUp time.Duration
+	// Duration of the test, counting the ramp up, players run the scenario
+	// repeatedly until it ends. If 0 each player runs the scenario once
+	Duration time.Duration
+	// RequestTimeout is the timeout of the requests, 5s if 0
+	RequestTimeout time.Duration
+	// InflightWindow is the limit of requests of a player waiting for a
+	// response, client.DefaultInflightWindow if 0
+	InflightWindow int
+	// HandshakeData returns the handshake data sent by a player, the
+	// client default is sent if nil
+	HandshakeData func(playerID int) *session.HandshakeData
+}
+
+// Run runs the scenario with the players of the config and reports the
+// latencies, errors and throughput of their requests. Canceling the context
+// stops the test, reporting what was done until then
+func Run(ctx context.Context, config Config, scenario Scenario) (*Report, error) {
+	if config.Address == "" || config.Players <= 0 {
+		return nil, constants.ErrInvalidLoadTestConfig
+	}
+	if config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Duration)
+		defer cancel()
+	}
+
+	r := newRecorder()
+	start := time.Now()
+	interval := config.RampUp / time.Duration(config.Players)
+	wg := sync.WaitGroup{}
+	for i := 0; i < config.Players; i++ {
+		if i > 0 && interval > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			runPlayer(ctx, config, scenario, id, r)
+		}(i)
+	}
+	wg.Wait()
+
+	return r.report(config.Players, time.Since(start)), nil
+}
+
+func runPlayer(ctx context.Context, config Config, scenario Scenario, id int, r *recorder) {
+	c, err := connect(config, id)
+	if err != nil {
+		r.recordConnect(err)
+		return
+	}
+	p := newPlayer(id, c, r)
+	go p.receivePushes()
+	defer p.disconnect()
+
+	for {
+		err := scenario.Run(ctx, p)
+		if ctx.Err() != nil {
+			// scenarios interrupted by the end of the test are not counted
+			if err == nil {
+				r.recordScenario(nil)
+			}
+			return
+		}
+		r.recordScenario(err)
+		if config.Duration == 0 || !c.ConnectedStatus() {
+			return
+		}
+	}
+}
+
+func connect(config Config, id int) (client.PitayaClient, error) {
+	timeout := config.RequestTimeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	c := client.New(logrus.ErrorLevel, timeout)
+	if config.InflightWindow > 0 {
+		c.SetInflightWindow(config.InflightWindow)
+	}
+	if config.HandshakeData != nil {
+		c.SetClientHandshakeData(config.HandshakeData(id))
+	}
+
+	var tlsConfig []*tls.Config
+	if config.TLS != nil {
+		tlsConfig = append(tlsConfig, config.TLS)
+	}
+	var err error
+	if config.WebSocket {
+		err = c.ConnectToWS(config.Address, config.Path, tlsConfig...)
+	} else {
+		err = c.ConnectTo(config.Address, tlsConfig...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
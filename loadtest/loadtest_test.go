@@ -0,0 +1,256 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/conn/codec"
+	"github.com/hnlxhzw/pitaya/conn/message"
+	"github.com/hnlxhzw/pitaya/conn/packet"
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/session"
+)
+
+// testServer is a frontend that answers requests to room.room.join, fails
+// requests to room.room.fail and pushes room.onMessage on notifies
+type testServer struct {
+	listener   net.Listener
+	mutex      sync.Mutex
+	handshakes []*session.HandshakeData
+}
+
+func newTestServer(t *testing.T) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &testServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *testServer) serve(conn net.Conn) {
+	defer conn.Close()
+	decoder := codec.NewPomeloPacketDecoder()
+	encoder := codec.NewPomeloPacketEncoder()
+	msgEncoder := message.NewMessagesEncoder(false)
+	buf := bytes.NewBuffer(nil)
+	data := make([]byte, 2048)
+	for {
+		n, err := conn.Read(data)
+		if err != nil {
+			return
+		}
+		buf.Write(data[:n])
+		packets, err := decoder.Decode(buf.Bytes())
+		if err != nil {
+			return
+		}
+		for _, p := range packets {
+			buf.Next(codec.HeadLength + p.Length)
+			var res *message.Message
+			switch p.Type {
+			case packet.Handshake:
+				handshake := &session.HandshakeData{}
+				json.Unmarshal(p.Data, handshake)
+				s.mutex.Lock()
+				s.handshakes = append(s.handshakes, handshake)
+				s.mutex.Unlock()
+				out, _ := encoder.Encode(packet.Handshake, []byte(`{"code":200,"sys":{"heartbeat":60,"serializer":"json"}}`))
+				conn.Write(out)
+				continue
+			case packet.Data:
+				m, err := message.Decode(p.Data)
+				if err != nil {
+					return
+				}
+				switch m.Route {
+				case "room.room.join":
+					res = &message.Message{Type: message.Response, ID: m.ID, Data: []byte(`{"code":200}`)}
+				case "room.room.fail":
+					res = &message.Message{Type: message.Response, ID: m.ID, Data: []byte(`{"ErrorCode":500}`), Err: true}
+				case "room.room.message":
+					res = &message.Message{Type: message.Push, Route: "room.onMessage", Data: m.Data}
+				}
+			}
+			if res != nil {
+				encMsg, _ := msgEncoder.Encode(res)
+				out, _ := encoder.Encode(packet.Data, encMsg)
+				conn.Write(out)
+			}
+		}
+	}
+}
+
+func routeReport(r *Report, route string) *RouteReport {
+	for _, routeReport := range r.Routes {
+		if routeReport.Route == route {
+			return routeReport
+		}
+	}
+	return nil
+}
+
+func TestRunYAMLScenario(t *testing.T) {
+	server := newTestServer(t)
+	scenario, err := ParseYAMLScenario([]byte(`
+handshake:
+  user:
+    name: "bot-{{.ID}}"
+steps:
+  - request: room.room.join
+  - think: 1ms
+  - notify: room.room.message
+    data: {content: "hello from {{.ID}}"}
+  - push: room.onMessage
+    timeout: 1s
+`))
+	assert.NoError(t, err)
+
+	report, err := Run(context.Background(), Config{
+		Address:       server.addr(),
+		Players:       5,
+		RampUp:        50 * time.Millisecond,
+		HandshakeData: scenario.HandshakeData,
+	}, scenario)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 5, report.Players)
+	assert.Equal(t, 0, report.ConnectErrors)
+	assert.Equal(t, 5, report.Scenarios)
+	assert.Equal(t, 0, report.ScenarioErrors)
+	assert.Equal(t, 10, report.Requests)
+	assert.Empty(t, report.Errors)
+	assert.True(t, report.Duration >= 40*time.Millisecond)
+	assert.Equal(t, 5, routeReport(report, "room.room.join").Requests)
+	assert.Equal(t, 5, routeReport(report, "push:room.onMessage").Requests)
+
+	names := []interface{}{}
+	server.mutex.Lock()
+	for _, handshake := range server.handshakes {
+		names = append(names, handshake.User["name"])
+	}
+	server.mutex.Unlock()
+	assert.ElementsMatch(t, []interface{}{"bot-0", "bot-1", "bot-2", "bot-3", "bot-4"}, names)
+}
+
+func TestRunScenarioWithDuration(t *testing.T) {
+	server := newTestServer(t)
+	scenario := ScenarioFunc(func(ctx context.Context, p *Player) error {
+		if err := p.Request(ctx, "room.room.join", []byte(`{}`), nil); err != nil {
+			return err
+		}
+		p.Request(ctx, "room.room.fail", []byte(`{}`), nil)
+		return p.Think(ctx, 5*time.Millisecond)
+	})
+
+	report, err := Run(context.Background(), Config{
+		Address:  server.addr(),
+		Players:  2,
+		Duration: 100 * time.Millisecond,
+	}, scenario)
+	assert.NoError(t, err)
+
+	assert.True(t, report.Scenarios > 2)
+	assert.Equal(t, 0, report.ScenarioErrors)
+	join := routeReport(report, "room.room.join")
+	fail := routeReport(report, "room.room.fail")
+	assert.Equal(t, 0, join.Errors)
+	assert.Equal(t, fail.Requests, fail.Errors)
+	assert.True(t, fail.Errors >= report.Scenarios)
+	assert.Equal(t, fail.Errors, report.RequestErrors)
+	assert.Equal(t, fail.Errors, report.Errors["room.room.fail: request to room.room.fail failed with error code 500"])
+	assert.True(t, report.Throughput > 0)
+}
+
+func TestRunScenarioPushTimeout(t *testing.T) {
+	server := newTestServer(t)
+	scenario := ScenarioFunc(func(ctx context.Context, p *Player) error {
+		_, err := p.WaitPush(ctx, "room.onMessage", 10*time.Millisecond)
+		return err
+	})
+
+	report, err := Run(context.Background(), Config{Address: server.addr(), Players: 1}, scenario)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.ScenarioErrors)
+	assert.Equal(t, 1, routeReport(report, "push:room.onMessage").Errors)
+}
+
+func TestWaitPushCanceled(t *testing.T) {
+	r := newRecorder()
+	p := newPlayer(0, nil, r)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := p.WaitPush(ctx, "room.onMessage", time.Second)
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, r.routeErrors)
+	assert.Empty(t, r.latencies)
+}
+
+func TestBufferPushKeepsNewest(t *testing.T) {
+	ch := make(chan *message.Message, 2)
+	for i := 0; i < 3; i++ {
+		bufferPush(ch, &message.Message{ID: uint(i)})
+	}
+	assert.Equal(t, uint(1), (<-ch).ID)
+	assert.Equal(t, uint(2), (<-ch).ID)
+}
+
+func TestRunConnectErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	report, err := Run(context.Background(), Config{Address: addr, Players: 3}, ScenarioFunc(func(ctx context.Context, p *Player) error {
+		return nil
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.ConnectErrors)
+	assert.Equal(t, 0, report.Scenarios)
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	_, err := Run(context.Background(), Config{Players: 1}, nil)
+	assert.Equal(t, constants.ErrInvalidLoadTestConfig, err)
+	_, err = Run(context.Background(), Config{Address: "localhost:3250"}, nil)
+	assert.Equal(t, constants.ErrInvalidLoadTestConfig, err)
+}
@@ -0,0 +1,164 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hnlxhzw/pitaya/client"
+	"github.com/hnlxhzw/pitaya/conn/message"
+)
+
+// pushBufferSize is the number of pushes of a route kept until they are
+// expected, the oldest push is dropped when a new one arrives on a full buffer
+const pushBufferSize = 100
+
+// Player is a simulated player, it is connected before its scenario is run
+// and records the latency of the requests made through it
+type Player struct {
+	// ID is the index of the player, from 0 to the number of players
+	ID int
+	// Client is the connected client of the player
+	Client client.PitayaClient
+	// Vars can be used by scenarios to keep state between iterations
+	Vars map[string]interface{}
+
+	recorder    *recorder
+	pushesMutex sync.Mutex
+	pushes      map[string]chan *message.Message
+	done        chan struct{}
+}
+
+func newPlayer(id int, c client.PitayaClient, r *recorder) *Player {
+	return &Player{
+		ID:       id,
+		Client:   c,
+		Vars:     map[string]interface{}{},
+		recorder: r,
+		pushes:   map[string]chan *message.Message{},
+		done:     make(chan struct{}),
+	}
+}
+
+// Request sends a request and waits for its response, recording its latency
+func (p *Player) Request(ctx context.Context, route string, req interface{}, resp interface{}) error {
+	start := time.Now()
+	err := p.Client.Request(ctx, route, req, resp)
+	if err != nil && ctx.Err() != nil {
+		// requests interrupted by the end of the test are not errors
+		return err
+	}
+	p.recorder.recordRequest(route, time.Since(start), err)
+	return err
+}
+
+// Notify sends a notify to the server
+func (p *Player) Notify(route string, data []byte) error {
+	return p.Client.SendNotify(route, data)
+}
+
+// Think waits for d, or for the context to be done
+func (p *Player) Think(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitPush waits for a push of route received after the previous one
+// expected, for at most timeout, and returns its data. The wait is recorded
+// as a request of the "push:<route>" route, unless ctx is done first
+func (p *Player) WaitPush(ctx context.Context, route string, timeout time.Duration) ([]byte, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	name := fmt.Sprintf("push:%s", route)
+	select {
+	case m := <-p.pushChan(route):
+		p.recorder.recordRequest(name, time.Since(start), nil)
+		return m.Data, nil
+	case <-waitCtx.Done():
+		if ctx.Err() != nil {
+			// waits interrupted by the end of the test are not errors
+			return nil, ctx.Err()
+		}
+		err := fmt.Errorf("no push received on %s after %s", route, timeout)
+		p.recorder.recordRequest(name, time.Since(start), err)
+		return nil, err
+	}
+}
+
+func (p *Player) pushChan(route string) chan *message.Message {
+	p.pushesMutex.Lock()
+	defer p.pushesMutex.Unlock()
+	ch, ok := p.pushes[route]
+	if !ok {
+		ch = make(chan *message.Message, pushBufferSize)
+		p.pushes[route] = ch
+	}
+	return ch
+}
+
+// receivePushes keeps the pushes of the client until they are expected, so
+// the client never blocks on its message channel
+func (p *Player) receivePushes() {
+	msgs := p.Client.MsgChannel()
+	for {
+		select {
+		case m := <-msgs:
+			if m.Type != message.Push {
+				continue
+			}
+			bufferPush(p.pushChan(m.Route), m)
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// bufferPush adds a push to the buffer of its route, dropping the oldest
+// push if the buffer is full
+func bufferPush(ch chan *message.Message, m *message.Message) {
+	for {
+		select {
+		case ch <- m:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
+
+func (p *Player) disconnect() {
+	close(p.done)
+	p.Client.Disconnect()
+}
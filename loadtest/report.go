@@ -0,0 +1,228 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// RouteReport has the statistics of the requests of a route, latencies are
+// in milliseconds
+type RouteReport struct {
+	Route      string  `json:"route"`
+	Requests   int     `json:"requests"`
+	Errors     int     `json:"errors"`
+	Throughput float64 `json:"throughput"`
+	Mean       float64 `json:"mean"`
+	P50        float64 `json:"p50"`
+	P90        float64 `json:"p90"`
+	P95        float64 `json:"p95"`
+	P99        float64 `json:"p99"`
+	Max        float64 `json:"max"`
+}
+
+// Report is the result of a load test
+type Report struct {
+	Duration       time.Duration  `json:"duration"`
+	Players        int            `json:"players"`
+	ConnectErrors  int            `json:"connectErrors"`
+	Scenarios      int            `json:"scenarios"`
+	ScenarioErrors int            `json:"scenarioErrors"`
+	Requests       int            `json:"requests"`
+	RequestErrors  int            `json:"requestErrors"`
+	Throughput     float64        `json:"throughput"`
+	Routes         []*RouteReport `json:"routes"`
+	Errors         map[string]int `json:"errors"`
+}
+
+// WriteText writes the report as a human readable table
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "duration: %s, players: %d, connect errors: %d\n", r.Duration.Round(time.Millisecond), r.Players, r.ConnectErrors)
+	fmt.Fprintf(w, "scenarios: %d, scenario errors: %d\n", r.Scenarios, r.ScenarioErrors)
+	fmt.Fprintf(w, "requests: %d, errors: %d, throughput: %.2f req/s\n\n", r.Requests, r.RequestErrors, r.Throughput)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "route\trequests\terrors\treq/s\tmean\tp50\tp90\tp95\tp99\tmax\t")
+	for _, route := range r.Routes {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t\n",
+			route.Route, route.Requests, route.Errors, route.Throughput,
+			route.Mean, route.P50, route.P90, route.P95, route.P99, route.Max)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.Errors) > 0 {
+		fmt.Fprintln(w, "\nerrors:")
+		msgs := make([]string, 0, len(r.Errors))
+		for msg := range r.Errors {
+			msgs = append(msgs, msg)
+		}
+		sort.Strings(msgs)
+		for _, msg := range msgs {
+			fmt.Fprintf(w, "  %d x %s\n", r.Errors[msg], msg)
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as json
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// recorder collects the latencies and errors of the players
+type recorder struct {
+	mutex          sync.Mutex
+	latencies      map[string][]time.Duration
+	routeErrors    map[string]int
+	errors         map[string]int
+	connectErrors  int
+	scenarios      int
+	scenarioErrors int
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		latencies:   map[string][]time.Duration{},
+		routeErrors: map[string]int{},
+		errors:      map[string]int{},
+	}
+}
+
+func (r *recorder) recordRequest(route string, latency time.Duration, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err != nil {
+		r.routeErrors[route]++
+		r.errors[fmt.Sprintf("%s: %s", route, err.Error())]++
+		return
+	}
+	r.latencies[route] = append(r.latencies[route], latency)
+}
+
+func (r *recorder) recordConnect(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.connectErrors++
+	r.errors[fmt.Sprintf("connect: %s", err.Error())]++
+}
+
+func (r *recorder) recordScenario(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.scenarios++
+	if err != nil {
+		r.scenarioErrors++
+		r.errors[fmt.Sprintf("scenario: %s", err.Error())]++
+	}
+}
+
+func (r *recorder) report(players int, duration time.Duration) *Report {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	report := &Report{
+		Duration:       duration,
+		Players:        players,
+		ConnectErrors:  r.connectErrors,
+		Scenarios:      r.scenarios,
+		ScenarioErrors: r.scenarioErrors,
+		Routes:         []*RouteReport{},
+		Errors:         map[string]int{},
+	}
+	for msg, count := range r.errors {
+		report.Errors[msg] = count
+	}
+
+	routes := map[string]bool{}
+	for route := range r.latencies {
+		routes[route] = true
+	}
+	for route := range r.routeErrors {
+		routes[route] = true
+	}
+	for route := range routes {
+		routeReport := newRouteReport(route, r.latencies[route], r.routeErrors[route], duration)
+		report.Requests += routeReport.Requests
+		report.RequestErrors += routeReport.Errors
+		report.Routes = append(report.Routes, routeReport)
+	}
+	sort.Slice(report.Routes, func(i, j int) bool {
+		return report.Routes[i].Route < report.Routes[j].Route
+	})
+	if duration > 0 {
+		report.Throughput = float64(report.Requests) / duration.Seconds()
+	}
+	return report
+}
+
+func newRouteReport(route string, latencies []time.Duration, errors int, duration time.Duration) *RouteReport {
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	report := &RouteReport{
+		Route:    route,
+		Requests: len(sorted) + errors,
+		Errors:   errors,
+		P50:      milliseconds(percentile(sorted, 50)),
+		P90:      milliseconds(percentile(sorted, 90)),
+		P95:      milliseconds(percentile(sorted, 95)),
+		P99:      milliseconds(percentile(sorted, 99)),
+	}
+	if len(sorted) > 0 {
+		var total time.Duration
+		for _, latency := range sorted {
+			total += latency
+		}
+		report.Mean = milliseconds(total / time.Duration(len(sorted)))
+		report.Max = milliseconds(sorted[len(sorted)-1])
+	}
+	if duration > 0 {
+		report.Throughput = float64(report.Requests) / duration.Seconds()
+	}
+	return report
+}
+
+// percentile returns the nearest-rank percentile p of the sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
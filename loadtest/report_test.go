@@ -0,0 +1,107 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package loadtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	assert.Equal(t, time.Duration(0), percentile(nil, 50))
+	assert.Equal(t, 50*time.Millisecond, percentile(sorted, 50))
+	assert.Equal(t, 99*time.Millisecond, percentile(sorted, 99))
+	assert.Equal(t, 100*time.Millisecond, percentile(sorted, 100))
+	assert.Equal(t, 3*time.Millisecond, percentile(sorted[:3], 99))
+}
+
+func TestRecorderReport(t *testing.T) {
+	t.Parallel()
+	r := newRecorder()
+	for _, latency := range []time.Duration{4, 1, 3, 2} {
+		r.recordRequest("room.room.join", latency*time.Millisecond, nil)
+	}
+	r.recordRequest("room.room.join", 0, errors.New("request timeout"))
+	r.recordRequest("room.room.leave", time.Millisecond, nil)
+	r.recordConnect(errors.New("connection refused"))
+	r.recordScenario(nil)
+	r.recordScenario(errors.New("failed"))
+
+	report := r.report(2, 2*time.Second)
+	assert.Equal(t, 2, report.Players)
+	assert.Equal(t, 1, report.ConnectErrors)
+	assert.Equal(t, 2, report.Scenarios)
+	assert.Equal(t, 1, report.ScenarioErrors)
+	assert.Equal(t, 6, report.Requests)
+	assert.Equal(t, 1, report.RequestErrors)
+	assert.Equal(t, 3.0, report.Throughput)
+	assert.Equal(t, map[string]int{
+		"room.room.join: request timeout": 1,
+		"connect: connection refused":     1,
+		"scenario: failed":                1,
+	}, report.Errors)
+
+	assert.Len(t, report.Routes, 2)
+	assert.Equal(t, &RouteReport{
+		Route:      "room.room.join",
+		Requests:   5,
+		Errors:     1,
+		Throughput: 2.5,
+		Mean:       2.5,
+		P50:        2,
+		P90:        4,
+		P95:        4,
+		P99:        4,
+		Max:        4,
+	}, report.Routes[0])
+	assert.Equal(t, "room.room.leave", report.Routes[1].Route)
+}
+
+func TestReportWrite(t *testing.T) {
+	t.Parallel()
+	r := newRecorder()
+	r.recordRequest("room.room.join", time.Millisecond, nil)
+	r.recordRequest("room.room.join", 0, errors.New("request timeout"))
+	report := r.report(1, time.Second)
+
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, report.WriteText(buf))
+	assert.Contains(t, buf.String(), "requests: 2, errors: 1, throughput: 2.00 req/s")
+	assert.Contains(t, buf.String(), "room.room.join")
+	assert.Contains(t, buf.String(), "1 x room.room.join: request timeout")
+
+	buf.Reset()
+	assert.NoError(t, report.WriteJSON(buf))
+	decoded := &Report{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), decoded))
+	assert.Equal(t, report, decoded)
+}
@@ -0,0 +1,205 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"github.com/hnlxhzw/pitaya/session"
+)
+
+const defaultPushTimeout = 5 * time.Second
+
+// YAMLScenario is a scenario defined by a list of steps in yaml, e.g.
+//
+//	handshake:
+//	  user:
+//	    name: "bot-{{.ID}}"
+//	steps:
+//	  - request: room.room.join
+//	    data: {name: "bot-{{.ID}}"}
+//	  - think: 500ms
+//	  - notify: room.room.message
+//	    data: {content: hello}
+//	  - push: room.onMessage
+//	    timeout: 2s
+//
+// Data and handshake strings are templates executed with the player ID. The
+// scenario stops at the first step that fails
+type YAMLScenario struct {
+	handshake *template.Template
+	steps     []*yamlStep
+}
+
+type yamlScenarioFile struct {
+	Handshake interface{} `yaml:"handshake"`
+	Steps     []*yamlStep `yaml:"steps"`
+}
+
+type yamlStep struct {
+	Request string      `yaml:"request"`
+	Notify  string      `yaml:"notify"`
+	Push    string      `yaml:"push"`
+	Think   string      `yaml:"think"`
+	Timeout string      `yaml:"timeout"`
+	Data    interface{} `yaml:"data"`
+
+	data    *template.Template
+	think   time.Duration
+	timeout time.Duration
+}
+
+type templateData struct {
+	ID int
+}
+
+// LoadYAMLScenario loads a scenario from a yaml file
+func LoadYAMLScenario(path string) (*YAMLScenario, error) {
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseYAMLScenario(bts)
+}
+
+// ParseYAMLScenario parses a scenario in yaml
+func ParseYAMLScenario(bts []byte) (*YAMLScenario, error) {
+	file := &yamlScenarioFile{}
+	if err := yaml.Unmarshal(bts, file); err != nil {
+		return nil, err
+	}
+	if len(file.Steps) == 0 {
+		return nil, fmt.Errorf("scenario has no steps")
+	}
+
+	scenario := &YAMLScenario{steps: file.Steps}
+	if file.Handshake != nil {
+		tmpl, err := jsonTemplate("handshake", file.Handshake)
+		if err != nil {
+			return nil, err
+		}
+		scenario.handshake = tmpl
+	}
+	for i, step := range file.Steps {
+		if err := step.parse(); err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return scenario, nil
+}
+
+func (s *yamlStep) parse() error {
+	kinds := 0
+	for _, field := range []string{s.Request, s.Notify, s.Push, s.Think} {
+		if field != "" {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("a step must have exactly one of request, notify, push or think")
+	}
+
+	var err error
+	if s.Think != "" {
+		if s.think, err = time.ParseDuration(s.Think); err != nil {
+			return err
+		}
+	}
+	s.timeout = defaultPushTimeout
+	if s.Timeout != "" {
+		if s.timeout, err = time.ParseDuration(s.Timeout); err != nil {
+			return err
+		}
+	}
+	if s.Data == nil {
+		s.Data = map[string]interface{}{}
+	}
+	s.data, err = jsonTemplate("data", s.Data)
+	return err
+}
+
+// jsonTemplate encodes v as json and parses it as a template
+func jsonTemplate(name string, v interface{}) (*template.Template, error) {
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(name).Parse(string(bts))
+}
+
+func execute(tmpl *template.Template, id int) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := tmpl.Execute(buf, &templateData{ID: id}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// HandshakeData returns the handshake data of the player, it can be used as
+// the HandshakeData of the load test config. It returns nil if the scenario
+// has no handshake or it could not be encoded
+func (s *YAMLScenario) HandshakeData(playerID int) *session.HandshakeData {
+	if s.handshake == nil {
+		return nil
+	}
+	bts, err := execute(s.handshake, playerID)
+	if err != nil {
+		return nil
+	}
+	data := &session.HandshakeData{}
+	if err := json.Unmarshal(bts, data); err != nil {
+		return nil
+	}
+	return data
+}
+
+// Run runs the steps of the scenario
+func (s *YAMLScenario) Run(ctx context.Context, p *Player) error {
+	for _, step := range s.steps {
+		data, err := execute(step.data, p.ID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case step.Request != "":
+			var resp []byte
+			err = p.Request(ctx, step.Request, data, &resp)
+		case step.Notify != "":
+			err = p.Notify(step.Notify, data)
+		case step.Push != "":
+			_, err = p.WaitPush(ctx, step.Push, step.timeout)
+		case step.Think != "":
+			err = p.Think(ctx, step.think)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
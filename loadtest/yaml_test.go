@@ -0,0 +1,82 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package loadtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseYAMLScenario(t *testing.T) {
+	t.Parallel()
+	scenario, err := ParseYAMLScenario([]byte(`
+handshake:
+  sys:
+    platform: bot
+  user:
+    token: "token-{{.ID}}"
+steps:
+  - request: room.room.join
+    data: {name: "bot-{{.ID}}", level: 3}
+  - think: 250ms
+  - push: room.onMessage
+  - push: room.onLeave
+    timeout: 1s
+`))
+	assert.NoError(t, err)
+	assert.Len(t, scenario.steps, 4)
+
+	data, err := execute(scenario.steps[0].data, 7)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"bot-7","level":3}`, string(data))
+	assert.Equal(t, 250*time.Millisecond, scenario.steps[1].think)
+	assert.Equal(t, defaultPushTimeout, scenario.steps[2].timeout)
+	assert.Equal(t, time.Second, scenario.steps[3].timeout)
+
+	handshake := scenario.HandshakeData(7)
+	assert.Equal(t, "bot", handshake.Sys.Platform)
+	assert.Equal(t, map[string]interface{}{"token": "token-7"}, handshake.User)
+}
+
+func TestParseYAMLScenarioErrors(t *testing.T) {
+	t.Parallel()
+	tables := []struct {
+		name string
+		yaml string
+	}{
+		{"invalid_yaml", "steps: ["},
+		{"no_steps", "steps: []"},
+		{"empty_step", "steps: [{data: {}}]"},
+		{"many_kinds", "steps: [{request: a.b.c, think: 1s}]"},
+		{"invalid_think", "steps: [{think: soon}]"},
+		{"invalid_timeout", "steps: [{push: a.b, timeout: never}]"},
+		{"invalid_template", `steps: [{request: a.b.c, data: {name: "{{.ID"}}]`},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			_, err := ParseYAMLScenario([]byte(table.yaml))
+			assert.Error(t, err)
+		})
+	}
+}
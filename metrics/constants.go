@@ -3,6 +3,9 @@ package metrics
 var (
 	// ResponseTime reports the response time of handlers and rpc
 	ResponseTime = "response_time_ns"
+	// ResponseTimeHistogram reports the response time of handlers and rpc
+	// as a histogram, which can be aggregated across servers
+	ResponseTimeHistogram = "response_time_histogram_ns"
 	// ConnectedClients represents the number of current connected clients in frontend servers
 	ConnectedClients = "connected_clients"
 	// CountServers counts the number of servers of different types
@@ -13,6 +16,8 @@ var (
 	DroppedMessages = "dropped_messages"
 	// ProcessDelay reports the message processing delay to handle the messages at the handler service
 	ProcessDelay = "handler_delay_ns"
+	// ProcessDelayHistogram reports the message processing delay as a histogram
+	ProcessDelayHistogram = "handler_delay_histogram_ns"
 	// Goroutines reports the number of goroutines
 	Goroutines = "goroutines"
 	// HeapSize reports the size of heap
@@ -25,7 +30,35 @@ var (
 	WorkerJobsRetry = "worker_jobs_retry_total"
 	// WorkerQueueSize reports the queue size on worker
 	WorkerQueueSize = "worker_queue_size"
+	// WorkerScheduledJobs reports the number of jobs waiting to run at a
+	// later time on worker
+	WorkerScheduledJobs = "worker_scheduled_jobs"
 	// ExceededRateLimiting reports the number of requests made in a connection
 	// after the rate limit was exceeded
 	ExceededRateLimiting = "exceeded_rate_limiting"
+	// DispatchQueueDepth reports the number of messages waiting in a
+	// dispatch channel of the handler service
+	DispatchQueueDepth = "dispatch_queue_depth"
+	// DispatchWaitTime reports the time a message waited for a dispatch slot
+	DispatchWaitTime = "dispatch_wait_ns"
+	// AgentSendBlocked reports the number of messages that blocked waiting
+	// for the agent send channel to have capacity
+	AgentSendBlocked = "agent_send_blocked_total"
+	// AgentSendDropped reports the number of messages dropped by the agent
+	// because it was closed before sending them
+	AgentSendDropped = "agent_send_dropped_total"
+	// RemoteServiceConcurrency reports the number of rpcs being processed
+	// or waiting for a response in the remote service
+	RemoteServiceConcurrency = "remote_service_concurrency"
+	// TimerQueueDepth reports the number of timers waiting to be created or
+	// closed by the dispatch goroutines
+	TimerQueueDepth = "timer_queue_depth"
+	// SLORequests reports the number of requests to routes with SLOs
+	SLORequests = "slo_requests_total"
+	// SLOViolations reports the number of requests that violated a route SLO
+	SLOViolations = "slo_violations_total"
+	// SLOErrorBudgetBurn reports the error budget spent by requests that
+	// violated a route SLO, its rate divided by the SLORequests rate is the
+	// burn rate of the SLO
+	SLOErrorBudgetBurn = "slo_error_budget_burn_total"
 )
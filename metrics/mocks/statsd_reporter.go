@@ -68,3 +68,15 @@ func (m *MockClient) TimeInMilliseconds(name string, value float64, tags []strin
 func (mr *MockClientMockRecorder) TimeInMilliseconds(name, value, tags, rate interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TimeInMilliseconds", reflect.TypeOf((*MockClient)(nil).TimeInMilliseconds), name, value, tags, rate)
 }
+
+// Histogram mocks base method
+func (m *MockClient) Histogram(name string, value float64, tags []string, rate float64) error {
+	ret := m.ctrl.Call(m, "Histogram", name, value, tags, rate)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Histogram indicates an expected call of Histogram
+func (mr *MockClientMockRecorder) Histogram(name, value, tags, rate interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Histogram", reflect.TypeOf((*MockClient)(nil).Histogram), name, value, tags, rate)
+}
@@ -1,7 +1,10 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/hnlxhzw/pitaya/config"
+	"github.com/hnlxhzw/pitaya/constants"
 )
 
 // Summary defines a summary metric
@@ -13,6 +16,15 @@ type Summary struct {
 	Labels     []string
 }
 
+// Histogram defines a histogram metric
+type Histogram struct {
+	Subsystem string
+	Name      string
+	Help      string
+	Buckets   []float64
+	Labels    []string
+}
+
 // Gauge defines a gauge metric
 type Gauge struct {
 	Subsystem string
@@ -31,9 +43,53 @@ type Counter struct {
 
 // CustomMetricsSpec has all metrics specs
 type CustomMetricsSpec struct {
-	Summaries []*Summary
-	Gauges    []*Gauge
-	Counters  []*Counter
+	Summaries  []*Summary
+	Histograms []*Histogram
+	Gauges     []*Gauge
+	Counters   []*Counter
+}
+
+// LatencyHistogramsSpec defines the response time and process delay
+// histograms, the buckets of a route override the default buckets
+type LatencyHistogramsSpec struct {
+	Enabled bool
+	Buckets []time.Duration
+	Routes  []*RouteBuckets
+}
+
+// RouteBuckets defines the latency histograms buckets of a route
+type RouteBuckets struct {
+	Route   string
+	Buckets []time.Duration
+}
+
+// SLO defines the latency and error rate objectives of a route
+type SLO struct {
+	Route string
+	// LatencyThreshold is the response time a request should not exceed
+	LatencyThreshold time.Duration
+	// LatencyObjective is the ratio of requests, e.g. 0.99, that must not
+	// exceed the LatencyThreshold
+	LatencyObjective float64
+	// ErrorRate is the maximum ratio of failed requests, e.g. 0.001
+	ErrorRate float64
+}
+
+func (s *SLO) hasLatencyObjective() bool {
+	return s.LatencyThreshold > 0 && s.LatencyObjective > 0
+}
+
+func (s *SLO) hasErrorRateObjective() bool {
+	return s.ErrorRate > 0
+}
+
+func (s *SLO) validate() error {
+	if s.Route == "" ||
+		s.LatencyObjective < 0 || s.LatencyObjective >= 1 ||
+		s.ErrorRate < 0 || s.ErrorRate > 1 {
+		return constants.ErrInvalidSLO
+	}
+	return nil
 }
 
 // NewCustomMetricsSpec returns a *CustomMetricsSpec by reading config key
@@ -47,3 +103,40 @@ func NewCustomMetricsSpec(config *config.Config) (*CustomMetricsSpec, error) {
 
 	return &spec, nil
 }
+
+// NewLatencyHistogramsSpec returns a *LatencyHistogramsSpec by reading config key
+func NewLatencyHistogramsSpec(config *config.Config) (*LatencyHistogramsSpec, error) {
+	spec := LatencyHistogramsSpec{
+		Enabled: config.GetBool("pitaya.metrics.histograms.enabled"),
+	}
+
+	err := config.UnmarshalKey("pitaya.metrics.histograms.buckets", &spec.Buckets)
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.UnmarshalKey("pitaya.metrics.histograms.routes", &spec.Routes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
+// NewSLOs returns the route SLOs by reading config key
+func NewSLOs(config *config.Config) ([]*SLO, error) {
+	var slos []*SLO
+
+	err := config.UnmarshalKey("pitaya.metrics.slos", &slos)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, slo := range slos {
+		if err := slo.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return slos, nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/config"
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+func TestNewLatencyHistogramsSpec(t *testing.T) {
+	cfg := viper.New()
+	cfg.Set("pitaya.metrics.histograms.routes", []map[string]interface{}{
+		{"route": "room.room.join", "buckets": []string{"10ms", "1s"}},
+	})
+
+	spec, err := NewLatencyHistogramsSpec(config.NewConfig(cfg))
+	assert.NoError(t, err)
+	assert.False(t, spec.Enabled)
+	assert.Len(t, spec.Buckets, 12)
+	assert.Equal(t, time.Millisecond, spec.Buckets[0])
+	assert.Equal(t, []*RouteBuckets{
+		{Route: "room.room.join", Buckets: []time.Duration{10 * time.Millisecond, time.Second}},
+	}, spec.Routes)
+}
+
+func TestNewSLOs(t *testing.T) {
+	tables := []struct {
+		name         string
+		slo          map[string]interface{}
+		expectedSLOs []*SLO
+		expectedErr  error
+	}{
+		{
+			"success",
+			map[string]interface{}{
+				"route":            "room.room.join",
+				"latencyThreshold": "50ms",
+				"latencyObjective": 0.99,
+				"errorRate":        0.001,
+			},
+			[]*SLO{{
+				Route:            "room.room.join",
+				LatencyThreshold: 50 * time.Millisecond,
+				LatencyObjective: 0.99,
+				ErrorRate:        0.001,
+			}},
+			nil,
+		},
+		{"no_route", map[string]interface{}{"errorRate": 0.001}, nil, constants.ErrInvalidSLO},
+		{"invalid_latency_objective", map[string]interface{}{"route": "room.room.join", "latencyObjective": 1}, nil, constants.ErrInvalidSLO},
+		{"invalid_error_rate", map[string]interface{}{"route": "room.room.join", "errorRate": 2}, nil, constants.ErrInvalidSLO},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			cfg := viper.New()
+			cfg.Set("pitaya.metrics.slos", []map[string]interface{}{table.slo})
+
+			slos, err := NewSLOs(config.NewConfig(cfg))
+			assert.Equal(t, table.expectedErr, err)
+			assert.Equal(t, table.expectedSLOs, slos)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package metrics
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hnlxhzw/pitaya/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const otelMetricsPrefix = "pitaya."
+
+type otelGaugeValue struct {
+	attrs attribute.Set
+	value float64
+}
+
+// otelGauge keeps the last value reported with each set of attributes, which
+// is observed by an OpenTelemetry asynchronous gauge when metrics are collected
+type otelGauge struct {
+	mutex  sync.Mutex
+	values map[attribute.Distinct]otelGaugeValue
+}
+
+func (g *otelGauge) record(value float64, attrs attribute.Set) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.values[attrs.Equivalent()] = otelGaugeValue{attrs: attrs, value: value}
+}
+
+func (g *otelGauge) observe(ctx context.Context, observer metric.Float64Observer) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	for _, v := range g.values {
+		observer.Observe(v.value, metric.WithAttributeSet(v.attrs))
+	}
+	return nil
+}
+
+// OTelReporter exports application metrics as OpenTelemetry metrics, using
+// the global meter provider unless another one is given
+type OTelReporter struct {
+	meter       metric.Meter
+	defaultTags []attribute.KeyValue
+
+	mutex      sync.RWMutex
+	counters   map[string]metric.Float64Counter
+	gauges     map[string]*otelGauge
+	histograms map[string]metric.Float64Histogram
+}
+
+// NewOTelReporter returns an instance of the OpenTelemetry reporter
+func NewOTelReporter(
+	serverType string,
+	tagsMap map[string]string,
+	providerOrNil ...metric.MeterProvider,
+) *OTelReporter {
+	var provider metric.MeterProvider
+	if len(providerOrNil) > 0 {
+		provider = providerOrNil[0]
+	} else {
+		provider = otel.GetMeterProvider()
+	}
+
+	defaultTags := make([]attribute.KeyValue, 0, len(tagsMap)+1)
+	defaultTags = append(defaultTags, attribute.String("serverType", serverType))
+	for k, v := range tagsMap {
+		defaultTags = append(defaultTags, attribute.String(k, v))
+	}
+
+	return &OTelReporter{
+		meter:       provider.Meter("github.com/hnlxhzw/pitaya"),
+		defaultTags: defaultTags,
+		counters:    map[string]metric.Float64Counter{},
+		gauges:      map[string]*otelGauge{},
+		histograms:  map[string]metric.Float64Histogram{},
+	}
+}
+
+func (o *OTelReporter) attributes(tagsMap map[string]string) metric.MeasurementOption {
+	return metric.WithAttributeSet(o.attributeSet(tagsMap))
+}
+
+func (o *OTelReporter) attributeSet(tagsMap map[string]string) attribute.Set {
+	attrs := make([]attribute.KeyValue, 0, len(o.defaultTags)+len(tagsMap))
+	attrs = append(attrs, o.defaultTags...)
+	for k, v := range tagsMap {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	return attribute.NewSet(attrs...)
+}
+
+func (o *OTelReporter) counter(name string) (metric.Float64Counter, error) {
+	o.mutex.RLock()
+	counter, ok := o.counters[name]
+	o.mutex.RUnlock()
+	if ok {
+		return counter, nil
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if counter, ok := o.counters[name]; ok {
+		return counter, nil
+	}
+	counter, err := o.meter.Float64Counter(otelMetricsPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	o.counters[name] = counter
+	return counter, nil
+}
+
+func (o *OTelReporter) gauge(name string) (*otelGauge, error) {
+	o.mutex.RLock()
+	gauge, ok := o.gauges[name]
+	o.mutex.RUnlock()
+	if ok {
+		return gauge, nil
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if gauge, ok := o.gauges[name]; ok {
+		return gauge, nil
+	}
+	gauge = &otelGauge{values: map[attribute.Distinct]otelGaugeValue{}}
+	_, err := o.meter.Float64ObservableGauge(otelMetricsPrefix+name, metric.WithFloat64Callback(gauge.observe))
+	if err != nil {
+		return nil, err
+	}
+	o.gauges[name] = gauge
+	return gauge, nil
+}
+
+func (o *OTelReporter) histogram(name string) (metric.Float64Histogram, error) {
+	o.mutex.RLock()
+	histogram, ok := o.histograms[name]
+	o.mutex.RUnlock()
+	if ok {
+		return histogram, nil
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if histogram, ok := o.histograms[name]; ok {
+		return histogram, nil
+	}
+	histogram, err := o.meter.Float64Histogram(otelMetricsPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	o.histograms[name] = histogram
+	return histogram, nil
+}
+
+// ReportCount adds the count to an OpenTelemetry counter
+func (o *OTelReporter) ReportCount(metric string, tagsMap map[string]string, count float64) error {
+	counter, err := o.counter(metric)
+	if err != nil {
+		logger.Log.Errorf("failed to report count: %q", err)
+		return err
+	}
+	counter.Add(context.Background(), count, o.attributes(tagsMap))
+	return nil
+}
+
+// ReportGauge records the value of an OpenTelemetry gauge
+func (o *OTelReporter) ReportGauge(metric string, tagsMap map[string]string, value float64) error {
+	gauge, err := o.gauge(metric)
+	if err != nil {
+		logger.Log.Errorf("failed to report gauge: %q", err)
+		return err
+	}
+	gauge.record(value, o.attributeSet(tagsMap))
+	return nil
+}
+
+// ReportSummary records the value in an OpenTelemetry histogram, as there
+// are no summaries in OpenTelemetry
+func (o *OTelReporter) ReportSummary(metric string, tagsMap map[string]string, value float64) error {
+	return o.ReportHistogram(metric, tagsMap, value)
+}
+
+// ReportHistogram records the value in an OpenTelemetry histogram
+func (o *OTelReporter) ReportHistogram(metric string, tagsMap map[string]string, value float64) error {
+	histogram, err := o.histogram(metric)
+	if err != nil {
+		logger.Log.Errorf("failed to report histogram: %q", err)
+		return err
+	}
+	histogram.Record(context.Background(), value, o.attributes(tagsMap))
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func newTestOTelReporter(t *testing.T) (*OTelReporter, *sdkmetric.ManualReader) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return NewOTelReporter("svType", map[string]string{"defaultTag": "value"}, provider), reader
+}
+
+func collectOTelMetrics(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Metrics {
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+	collected := map[string]metricdata.Metrics{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			collected[m.Name] = m
+		}
+	}
+	return collected
+}
+
+func expectedOTelAttributes(route string) attribute.Set {
+	return attribute.NewSet(
+		attribute.String("serverType", "svType"),
+		attribute.String("defaultTag", "value"),
+		attribute.String("route", route),
+	)
+}
+
+func TestOTelReporterReportCount(t *testing.T) {
+	reporter, reader := newTestOTelReporter(t)
+
+	assert.NoError(t, reporter.ReportCount(SLORequests, map[string]string{"route": "a.b.c"}, 1))
+	assert.NoError(t, reporter.ReportCount(SLORequests, map[string]string{"route": "a.b.c"}, 2))
+
+	collected := collectOTelMetrics(t, reader)
+	sum, ok := collected["pitaya."+SLORequests].Data.(metricdata.Sum[float64])
+	assert.True(t, ok)
+	assert.True(t, sum.IsMonotonic)
+	assert.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, float64(3), sum.DataPoints[0].Value)
+	assert.Equal(t, expectedOTelAttributes("a.b.c"), sum.DataPoints[0].Attributes)
+}
+
+func TestOTelReporterReportGauge(t *testing.T) {
+	reporter, reader := newTestOTelReporter(t)
+
+	assert.NoError(t, reporter.ReportGauge(ConnectedClients, map[string]string{"route": "a.b.c"}, 5))
+	assert.NoError(t, reporter.ReportGauge(ConnectedClients, map[string]string{"route": "a.b.c"}, 3))
+
+	collected := collectOTelMetrics(t, reader)
+	gauge, ok := collected["pitaya."+ConnectedClients].Data.(metricdata.Gauge[float64])
+	assert.True(t, ok)
+	assert.Len(t, gauge.DataPoints, 1)
+	assert.Equal(t, float64(3), gauge.DataPoints[0].Value)
+	assert.Equal(t, expectedOTelAttributes("a.b.c"), gauge.DataPoints[0].Attributes)
+}
+
+func TestOTelReporterReportSummaryAndHistogram(t *testing.T) {
+	reporter, reader := newTestOTelReporter(t)
+
+	assert.NoError(t, reporter.ReportSummary(ResponseTime, map[string]string{"route": "a.b.c"}, 10))
+	assert.NoError(t, reporter.ReportHistogram(ResponseTimeHistogram, map[string]string{"route": "a.b.c"}, 20))
+	assert.NoError(t, reporter.ReportHistogram(ResponseTimeHistogram, map[string]string{"route": "a.b.c"}, 30))
+
+	collected := collectOTelMetrics(t, reader)
+	summary, ok := collected["pitaya."+ResponseTime].Data.(metricdata.Histogram[float64])
+	assert.True(t, ok)
+	assert.Len(t, summary.DataPoints, 1)
+	assert.Equal(t, uint64(1), summary.DataPoints[0].Count)
+	assert.Equal(t, float64(10), summary.DataPoints[0].Sum)
+
+	histogram, ok := collected["pitaya."+ResponseTimeHistogram].Data.(metricdata.Histogram[float64])
+	assert.True(t, ok)
+	assert.Len(t, histogram.DataPoints, 1)
+	assert.Equal(t, uint64(2), histogram.DataPoints[0].Count)
+	assert.Equal(t, float64(50), histogram.DataPoints[0].Sum)
+	assert.Equal(t, expectedOTelAttributes("a.b.c"), histogram.DataPoints[0].Attributes)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// routeHistogramVec is a histogram vec that observes the routes with their
+// own buckets in separate vecs, all of them are collected as the same metric
+type routeHistogramVec struct {
+	*prometheus.HistogramVec
+	routes map[string]*prometheus.HistogramVec
+}
+
+func newRouteHistogramVec(
+	opts prometheus.HistogramOpts,
+	labelNames []string,
+	routes []*RouteBuckets,
+) *routeHistogramVec {
+	vec := &routeHistogramVec{
+		HistogramVec: prometheus.NewHistogramVec(opts, labelNames),
+		routes:       make(map[string]*prometheus.HistogramVec, len(routes)),
+	}
+
+	for _, route := range routes {
+		routeOpts := opts
+		routeOpts.Buckets = durationsToBuckets(route.Buckets)
+		vec.routes[route.Route] = prometheus.NewHistogramVec(routeOpts, labelNames)
+	}
+
+	return vec
+}
+
+// Collect implements prometheus.Collector
+func (v *routeHistogramVec) Collect(ch chan<- prometheus.Metric) {
+	v.HistogramVec.Collect(ch)
+	for _, vec := range v.routes {
+		vec.Collect(ch)
+	}
+}
+
+func (v *routeHistogramVec) with(labels prometheus.Labels) prometheus.Histogram {
+	if vec, ok := v.routes[labels["route"]]; ok {
+		return vec.With(labels)
+	}
+	return v.HistogramVec.With(labels)
+}
+
+// durationsToBuckets converts durations to buckets in nanoseconds
+func durationsToBuckets(durations []time.Duration) []float64 {
+	if len(durations) == 0 {
+		return nil
+	}
+
+	buckets := make([]float64, len(durations))
+	for i, d := range durations {
+		buckets[i] = float64(d.Nanoseconds())
+	}
+	return buckets
+}
@@ -0,0 +1,69 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteHistogramVec(t *testing.T) {
+	vec := newRouteHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "test_histogram",
+			Help:    "test histogram",
+			Buckets: durationsToBuckets([]time.Duration{time.Millisecond, time.Second}),
+		},
+		[]string{"route"},
+		[]*RouteBuckets{{Route: "room.room.join", Buckets: []time.Duration{time.Minute}}},
+	)
+
+	join := prometheus.Labels{"route": "room.room.join"}
+	leave := prometheus.Labels{"route": "room.room.leave"}
+	vec.with(join).Observe(1)
+	vec.with(leave).Observe(1)
+
+	assert.Equal(t, vec.routes["room.room.join"].With(join), vec.with(join))
+	assert.Equal(t, vec.HistogramVec.With(leave), vec.with(leave))
+
+	ch := make(chan prometheus.Metric, 10)
+	vec.Collect(ch)
+	close(ch)
+	assert.Len(t, ch, 2)
+
+	registry := prometheus.NewRegistry()
+	assert.NoError(t, registry.Register(vec))
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	assert.Len(t, families, 1)
+	assert.Len(t, families[0].GetMetric(), 2)
+}
+
+func TestDurationsToBuckets(t *testing.T) {
+	assert.Nil(t, durationsToBuckets(nil))
+	assert.Equal(t,
+		[]float64{1e6, 1e9},
+		durationsToBuckets([]time.Duration{time.Millisecond, time.Second}),
+	)
+}
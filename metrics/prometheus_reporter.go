@@ -39,12 +39,13 @@ var (
 
 // PrometheusReporter reports metrics to prometheus
 type PrometheusReporter struct {
-	serverType          string
-	game                string
-	countReportersMap   map[string]*prometheus.CounterVec
-	summaryReportersMap map[string]*prometheus.SummaryVec
-	gaugeReportersMap   map[string]*prometheus.GaugeVec
-	additionalLabels    map[string]string
+	serverType            string
+	game                  string
+	countReportersMap     map[string]*prometheus.CounterVec
+	summaryReportersMap   map[string]*prometheus.SummaryVec
+	histogramReportersMap map[string]*routeHistogramVec
+	gaugeReportersMap     map[string]*prometheus.GaugeVec
+	additionalLabels      map[string]string
 }
 
 func (p *PrometheusReporter) registerCustomMetrics(
@@ -66,6 +67,21 @@ func (p *PrometheusReporter) registerCustomMetrics(
 		)
 	}
 
+	for _, histogram := range spec.Histograms {
+		p.histogramReportersMap[histogram.Name] = newRouteHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   "pitaya",
+				Subsystem:   histogram.Subsystem,
+				Name:        histogram.Name,
+				Help:        histogram.Help,
+				Buckets:     histogram.Buckets,
+				ConstLabels: constLabels,
+			},
+			append(additionalLabelsKeys, histogram.Labels...),
+			nil,
+		)
+	}
+
 	for _, gauge := range spec.Gauges {
 		p.gaugeReportersMap[gauge.Name] = prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -93,9 +109,46 @@ func (p *PrometheusReporter) registerCustomMetrics(
 	}
 }
 
+func (p *PrometheusReporter) registerLatencyHistograms(
+	constLabels map[string]string,
+	additionalLabelsKeys []string,
+	spec *LatencyHistogramsSpec,
+) {
+	buckets := durationsToBuckets(spec.Buckets)
+
+	// HandlerResponseTimeMs histogram
+	p.histogramReportersMap[ResponseTimeHistogram] = newRouteHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "handler",
+			Name:        ResponseTimeHistogram,
+			Help:        "the time to process a msg in nanoseconds",
+			Buckets:     buckets,
+			ConstLabels: constLabels,
+		},
+		append([]string{"route", "status", "type", "code"}, additionalLabelsKeys...),
+		spec.Routes,
+	)
+
+	// ProcessDelay histogram
+	p.histogramReportersMap[ProcessDelayHistogram] = newRouteHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "handler",
+			Name:        ProcessDelayHistogram,
+			Help:        "the delay to start processing a msg in nanoseconds",
+			Buckets:     buckets,
+			ConstLabels: constLabels,
+		},
+		append([]string{"route", "type"}, additionalLabelsKeys...),
+		spec.Routes,
+	)
+}
+
 func (p *PrometheusReporter) registerMetrics(
 	constLabels, additionalLabels map[string]string,
 	spec *CustomMetricsSpec,
+	histogramsSpec *LatencyHistogramsSpec,
 ) {
 
 	constLabels["game"] = p.game
@@ -109,6 +162,79 @@ func (p *PrometheusReporter) registerMetrics(
 
 	p.registerCustomMetrics(constLabels, additionalLabelsKeys, spec)
 
+	if histogramsSpec.Enabled {
+		p.registerLatencyHistograms(constLabels, additionalLabelsKeys, histogramsSpec)
+	}
+
+	// DispatchWaitTime histogram
+	p.histogramReportersMap[DispatchWaitTime] = newRouteHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "handler",
+			Name:        DispatchWaitTime,
+			Help:        "the time a msg waited for a dispatch slot in nanoseconds",
+			Buckets:     durationsToBuckets(histogramsSpec.Buckets),
+			ConstLabels: constLabels,
+		},
+		append([]string{"dispatch", "queue"}, additionalLabelsKeys...),
+		nil,
+	)
+
+	p.gaugeReportersMap[DispatchQueueDepth] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "handler",
+			Name:        DispatchQueueDepth,
+			Help:        "the number of msgs waiting in a dispatch channel",
+			ConstLabels: constLabels,
+		},
+		append([]string{"dispatch", "queue"}, additionalLabelsKeys...),
+	)
+
+	p.countReportersMap[AgentSendBlocked] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "agent",
+			Name:        AgentSendBlocked,
+			Help:        "the number of msgs that blocked waiting for the agent send channel",
+			ConstLabels: constLabels,
+		},
+		append([]string{"dispatch"}, additionalLabelsKeys...),
+	)
+
+	p.countReportersMap[AgentSendDropped] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "agent",
+			Name:        AgentSendDropped,
+			Help:        "the number of msgs dropped because the agent was closed",
+			ConstLabels: constLabels,
+		},
+		append([]string{"dispatch"}, additionalLabelsKeys...),
+	)
+
+	p.gaugeReportersMap[RemoteServiceConcurrency] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "remote_service",
+			Name:        RemoteServiceConcurrency,
+			Help:        "the number of rpcs being processed or waiting for a response",
+			ConstLabels: constLabels,
+		},
+		append([]string{"direction"}, additionalLabelsKeys...),
+	)
+
+	p.gaugeReportersMap[TimerQueueDepth] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "timer",
+			Name:        TimerQueueDepth,
+			Help:        "the number of timers waiting to be created or closed",
+			ConstLabels: constLabels,
+		},
+		append([]string{"channel"}, additionalLabelsKeys...),
+	)
+
 	// HandlerResponseTimeMs summary
 	p.summaryReportersMap[ResponseTime] = prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
@@ -235,6 +361,17 @@ func (p *PrometheusReporter) registerMetrics(
 		append([]string{"queue"}, additionalLabelsKeys...),
 	)
 
+	p.gaugeReportersMap[WorkerScheduledJobs] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "worker",
+			Name:        WorkerScheduledJobs,
+			Help:        "the current number of jobs scheduled to run later",
+			ConstLabels: constLabels,
+		},
+		append([]string{"queue"}, additionalLabelsKeys...),
+	)
+
 	p.gaugeReportersMap[WorkerJobsTotal] = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace:   "pitaya",
@@ -257,6 +394,36 @@ func (p *PrometheusReporter) registerMetrics(
 		additionalLabelsKeys,
 	)
 
+	p.countReportersMap[SLORequests] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   "pitaya",
+			Name:        SLORequests,
+			Help:        "the number of requests to routes with slos",
+			ConstLabels: constLabels,
+		},
+		append([]string{"route", "slo"}, additionalLabelsKeys...),
+	)
+
+	p.countReportersMap[SLOViolations] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   "pitaya",
+			Name:        SLOViolations,
+			Help:        "the number of requests that violated a route slo",
+			ConstLabels: constLabels,
+		},
+		append([]string{"route", "slo"}, additionalLabelsKeys...),
+	)
+
+	p.countReportersMap[SLOErrorBudgetBurn] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   "pitaya",
+			Name:        SLOErrorBudgetBurn,
+			Help:        "the error budget spent by requests that violated a route slo",
+			ConstLabels: constLabels,
+		},
+		append([]string{"route", "slo"}, additionalLabelsKeys...),
+	)
+
 	toRegister := make([]prometheus.Collector, 0)
 	for _, c := range p.countReportersMap {
 		toRegister = append(toRegister, c)
@@ -270,6 +437,10 @@ func (p *PrometheusReporter) registerMetrics(
 		toRegister = append(toRegister, c)
 	}
 
+	for _, c := range p.histogramReportersMap {
+		toRegister = append(toRegister, c)
+	}
+
 	prometheus.MustRegister(toRegister...)
 }
 
@@ -290,15 +461,21 @@ func GetPrometheusReporter(
 		return nil, err
 	}
 
+	histogramsSpec, err := NewLatencyHistogramsSpec(config)
+	if err != nil {
+		return nil, err
+	}
+
 	once.Do(func() {
 		prometheusReporter = &PrometheusReporter{
-			serverType:          serverType,
-			game:                game,
-			countReportersMap:   make(map[string]*prometheus.CounterVec),
-			summaryReportersMap: make(map[string]*prometheus.SummaryVec),
-			gaugeReportersMap:   make(map[string]*prometheus.GaugeVec),
+			serverType:            serverType,
+			game:                  game,
+			countReportersMap:     make(map[string]*prometheus.CounterVec),
+			summaryReportersMap:   make(map[string]*prometheus.SummaryVec),
+			histogramReportersMap: make(map[string]*routeHistogramVec),
+			gaugeReportersMap:     make(map[string]*prometheus.GaugeVec),
 		}
-		prometheusReporter.registerMetrics(constLabels, additionalLabels, spec)
+		prometheusReporter.registerMetrics(constLabels, additionalLabels, spec, histogramsSpec)
 		http.Handle("/metrics", promhttp.Handler())
 		go (func() {
 			log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
@@ -319,6 +496,17 @@ func (p *PrometheusReporter) ReportSummary(metric string, labels map[string]stri
 	return constants.ErrMetricNotKnown
 }
 
+// ReportHistogram reports a histogram metric
+func (p *PrometheusReporter) ReportHistogram(metric string, labels map[string]string, value float64) error {
+	hist := p.histogramReportersMap[metric]
+	if hist != nil {
+		labels = p.ensureLabels(labels)
+		hist.with(labels).Observe(value)
+		return nil
+	}
+	return constants.ErrMetricNotKnown
+}
+
 // ReportCount reports a summary metric
 func (p *PrometheusReporter) ReportCount(metric string, labels map[string]string, count float64) error {
 	cnt := p.countReportersMap[metric]
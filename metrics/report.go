@@ -23,14 +23,57 @@ package metrics
 import (
 	"context"
 	"runtime"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/hnlxhzw/pitaya/config"
 	"github.com/hnlxhzw/pitaya/constants"
 	"github.com/hnlxhzw/pitaya/errors"
 
 	pcontext "github.com/hnlxhzw/pitaya/context"
 )
 
+type reportOptions struct {
+	histograms bool
+	slos       map[string]*SLO
+}
+
+var reportOpts atomic.Value
+
+// Configure sets, by reading the config, whether the latencies are also
+// reported as histograms and which route SLOs are tracked, it must be
+// called before reporting any latency
+func Configure(config *config.Config) error {
+	histograms, err := NewLatencyHistogramsSpec(config)
+	if err != nil {
+		return err
+	}
+
+	slos, err := NewSLOs(config)
+	if err != nil {
+		return err
+	}
+
+	opts := &reportOptions{
+		histograms: histograms.Enabled,
+		slos:       make(map[string]*SLO, len(slos)),
+	}
+	for _, slo := range slos {
+		opts.slos[slo.Route] = slo
+	}
+	reportOpts.Store(opts)
+
+	return nil
+}
+
+func getReportOptions() *reportOptions {
+	if opts, ok := reportOpts.Load().(*reportOptions); ok {
+		return opts
+	}
+	return &reportOptions{}
+}
+
 // ReportTimingFromCtx reports the latency from the context
 func ReportTimingFromCtx(ctx context.Context, reporters []Reporter, typ string, err error) {
 	if ctx == nil {
@@ -51,8 +94,15 @@ func ReportTimingFromCtx(ctx context.Context, reporters []Reporter, typ string,
 			"type":   typ,
 			"code":   code,
 		})
+		opts := getReportOptions()
 		for _, r := range reporters {
 			r.ReportSummary(ResponseTime, tags, float64(elapsed.Nanoseconds()))
+			if opts.histograms {
+				r.ReportHistogram(ResponseTimeHistogram, tags, float64(elapsed.Nanoseconds()))
+			}
+		}
+		if slo, ok := opts.slos[route.(string)]; ok {
+			reportSLO(ctx, reporters, slo, elapsed, err)
 		}
 	}
 }
@@ -67,12 +117,39 @@ func ReportMessageProcessDelayFromCtx(ctx context.Context, reporters []Reporter,
 			"route": route.(string),
 			"type":  typ,
 		})
+		opts := getReportOptions()
 		for _, r := range reporters {
 			r.ReportSummary(ProcessDelay, tags, float64(elapsed.Nanoseconds()))
+			if opts.histograms {
+				r.ReportHistogram(ProcessDelayHistogram, tags, float64(elapsed.Nanoseconds()))
+			}
 		}
 	}
 }
 
+// ReportDispatchWaitTime reports the time a message waited for a slot in
+// a dispatch channel, from being enqueued to being processed
+func ReportDispatchWaitTime(reporters []Reporter, dispatch int, queue string, elapsed time.Duration) {
+	tags := map[string]string{
+		"dispatch": strconv.Itoa(dispatch),
+		"queue":    queue,
+	}
+	for _, r := range reporters {
+		r.ReportHistogram(DispatchWaitTime, tags, float64(elapsed.Nanoseconds()))
+	}
+}
+
+// ReportDispatchQueueDepth reports the number of messages in a dispatch channel
+func ReportDispatchQueueDepth(reporters []Reporter, dispatch int, queue string, depth int) {
+	tags := map[string]string{
+		"dispatch": strconv.Itoa(dispatch),
+		"queue":    queue,
+	}
+	for _, r := range reporters {
+		r.ReportGauge(DispatchQueueDepth, tags, float64(depth))
+	}
+}
+
 // ReportNumberOfConnectedClients reports the number of connected clients
 func ReportNumberOfConnectedClients(reporters []Reporter, number int64) {
 	for _, r := range reporters {
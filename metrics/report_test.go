@@ -28,7 +28,9 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/config"
 	"github.com/hnlxhzw/pitaya/constants"
 	pcontext "github.com/hnlxhzw/pitaya/context"
 	e "github.com/hnlxhzw/pitaya/errors"
@@ -120,7 +122,7 @@ func TestReportTimingFromCtx(t *testing.T) {
 		expectedRoute := uuid.New().String()
 		expectedType := uuid.New().String()
 		code := "GAME-404"
-		expectedErr := e.NewError(errors.New("error"), code)
+		expectedErr := e.NewError(errors.New("error"), code, 0)
 		ctx := pcontext.AddToPropagateCtx(context.Background(), constants.StartTimeKey, originalTs)
 		ctx = pcontext.AddToPropagateCtx(ctx, constants.RouteKey, expectedRoute)
 
@@ -150,7 +152,7 @@ func TestReportTimingFromCtx(t *testing.T) {
 			"route":  expectedRoute,
 			"status": "failed",
 			"type":   expectedType,
-			"code":   e.ErrUnknownCode,
+			"code":   e.ErrUnknownCode.Desc,
 		}, gomock.Any())
 
 		ReportTimingFromCtx(ctx, []Reporter{mockMetricsReporter}, expectedType, expectedErr)
@@ -183,3 +185,121 @@ func TestReportMessageProcessDelayFromCtx(t *testing.T) {
 		ReportMessageProcessDelayFromCtx(ctx, []Reporter{mockMetricsReporter}, expectedType)
 	})
 }
+
+func TestReportMessageProcessDelayFromCtxWithHistograms(t *testing.T) {
+	cfg := viper.New()
+	cfg.Set("pitaya.metrics.histograms.enabled", true)
+	assert.NoError(t, Configure(config.NewConfig(cfg)))
+	defer Configure(config.NewConfig())
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockMetricsReporter := mocks.NewMockReporter(ctrl)
+
+	expectedRoute := uuid.New().String()
+	expectedType := uuid.New().String()
+	ctx := pcontext.AddToPropagateCtx(context.Background(), constants.StartTimeKey, time.Now().UnixNano())
+	ctx = pcontext.AddToPropagateCtx(ctx, constants.RouteKey, expectedRoute)
+
+	expectedTags := map[string]string{
+		"route": expectedRoute,
+		"type":  expectedType,
+	}
+
+	mockMetricsReporter.EXPECT().ReportSummary(ProcessDelay, expectedTags, gomock.Any())
+	mockMetricsReporter.EXPECT().ReportHistogram(ProcessDelayHistogram, expectedTags, gomock.Any())
+
+	ReportMessageProcessDelayFromCtx(ctx, []Reporter{mockMetricsReporter}, expectedType)
+}
+
+func TestReportTimingFromCtxWithHistogramsAndSLOs(t *testing.T) {
+	cfg := viper.New()
+	cfg.Set("pitaya.metrics.histograms.enabled", true)
+	cfg.Set("pitaya.metrics.slos", []map[string]interface{}{
+		{
+			"route":            "room.room.join",
+			"latencyThreshold": "100ms",
+			"latencyObjective": 0.99,
+			"errorRate":        0.5,
+		},
+	})
+	assert.NoError(t, Configure(config.NewConfig(cfg)))
+	defer Configure(config.NewConfig())
+
+	tables := []struct {
+		name               string
+		route              string
+		elapsed            time.Duration
+		err                error
+		expectedViolations []string
+	}{
+		{"route_without_slo", "room.room.leave", 200 * time.Millisecond, errors.New("error"), nil},
+		{"no_violations", "room.room.join", 0, nil, []string{}},
+		{"latency_violation", "room.room.join", 200 * time.Millisecond, nil, []string{"latency"}},
+		{"error_rate_violation", "room.room.join", 0, errors.New("error"), []string{"error_rate"}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockMetricsReporter := mocks.NewMockReporter(ctrl)
+
+			startTime := time.Now().Add(-table.elapsed).UnixNano()
+			ctx := pcontext.AddToPropagateCtx(context.Background(), constants.StartTimeKey, startTime)
+			ctx = pcontext.AddToPropagateCtx(ctx, constants.RouteKey, table.route)
+
+			mockMetricsReporter.EXPECT().ReportSummary(ResponseTime, gomock.Any(), gomock.Any())
+			mockMetricsReporter.EXPECT().ReportHistogram(ResponseTimeHistogram, gomock.Any(), gomock.Any())
+
+			if table.expectedViolations != nil {
+				for _, slo := range []string{"latency", "error_rate"} {
+					tags := map[string]string{"route": table.route, "slo": slo}
+					mockMetricsReporter.EXPECT().ReportCount(SLORequests, tags, float64(1))
+				}
+			}
+			for _, slo := range table.expectedViolations {
+				slo := slo
+				tags := map[string]string{"route": table.route, "slo": slo}
+				mockMetricsReporter.EXPECT().ReportCount(SLOViolations, tags, float64(1))
+				mockMetricsReporter.EXPECT().ReportCount(SLOErrorBudgetBurn, tags, gomock.Any()).Do(
+					func(metric string, tags map[string]string, burn float64) {
+						if slo == "latency" {
+							assert.InDelta(t, 100, burn, 1e-6)
+						} else {
+							assert.InDelta(t, 2, burn, 1e-6)
+						}
+					},
+				)
+			}
+
+			ReportTimingFromCtx(ctx, []Reporter{mockMetricsReporter}, "handler", table.err)
+		})
+	}
+}
+
+func TestReportDispatchWaitTime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockMetricsReporter := mocks.NewMockReporter(ctrl)
+
+	mockMetricsReporter.EXPECT().ReportHistogram(DispatchWaitTime, map[string]string{
+		"dispatch": "2",
+		"queue":    "local",
+	}, float64(time.Millisecond.Nanoseconds()))
+
+	ReportDispatchWaitTime([]Reporter{mockMetricsReporter}, 2, "local", time.Millisecond)
+}
+
+func TestReportDispatchQueueDepth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockMetricsReporter := mocks.NewMockReporter(ctrl)
+
+	mockMetricsReporter.EXPECT().ReportGauge(DispatchQueueDepth, map[string]string{
+		"dispatch": "1",
+		"queue":    "remote",
+	}, float64(10))
+
+	ReportDispatchQueueDepth([]Reporter{mockMetricsReporter}, 1, "remote", 10)
+}
@@ -0,0 +1,60 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package metrics
+
+import (
+	"context"
+	"time"
+)
+
+// reportSLO reports a request to a route with an SLO and, if the request
+// violated one of its objectives, the error budget it spent. A violation
+// spends 1 / (1 - objective) of the budget, so the budget burn rate is the
+// rate of SLOErrorBudgetBurn divided by the rate of SLORequests
+func reportSLO(ctx context.Context, reporters []Reporter, slo *SLO, elapsed time.Duration, err error) {
+	if slo.hasLatencyObjective() {
+		reportObjective(ctx, reporters, slo.Route, "latency",
+			elapsed > slo.LatencyThreshold, 1-slo.LatencyObjective)
+	}
+	if slo.hasErrorRateObjective() {
+		reportObjective(ctx, reporters, slo.Route, "error_rate", err != nil, slo.ErrorRate)
+	}
+}
+
+func reportObjective(
+	ctx context.Context,
+	reporters []Reporter,
+	route, objective string,
+	violated bool,
+	errorBudget float64,
+) {
+	tags := getTags(ctx, map[string]string{
+		"route": route,
+		"slo":   objective,
+	})
+	for _, r := range reporters {
+		r.ReportCount(SLORequests, tags, 1)
+		if violated {
+			r.ReportCount(SLOViolations, tags, 1)
+			r.ReportCount(SLOErrorBudgetBurn, tags, 1/errorBudget)
+		}
+	}
+}
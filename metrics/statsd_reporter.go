@@ -34,6 +34,7 @@ type Client interface {
 	Count(name string, value int64, tags []string, rate float64) error
 	Gauge(name string, value float64, tags []string, rate float64) error
 	TimeInMilliseconds(name string, value float64, tags []string, rate float64) error
+	Histogram(name string, value float64, tags []string, rate float64) error
 }
 
 // StatsdReporter sends application metrics to statsd
@@ -138,3 +139,19 @@ func (s *StatsdReporter) ReportSummary(metric string, tagsMap map[string]string,
 
 	return err
 }
+
+// ReportHistogram observes the histogram value and reports to statsd
+func (s *StatsdReporter) ReportHistogram(metric string, tagsMap map[string]string, value float64) error {
+	fullTags := s.defaultTags
+
+	for k, v := range tagsMap {
+		fullTags = append(fullTags, fmt.Sprintf("%s:%s", k, v))
+	}
+
+	err := s.client.Histogram(metric, value, fullTags, s.rate)
+	if err != nil {
+		logger.Log.Errorf("failed to report histogram: %q", err)
+	}
+
+	return err
+}
@@ -140,3 +140,43 @@ func TestReportCountError(t *testing.T) {
 	err = sr.ReportCount("123", map[string]string{}, float64(123))
 	assert.Equal(t, expectedError, err)
 }
+
+func TestReportHistogram(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := metricsmocks.NewMockClient(ctrl)
+
+	cfg := config.NewConfig()
+	sr, err := NewStatsdReporter(cfg, "svType", map[string]string{
+		"defaultTag": "value",
+	}, mockClient)
+	assert.NoError(t, err)
+
+	expectedRoute := uuid.New().String()
+	mockClient.EXPECT().Histogram(ResponseTimeHistogram, float64(123), gomock.Any(), sr.rate).Do(func(n string, v float64, tags []string, r float64) {
+		assert.Contains(t, tags, fmt.Sprintf("route:%s", expectedRoute))
+		assert.Contains(t, tags, fmt.Sprintf("serverType:%s", sr.serverType))
+		assert.Contains(t, tags, "defaultTag:value")
+	})
+
+	err = sr.ReportHistogram(ResponseTimeHistogram, map[string]string{
+		"route": expectedRoute,
+	}, float64(123))
+	assert.NoError(t, err)
+}
+
+func TestReportHistogramError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := metricsmocks.NewMockClient(ctrl)
+
+	cfg := config.NewConfig()
+	sr, err := NewStatsdReporter(cfg, "svType", map[string]string{}, mockClient)
+	assert.NoError(t, err)
+
+	expectedError := errors.New("some error")
+	mockClient.EXPECT().Histogram(gomock.Any(), gomock.Any(), gomock.Any(), sr.rate).Return(expectedError)
+
+	err = sr.ReportHistogram(ResponseTimeHistogram, map[string]string{}, float64(123))
+	assert.Equal(t, expectedError, err)
+}
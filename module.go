@@ -22,15 +22,19 @@ package pitaya
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/hnlxhzw/pitaya/interfaces"
 	"github.com/hnlxhzw/pitaya/logger"
+	mods "github.com/hnlxhzw/pitaya/modules"
 )
 
 var (
 	modulesMap         = make(map[string]interfaces.Module)
 	modulesArr         = []moduleWrapper{}
 	customerModulesArr = []moduleWrapper{} //add by shawn 用户自定义组件 加载流程 component > customerComp > module > customerModule 销毁流程 customerModule > module > component > customerComp
+	modulesStates      = make(map[string]string)
+	modulesStatesMutex sync.RWMutex
 )
 
 type moduleWrapper struct {
@@ -50,6 +54,7 @@ func RegisterModuleAfter(module interfaces.Module, name string) error {
 	}
 
 	modulesMap[name] = module
+	setModuleState(name, mods.ModuleRegistered)
 	modulesArr = append(modulesArr, moduleWrapper{
 		module: module,
 		name:   name,
@@ -65,6 +70,7 @@ func RegisterModuleBefore(module interfaces.Module, name string) error {
 	}
 
 	modulesMap[name] = module
+	setModuleState(name, mods.ModuleRegistered)
 	modulesArr = append([]moduleWrapper{
 		{
 			module: module,
@@ -82,6 +88,7 @@ func RegisterCustomerModule(module interfaces.Module, name string) error {
 	}
 
 	modulesMap[name] = module
+	setModuleState(name, mods.ModuleRegistered)
 	customerModulesArr = append(customerModulesArr, moduleWrapper{
 		module: module,
 		name:   name,
@@ -98,6 +105,41 @@ func GetModule(name string) (interfaces.Module, error) {
 	return nil, fmt.Errorf("module with name %s not found", name)
 }
 
+// ModulesStates returns the state of the registered modules, in the order
+// they are started
+func ModulesStates() []*mods.ModuleState {
+	modulesStatesMutex.RLock()
+	defer modulesStatesMutex.RUnlock()
+
+	states := make([]*mods.ModuleState, 0, len(modulesArr)+len(customerModulesArr))
+	for _, modWrapper := range modulesArr {
+		states = append(states, &mods.ModuleState{
+			Name:  modWrapper.name,
+			State: modulesStates[modWrapper.name],
+		})
+	}
+	for _, modWrapper := range customerModulesArr {
+		states = append(states, &mods.ModuleState{
+			Name:     modWrapper.name,
+			State:    modulesStates[modWrapper.name],
+			Customer: true,
+		})
+	}
+	return states
+}
+
+func moduleState(name string) string {
+	modulesStatesMutex.RLock()
+	defer modulesStatesMutex.RUnlock()
+	return modulesStates[name]
+}
+
+func setModuleState(name, state string) {
+	modulesStatesMutex.Lock()
+	defer modulesStatesMutex.Unlock()
+	modulesStates[name] = state
+}
+
 func alreadyRegistered(name string) error {
 	if _, ok := modulesMap[name]; ok {
 		return fmt.Errorf("module with name %s already exists", name)
@@ -114,10 +156,12 @@ func startModules() {
 		if err := modWrapper.module.Init(); err != nil {
 			logger.Log.Fatalf("error starting module %s, error: %s", modWrapper.name, err.Error())
 		}
+		setModuleState(modWrapper.name, mods.ModuleInitialized)
 	}
 
 	for _, modWrapper := range modulesArr {
 		modWrapper.module.AfterInit()
+		setModuleState(modWrapper.name, mods.ModuleStarted)
 		logger.Log.Infof("module: %s successfully loaded", modWrapper.name)
 	}
 }
@@ -130,10 +174,12 @@ func startCustomerModules() {
 		if err := modWrapper.module.Init(); err != nil {
 			logger.Log.Fatalf("error starting customer module %s, error: %s", modWrapper.name, err.Error())
 		}
+		setModuleState(modWrapper.name, mods.ModuleInitialized)
 	}
 
 	for _, modWrapper := range customerModulesArr {
 		modWrapper.module.AfterInit()
+		setModuleState(modWrapper.name, mods.ModuleStarted)
 		logger.Log.Infof("customer module: %s successfully loaded", modWrapper.name)
 	}
 }
@@ -141,6 +187,7 @@ func startCustomerModules() {
 // shutdownModules starts all modules in reverse order
 func shutdownModules() {
 	for i := len(modulesArr) - 1; i >= 0; i-- {
+		setModuleState(modulesArr[i].name, mods.ModuleStopping)
 		modulesArr[i].module.BeforeShutdown()
 	}
 
@@ -152,6 +199,7 @@ func shutdownModules() {
 		if err := mod.Shutdown(); err != nil {
 			logger.Log.Warnf("error stopping module: %s", name)
 		}
+		setModuleState(name, mods.ModuleStopped)
 		logger.Log.Infof("module: %s stopped!", name)
 	}
 }
@@ -159,6 +207,7 @@ func shutdownModules() {
 // shutdownCustomerModules add by shawn 关闭所有自定义的模块
 func shutdownCustomerModules() {
 	for i := len(customerModulesArr) - 1; i >= 0; i-- {
+		setModuleState(customerModulesArr[i].name, mods.ModuleStopping)
 		customerModulesArr[i].module.BeforeShutdown()
 	}
 
@@ -170,6 +219,7 @@ func shutdownCustomerModules() {
 		if err := mod.Shutdown(); err != nil {
 			logger.Log.Warnf("error stopping customer module: %s", name)
 		}
+		setModuleState(name, mods.ModuleStopped)
 		logger.Log.Infof("customer module: %s stopped!", name)
 	}
 }
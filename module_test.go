@@ -27,6 +27,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/hnlxhzw/pitaya/component"
 	"github.com/hnlxhzw/pitaya/interfaces"
+	mods "github.com/hnlxhzw/pitaya/modules"
 )
 
 type MyMod struct {
@@ -40,6 +41,8 @@ var modulesOrder []string
 func resetModules() {
 	modulesMap = make(map[string]interfaces.Module)
 	modulesArr = []moduleWrapper{}
+	customerModulesArr = []moduleWrapper{}
+	modulesStates = make(map[string]string)
 	modulesOrder = []string{}
 }
 
@@ -127,3 +130,33 @@ func TestShutdownModules(t *testing.T) {
 	assert.Equal(t, false, modulesMap["mod4"].(*MyMod).running)
 	assert.Equal(t, []string{"mod4", "mod1", "mod2", "mod3"}, modulesOrder)
 }
+
+func TestModulesStates(t *testing.T) {
+	resetModules()
+	initApp()
+	Configure(true, "testtype", Standalone, map[string]string{}, viper.New())
+
+	assert.NoError(t, RegisterModule(&MyMod{name: "mod1"}, "mod1"))
+	assert.NoError(t, RegisterModuleBefore(&MyMod{name: "mod2"}, "mod2"))
+	assert.NoError(t, RegisterCustomerModule(&MyMod{name: "mod3"}, "mod3"))
+	assert.Equal(t, []*mods.ModuleState{
+		{Name: "mod2", State: mods.ModuleRegistered},
+		{Name: "mod1", State: mods.ModuleRegistered},
+		{Name: "mod3", State: mods.ModuleRegistered, Customer: true},
+	}, ModulesStates())
+
+	startModules()
+	assert.Equal(t, []*mods.ModuleState{
+		{Name: "mod2", State: mods.ModuleStarted},
+		{Name: "mod1", State: mods.ModuleStarted},
+		{Name: "mod3", State: mods.ModuleRegistered, Customer: true},
+	}, ModulesStates())
+
+	startCustomerModules()
+	shutdownModules()
+	assert.Equal(t, []*mods.ModuleState{
+		{Name: "mod2", State: mods.ModuleStopped},
+		{Name: "mod1", State: mods.ModuleStopped},
+		{Name: "mod3", State: mods.ModuleStarted, Customer: true},
+	}, ModulesStates())
+}
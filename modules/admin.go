@@ -0,0 +1,223 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package modules
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"github.com/hnlxhzw/pitaya/cluster"
+	"github.com/hnlxhzw/pitaya/config"
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/logger"
+)
+
+// States of the registered modules
+const (
+	ModuleRegistered  = "registered"
+	ModuleInitialized = "initialized"
+	ModuleStarted     = "started"
+	ModuleStopping    = "stopping"
+	ModuleStopped     = "stopped"
+)
+
+// ModuleState is the state of a registered module
+type ModuleState struct {
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	Customer bool   `json:"customer"`
+}
+
+// AdminStatus provides the state of the application served by the Admin module
+type AdminStatus interface {
+	// Ready returns an error if the server must not receive requests
+	Ready() error
+	// Routes returns the documentation of the handlers and remotes
+	Routes() (map[string]interface{}, error)
+	// Modules returns the state of the registered modules
+	Modules() []*ModuleState
+}
+
+// Admin is a pitaya module that serves health, readiness and introspection
+// endpoints and pprof on a separate HTTP port
+type Admin struct {
+	Base
+	port            int
+	token           string
+	pprof           bool
+	shutdownTimeout time.Duration
+	server          *cluster.Server
+	sd              cluster.ServiceDiscovery
+	status          AdminStatus
+	httpServer      *http.Server
+	listener        net.Listener
+}
+
+// NewAdmin creates a new admin module, the service discovery can be nil
+// if the server is running in standalone mode
+func NewAdmin(
+	config *config.Config,
+	server *cluster.Server,
+	sd cluster.ServiceDiscovery,
+	status AdminStatus,
+) *Admin {
+	token := config.GetString("pitaya.modules.admin.token")
+	pprof := config.GetBool("pitaya.modules.admin.pprof")
+	if pprof && token == "" {
+		logger.Log.Warn("admin pprof endpoints are not served without a token")
+		pprof = false
+	}
+	return &Admin{
+		port:            config.GetInt("pitaya.modules.admin.port"),
+		token:           token,
+		pprof:           pprof,
+		shutdownTimeout: config.GetDuration("pitaya.modules.admin.shutdowntimeout"),
+		server:          server,
+		sd:              sd,
+		status:          status,
+	}
+}
+
+// Handler returns the HTTP handler of the admin endpoints
+func (a *Admin) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", a.healthz)
+	mux.HandleFunc("/readyz", a.readyz)
+	mux.Handle("/servers", a.authenticated(a.servers))
+	mux.Handle("/routes", a.authenticated(a.routes))
+	mux.Handle("/modules", a.authenticated(a.modules))
+	if a.pprof {
+		mux.Handle("/debug/pprof/", a.authenticated(pprof.Index))
+		mux.Handle("/debug/pprof/cmdline", a.authenticated(pprof.Cmdline))
+		mux.Handle("/debug/pprof/profile", a.authenticated(pprof.Profile))
+		mux.Handle("/debug/pprof/symbol", a.authenticated(pprof.Symbol))
+		mux.Handle("/debug/pprof/trace", a.authenticated(pprof.Trace))
+	}
+	return mux
+}
+
+// Addr returns the address the admin endpoints are served on, it is only
+// known after the module is initialized
+func (a *Admin) Addr() string {
+	if a.listener == nil {
+		return ""
+	}
+	return a.listener.Addr().String()
+}
+
+// Init starts serving the admin endpoints, before the other modules are
+// initialized, so the health endpoint answers while the server starts
+func (a *Admin) Init() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	if err != nil {
+		return err
+	}
+	a.listener = listener
+	a.httpServer = &http.Server{Handler: a.Handler()}
+
+	go func() {
+		if err := a.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Log.Errorf("admin server stopped: %s", err.Error())
+		}
+	}()
+	logger.Log.Infof("admin endpoints listening on %s", listener.Addr().String())
+	return nil
+}
+
+// Shutdown stops serving the admin endpoints
+func (a *Admin) Shutdown() error {
+	if a.httpServer == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+	return a.httpServer.Shutdown(ctx)
+}
+
+func (a *Admin) authenticated(handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.token != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+				writeAdminError(w, http.StatusUnauthorized, constants.ErrAdminUnauthorized)
+				return
+			}
+		}
+		handler(w, r)
+	})
+}
+
+func (a *Admin) healthz(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (a *Admin) readyz(w http.ResponseWriter, r *http.Request) {
+	if err := a.status.Ready(); err != nil {
+		writeAdminError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (a *Admin) servers(w http.ResponseWriter, r *http.Request) {
+	servers := []*cluster.Server{a.server}
+	if a.sd != nil {
+		servers = a.sd.GetServers()
+	}
+	writeAdminJSON(w, http.StatusOK, map[string]interface{}{
+		"server":  a.server,
+		"servers": servers,
+	})
+}
+
+func (a *Admin) routes(w http.ResponseWriter, r *http.Request) {
+	routes, err := a.status.Routes()
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, routes)
+}
+
+func (a *Admin) modules(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, map[string]interface{}{
+		"modules": a.status.Modules(),
+	})
+}
+
+func writeAdminError(w http.ResponseWriter, status int, err error) {
+	writeAdminJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Log.Errorf("failed to write admin response: %s", err.Error())
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/cluster"
+	clustermocks "github.com/hnlxhzw/pitaya/cluster/mocks"
+	"github.com/hnlxhzw/pitaya/config"
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+type fakeAdminStatus struct {
+	ready   error
+	routes  map[string]interface{}
+	modules []*ModuleState
+}
+
+func (f *fakeAdminStatus) Ready() error                            { return f.ready }
+func (f *fakeAdminStatus) Routes() (map[string]interface{}, error) { return f.routes, nil }
+func (f *fakeAdminStatus) Modules() []*ModuleState                 { return f.modules }
+
+func newTestAdmin(token string, sd cluster.ServiceDiscovery, status AdminStatus) *Admin {
+	cfg := viper.New()
+	cfg.Set("pitaya.modules.admin.port", 0)
+	cfg.Set("pitaya.modules.admin.token", token)
+	cfg.Set("pitaya.modules.admin.pprof", true)
+	server := cluster.NewServer("id", "connector", true)
+	return NewAdmin(config.NewConfig(cfg), server, sd, status)
+}
+
+func adminRequest(t *testing.T, handler http.Handler, path, token string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	body := map[string]interface{}{}
+	if rec.Header().Get("Content-Type") == "application/json" {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	}
+	return rec.Code, body
+}
+
+func TestAdminHealthAndReadiness(t *testing.T) {
+	status := &fakeAdminStatus{}
+	handler := newTestAdmin("", nil, status).Handler()
+
+	code, body := adminRequest(t, handler, "/healthz", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body["status"])
+
+	code, _ = adminRequest(t, handler, "/readyz", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	status.ready = constants.ErrServerStopping
+	code, body = adminRequest(t, handler, "/healthz", "")
+	assert.Equal(t, http.StatusOK, code)
+	code, body = adminRequest(t, handler, "/readyz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, constants.ErrServerStopping.Error(), body["error"])
+}
+
+func TestAdminIntrospection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sd := clustermocks.NewMockServiceDiscovery(ctrl)
+	sd.EXPECT().GetServers().Return([]*cluster.Server{{ID: "id"}, {ID: "other"}})
+	status := &fakeAdminStatus{
+		routes:  map[string]interface{}{"handlers": map[string]interface{}{}},
+		modules: []*ModuleState{{Name: "rpcServer", State: ModuleStarted}},
+	}
+	handler := newTestAdmin("", sd, status).Handler()
+
+	code, body := adminRequest(t, handler, "/servers", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "id", body["server"].(map[string]interface{})["id"])
+	assert.Len(t, body["servers"], 2)
+
+	code, body = adminRequest(t, handler, "/routes", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "handlers")
+
+	code, body = adminRequest(t, handler, "/modules", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "rpcServer", "state": ModuleStarted, "customer": false},
+	}, body["modules"])
+
+	// pprof is not served without a token
+	code, _ = adminRequest(t, handler, "/debug/pprof/", "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestAdminServersStandalone(t *testing.T) {
+	handler := newTestAdmin("", nil, &fakeAdminStatus{}).Handler()
+
+	code, body := adminRequest(t, handler, "/servers", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, body["servers"], 1)
+}
+
+func TestAdminToken(t *testing.T) {
+	handler := newTestAdmin("secret", nil, &fakeAdminStatus{}).Handler()
+
+	tables := []struct {
+		path  string
+		token string
+		code  int
+	}{
+		{"/healthz", "", http.StatusOK},
+		{"/readyz", "", http.StatusOK},
+		{"/modules", "", http.StatusUnauthorized},
+		{"/modules", "wrong", http.StatusUnauthorized},
+		{"/modules", "secret", http.StatusOK},
+		{"/debug/pprof/", "", http.StatusUnauthorized},
+		{"/debug/pprof/", "secret", http.StatusOK},
+	}
+
+	for _, table := range tables {
+		t.Run(fmt.Sprintf("%s_%s", table.path, table.token), func(t *testing.T) {
+			code, _ := adminRequest(t, handler, table.path, table.token)
+			assert.Equal(t, table.code, code)
+		})
+	}
+}
+
+func TestAdminInitAndShutdown(t *testing.T) {
+	admin := newTestAdmin("", nil, &fakeAdminStatus{})
+	assert.Empty(t, admin.Addr())
+	assert.NoError(t, admin.Init())
+	assert.NotEmpty(t, admin.Addr())
+
+	res, err := http.Get(fmt.Sprintf("http://%s/healthz", admin.Addr()))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	assert.NoError(t, admin.Shutdown())
+	_, err = http.Get(fmt.Sprintf("http://%s/healthz", admin.Addr()))
+	assert.Error(t, err)
+}
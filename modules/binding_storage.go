@@ -76,8 +76,14 @@ func (b *ETCDBindingStorage) PutBinding(uid string) error {
 	return err
 }
 
+// removeBinding only deletes the binding if it still points to this server,
+// so a session handed off to another frontend keeps its new binding
 func (b *ETCDBindingStorage) removeBinding(uid string) error {
-	_, err := b.cli.Delete(context.Background(), getUserBindingKey(uid, b.thisServer.Type))
+	key := getUserBindingKey(uid, b.thisServer.Type)
+	_, err := b.cli.Txn(context.Background()).
+		If(clientv3.Compare(clientv3.Value(key), "=", b.thisServer.ID)).
+		Then(clientv3.OpDelete(key)).
+		Commit()
 	return err
 }
 
@@ -33,3 +33,25 @@ func BeforeHandler(h pipeline.HandlerTempl) {
 func AfterHandler(h pipeline.AfterHandlerTempl) {
 	pipeline.AfterHandler.PushBack(h)
 }
+
+// BeforeRoute pushs a function to the back of the functions pipeline that will
+// be executed before the handler and remote methods whose route, in the format
+// service.method, matches the pattern, e.g. room.* or room.join. The pattern
+// syntax is the one of path.Match
+func BeforeRoute(pattern string, h pipeline.HandlerTempl) error {
+	return pipeline.RoutePipelines.PushBefore(pattern, h)
+}
+
+// AfterRoute pushs a function to the back of the functions pipeline that will
+// be executed after the handler and remote methods whose route, in the format
+// service.method, matches the pattern, e.g. room.* or room.join. The pattern
+// syntax is the one of path.Match
+func AfterRoute(pattern string, h pipeline.AfterHandlerTempl) error {
+	return pipeline.RoutePipelines.PushAfter(pattern, h)
+}
+
+// RemoveRoutePipelines removes the functions pushed with the pattern by
+// BeforeRoute and AfterRoute
+func RemoveRoutePipelines(pattern string) {
+	pipeline.RoutePipelines.Remove(pattern)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pipeline
+
+import (
+	"path"
+	"sync"
+)
+
+// RoutePipelines contains the functions to be called before and after the
+// handler and remote methods whose route matches a pattern
+var RoutePipelines = newRouteChannel()
+
+type (
+	// Chain has the functions to be called before and after a method, in the
+	// order they were pushed
+	Chain struct {
+		Before []HandlerTempl
+		After  []AfterHandlerTempl
+	}
+
+	routePipeline struct {
+		pattern string
+		chain   Chain
+	}
+
+	routeChannel struct {
+		mutex     sync.RWMutex
+		pipelines []*routePipeline
+	}
+)
+
+// PushBefore pushes a function to the back of the functions called before h
+func (c *Chain) PushBefore(h HandlerTempl) {
+	c.Before = append(c.Before, h)
+}
+
+// PushAfter pushes a function to the back of the functions called after h
+func (c *Chain) PushAfter(h AfterHandlerTempl) {
+	c.After = append(c.After, h)
+}
+
+// Then returns a chain whose before functions are the ones of c followed by
+// the ones of next, and whose after functions are the ones of next followed
+// by the ones of c, so the functions of c wrap the functions of next
+func (c Chain) Then(next Chain) Chain {
+	before := make([]HandlerTempl, 0, len(c.Before)+len(next.Before))
+	before = append(append(before, c.Before...), next.Before...)
+	after := make([]AfterHandlerTempl, 0, len(c.After)+len(next.After))
+	after = append(append(after, next.After...), c.After...)
+	return Chain{Before: before, After: after}
+}
+
+func newRouteChannel() *routeChannel {
+	return &routeChannel{pipelines: []*routePipeline{}}
+}
+
+// PushBefore pushes a function to the back of the functions called before the
+// methods whose route, in the format service.method, matches the pattern,
+// using the syntax of path.Match, e.g. room.* or room.join
+func (r *routeChannel) PushBefore(pattern string, h HandlerTempl) error {
+	return r.push(pattern, func(c *Chain) { c.PushBefore(h) })
+}
+
+// PushAfter pushes a function to the back of the functions called after the
+// methods whose route, in the format service.method, matches the pattern,
+// using the syntax of path.Match, e.g. room.* or room.join
+func (r *routeChannel) PushAfter(pattern string, h AfterHandlerTempl) error {
+	return r.push(pattern, func(c *Chain) { c.PushAfter(h) })
+}
+
+func (r *routeChannel) push(pattern string, add func(c *Chain)) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, p := range r.pipelines {
+		if p.pattern == pattern {
+			add(&p.chain)
+			return nil
+		}
+	}
+	p := &routePipeline{pattern: pattern}
+	add(&p.chain)
+	r.pipelines = append(r.pipelines, p)
+	return nil
+}
+
+// Remove removes the functions pushed with the pattern
+func (r *routeChannel) Remove(pattern string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, p := range r.pipelines {
+		if p.pattern == pattern {
+			r.pipelines = append(r.pipelines[:i:i], r.pipelines[i+1:]...)
+			return
+		}
+	}
+}
+
+// Clear removes all route pipelines
+func (r *routeChannel) Clear() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.pipelines = []*routePipeline{}
+}
+
+// Match returns the functions of the patterns matching the route, the
+// patterns are applied in the order they were first pushed
+func (r *routeChannel) Match(route string) Chain {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	chain := Chain{}
+	for _, p := range r.pipelines {
+		if ok, _ := path.Match(p.pattern, route); ok {
+			chain = chain.Then(p.chain)
+		}
+	}
+	return chain
+}
@@ -0,0 +1,120 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pipeline
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pipelineOrder []string
+
+func (o *pipelineOrder) before(name string) HandlerTempl {
+	return func(ctx context.Context, in interface{}) (context.Context, interface{}, error, int32) {
+		*o = append(*o, name)
+		return ctx, in, nil, 0
+	}
+}
+
+func (o *pipelineOrder) after(name string) AfterHandlerTempl {
+	return func(ctx context.Context, out interface{}, err error) (interface{}, error, int32) {
+		*o = append(*o, name)
+		return out, err, 0
+	}
+}
+
+func runChain(chain Chain) {
+	for _, h := range chain.Before {
+		h(context.Background(), nil)
+	}
+	for _, h := range chain.After {
+		h(context.Background(), nil, nil)
+	}
+}
+
+func TestChainThen(t *testing.T) {
+	order := &pipelineOrder{}
+	outer := Chain{}
+	outer.PushBefore(order.before("outer1"))
+	outer.PushBefore(order.before("outer2"))
+	outer.PushAfter(order.after("outer1"))
+	outer.PushAfter(order.after("outer2"))
+	inner := Chain{}
+	inner.PushBefore(order.before("inner"))
+	inner.PushAfter(order.after("inner"))
+
+	runChain(outer.Then(inner))
+	assert.Equal(t, pipelineOrder{"outer1", "outer2", "inner", "inner", "outer1", "outer2"}, *order)
+	assert.Len(t, outer.Before, 2)
+	assert.Len(t, inner.After, 1)
+}
+
+func TestRouteChannelMatch(t *testing.T) {
+	order := &pipelineOrder{}
+	r := newRouteChannel()
+	assert.NoError(t, r.PushBefore("room.*", order.before("room")))
+	assert.NoError(t, r.PushAfter("room.*", order.after("room")))
+	assert.NoError(t, r.PushBefore("room.join", order.before("join")))
+	assert.NoError(t, r.PushAfter("room.join", order.after("join")))
+	assert.NoError(t, r.PushBefore("*", order.before("all")))
+	assert.NoError(t, r.PushBefore("room.*", order.before("room2")))
+
+	tables := []struct {
+		route    string
+		expected pipelineOrder
+	}{
+		{"room.join", pipelineOrder{"room", "room2", "join", "all", "join", "room"}},
+		{"room.leave", pipelineOrder{"room", "room2", "all", "room"}},
+		{"lobby.enter", pipelineOrder{"all"}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.route, func(t *testing.T) {
+			*order = pipelineOrder{}
+			runChain(r.Match(table.route))
+			assert.Equal(t, table.expected, *order)
+		})
+	}
+}
+
+func TestRouteChannelRemoveAndClear(t *testing.T) {
+	order := &pipelineOrder{}
+	r := newRouteChannel()
+	assert.NoError(t, r.PushBefore("room.*", order.before("room")))
+	assert.NoError(t, r.PushBefore("room.join", order.before("join")))
+
+	r.Remove("room.*")
+	runChain(r.Match("room.join"))
+	assert.Equal(t, pipelineOrder{"join"}, *order)
+
+	r.Clear()
+	assert.Empty(t, r.Match("room.join").Before)
+}
+
+func TestRouteChannelBadPattern(t *testing.T) {
+	r := newRouteChannel()
+	err := r.PushBefore("room.[", (&pipelineOrder{}).before("room"))
+	assert.Equal(t, path.ErrBadPattern, err)
+	assert.Empty(t, r.pipelines)
+}
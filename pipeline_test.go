@@ -31,6 +31,7 @@ import (
 func resetPipelines() {
 	pipeline.BeforeHandler.Handlers = make([]pipeline.HandlerTempl, 0)
 	pipeline.AfterHandler.Handlers = make([]pipeline.AfterHandlerTempl, 0)
+	pipeline.RoutePipelines.Clear()
 }
 
 var myHandler = func(ctx context.Context, in interface{}) (context.Context, interface{}, error, int32) {
@@ -58,3 +59,18 @@ func TestAfterHandler(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []byte("test"), r)
 }
+
+func TestRoutePipelines(t *testing.T) {
+	resetPipelines()
+	assert.NoError(t, BeforeRoute("room.*", myHandler))
+	assert.NoError(t, AfterRoute("room.*", myAfterHandler))
+	assert.Error(t, BeforeRoute("room.[", myHandler))
+
+	chain := pipeline.RoutePipelines.Match("room.join")
+	assert.Len(t, chain.Before, 1)
+	assert.Len(t, chain.After, 1)
+	assert.Empty(t, pipeline.RoutePipelines.Match("lobby.join").Before)
+
+	RemoveRoutePipelines("room.*")
+	assert.Empty(t, pipeline.RoutePipelines.Match("room.join").Before)
+}
@@ -21,6 +21,10 @@
 package pitaya
 
 import (
+	"fmt"
+	"reflect"
+	"sync"
+
 	"github.com/hnlxhzw/pitaya/cluster"
 	"github.com/hnlxhzw/pitaya/constants"
 	"github.com/hnlxhzw/pitaya/logger"
@@ -29,8 +33,128 @@ import (
 	"github.com/hnlxhzw/pitaya/util"
 )
 
+var (
+	pushesMu sync.RWMutex
+	pushes   = make(map[string]reflect.Type)
+)
+
+// RegisterPush declares a route the server pushes to clients and the type of
+// its payload, a struct, a protobuf message or []byte for raw data. Declared
+// pushes are included in the documentation, decoded by frontends to push them
+// to sessions that negotiated another serializer and, in debug mode, pushes
+// to undeclared routes or with other payload types fail once any is declared
+func RegisterPush(route string, payload interface{}) error {
+	if route == "" {
+		return constants.ErrEmptyPushRoute
+	}
+	if payload == nil {
+		return constants.ErrNilPushPayload
+	}
+	pushesMu.Lock()
+	defer pushesMu.Unlock()
+	pushes[route] = reflect.TypeOf(payload)
+	return nil
+}
+
+// declaredPush returns the payload type declared for route
+func declaredPush(route string) (reflect.Type, bool) {
+	pushesMu.RLock()
+	defer pushesMu.RUnlock()
+	typ, ok := pushes[route]
+	return typ, ok
+}
+
+// declaredPushes returns a copy of the declared pushes
+func declaredPushes() map[string]reflect.Type {
+	pushesMu.RLock()
+	defer pushesMu.RUnlock()
+	declared := make(map[string]reflect.Type, len(pushes))
+	for route, typ := range pushes {
+		declared[route] = typ
+	}
+	return declared
+}
+
+// validatePush checks a push against the declared pushes when debug is on,
+// raw data is only checked for the route as it may be already serialized
+func validatePush(route string, v interface{}) error {
+	if !app.debug {
+		return nil
+	}
+	pushesMu.RLock()
+	declared := len(pushes) > 0
+	typ, ok := pushes[route]
+	pushesMu.RUnlock()
+	if !declared {
+		return nil
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", constants.ErrPushNotDeclared, route)
+	}
+	if _, raw := v.([]byte); raw {
+		return nil
+	}
+	if reflect.TypeOf(v) != typ {
+		return fmt.Errorf("%w: %s expects %s, got %T", constants.ErrPushPayloadMismatch, route, typ, v)
+	}
+	return nil
+}
+
+// validateSessionPush validates the pushes sent through a session, raw data
+// is skipped as it is how pushes forwarded from other servers arrive
+func validateSessionPush(route string, v interface{}) error {
+	if _, raw := v.([]byte); raw {
+		return nil
+	}
+	return validatePush(route, v)
+}
+
+// sessionPushData returns what to push to a local session, the data
+// serialized with the app serializer or, for sessions that negotiated
+// another serializer, v itself for their agent to serialize
+func sessionPushData(s *session.Session, v interface{}, data []byte) interface{} {
+	if s.Serializer() != nil {
+		return v
+	}
+	return data
+}
+
+// decodePush decodes a push forwarded by another server into the declared
+// payload type of its route, or a generic value if it was not declared, so
+// the frontend can serialize it for sessions that negotiated another
+// serializer, pushes declared as raw data are pushed as they are
+func decodePush(route string, data []byte) (interface{}, error) {
+	typ, ok := declaredPush(route)
+	if !ok {
+		var v interface{}
+		if err := app.serializer.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	if typ == reflect.TypeOf([]byte(nil)) {
+		return data, nil
+	}
+	if typ.Kind() != reflect.Ptr {
+		v := reflect.New(typ)
+		if err := app.serializer.Unmarshal(data, v.Interface()); err != nil {
+			return nil, err
+		}
+		return v.Elem().Interface(), nil
+	}
+	v := reflect.New(typ.Elem()).Interface()
+	if err := app.serializer.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
 // SendPushToUsers sends a message to the given list of users
 func SendPushToUsers(route string, v interface{}, uids []string, frontendType string) ([]string, error) {
+	if err := validatePush(route, v); err != nil {
+		return uids, err
+	}
+
 	data, err := util.SerializeOrRaw(app.serializer, v)
 	if err != nil {
 		return uids, err
@@ -46,7 +170,7 @@ func SendPushToUsers(route string, v interface{}, uids []string, frontendType st
 
 	for _, uid := range uids {
 		if s := session.GetSessionByUID(uid); s != nil && app.server.Type == frontendType {
-			if err := s.Push(route, data); err != nil {
+			if err := s.Push(route, sessionPushData(s, v, data)); err != nil {
 				notPushedUids = append(notPushedUids, uid)
 				logger.Log.Warnf("Session push message error, ID=%d, UID=%s, Error=%s",
 					s.ID(), s.UID(), err.Error())
@@ -22,6 +22,9 @@ package pitaya
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -30,6 +33,7 @@ import (
 	clustermocks "github.com/hnlxhzw/pitaya/cluster/mocks"
 	"github.com/hnlxhzw/pitaya/constants"
 	"github.com/hnlxhzw/pitaya/protos"
+	"github.com/hnlxhzw/pitaya/serialize/json"
 	serializemocks "github.com/hnlxhzw/pitaya/serialize/mocks"
 	"github.com/hnlxhzw/pitaya/session"
 	"github.com/hnlxhzw/pitaya/session/mocks"
@@ -142,3 +146,146 @@ func TestSendToUsersRemoteSession(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterPushConcurrently(t *testing.T) {
+	defer func() { pushes = make(map[string]reflect.Type) }()
+	debug := app.debug
+	defer SetDebug(debug)
+	SetDebug(true)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		route := fmt.Sprintf("onSome%d", i)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, RegisterPush(route, &someStruct{}))
+		}()
+		go func() {
+			defer wg.Done()
+			validatePush(route, &someStruct{})
+			declaredPushes()
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, declaredPushes(), 10)
+}
+
+func TestDecodePush(t *testing.T) {
+	defer func() { pushes = make(map[string]reflect.Type) }()
+	serializer := app.serializer
+	defer func() { app.serializer = serializer }()
+	app.serializer = json.NewSerializer()
+
+	assert.NoError(t, RegisterPush("onPointer", &someStruct{}))
+	assert.NoError(t, RegisterPush("onValue", someStruct{}))
+	assert.NoError(t, RegisterPush("onRaw", []byte{}))
+
+	tables := []struct {
+		name  string
+		route string
+		data  []byte
+		v     interface{}
+		err   bool
+	}{
+		{"declared_pointer", "onPointer", []byte(`{"A":1}`), &someStruct{A: 1}, false},
+		{"declared_value", "onValue", []byte(`{"A":1}`), someStruct{A: 1}, false},
+		{"declared_raw", "onRaw", []byte("raw"), []byte("raw"), false},
+		{"undeclared", "onOther", []byte(`{"A":1}`), map[string]interface{}{"A": float64(1)}, false},
+		{"invalid_data", "onPointer", []byte("raw"), nil, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			v, err := decodePush(table.route, table.data)
+			if table.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, table.v, v)
+		})
+	}
+}
+
+func TestRegisterPush(t *testing.T) {
+	defer func() { pushes = make(map[string]reflect.Type) }()
+
+	assert.Equal(t, constants.ErrEmptyPushRoute, RegisterPush("", &someStruct{}))
+	assert.Equal(t, constants.ErrNilPushPayload, RegisterPush("onSome", nil))
+	assert.NoError(t, RegisterPush("onSome", &someStruct{}))
+	assert.Equal(t, reflect.TypeOf(&someStruct{}), pushes["onSome"])
+
+	doc, err := Documentation(false)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"onSome": map[string]interface{}{
+			"input":  nil,
+			"output": []interface{}{map[string]interface{}{"A": "int"}},
+		},
+	}, doc["pushes"])
+}
+
+func TestValidatePush(t *testing.T) {
+	defer func() { pushes = make(map[string]reflect.Type) }()
+	defer SetDebug(false)
+
+	tables := []struct {
+		name    string
+		debug   bool
+		declare bool
+		route   string
+		v       interface{}
+		err     error
+	}{
+		{"not_debug", false, true, "undeclared", &someStruct{}, nil},
+		{"nothing_declared", true, false, "undeclared", &someStruct{}, nil},
+		{"declared", true, true, "onSome", &someStruct{}, nil},
+		{"raw", true, true, "onSome", []byte("raw"), nil},
+		{"undeclared", true, true, "undeclared", &someStruct{}, constants.ErrPushNotDeclared},
+		{"undeclared_raw", true, true, "undeclared", []byte("raw"), constants.ErrPushNotDeclared},
+		{"mismatch", true, true, "onSome", someStruct{}, constants.ErrPushPayloadMismatch},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			pushes = make(map[string]reflect.Type)
+			if table.declare {
+				assert.NoError(t, RegisterPush("onSome", &someStruct{}))
+			}
+			SetDebug(table.debug)
+
+			err := validatePush(table.route, table.v)
+			if table.err != nil {
+				assert.ErrorIs(t, err, table.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSessionPushValidation(t *testing.T) {
+	defer func() { pushes = make(map[string]reflect.Type) }()
+	defer SetDebug(false)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockNetworkEntity := mocks.NewMockNetworkEntity(ctrl)
+	s := session.New(mockNetworkEntity, false)
+
+	assert.NoError(t, RegisterPush("onSome", &someStruct{}))
+	SetDebug(true)
+
+	data := &someStruct{A: 1}
+	mockNetworkEntity.EXPECT().Push("onSome", data)
+	assert.NoError(t, s.Push("onSome", data))
+	assert.ErrorIs(t, s.Push("undeclared", data), constants.ErrPushNotDeclared)
+
+	// pushes forwarded by other servers arrive already serialized
+	mockNetworkEntity.EXPECT().Push("undeclared", []byte("raw"))
+	assert.NoError(t, s.Push("undeclared", []byte("raw")))
+
+	SetDebug(false)
+	mockNetworkEntity.EXPECT().Push("undeclared", data)
+	assert.NoError(t, s.Push("undeclared", data))
+}
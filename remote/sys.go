@@ -22,16 +22,22 @@ package remote
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/hnlxhzw/pitaya/component"
 	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/logger"
 	"github.com/hnlxhzw/pitaya/protos"
 	"github.com/hnlxhzw/pitaya/session"
+	"github.com/hnlxhzw/pitaya/worker"
 )
 
 // Sys contains logic for handling sys remotes
 type Sys struct {
 	component.Base
+	// Worker returns the worker of the dead job remotes, it is called on each
+	// request so a worker started after the remote is registered is used
+	Worker func() *worker.Worker
 }
 
 // BindSession binds the local session
@@ -58,6 +64,32 @@ func (s *Sys) PushSession(ctx context.Context, sessionData *protos.Session) (*pr
 	return &protos.Response{Data: []byte("ack")}, nil
 }
 
+// HandoffSession receives a session handed off by another frontend and keeps
+// it until the client reconnects with the resume token
+func (s *Sys) HandoffSession(ctx context.Context, sessionData *protos.Session) (*protos.Response, error) {
+	data := &session.HandoffData{}
+	if err := json.Unmarshal(sessionData.Data, data); err != nil {
+		return nil, err
+	}
+	session.AddHandoff(data)
+	return &protos.Response{Data: []byte("ack")}, nil
+}
+
+// GroupBroadcast pushes a message to every local session of a group
+func (s *Sys) GroupBroadcast(ctx context.Context, push *protos.GroupPush) (*protos.Response, error) {
+	for _, sess := range session.GetSessionsByGroup(push.GetGroup()) {
+		data, err := sess.PushData(push.GetRoute(), push.GetData())
+		if err == nil {
+			err = sess.Push(push.GetRoute(), data)
+		}
+		if err != nil {
+			logger.Log.Warnf("Session push message error, ID=%d, UID=%s, Error=%s",
+				sess.ID(), sess.UID(), err.Error())
+		}
+	}
+	return &protos.Response{Data: []byte("ack")}, nil
+}
+
 // Kick kicks a local user
 func (s *Sys) Kick(ctx context.Context, msg *protos.KickMsg) (*protos.KickAnswer, error) {
 	res := &protos.KickAnswer{
@@ -74,3 +106,98 @@ func (s *Sys) Kick(ctx context.Context, msg *protos.KickMsg) (*protos.KickAnswer
 	res.Kicked = true
 	return res, nil
 }
+
+// DeadJobs returns the dead reliable RPC jobs of the route sent in the name
+// field, or of all routes if it is empty
+func (s *Sys) DeadJobs(ctx context.Context, msg *protos.ProtoName) (*protos.Response, error) {
+	w := s.worker()
+	if w == nil {
+		return nil, constants.ErrWorkerNotStarted
+	}
+	deadJobs, err := w.DeadRPCJobs(msg.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return jsonResponse(deadJobs)
+}
+
+// DeadJob returns the dead reliable RPC job with the ID sent in the name field
+func (s *Sys) DeadJob(ctx context.Context, msg *protos.ProtoName) (*protos.Response, error) {
+	w := s.worker()
+	if w == nil {
+		return nil, constants.ErrWorkerNotStarted
+	}
+	deadJob, err := w.DeadRPCJob(msg.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return jsonResponse(deadJob)
+}
+
+// ReplayDeadJobs enqueues again the dead reliable RPC jobs of the route sent
+// in the name field, or of all routes if it is empty, returning their IDs
+func (s *Sys) ReplayDeadJobs(ctx context.Context, msg *protos.ProtoName) (*protos.Response, error) {
+	w := s.worker()
+	if w == nil {
+		return nil, constants.ErrWorkerNotStarted
+	}
+	jids, err := w.ReplayDeadRPCJobs(msg.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return jsonResponse(jids)
+}
+
+// ReplayDeadJob enqueues again the dead reliable RPC job with the ID sent in
+// the name field
+func (s *Sys) ReplayDeadJob(ctx context.Context, msg *protos.ProtoName) (*protos.Response, error) {
+	w := s.worker()
+	if w == nil {
+		return nil, constants.ErrWorkerNotStarted
+	}
+	if err := w.ReplayDeadRPCJob(msg.GetName()); err != nil {
+		return nil, err
+	}
+	return &protos.Response{Data: []byte("ack")}, nil
+}
+
+// PurgeDeadJobs removes the dead reliable RPC jobs of the route sent in the
+// name field, or of all routes if it is empty, returning their IDs
+func (s *Sys) PurgeDeadJobs(ctx context.Context, msg *protos.ProtoName) (*protos.Response, error) {
+	w := s.worker()
+	if w == nil {
+		return nil, constants.ErrWorkerNotStarted
+	}
+	jids, err := w.PurgeDeadRPCJobs(msg.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return jsonResponse(jids)
+}
+
+// PurgeDeadJob removes the dead reliable RPC job with the ID sent in the name field
+func (s *Sys) PurgeDeadJob(ctx context.Context, msg *protos.ProtoName) (*protos.Response, error) {
+	w := s.worker()
+	if w == nil {
+		return nil, constants.ErrWorkerNotStarted
+	}
+	if err := w.PurgeDeadRPCJob(msg.GetName()); err != nil {
+		return nil, err
+	}
+	return &protos.Response{Data: []byte("ack")}, nil
+}
+
+func (s *Sys) worker() *worker.Worker {
+	if s.Worker == nil {
+		return nil
+	}
+	return s.Worker()
+}
+
+func jsonResponse(v interface{}) (*protos.Response, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &protos.Response{Data: data}, nil
+}
@@ -21,16 +21,19 @@
 package remote
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/config"
 	"github.com/hnlxhzw/pitaya/constants"
 	"github.com/hnlxhzw/pitaya/protos"
 	"github.com/hnlxhzw/pitaya/session"
 	"github.com/hnlxhzw/pitaya/session/mocks"
+	"github.com/hnlxhzw/pitaya/worker"
 )
 
 func TestBindSession(t *testing.T) {
@@ -165,3 +168,109 @@ func TestKickSessionShouldFailIfSessionDoesntExists(t *testing.T) {
 	_, err := s.Kick(nil, &protos.KickMsg{UserId: uuid.New().String()})
 	assert.EqualError(t, constants.ErrSessionNotFound, err.Error())
 }
+
+func TestHandoffSession(t *testing.T) {
+	t.Parallel()
+	s := &Sys{}
+	uid := uuid.New().String()
+	token := uuid.New().String()
+	d, err := json.Marshal(&session.HandoffData{
+		UID:         uid,
+		Data:        []byte(`{"hello":"test"}`),
+		ResumeToken: token,
+	})
+	assert.NoError(t, err)
+	res, err := s.HandoffSession(nil, &protos.Session{Uid: uid, Data: d})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ack"), res.Data)
+
+	ss := session.New(nil, true)
+	err = ss.Resume(nil, token)
+	assert.NoError(t, err)
+	assert.Equal(t, uid, ss.UID())
+	assert.Equal(t, "test", ss.Get("hello"))
+}
+
+func TestHandoffSessionShouldFailIfInvalidData(t *testing.T) {
+	t.Parallel()
+	s := &Sys{}
+	_, err := s.HandoffSession(nil, &protos.Session{Data: []byte("invalid")})
+	assert.Error(t, err)
+}
+
+func TestGroupBroadcast(t *testing.T) {
+	t.Parallel()
+	s := &Sys{}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockEntity := mocks.NewMockNetworkEntity(ctrl)
+	ss := session.New(mockEntity, true)
+	defer ss.Close()
+	err := ss.Set(constants.SessionGroupsKey, []string{"sysGroupBroadcast"})
+	assert.NoError(t, err)
+
+	mockEntity.EXPECT().Push("some.route", []byte("hello"))
+	res, err := s.GroupBroadcast(nil, &protos.GroupPush{
+		Route: "some.route",
+		Group: "sysGroupBroadcast",
+		Data:  []byte("hello"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ack"), res.Data)
+
+	mockEntity.EXPECT().Close()
+}
+
+func TestDeadJobsShouldFailIfWorkerNotStarted(t *testing.T) {
+	t.Parallel()
+	s := &Sys{}
+	_, err := s.DeadJobs(nil, &protos.ProtoName{})
+	assert.Equal(t, constants.ErrWorkerNotStarted, err)
+	_, err = s.ReplayDeadJob(nil, &protos.ProtoName{Name: "jid"})
+	assert.Equal(t, constants.ErrWorkerNotStarted, err)
+}
+
+func TestDeadJobs(t *testing.T) {
+	t.Parallel()
+	backend := worker.NewMemoryBackend()
+	var w *worker.Worker
+	s := &Sys{Worker: func() *worker.Worker { return w }}
+	_, err := s.DeadJobs(nil, &protos.ProtoName{})
+	assert.Equal(t, constants.ErrWorkerNotStarted, err)
+	w = worker.NewWorkerWithBackend(config.NewConfig(), backend)
+
+	route := "server.svc.method"
+	jid, err := w.EnqueueRPC(route, nil, &protos.Response{}, &protos.Response{})
+	assert.NoError(t, err)
+	job, err := backend.Fetch(context.Background(), "rpc")
+	assert.NoError(t, err)
+	job.ErrorMessage = "failed"
+	err = backend.Kill(job)
+	assert.NoError(t, err)
+
+	res, err := s.DeadJobs(nil, &protos.ProtoName{Name: route})
+	assert.NoError(t, err)
+	var deadJobs []*worker.DeadRPCJob
+	err = json.Unmarshal(res.Data, &deadJobs)
+	assert.NoError(t, err)
+	assert.Len(t, deadJobs, 1)
+	assert.Equal(t, jid, deadJobs[0].ID)
+	assert.Equal(t, "failed", deadJobs[0].ErrorMessage)
+
+	res, err = s.DeadJob(nil, &protos.ProtoName{Name: jid})
+	assert.NoError(t, err)
+	deadJob := &worker.DeadRPCJob{}
+	err = json.Unmarshal(res.Data, deadJob)
+	assert.NoError(t, err)
+	assert.Equal(t, route, deadJob.Route)
+
+	res, err = s.ReplayDeadJob(nil, &protos.ProtoName{Name: jid})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ack"), res.Data)
+	_, err = s.PurgeDeadJob(nil, &protos.ProtoName{Name: jid})
+	assert.Equal(t, constants.ErrJobNotFound, err)
+
+	res, err = s.PurgeDeadJobs(nil, &protos.ProtoName{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("[]"), res.Data)
+}
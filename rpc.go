@@ -23,6 +23,7 @@ package pitaya
 import (
 	"context"
 	"github.com/golang/protobuf/proto"
+	"github.com/hnlxhzw/pitaya/auth"
 	"github.com/hnlxhzw/pitaya/constants"
 	"github.com/hnlxhzw/pitaya/protos"
 	"github.com/hnlxhzw/pitaya/route"
@@ -57,6 +58,26 @@ func RPCForHttp(routeStr string, arg proto.Message) (*protos.Response, error) {
 	return handlerService.ProcessMessageForHttp(routeStr, data)
 }
 
+// RPCForHttpWithToken is like RPCForHttp but it first verifies the jwt token
+// of the http caller with the authenticator, the request is then processed
+// with a session bound to the token uid and holding its claims, so handlers
+// and pipelines see the same session of socket clients. It returns an
+// *errors.Error with the PIT-401 code if the token is missing or invalid
+func RPCForHttpWithToken(authenticator *auth.JWTAuthenticator, token, routeStr string, arg proto.Message) (*protos.Response, error) {
+	identity, err := authenticator.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if arg != nil {
+		data, err = proto.Marshal(arg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return handlerService.ProcessAuthenticatedMessageForHttp(routeStr, data, identity.UID, identity.Data)
+}
+
 // ReliableRPC enqueues RPC to worker so it's executed asynchronously
 // Default enqueue options are used
 func ReliableRPC(
@@ -78,6 +99,45 @@ func ReliableRPCWithOptions(
 	return app.worker.EnqueueRPCWithOptions(routeStr, metadata, reply, arg, opts)
 }
 
+// CancelReliableRPC cancels a ReliableRPC scheduled with the
+// RunAt or RunAfter options that was not executed yet
+func CancelReliableRPC(jid string) error {
+	return app.worker.Cancel(jid)
+}
+
+// DeadReliableRPCs returns the ReliableRPCs of a route, or of all routes
+// if route is empty, that exhausted their retries
+func DeadReliableRPCs(route string) ([]*worker.DeadRPCJob, error) {
+	return app.worker.DeadRPCJobs(route)
+}
+
+// DeadReliableRPC returns a ReliableRPC that exhausted its retries by its job id
+func DeadReliableRPC(jid string) (*worker.DeadRPCJob, error) {
+	return app.worker.DeadRPCJob(jid)
+}
+
+// ReplayDeadReliableRPCs enqueues again the dead ReliableRPCs of a route, or
+// of all routes if route is empty, and returns their job ids
+func ReplayDeadReliableRPCs(route string) ([]string, error) {
+	return app.worker.ReplayDeadRPCJobs(route)
+}
+
+// ReplayDeadReliableRPC enqueues again a dead ReliableRPC by its job id
+func ReplayDeadReliableRPC(jid string) error {
+	return app.worker.ReplayDeadRPCJob(jid)
+}
+
+// PurgeDeadReliableRPCs removes the dead ReliableRPCs of a route, or of all
+// routes if route is empty, and returns their job ids
+func PurgeDeadReliableRPCs(route string) ([]string, error) {
+	return app.worker.PurgeDeadRPCJobs(route)
+}
+
+// PurgeDeadReliableRPC removes a dead ReliableRPC by its job id
+func PurgeDeadReliableRPC(jid string) error {
+	return app.worker.PurgeDeadRPCJob(jid)
+}
+
 func doSendRPC(ctx context.Context, serverID, routeStr string, reply proto.Message, arg proto.Message) error {
 	if app.rpcServer == nil {
 		return constants.ErrRPCServerNotInitialized
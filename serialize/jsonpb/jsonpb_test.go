@@ -0,0 +1,125 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jsonpb
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hnlxhzw/pitaya/protos"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSerializer(t *testing.T) {
+	t.Parallel()
+
+	serializer := NewSerializer()
+
+	assert.NotNil(t, serializer)
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+
+	type MyStruct struct {
+		Str    string
+		Number float64
+	}
+	var marshalTables = map[string]struct {
+		raw       interface{}
+		marshaled string
+	}{
+		"test_proto_message": {
+			&protos.Msg{Id: 1, Route: "room.join", Type: protos.MsgType_MsgPush},
+			`{"id":"1","route":"room.join","type":"MsgPush"}`,
+		},
+		"test_proto_original_names": {
+			&protos.ClientError{ErrorCode: 500},
+			`{"ErrorCode":500}`,
+		},
+		"test_struct": {
+			&MyStruct{Str: "hello", Number: 42},
+			`{"Str":"hello","Number":42}`,
+		},
+	}
+	serializer := NewSerializer()
+
+	for name, table := range marshalTables {
+		t.Run(name, func(t *testing.T) {
+			result, err := serializer.Marshal(table.raw)
+			assert.NoError(t, err)
+			assert.JSONEq(t, table.marshaled, string(result))
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	type MyStruct struct {
+		Str    string
+		Number int
+	}
+	var unmarshalTables = map[string]struct {
+		data        string
+		dest        interface{}
+		unmarshaled interface{}
+		hasErr      bool
+	}{
+		"test_proto_enum_name": {
+			`{"id":"1","route":"room.join","type":"MsgPush","unknown":true}`,
+			&protos.Msg{},
+			&protos.Msg{Id: 1, Route: "room.join", Type: protos.MsgType_MsgPush},
+			false,
+		},
+		"test_proto_enum_number": {
+			`{"id":1,"type":3}`,
+			&protos.Msg{},
+			&protos.Msg{Id: 1, Type: protos.MsgType_MsgPush},
+			false,
+		},
+		"test_struct": {
+			`{"Str":"hello","Number":42}`,
+			&MyStruct{},
+			&MyStruct{Str: "hello", Number: 42},
+			false,
+		},
+		"test_proto_nok":  {`invalid`, &protos.Msg{}, nil, true},
+		"test_struct_nok": {`invalid`, &MyStruct{}, nil, true},
+	}
+	serializer := NewSerializer()
+
+	for name, table := range unmarshalTables {
+		t.Run(name, func(t *testing.T) {
+			err := serializer.Unmarshal([]byte(table.data), table.dest)
+			if table.hasErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				if pb, ok := table.unmarshaled.(proto.Message); ok {
+					assert.True(t, proto.Equal(pb, table.dest.(proto.Message)))
+				} else {
+					assert.Equal(t, table.unmarshaled, table.dest)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package msgpack
+
+import (
+	"testing"
+
+	"github.com/hnlxhzw/pitaya/protos"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSerializer(t *testing.T) {
+	t.Parallel()
+
+	serializer := NewSerializer()
+
+	assert.NotNil(t, serializer)
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+
+	type MyStruct struct {
+		Str    string `json:"str"`
+		Number int
+	}
+	var marshalTables = map[string]struct {
+		raw      interface{}
+		expected map[string]interface{}
+	}{
+		"test_struct": {
+			&MyStruct{Str: "hello", Number: 42},
+			map[string]interface{}{"str": "hello", "Number": int8(42)},
+		},
+		"test_proto_message": {
+			&protos.Error{Code: "PIT-400", Msg: "bad request"},
+			map[string]interface{}{"code": "PIT-400", "msg": "bad request"},
+		},
+	}
+	serializer := NewSerializer()
+
+	for name, table := range marshalTables {
+		t.Run(name, func(t *testing.T) {
+			result, err := serializer.Marshal(table.raw)
+			assert.NoError(t, err)
+
+			var decoded map[string]interface{}
+			assert.NoError(t, serializer.Unmarshal(result, &decoded))
+			assert.Equal(t, table.expected, decoded)
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	type MyStruct struct {
+		Str    string `json:"str"`
+		Number int
+	}
+	serializer := NewSerializer()
+	data, err := serializer.Marshal(map[string]interface{}{"str": "hello", "Number": 42})
+	assert.NoError(t, err)
+
+	var unmarshalTables = map[string]struct {
+		data        []byte
+		unmarshaled *MyStruct
+		hasErr      bool
+	}{
+		"test_ok":  {data, &MyStruct{Str: "hello", Number: 42}, false},
+		"test_nok": {[]byte{0xc1}, nil, true},
+	}
+
+	for name, table := range unmarshalTables {
+		t.Run(name, func(t *testing.T) {
+			var result MyStruct
+			err := serializer.Unmarshal(table.data, &result)
+			if table.hasErr {
+				assert.Error(t, err)
+				assert.Empty(t, &result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, table.unmarshaled, &result)
+			}
+		})
+	}
+}
@@ -20,6 +20,8 @@
 
 package serialize
 
+import "sync"
+
 type (
 
 	// Marshaler represents a marshal interface
@@ -39,3 +41,32 @@ type (
 		GetName() string
 	}
 )
+
+var (
+	serializersMutex sync.RWMutex
+	serializers      = map[string]Serializer{}
+)
+
+// Register registers a serializer by its name, so clients can choose it in
+// the handshake and servers can find the one of a session
+func Register(serializer Serializer) {
+	serializersMutex.Lock()
+	defer serializersMutex.Unlock()
+	serializers[serializer.GetName()] = serializer
+}
+
+// Get returns the registered serializer with the given name, or nil if there
+// is none
+func Get(name string) Serializer {
+	serializersMutex.RLock()
+	defer serializersMutex.RUnlock()
+	return serializers[name]
+}
+
+// Unregister removes the serializer with the given name, clients can no
+// longer choose it in the handshake
+func Unregister(name string) {
+	serializersMutex.Lock()
+	defer serializersMutex.Unlock()
+	delete(serializers, name)
+}
@@ -83,10 +83,11 @@ type (
 	}
 
 	unhandledMessage struct {
-		ctx   context.Context
-		agent *agent.Agent
-		route *route.Route
-		msg   *message.Message
+		ctx        context.Context
+		agent      *agent.Agent
+		route      *route.Route
+		msg        *message.Message
+		enqueuedAt time.Time
 	}
 )
 
@@ -163,10 +164,12 @@ func (h *HandlerService) Dispatch(thread int, wg *sync.WaitGroup) {
 		// Calls to remote servers block calls to local server
 		select {
 		case lm := <-chLocalProcess:
+			metrics.ReportDispatchWaitTime(h.metricsReporters, thread, "local", time.Since(lm.enqueuedAt))
 			metrics.ReportMessageProcessDelayFromCtx(lm.ctx, h.metricsReporters, "local")
 			h.localProcess(lm.ctx, lm.agent, lm.route, lm.msg)
 
 		case rm := <-chRemoteProcess:
+			metrics.ReportDispatchWaitTime(h.metricsReporters, thread, "remote", time.Since(rm.enqueuedAt))
 			metrics.ReportMessageProcessDelayFromCtx(rm.ctx, h.metricsReporters, "remote")
 			h.remoteService.remoteProcess(rm.ctx, nil, rm.agent, rm.route, rm.msg)
 
@@ -207,6 +210,7 @@ func (h *HandlerService) Handle(conn acceptor.PlayerConn) {
 	defer util.AutoRecover("HandlerService Handle")
 	// create a client agent and startup write goroutine
 	a := agent.NewAgent(conn, h.decoder, h.encoder, h.serializer, h.heartbeatTimeout, h.messagesBufferSize, h.appDieChan, h.messageEncoder, h.metricsReporters)
+	a.SetDispatchIndex(h.GetChProcessIndex(a))
 
 	// startup agent goroutine
 	go a.Handle()
@@ -260,21 +264,56 @@ func (h *HandlerService) processPacket(a *agent.Agent, p *packet.Packet) error {
 	switch p.Type {
 	case packet.Handshake:
 		logger.Log.Debug("Received handshake packet")
-		if err := a.SendHandshakeResponse(); err != nil {
-			logger.Log.Errorf("Error sending handshake response: %s", err.Error())
-			return err
-		}
-		logger.Log.Debugf("Session handshake Id=%d, Remote=%s", a.Session.ID(), a.RemoteAddr())
 
 		// Parse the json sent with the handshake by the client
 		handshakeData := &session.HandshakeData{}
 		err := json.Unmarshal(p.Data, handshakeData)
 		if err != nil {
 			a.SetStatus(constants.StatusClosed)
+			invalidErr := e.NewError(err, e.ErrBadRequestCode.Desc, e.ErrBadRequestCode.ErrorCode)
+			if err := a.SendHandshakeErrorResponse(invalidErr); err != nil {
+				logger.Log.Errorf("Error sending handshake response: %s", err.Error())
+			}
 			return fmt.Errorf("Invalid handshake data. Id=%d", a.Session.ID())
 		}
 
 		a.Session.SetHandshakeData(handshakeData)
+		if err := a.Session.ValidateHandshake(context.Background(), a.RemoteAddr()); err != nil {
+			a.SetStatus(constants.StatusClosed)
+			if err := a.SendHandshakeErrorResponse(err); err != nil {
+				logger.Log.Errorf("Error sending handshake response: %s", err.Error())
+			}
+			return fmt.Errorf("Handshake rejected. Id=%d: %s", a.Session.ID(), err.Error())
+		}
+
+		if err := h.negotiateSerializer(a, handshakeData.Sys.Serializer); err != nil {
+			a.SetStatus(constants.StatusClosed)
+			invalidErr := e.NewError(err, e.ErrBadRequestCode.Desc, e.ErrBadRequestCode.ErrorCode)
+			if err := a.SendHandshakeErrorResponse(invalidErr); err != nil {
+				logger.Log.Errorf("Error sending handshake response: %s", err.Error())
+			}
+			return fmt.Errorf("Handshake rejected. Id=%d: %s", a.Session.ID(), err.Error())
+		}
+
+		if resumeToken := handshakeData.Sys.ResumeToken; resumeToken != "" {
+			// resumed only after the handshake is accepted, so a rejected
+			// client neither claims the handed off session nor binds its uid
+			if err := a.Session.Resume(context.Background(), resumeToken); err != nil {
+				// the client must authenticate again in a new connection
+				a.SetStatus(constants.StatusClosed)
+				if err := a.SendHandshakeErrorResponse(err); err != nil {
+					logger.Log.Errorf("Error sending handshake response: %s", err.Error())
+				}
+				return fmt.Errorf("Failed to resume handed off session. Id=%d: %s", a.Session.ID(), err.Error())
+			}
+		}
+
+		if err := a.SendHandshakeResponse(); err != nil {
+			logger.Log.Errorf("Error sending handshake response: %s", err.Error())
+			return err
+		}
+		logger.Log.Debugf("Session handshake Id=%d, Remote=%s", a.Session.ID(), a.RemoteAddr())
+
 		a.SetStatus(constants.StatusHandshake)
 		err = a.Session.Set(constants.IPVersionKey, a.IPVersion())
 		if err != nil {
@@ -307,6 +346,19 @@ func (h *HandlerService) processPacket(a *agent.Agent, p *packet.Packet) error {
 	return nil
 }
 
+// negotiateSerializer sets the serializer requested by the client in the
+// handshake on its agent, it must be registered unless it is the server one
+func (h *HandlerService) negotiateSerializer(a *agent.Agent, name string) error {
+	if name == "" || name == h.serializer.GetName() {
+		return nil
+	}
+	serializer := serialize.Get(name)
+	if serializer == nil {
+		return fmt.Errorf("%w: %s", constants.ErrUnsupportedSerializer, name)
+	}
+	return a.SetSerializer(serializer)
+}
+
 func (h *HandlerService) processMessage(a *agent.Agent, msg *message.Message) {
 	requestID := uuid.New()
 	ctx := pcontext.AddToPropagateCtx(context.Background(), constants.StartTimeKey, time.Now().UnixNano())
@@ -334,10 +386,11 @@ func (h *HandlerService) processMessage(a *agent.Agent, msg *message.Message) {
 	}
 
 	message := unhandledMessage{
-		ctx:   ctx,
-		agent: a,
-		route: r,
-		msg:   msg,
+		ctx:        ctx,
+		agent:      a,
+		route:      r,
+		msg:        msg,
+		enqueuedAt: time.Now(),
 	}
 
 	dispatchIndex := h.GetChProcessIndex(a)
@@ -353,11 +406,32 @@ func (h *HandlerService) processMessage(a *agent.Agent, msg *message.Message) {
 }
 
 func (h *HandlerService) ProcessMessageForHttp(routeStr string, data []byte) (*protos.Response, error) {
+	return h.processMessageForHttp(agent.NewAgentForHttp(), routeStr, data)
+}
+
+// ProcessAuthenticatedMessageForHttp processes a http request like
+// ProcessMessageForHttp, with its session bound to the uid and holding the
+// session data of the authenticated caller
+func (h *HandlerService) ProcessAuthenticatedMessageForHttp(
+	routeStr string,
+	data []byte,
+	uid string,
+	sessionData map[string]interface{},
+) (*protos.Response, error) {
+	agentForHttp := agent.NewAgentForHttp(uid)
+	if sessionData != nil {
+		if err := agentForHttp.Session.SetData(sessionData); err != nil {
+			return nil, err
+		}
+	}
+	return h.processMessageForHttp(agentForHttp, routeStr, data)
+}
+
+func (h *HandlerService) processMessageForHttp(agentForHttp *agent.Agent, routeStr string, data []byte) (*protos.Response, error) {
 	msg := message.New()
 	msg.Type = message.Request
 	msg.Route = routeStr
 	msg.Data = data
-	agentForHttp := agent.NewAgentForHttp()
 	requestID := uuid.New()
 	ctx := pcontext.AddToPropagateCtx(context.Background(), constants.StartTimeKey, time.Now().UnixNano())
 	ctx = pcontext.AddToPropagateCtx(ctx, constants.RouteKey, msg.Route)
@@ -402,7 +476,11 @@ func (h *HandlerService) localProcess(ctx context.Context, a *agent.Agent, route
 		mid = 0
 	}
 
-	ret, err := processHandlerMessage(ctx, route, h.serializer, a.Session, msg.Data, msg.Type, false)
+	serializer := h.serializer
+	if negotiated := a.Session.Serializer(); negotiated != nil {
+		serializer = negotiated
+	}
+	ret, err := processHandlerMessage(ctx, route, serializer, a.Session, msg.Data, msg.Type, false)
 	if msg.Type != message.Notify {
 		if err != nil {
 			logger.Log.Errorf("Failed to process handler message: %s", err.Error())
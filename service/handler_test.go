@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"net"
 	"reflect"
 	"sync"
 	"testing"
@@ -41,14 +42,19 @@ import (
 	"github.com/hnlxhzw/pitaya/conn/packet"
 	"github.com/hnlxhzw/pitaya/constants"
 	pcontext "github.com/hnlxhzw/pitaya/context"
+	e "github.com/hnlxhzw/pitaya/errors"
 	"github.com/hnlxhzw/pitaya/helpers"
 	"github.com/hnlxhzw/pitaya/metrics"
 	metricsmocks "github.com/hnlxhzw/pitaya/metrics/mocks"
 	connmock "github.com/hnlxhzw/pitaya/mocks"
 	"github.com/hnlxhzw/pitaya/protos"
 	"github.com/hnlxhzw/pitaya/route"
+	"github.com/hnlxhzw/pitaya/serialize"
 	"github.com/hnlxhzw/pitaya/serialize/json"
+	"github.com/hnlxhzw/pitaya/serialize/protobuf"
 	serializemocks "github.com/hnlxhzw/pitaya/serialize/mocks"
+	"github.com/hnlxhzw/pitaya/session"
+	sessionmocks "github.com/hnlxhzw/pitaya/session/mocks"
 )
 
 var (
@@ -105,6 +111,7 @@ func TestNewHandlerService(t *testing.T) {
 		remoteSvc,
 		messageEncoder,
 		mockMetricsReporters,
+		1,
 	)
 
 	assert.NotNil(t, svc)
@@ -117,12 +124,13 @@ func TestNewHandlerService(t *testing.T) {
 	assert.Equal(t, 10, svc.messagesBufferSize)
 	assert.Equal(t, sv, svc.server)
 	assert.Equal(t, remoteSvc, svc.remoteService)
-	assert.NotNil(t, svc.chLocalProcess)
-	assert.NotNil(t, svc.chRemoteProcess)
+	assert.Equal(t, 1, svc.dispatchNum)
+	assert.NotNil(t, svc.chLocalProcessMap)
+	assert.NotNil(t, svc.chRemoteProcessMap)
 }
 
 func TestHandlerServiceRegister(t *testing.T) {
-	svc := NewHandlerService(nil, nil, nil, nil, 0, 0, 0, 0, nil, nil, nil, nil)
+	svc := NewHandlerService(nil, nil, nil, nil, 0, 0, 0, 0, nil, nil, nil, nil, 1)
 	err := svc.Register(&MyComp{}, []component.Option{})
 	assert.NoError(t, err)
 	defer func() { handlers = make(map[string]*component.Handler, 0) }()
@@ -142,7 +150,7 @@ func TestHandlerServiceRegister(t *testing.T) {
 }
 
 func TestHandlerServiceRegisterFailsIfRegisterTwice(t *testing.T) {
-	svc := NewHandlerService(nil, nil, nil, nil, 0, 0, 0, 0, nil, nil, nil, nil)
+	svc := NewHandlerService(nil, nil, nil, nil, 0, 0, 0, 0, nil, nil, nil, nil, 1)
 	err := svc.Register(&MyComp{}, []component.Option{})
 	assert.NoError(t, err)
 	err = svc.Register(&MyComp{}, []component.Option{})
@@ -150,7 +158,7 @@ func TestHandlerServiceRegisterFailsIfRegisterTwice(t *testing.T) {
 }
 
 func TestHandlerServiceRegisterFailsIfNoHandlerMethods(t *testing.T) {
-	svc := NewHandlerService(nil, nil, nil, nil, 0, 0, 0, 0, nil, nil, nil, nil)
+	svc := NewHandlerService(nil, nil, nil, nil, 0, 0, 0, 0, nil, nil, nil, nil, 1)
 	err := svc.Register(&NoHandlerRemoteComp{}, []component.Option{})
 	assert.Equal(t, errors.New("type NoHandlerRemoteComp has no exported methods of handler type"), err)
 }
@@ -162,7 +170,7 @@ func TestHandlerServiceProcessMessage(t *testing.T) {
 		err   interface{}
 		local bool
 	}{
-		{"failed_decode", &message.Message{ID: 1, Route: "k.k.k.k"}, &protos.Error{Msg: "invalid route", Code: "PIT-400"}, false},
+		{"failed_decode", &message.Message{ID: 1, Route: "k.k.k.k"}, &protos.ClientError{ErrorCode: 400}, false},
 		{"local_process", &message.Message{ID: 1, Route: "k.k"}, nil, true},
 		{"remote_process", &message.Message{ID: 1, Route: "k.k.k"}, nil, false},
 	}
@@ -176,7 +184,10 @@ func TestHandlerServiceProcessMessage(t *testing.T) {
 
 			mockConn := connmock.NewMockPlayerConn(ctrl)
 			sv := &cluster.Server{}
-			svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, sv, &RemoteService{}, nil, nil)
+			svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, sv, &RemoteService{}, nil, nil, 1)
+			// the channels are created by Dispatch, there is a single one here
+			svc.chLocalProcessMap[0] = make(chan unhandledMessage, 1)
+			svc.chRemoteProcessMap[0] = make(chan unhandledMessage, 1)
 
 			if table.err != nil {
 				mockSerializer.EXPECT().Marshal(table.err).Return([]byte("err"), nil)
@@ -190,9 +201,9 @@ func TestHandlerServiceProcessMessage(t *testing.T) {
 			if table.err == nil {
 				var recvMsg unhandledMessage
 				if table.err == nil && table.local {
-					recvMsg = helpers.ShouldEventuallyReceive(t, svc.chLocalProcess).(unhandledMessage)
+					recvMsg = helpers.ShouldEventuallyReceive(t, svc.chLocalProcessMap[0]).(unhandledMessage)
 				} else if table.err == nil {
-					recvMsg = helpers.ShouldEventuallyReceive(t, svc.chRemoteProcess).(unhandledMessage)
+					recvMsg = helpers.ShouldEventuallyReceive(t, svc.chRemoteProcessMap[0]).(unhandledMessage)
 				}
 				assert.Equal(t, table.msg, recvMsg.msg)
 				assert.NotNil(t, pcontext.GetFromPropagateCtx(recvMsg.ctx, constants.StartTimeKey))
@@ -216,7 +227,7 @@ func TestHandlerServiceLocalProcess(t *testing.T) {
 		rt   *route.Route
 		err  interface{}
 	}{
-		{"process_handler_msg_err", &message.Message{}, route.NewRoute("bla", "bla", "bla"), &protos.Error{Msg: "pitaya/handler: bla.bla.bla not found", Code: "PIT-404"}},
+		{"process_handler_msg_err", &message.Message{}, route.NewRoute("bla", "bla", "bla"), &protos.ClientError{ErrorCode: 404}},
 		{"success", &message.Message{ID: 1, Data: []byte(`["ok"]`)}, rt, nil},
 	}
 	for _, table := range tables {
@@ -228,7 +239,7 @@ func TestHandlerServiceLocalProcess(t *testing.T) {
 			mockConn := connmock.NewMockPlayerConn(ctrl)
 			packetEncoder := codec.NewPomeloPacketEncoder()
 			messageEncoder := message.NewMessagesEncoder(false)
-			svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, nil, nil, nil, nil)
+			svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, nil, nil, nil, nil, 1)
 
 			if table.err != nil {
 				mockSerializer.EXPECT().Marshal(table.err)
@@ -247,9 +258,11 @@ func TestHandlerServiceProcessPacketHandshake(t *testing.T) {
 		packet       *packet.Packet
 		socketStatus int32
 		errStr       string
+		response     string
 	}{
-		{"invalid_handshake_data", &packet.Packet{Type: packet.Handshake, Data: []byte("asiodjasd")}, constants.StatusClosed, "Invalid handshake data"},
-		{"valid_handshake_data", &packet.Packet{Type: packet.Handshake, Data: []byte(`{"sys":{"platform":"mac"}}`)}, constants.StatusHandshake, ""},
+		{"invalid_handshake_data", &packet.Packet{Type: packet.Handshake, Data: []byte("asiodjasd")}, constants.StatusClosed, "Invalid handshake data", `"code":400`},
+		{"valid_handshake_data", &packet.Packet{Type: packet.Handshake, Data: []byte(`{"sys":{"platform":"mac"}}`)}, constants.StatusHandshake, "", "heartbeat"},
+		{"unknown_resume_token", &packet.Packet{Type: packet.Handshake, Data: []byte(`{"sys":{"platform":"mac","resumeToken":"unknown"}}`)}, constants.StatusClosed, constants.ErrHandoffNotFound.Error(), `"code":401`},
 	}
 	for _, table := range tables {
 		t.Run(table.name, func(t *testing.T) {
@@ -261,15 +274,12 @@ func TestHandlerServiceProcessPacketHandshake(t *testing.T) {
 			mockConn := connmock.NewMockPlayerConn(ctrl)
 			packetEncoder := codec.NewPomeloPacketEncoder()
 			messageEncoder := message.NewMessagesEncoder(false)
-			svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, nil, nil, nil, nil)
+			svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, nil, nil, nil, nil, 1)
 
-			mockConn.EXPECT().RemoteAddr().Return(&mockAddr{})
+			mockConn.EXPECT().RemoteAddr().Return(&mockAddr{}).AnyTimes()
 			mockConn.EXPECT().Write(gomock.Any()).Do(func(d []byte) {
-				assert.Contains(t, string(d), "heartbeat")
+				assert.Contains(t, string(d), table.response)
 			})
-			if table.errStr == "" {
-				mockConn.EXPECT().RemoteAddr().Return(&mockAddr{})
-			}
 
 			mockSerializer.EXPECT().GetName()
 			ag := agent.NewAgent(mockConn, nil, packetEncoder, mockSerializer, 1*time.Second, 1, nil, messageEncoder, nil)
@@ -286,6 +296,103 @@ func TestHandlerServiceProcessPacketHandshake(t *testing.T) {
 	}
 }
 
+func TestHandlerServiceProcessPacketHandshakeRejectedResume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rejectedErr := e.NewError(errors.New("banned"), e.ErrUnauthorizedCode.Desc, e.ErrUnauthorizedCode.ErrorCode)
+	// only rejects this test's handshakes, validators cannot be removed
+	session.OnHandshake(func(ctx context.Context, s *session.Session, data *session.HandshakeData, remoteAddr net.Addr) error {
+		if data.Sys.Platform == "rejected_resume" {
+			return rejectedErr
+		}
+		return nil
+	})
+
+	uid := uuid.New().String()
+	token := uuid.New().String()
+	session.AddHandoff(&session.HandoffData{UID: uid, Data: []byte(`{"hello":"test"}`), ResumeToken: token})
+
+	mockSerializer := serializemocks.NewMockSerializer(ctrl)
+	mockConn := connmock.NewMockPlayerConn(ctrl)
+	packetEncoder := codec.NewPomeloPacketEncoder()
+	messageEncoder := message.NewMessagesEncoder(false)
+	svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, nil, nil, nil, nil, 1)
+
+	mockConn.EXPECT().RemoteAddr().Return(&mockAddr{}).AnyTimes()
+	mockConn.EXPECT().Write(gomock.Any()).Do(func(d []byte) {
+		assert.Contains(t, string(d), `"code":401`)
+	})
+
+	mockSerializer.EXPECT().GetName()
+	ag := agent.NewAgent(mockConn, nil, packetEncoder, mockSerializer, 1*time.Second, 1, nil, messageEncoder, nil)
+
+	p := &packet.Packet{Type: packet.Handshake, Data: []byte(fmt.Sprintf(`{"sys":{"platform":"rejected_resume","resumeToken":"%s"}}`, token))}
+	err := svc.processPacket(ag, p)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "banned")
+	assert.Equal(t, constants.StatusClosed, ag.GetStatus())
+	assert.Equal(t, "", ag.Session.UID())
+	assert.Nil(t, session.GetSessionByUID(uid))
+
+	// the handed off session can still be resumed by an accepted handshake
+	entity := sessionmocks.NewMockNetworkEntity(ctrl)
+	entity.EXPECT().Close()
+	ss := session.New(entity, true)
+	defer ss.Close()
+	assert.NoError(t, ss.Resume(context.Background(), token))
+	assert.Equal(t, uid, ss.UID())
+}
+
+func TestHandlerServiceProcessPacketHandshakeSerializer(t *testing.T) {
+	serialize.Register(protobuf.NewSerializer())
+	defer serialize.Unregister("protobuf")
+	tables := []struct {
+		name         string
+		serializer   string
+		socketStatus int32
+		errStr       string
+		response     string
+	}{
+		{"default_serializer", "json", constants.StatusHandshake, "", "heartbeat"},
+		{"registered_serializer", "protobuf", constants.StatusHandshake, "", `"serializer":"protobuf"`},
+		{"unknown_serializer", "unknown", constants.StatusClosed, constants.ErrUnsupportedSerializer.Error(), `"code":400`},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockConn := connmock.NewMockPlayerConn(ctrl)
+			packetEncoder := codec.NewPomeloPacketEncoder()
+			messageEncoder := message.NewMessagesEncoder(false)
+			svc := NewHandlerService(nil, nil, nil, json.NewSerializer(), 1*time.Second, 1, 1, 1, nil, nil, nil, nil, 1)
+
+			mockConn.EXPECT().RemoteAddr().Return(&mockAddr{}).AnyTimes()
+			mockConn.EXPECT().Write(gomock.Any()).Do(func(d []byte) {
+				assert.Contains(t, string(d), table.response)
+			})
+
+			ag := agent.NewAgent(mockConn, nil, packetEncoder, json.NewSerializer(), 1*time.Second, 1, nil, messageEncoder, nil)
+
+			data := []byte(fmt.Sprintf(`{"sys":{"platform":"mac","serializer":"%s"}}`, table.serializer))
+			err := svc.processPacket(ag, &packet.Packet{Type: packet.Handshake, Data: data})
+			if table.errStr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), table.errStr)
+			}
+			assert.Equal(t, table.socketStatus, ag.GetStatus())
+			if table.serializer == "protobuf" {
+				assert.Equal(t, "protobuf", ag.Session.Serializer().GetName())
+			} else {
+				assert.Nil(t, ag.Session.Serializer())
+			}
+		})
+	}
+}
+
 func TestHandlerServiceProcessPacketHandshakeAck(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -293,7 +400,7 @@ func TestHandlerServiceProcessPacketHandshakeAck(t *testing.T) {
 	mockConn := connmock.NewMockPlayerConn(ctrl)
 	packetEncoder := codec.NewPomeloPacketEncoder()
 	messageEncoder := message.NewMessagesEncoder(false)
-	svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, nil, nil, nil, nil)
+	svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, nil, nil, nil, nil, 1)
 
 	mockConn.EXPECT().RemoteAddr().Return(&mockAddr{})
 	mockSerializer := serializemocks.NewMockSerializer(ctrl)
@@ -312,7 +419,7 @@ func TestHandlerServiceProcessPacketHeartbeat(t *testing.T) {
 	mockConn := connmock.NewMockPlayerConn(ctrl)
 	packetEncoder := codec.NewPomeloPacketEncoder()
 	messageEncoder := message.NewMessagesEncoder(false)
-	svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, nil, nil, nil, nil)
+	svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, nil, nil, nil, nil, 1)
 
 	mockConn.EXPECT().RemoteAddr().Return(&mockAddr{})
 	mockSerializer := serializemocks.NewMockSerializer(ctrl)
@@ -350,7 +457,7 @@ func TestHandlerServiceProcessPacketData(t *testing.T) {
 			mockConn := connmock.NewMockPlayerConn(ctrl)
 			packetEncoder := codec.NewPomeloPacketEncoder()
 			messageEncoder := message.NewMessagesEncoder(false)
-			svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, &cluster.Server{}, nil, nil, nil)
+			svc := NewHandlerService(nil, nil, nil, nil, 1*time.Second, 1, 1, 1, &cluster.Server{}, nil, nil, nil, 1)
 			if table.socketStatus < constants.StatusWorking {
 				mockConn.EXPECT().RemoteAddr().Return(&mockAddr{})
 			}
@@ -359,7 +466,7 @@ func TestHandlerServiceProcessPacketData(t *testing.T) {
 			ag.SetStatus(table.socketStatus)
 
 			if table.errStr == "" {
-				mockSerializer.EXPECT().Marshal(&protos.Error{Code: "PIT-400", Msg: route.ErrRouteFieldCantEmpty.Error()})
+				mockSerializer.EXPECT().Marshal(&protos.ClientError{ErrorCode: 400})
 			}
 			err := svc.processPacket(ag, table.packet)
 			if table.errStr != "" {
@@ -380,7 +487,7 @@ func TestHandlerServiceHandle(t *testing.T) {
 	packetEncoder := codec.NewPomeloPacketEncoder()
 	packetDecoder := codec.NewPomeloPacketDecoder()
 	messageEncoder := message.NewMessagesEncoder(false)
-	svc := NewHandlerService(nil, packetDecoder, packetEncoder, mockSerializer, 1*time.Second, 1, 1, 1, nil, nil, messageEncoder, nil)
+	svc := NewHandlerService(nil, packetDecoder, packetEncoder, mockSerializer, 1*time.Second, 1, 1, 1, nil, nil, messageEncoder, nil, 1)
 	var wg sync.WaitGroup
 
 	handshakeBuffer := `{"sys":{"platform":"mac","libVersion":"0.3.5-release","clientBuildNumber":"20","clientVersion":"2.1"},"user":{"age":30}}`
@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync/atomic"
 
 	"github.com/golang/protobuf/proto"
 
@@ -48,6 +49,8 @@ import (
 
 // RemoteService struct
 type RemoteService struct {
+	incomingRPCs           int64 // number of rpcs being processed, first to be 64-bit aligned
+	outgoingRPCs           int64 // number of rpcs waiting for a response
 	rpcServer              cluster.RPCServer
 	serviceDiscovery       cluster.ServiceDiscovery
 	serializer             serialize.Serializer
@@ -138,6 +141,9 @@ func (r *RemoteService) AddRemoteBindingListener(bindingListener cluster.RemoteB
 func (r *RemoteService) Call(ctx context.Context, req *protos.Request) (*protos.Response, error) {
 	defer util.AutoRecover("Call")
 
+	atomic.AddInt64(&r.incomingRPCs, 1)
+	defer atomic.AddInt64(&r.incomingRPCs, -1)
+
 	c, err := util.GetContextFromRequest(req, r.server.ID)
 	c = util.StartSpanFromRequest(c, r.server.ID, req.GetMsg().GetRoute())
 	var res *protos.Response
@@ -179,7 +185,11 @@ func (r *RemoteService) PushToUser(ctx context.Context, push *protos.Push) (*pro
 	logger.Log.Debugf("sending push to user %s: %v", push.GetUid(), string(push.Data))
 	s := session.GetSessionByUID(push.GetUid())
 	if s != nil {
-		err := s.Push(push.Route, push.Data)
+		data, err := s.PushData(push.Route, push.Data)
+		if err != nil {
+			return nil, err
+		}
+		err = s.Push(push.Route, data)
 		if err != nil {
 			return nil, err
 		}
@@ -340,9 +350,10 @@ func (r *RemoteService) handleRPCUser(ctx context.Context, req *protos.Request,
 		}
 		return response
 	}
-	params := []reflect.Value{remote.Receiver, reflect.ValueOf(ctx)}
+	var arg interface{}
 	if remote.HasArgs {
-		arg, err := unmarshalRemoteArg(remote, req.GetMsg().GetData())
+		var err error
+		arg, err = unmarshalRemoteArg(remote, req.GetMsg().GetData())
 		if err != nil {
 			response := &protos.Response{
 				Error: &protos.Error{
@@ -353,10 +364,32 @@ func (r *RemoteService) handleRPCUser(ctx context.Context, req *protos.Request,
 			}
 			return response
 		}
+	}
+
+	// remotes only execute the scoped pipelines, the global one is
+	// only executed for handlers
+	scoped := scopedPipeline(rt, remote.Pipeline)
+	ctx, arg, err, errorCode := executeBeforeHandlers(ctx, arg, scoped.Before)
+	if err != nil {
+		pErr := e.NewError(err, "BeforePipeline", errorCode)
+		response := &protos.Response{
+			Error: &protos.Error{
+				Code:      pErr.Code,
+				ErrorCode: pErr.ErrorCode,
+				Msg:       pErr.Message,
+				Metadata:  pErr.Metadata,
+			},
+		}
+		return response
+	}
+
+	params := []reflect.Value{remote.Receiver, reflect.ValueOf(ctx)}
+	if remote.HasArgs {
 		params = append(params, reflect.ValueOf(arg))
 	}
 
 	ret, err := util.Pcall(remote.Method, params)
+	ret, err, errorCode = executeAfterHandlers(ctx, ret, err, 0, scoped.After)
 	if err != nil {
 		response := &protos.Response{
 			Error: &protos.Error{
@@ -365,6 +398,10 @@ func (r *RemoteService) handleRPCUser(ctx context.Context, req *protos.Request,
 				Msg:       err.Error(),
 			},
 		}
+		if errorCode != 0 {
+			response.Error.Code = "AfterPipeline"
+			response.Error.ErrorCode = errorCode
+		}
 		if val, ok := err.(*e.Error); ok {
 			response.Error.Code = val.Code
 			if val.Metadata != nil {
@@ -429,7 +466,11 @@ func (r *RemoteService) handleRPCSys(ctx context.Context, req *protos.Request, r
 		return response
 	}
 
-	ret, err := processHandlerMessage(ctx, rt, r.serializer, a.Session, req.GetMsg().GetData(), req.GetMsg().GetType(), true)
+	serializer := r.serializer
+	if negotiated := a.Session.Serializer(); negotiated != nil {
+		serializer = negotiated
+	}
+	ret, err := processHandlerMessage(ctx, rt, serializer, a.Session, req.GetMsg().GetData(), req.GetMsg().GetType(), true)
 	if err != nil {
 		logger.Log.Warnf(err.Error())
 		response = &protos.Response{
@@ -472,7 +513,9 @@ func (r *RemoteService) remoteCall(
 		}
 	}
 
+	atomic.AddInt64(&r.outgoingRPCs, 1)
 	res, err := r.rpcClient.Call(ctx, rpcType, route, session, msg, target)
+	atomic.AddInt64(&r.outgoingRPCs, -1)
 	if err != nil {
 		if err, ok := err.(*e.Error); ok {
 			return nil, e.NewError(
@@ -488,6 +531,12 @@ func (r *RemoteService) remoteCall(
 	return res, err
 }
 
+// Concurrency returns the number of rpcs received that are being processed
+// and the number of rpcs sent that are waiting for a response
+func (r *RemoteService) Concurrency() (incoming, outgoing int64) {
+	return atomic.LoadInt64(&r.incomingRPCs), atomic.LoadInt64(&r.outgoingRPCs)
+}
+
 // DumpServices outputs all registered services
 func (r *RemoteService) DumpServices() {
 	for name := range remotes {
@@ -43,10 +43,13 @@ import (
 	"github.com/hnlxhzw/pitaya/constants"
 	e "github.com/hnlxhzw/pitaya/errors"
 	connmock "github.com/hnlxhzw/pitaya/mocks"
+	"github.com/hnlxhzw/pitaya/pipeline"
 	"github.com/hnlxhzw/pitaya/protos"
 	"github.com/hnlxhzw/pitaya/protos/test"
 	"github.com/hnlxhzw/pitaya/route"
 	"github.com/hnlxhzw/pitaya/router"
+	"github.com/hnlxhzw/pitaya/serialize"
+	"github.com/hnlxhzw/pitaya/serialize/json"
 	serializemocks "github.com/hnlxhzw/pitaya/serialize/mocks"
 	"github.com/hnlxhzw/pitaya/session"
 	sessionmocks "github.com/hnlxhzw/pitaya/session/mocks"
@@ -158,6 +161,8 @@ func TestRemoteServicePushToUser(t *testing.T) {
 	svc := NewRemoteService(nil, nil, nil, nil, nil, nil, nil, nil)
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
+	serialize.Register(json.NewSerializer())
+	defer serialize.Unregister("json")
 
 	mockNetEntity := sessionmocks.NewMockNetworkEntity(ctrl)
 	tables := []struct {
@@ -177,6 +182,11 @@ func TestRemoteServicePushToUser(t *testing.T) {
 			Uid:   "uid2",
 			Data:  []byte{0x01},
 		}, constants.ErrSessionNotFound},
+		{"undecodable_push", "uid3", session.New(mockNetEntity, true), &protos.Push{
+			Route: "sv.svc.mth",
+			Uid:   "uid3",
+			Data:  []byte{0x01},
+		}, constants.ErrPushNotDecoded},
 	}
 
 	for _, table := range tables {
@@ -184,7 +194,12 @@ func TestRemoteServicePushToUser(t *testing.T) {
 			if table.sess != nil {
 				err := table.sess.Bind(context.Background(), table.uid)
 				assert.NoError(t, err)
-				mockNetEntity.EXPECT().Push(table.p.Route, table.p.Data)
+				if table.err != nil {
+					// the session negotiated a serializer and no push decoder is set
+					assert.NoError(t, table.sess.Set(constants.SessionSerializerKey, "json"))
+				} else {
+					mockNetEntity.EXPECT().Push(table.p.Route, table.p.Data)
+				}
 			}
 			_, err := svc.PushToUser(context.Background(), table.p)
 			if table.err != nil {
@@ -349,6 +364,23 @@ func TestRemoteServiceHandleRPCUser(t *testing.T) {
 	}
 }
 
+func TestRemoteServiceHandleRPCUserAfterPipelineError(t *testing.T) {
+	tObj := &MyComp{}
+	m, ok := reflect.TypeOf(tObj).MethodByName("Remote2")
+	assert.True(t, ok)
+	rt := route.NewRoute("", uuid.New().String(), uuid.New().String())
+	chain := pipeline.Chain{}
+	chain.PushAfter(func(ctx context.Context, out interface{}, err error) (interface{}, error, int32) {
+		return nil, errors.New("after err"), 418
+	})
+	remotes[rt.Short()] = &component.Remote{Receiver: reflect.ValueOf(tObj), Method: m, HasArgs: m.Type.NumIn() > 2, Pipeline: chain}
+	defer delete(remotes, rt.Short())
+
+	svc := NewRemoteService(nil, nil, nil, nil, nil, nil, nil, &cluster.Server{})
+	res := svc.handleRPCUser(context.Background(), &protos.Request{Msg: &protos.Msg{}}, rt)
+	assert.Equal(t, &protos.Error{Code: "AfterPipeline", ErrorCode: 418, Msg: "after err"}, res.Error)
+}
+
 func TestRemoteServiceHandleRPCSys(t *testing.T) {
 	tObj := &TestType{}
 	m, ok := reflect.TypeOf(tObj).MethodByName("HandlerPointerRaw")
@@ -0,0 +1,79 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package service
+
+import (
+	"time"
+
+	"github.com/hnlxhzw/pitaya/metrics"
+	"github.com/hnlxhzw/pitaya/timer"
+)
+
+// ReportSaturationMetrics periodically reports the depth of the dispatch
+// and timer channels and the concurrency of the remote service
+func (h *HandlerService) ReportSaturationMetrics(period time.Duration) {
+	for {
+		h.reportDispatchQueues()
+		h.reportTimerQueues()
+		h.reportRemoteServiceConcurrency()
+
+		time.Sleep(period)
+	}
+}
+
+func (h *HandlerService) reportDispatchQueues() {
+	h.rw.RLock()
+	defer h.rw.RUnlock()
+
+	for dispatch, ch := range h.chLocalProcessMap {
+		metrics.ReportDispatchQueueDepth(h.metricsReporters, dispatch, "local", len(ch))
+	}
+	for dispatch, ch := range h.chRemoteProcessMap {
+		metrics.ReportDispatchQueueDepth(h.metricsReporters, dispatch, "remote", len(ch))
+	}
+}
+
+func (h *HandlerService) reportTimerQueues() {
+	for _, r := range h.metricsReporters {
+		r.ReportGauge(metrics.TimerQueueDepth, map[string]string{
+			"channel": "created",
+		}, float64(len(timer.Manager.ChCreatedTimer)))
+		r.ReportGauge(metrics.TimerQueueDepth, map[string]string{
+			"channel": "closing",
+		}, float64(len(timer.Manager.ChClosingTimer)))
+	}
+}
+
+func (h *HandlerService) reportRemoteServiceConcurrency() {
+	if h.remoteService == nil {
+		return
+	}
+
+	incoming, outgoing := h.remoteService.Concurrency()
+	for _, r := range h.metricsReporters {
+		r.ReportGauge(metrics.RemoteServiceConcurrency, map[string]string{
+			"direction": "incoming",
+		}, float64(incoming))
+		r.ReportGauge(metrics.RemoteServiceConcurrency, map[string]string{
+			"direction": "outgoing",
+		}, float64(outgoing))
+	}
+}
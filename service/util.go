@@ -96,33 +96,58 @@ func getMsgType(msgTypeIface interface{}) (message.Type, error) {
 	return msgType, nil
 }
 
-func executeBeforePipeline(ctx context.Context, data interface{}) (context.Context, interface{}, error, int32) {
+// executeBeforePipeline executes the global pipeline followed by the
+// scoped ones, in order
+func executeBeforePipeline(ctx context.Context, data interface{}, scoped ...[]pipeline.HandlerTempl) (context.Context, interface{}, error, int32) {
+	ctx, res, err, errorCode := executeBeforeHandlers(ctx, data, pipeline.BeforeHandler.Handlers)
+	for _, handlers := range scoped {
+		if err != nil {
+			break
+		}
+		ctx, res, err, errorCode = executeBeforeHandlers(ctx, res, handlers)
+	}
+	return ctx, res, err, errorCode
+}
+
+func executeBeforeHandlers(ctx context.Context, data interface{}, handlers []pipeline.HandlerTempl) (context.Context, interface{}, error, int32) {
 	var err error
 	var errorCode int32
 	res := data
-	if len(pipeline.BeforeHandler.Handlers) > 0 {
-		for _, h := range pipeline.BeforeHandler.Handlers {
-			ctx, res, err, errorCode = h(ctx, res)
-			if err != nil {
-				logger.Log.Debugf("pitaya/handler: broken pipeline: %s", err.Error())
-				return ctx, res, err, errorCode
-			}
+	for _, h := range handlers {
+		ctx, res, err, errorCode = h(ctx, res)
+		if err != nil {
+			logger.Log.Debugf("pitaya/handler: broken pipeline: %s", err.Error())
+			return ctx, res, err, errorCode
 		}
 	}
 	return ctx, res, nil, 0
 }
 
-func executeAfterPipeline(ctx context.Context, res interface{}, err error) (interface{}, error, int32) {
+// executeAfterPipeline executes the scoped pipelines, in order, followed by
+// the global one
+func executeAfterPipeline(ctx context.Context, res interface{}, err error, scoped ...[]pipeline.AfterHandlerTempl) (interface{}, error, int32) {
 	var errorCode int32
 	ret := res
-	if len(pipeline.AfterHandler.Handlers) > 0 {
-		for _, h := range pipeline.AfterHandler.Handlers {
-			ret, err, errorCode = h(ctx, ret, err)
-		}
+	for _, handlers := range scoped {
+		ret, err, errorCode = executeAfterHandlers(ctx, ret, err, errorCode, handlers)
+	}
+	return executeAfterHandlers(ctx, ret, err, errorCode, pipeline.AfterHandler.Handlers)
+}
+
+func executeAfterHandlers(ctx context.Context, res interface{}, err error, errorCode int32, handlers []pipeline.AfterHandlerTempl) (interface{}, error, int32) {
+	ret := res
+	for _, h := range handlers {
+		ret, err, errorCode = h(ctx, ret, err)
 	}
 	return ret, err, errorCode
 }
 
+// scopedPipeline returns the pipeline of the route patterns matching the
+// route wrapping the pipeline of the component and method
+func scopedPipeline(rt *route.Route, methodPipeline pipeline.Chain) pipeline.Chain {
+	return pipeline.RoutePipelines.Match(rt.Short()).Then(methodPipeline)
+}
+
 func serializeReturn(ser serialize.Serializer, ret interface{}) ([]byte, error) {
 	res, err := util.SerializeOrRaw(ser, ret)
 	if err != nil {
@@ -156,6 +181,10 @@ func processHandlerMessage(
 		return nil, e.NewError(err, e.ErrNotFoundCode.Desc, e.ErrNotFoundCode.ErrorCode)
 	}
 
+	if h.RequireBoundSession && session.UID() == "" {
+		return nil, e.NewError(constants.ErrNoUIDBind, e.ErrUnauthorizedCode.Desc, e.ErrUnauthorizedCode.ErrorCode)
+	}
+
 	msgType, err := getMsgType(msgTypeIface)
 	if err != nil {
 		return nil, e.NewError(err, e.ErrInternalCode.Desc, e.ErrInternalCode.ErrorCode)
@@ -176,7 +205,8 @@ func processHandlerMessage(
 		return nil, e.NewError(err, e.ErrBadRequestCode.Desc, e.ErrBadRequestCode.ErrorCode)
 	}
 	var errorCode int32
-	ctx, arg, err, errorCode = executeBeforePipeline(ctx, arg)
+	scoped := scopedPipeline(rt, h.Pipeline)
+	ctx, arg, err, errorCode = executeBeforePipeline(ctx, arg, scoped.Before)
 	if err != nil {
 		return nil, e.NewError(err, "BeforePipeline", errorCode)
 	}
@@ -196,7 +226,7 @@ func processHandlerMessage(
 		resp = []byte("ack")
 	}
 
-	resp, err, errorCode = executeAfterPipeline(ctx, resp, err)
+	resp, err, errorCode = executeAfterPipeline(ctx, resp, err, scoped.After)
 	if err != nil {
 		return nil, e.NewError(err, "AfterPipeline", errorCode)
 	}
@@ -164,7 +164,7 @@ func TestUnmarshalRemoteArg(t *testing.T) {
 
 			arg, err := unmarshalRemoteArg(remote, payload)
 			assert.NoError(t, err)
-			assert.Equal(t, table.arg, arg)
+			assert.True(t, proto.Equal(table.arg, arg.(proto.Message)))
 		})
 	}
 }
@@ -207,7 +207,7 @@ func TestGetMsgType(t *testing.T) {
 
 func TestExecuteBeforePipelineEmpty(t *testing.T) {
 	expected := []byte("ok")
-	_, res, err := executeBeforePipeline(nil, expected)
+	_, res, err, _ := executeBeforePipeline(nil, expected)
 	assert.NoError(t, err)
 	assert.Equal(t, expected, res)
 }
@@ -217,21 +217,21 @@ func TestExecuteBeforePipelineSuccess(t *testing.T) {
 	data := []byte("ok")
 	expected1 := []byte("oh noes 1")
 	expected2 := []byte("oh noes 2")
-	before1 := func(ctx context.Context, in interface{}) (context.Context, interface{}, error) {
+	before1 := func(ctx context.Context, in interface{}) (context.Context, interface{}, error, int32) {
 		assert.Equal(t, c, ctx)
 		assert.Equal(t, data, in)
-		return ctx, expected1, nil
+		return ctx, expected1, nil, 0
 	}
-	before2 := func(ctx context.Context, in interface{}) (context.Context, interface{}, error) {
+	before2 := func(ctx context.Context, in interface{}) (context.Context, interface{}, error, int32) {
 		assert.Equal(t, c, ctx)
 		assert.Equal(t, expected1, in)
-		return ctx, expected2, nil
+		return ctx, expected2, nil, 0
 	}
 	pipeline.BeforeHandler.PushBack(before1)
 	pipeline.BeforeHandler.PushBack(before2)
 	defer pipeline.BeforeHandler.Clear()
 
-	_, res, err := executeBeforePipeline(c, data)
+	_, res, err, _ := executeBeforePipeline(c, data)
 	assert.NoError(t, err)
 	assert.Equal(t, expected2, res)
 }
@@ -239,20 +239,20 @@ func TestExecuteBeforePipelineSuccess(t *testing.T) {
 func TestExecuteBeforePipelineError(t *testing.T) {
 	c := context.Background()
 	expected := errors.New("oh noes")
-	before := func(ctx context.Context, in interface{}) (context.Context, interface{}, error) {
+	before := func(ctx context.Context, in interface{}) (context.Context, interface{}, error, int32) {
 		assert.Equal(t, c, ctx)
-		return ctx, nil, expected
+		return ctx, nil, expected, 0
 	}
 	pipeline.BeforeHandler.PushFront(before)
 	defer pipeline.BeforeHandler.Clear()
 
-	_, _, err := executeBeforePipeline(c, []byte("ok"))
+	_, _, err, _ := executeBeforePipeline(c, []byte("ok"))
 	assert.Equal(t, expected, err)
 }
 
 func TestExecuteAfterPipelineEmpty(t *testing.T) {
 	expected := []byte("whatever")
-	res, err := executeAfterPipeline(nil, expected, nil)
+	res, err, _ := executeAfterPipeline(nil, expected, nil)
 	assert.Equal(t, expected, res)
 	assert.Nil(t, err)
 }
@@ -264,37 +264,37 @@ func TestExecuteAfterPipelineSuccess(t *testing.T) {
 	expected2 := []byte("oh noes 2")
 	err0 := errors.New("start with this")
 	err1 := errors.New("send this error")
-	after1 := func(ctx context.Context, out interface{}, err error) (interface{}, error) {
+	after1 := func(ctx context.Context, out interface{}, err error) (interface{}, error, int32) {
 		assert.Equal(t, c, ctx)
 		assert.Equal(t, data, out)
 		assert.Equal(t, err0, err)
-		return expected1, err1
+		return expected1, err1, 0
 	}
-	after2 := func(ctx context.Context, out interface{}, err error) (interface{}, error) {
+	after2 := func(ctx context.Context, out interface{}, err error) (interface{}, error, int32) {
 		assert.Equal(t, c, ctx)
 		assert.Equal(t, expected1, out)
 		assert.Equal(t, err1, err)
-		return expected2, nil
+		return expected2, nil, 0
 	}
 	pipeline.AfterHandler.PushBack(after1)
 	pipeline.AfterHandler.PushBack(after2)
 	defer pipeline.AfterHandler.Clear()
 
-	res, err := executeAfterPipeline(c, []byte("ok"), err0)
+	res, err, _ := executeAfterPipeline(c, []byte("ok"), err0)
 	assert.Equal(t, expected2, res)
 	assert.Nil(t, err)
 }
 
 func TestExecuteAfterPipelineError(t *testing.T) {
 	c := context.Background()
-	after := func(ctx context.Context, out interface{}, err error) (interface{}, error) {
+	after := func(ctx context.Context, out interface{}, err error) (interface{}, error, int32) {
 		assert.Equal(t, c, ctx)
-		return nil, errors.New("oh noes")
+		return nil, errors.New("oh noes"), 0
 	}
 	pipeline.AfterHandler.PushFront(after)
 	defer pipeline.AfterHandler.Clear()
 
-	res, err := executeAfterPipeline(c, []byte("ok"), nil)
+	res, err, _ := executeAfterPipeline(c, []byte("ok"), nil)
 	assert.Nil(t, res)
 	assert.Equal(t, errors.New("oh noes"), err)
 }
@@ -368,11 +368,11 @@ func TestProcessHandlerMessage(t *testing.T) {
 		out          []byte
 		err          error
 	}{
-		{"invalid_route", route.NewRoute("", "no", "no"), nil, nil, nil, message.Request, nil, false, nil, e.NewError(errors.New("pitaya/handler: no.no not found"), e.ErrNotFoundCode)},
-		{"invalid_msg_type", rt, nil, nil, nil, message.Request, nil, false, nil, e.NewError(errInvalidMsg, e.ErrInternalCode)},
-		{"request_on_notify", rt, nil, nil, nil, message.Notify, message.Request, false, nil, e.NewError(constants.ErrRequestOnNotify, e.ErrBadRequestCode)},
-		{"failed_handle_args_unmarshal", rt, nil, errors.New("some error"), &test.SomeStruct{}, message.Request, message.Request, false, nil, e.NewError(errors.New("some error"), e.ErrBadRequestCode)},
-		{"failed_pcall", rtErr, nil, nil, &test.SomeStruct{A: 1, B: "ok"}, message.Request, message.Request, false, nil, errors.New("HandlerPointerErr")},
+		{"invalid_route", route.NewRoute("", "no", "no"), nil, nil, nil, message.Request, nil, false, nil, e.NewError(errors.New("pitaya/handler: no.no not found"), e.ErrNotFoundCode.Desc, e.ErrNotFoundCode.ErrorCode)},
+		{"invalid_msg_type", rt, nil, nil, nil, message.Request, nil, false, nil, e.NewError(errInvalidMsg, e.ErrInternalCode.Desc, e.ErrInternalCode.ErrorCode)},
+		{"request_on_notify", rt, nil, nil, nil, message.Notify, message.Request, false, nil, e.NewError(constants.ErrRequestOnNotify, e.ErrBadRequestCode.Desc, e.ErrBadRequestCode.ErrorCode)},
+		{"failed_handle_args_unmarshal", rt, nil, errors.New("some error"), &test.SomeStruct{}, message.Request, message.Request, false, nil, e.NewError(errors.New("some error"), e.ErrBadRequestCode.Desc, e.ErrBadRequestCode.ErrorCode)},
+		{"failed_pcall", rtErr, nil, nil, &test.SomeStruct{A: 1, B: "ok"}, message.Request, message.Request, false, nil, e.NewError(errors.New("HandlerPointerErr"), "AfterPipeline", 0)},
 		{"failed_serialize_return", rtSt, errors.New("ser ret error"), nil, &test.SomeStruct{A: 1, B: "ok"}, message.Request, message.Request, false, []byte("failed"), nil},
 		{"ok", rt, nil, nil, &test.SomeStruct{}, message.Request, message.Request, false, []byte("ok"), nil},
 		{"notify_on_request", rt, nil, nil, &test.SomeStruct{}, message.Request, message.Notify, false, []byte("ok"), nil},
@@ -408,8 +408,8 @@ func TestProcessHandlerMessageBrokenBeforePipeline(t *testing.T) {
 	handlers[rt.Short()] = &component.Handler{}
 	defer func() { delete(handlers, rt.Short()) }()
 	expected := errors.New("oh noes")
-	before := func(ctx context.Context, in interface{}) (context.Context, interface{}, error) {
-		return ctx, nil, expected
+	before := func(ctx context.Context, in interface{}) (context.Context, interface{}, error, int32) {
+		return ctx, nil, expected, 0
 	}
 	pipeline.BeforeHandler.PushFront(before)
 	defer pipeline.BeforeHandler.Clear()
@@ -417,7 +417,7 @@ func TestProcessHandlerMessageBrokenBeforePipeline(t *testing.T) {
 	ss := session.New(nil, false)
 	out, err := processHandlerMessage(nil, rt, nil, ss, nil, message.Request, false)
 	assert.Nil(t, out)
-	assert.Equal(t, expected, err)
+	assert.Equal(t, e.NewError(expected, "BeforePipeline", 0), err)
 }
 
 func TestProcessHandlerMessageBrokenAfterPipeline(t *testing.T) {
@@ -429,8 +429,8 @@ func TestProcessHandlerMessageBrokenAfterPipeline(t *testing.T) {
 	handlers[rt.Short()] = &component.Handler{Receiver: reflect.ValueOf(tObj), Method: m, Type: m.Type.In(2)}
 	defer func() { delete(handlers, rt.Short()) }()
 
-	after := func(ctx context.Context, out interface{}, err error) (interface{}, error) {
-		return nil, errors.New("oh noes")
+	after := func(ctx context.Context, out interface{}, err error) (interface{}, error, int32) {
+		return nil, errors.New("oh noes"), 0
 	}
 	pipeline.AfterHandler.PushFront(after)
 	defer pipeline.AfterHandler.Clear()
@@ -447,5 +447,5 @@ func TestProcessHandlerMessageBrokenAfterPipeline(t *testing.T) {
 
 	out, err := processHandlerMessage(nil, rt, mockSerializer, ss, nil, message.Request, false)
 	assert.Nil(t, out)
-	assert.Equal(t, errors.New("oh noes"), err)
+	assert.Equal(t, e.NewError(errors.New("oh noes"), "AfterPipeline", 0), err)
 }
@@ -0,0 +1,109 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package session
+
+import (
+	"sync"
+
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+// sessionsByGroup indexes the frontend sessions by the groups stored in their
+// data under constants.SessionGroupsKey, allowing group broadcasts to be
+// fanned out locally
+var (
+	sessionsByGroup   = make(map[string]map[int64]*Session)
+	sessionsByGroupMu sync.RWMutex
+)
+
+// GetSessionsByGroup returns the local sessions that joined the group
+func GetSessionsByGroup(groupName string) []*Session {
+	sessionsByGroupMu.RLock()
+	defer sessionsByGroupMu.RUnlock()
+
+	sessions := make([]*Session, 0, len(sessionsByGroup[groupName]))
+	for _, s := range sessionsByGroup[groupName] {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// Groups returns the groups stored in the session data under constants.SessionGroupsKey
+func (s *Session) Groups() []string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.groupsFromData()
+}
+
+func (s *Session) groupsFromData() []string {
+	var groupNames []string
+	switch v := s.data[constants.SessionGroupsKey].(type) {
+	case []string:
+		groupNames = append(groupNames, v...)
+	case []interface{}:
+		// session data pushed between servers is decoded from json
+		for _, groupName := range v {
+			if name, ok := groupName.(string); ok {
+				groupNames = append(groupNames, name)
+			}
+		}
+	}
+	return groupNames
+}
+
+// updateGroupIndex must be called with the session locked, whenever its data changes
+func (s *Session) updateGroupIndex(closed bool) {
+	if !s.IsFrontend {
+		return
+	}
+
+	var groupNames []string
+	if !closed {
+		groupNames = s.groupsFromData()
+	}
+	if len(groupNames) == 0 && len(s.groups) == 0 {
+		return
+	}
+
+	current := make(map[string]bool, len(groupNames))
+	for _, name := range groupNames {
+		current[name] = true
+	}
+
+	sessionsByGroupMu.Lock()
+	defer sessionsByGroupMu.Unlock()
+
+	for _, name := range s.groups {
+		if !current[name] {
+			delete(sessionsByGroup[name], s.id)
+			if len(sessionsByGroup[name]) == 0 {
+				delete(sessionsByGroup, name)
+			}
+		}
+	}
+	for name := range current {
+		if sessionsByGroup[name] == nil {
+			sessionsByGroup[name] = make(map[int64]*Session)
+		}
+		sessionsByGroup[name][s.id] = s
+	}
+	s.groups = groupNames
+}
@@ -0,0 +1,82 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package session
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/session/mocks"
+)
+
+func TestSessionGroups(t *testing.T) {
+	t.Parallel()
+	tables := []struct {
+		name   string
+		data   map[string]interface{}
+		groups []string
+	}{
+		{"no_groups", map[string]interface{}{}, nil},
+		{"string_slice", map[string]interface{}{constants.SessionGroupsKey: []string{"g1", "g2"}}, []string{"g1", "g2"}},
+		{"interface_slice", map[string]interface{}{constants.SessionGroupsKey: []interface{}{"g1", 2}}, []string{"g1"}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			ss := New(nil, false)
+			err := ss.SetData(table.data)
+			assert.NoError(t, err)
+			assert.Equal(t, table.groups, ss.Groups())
+		})
+	}
+}
+
+func TestGetSessionsByGroup(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockEntity := mocks.NewMockNetworkEntity(ctrl)
+	ss := New(mockEntity, true)
+
+	err := ss.Set(constants.SessionGroupsKey, []string{"indexGroup1", "indexGroup2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*Session{ss}, GetSessionsByGroup("indexGroup1"))
+	assert.Equal(t, []*Session{ss}, GetSessionsByGroup("indexGroup2"))
+
+	err = ss.Set(constants.SessionGroupsKey, []string{"indexGroup2"})
+	assert.NoError(t, err)
+	assert.Empty(t, GetSessionsByGroup("indexGroup1"))
+	assert.Equal(t, []*Session{ss}, GetSessionsByGroup("indexGroup2"))
+
+	mockEntity.EXPECT().Close()
+	ss.Close()
+	assert.Empty(t, GetSessionsByGroup("indexGroup2"))
+}
+
+func TestGetSessionsByGroupIgnoresBackendSessions(t *testing.T) {
+	t.Parallel()
+	ss := New(nil, false)
+	err := ss.Set(constants.SessionGroupsKey, []string{"backendIndexGroup"})
+	assert.NoError(t, err)
+	assert.Empty(t, GetSessionsByGroup("backendIndexGroup"))
+}
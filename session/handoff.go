@@ -0,0 +1,177 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+// HandoffData is the state transferred when a session moves to another
+// frontend server. The data field holds the session data already encoded.
+type HandoffData struct {
+	UID           string         `json:"uid"`
+	Data          []byte         `json:"data,omitempty"`
+	HandshakeData *HandshakeData `json:"handshakeData,omitempty"`
+	ResumeToken   string         `json:"resumeToken"`
+}
+
+// Redirect is the payload pushed to a client on constants.RedirectRoute,
+// telling it which frontend to reconnect to and how to resume its session
+type Redirect struct {
+	Address     string `json:"address"`
+	ResumeToken string `json:"resumeToken"`
+}
+
+type pendingHandoff struct {
+	data      *HandoffData
+	expiresAt time.Time
+}
+
+var (
+	handoffTimeout    = time.Minute
+	pendingHandoffs   = make(map[string]*pendingHandoff)
+	pendingHandoffsMu sync.Mutex
+)
+
+// SetHandoffTimeout sets how long a session received from another frontend
+// waits for its client to reconnect before being discarded
+func SetHandoffTimeout(timeout time.Duration) {
+	pendingHandoffsMu.Lock()
+	defer pendingHandoffsMu.Unlock()
+
+	handoffTimeout = timeout
+}
+
+// AddHandoff stores a session handed off by another frontend until its
+// client reconnects using the resume token
+func AddHandoff(data *HandoffData) {
+	pendingHandoffsMu.Lock()
+	defer pendingHandoffsMu.Unlock()
+
+	now := time.Now()
+	for token, p := range pendingHandoffs {
+		if now.After(p.expiresAt) {
+			delete(pendingHandoffs, token)
+		}
+	}
+	pendingHandoffs[data.ResumeToken] = &pendingHandoff{
+		data:      data,
+		expiresAt: now.Add(handoffTimeout),
+	}
+}
+
+func claimHandoff(resumeToken string) *HandoffData {
+	pendingHandoffsMu.Lock()
+	defer pendingHandoffsMu.Unlock()
+
+	p, ok := pendingHandoffs[resumeToken]
+	if !ok {
+		return nil
+	}
+	delete(pendingHandoffs, resumeToken)
+	if time.Now().After(p.expiresAt) {
+		return nil
+	}
+	return p.data
+}
+
+// GetHandoffData returns the state needed to resume this session on another frontend
+func (s *Session) GetHandoffData(resumeToken string) *HandoffData {
+	s.RLock()
+	defer s.RUnlock()
+
+	return &HandoffData{
+		UID:           s.uid,
+		Data:          s.encodedData,
+		HandshakeData: s.handshakeData,
+		ResumeToken:   resumeToken,
+	}
+}
+
+// Resume restores the state of a session handed off by another frontend,
+// binding the UID again so that every bind callback is executed on this server.
+// The data and UID already set on the session during the handshake, e.g. by
+// handshake validators, take precedence over the handed off ones
+func (s *Session) Resume(ctx context.Context, resumeToken string) error {
+	data := claimHandoff(resumeToken)
+	if data == nil {
+		return constants.ErrHandoffNotFound
+	}
+
+	uid := s.UID()
+	if uid != "" && data.UID != "" && uid != data.UID {
+		return constants.ErrHandoffUIDMismatch
+	}
+
+	if data.HandshakeData != nil && s.GetHandshakeData() == nil {
+		s.SetHandshakeData(data.HandshakeData)
+	}
+	if len(data.Data) > 0 {
+		var handedOff map[string]interface{}
+		if err := json.Unmarshal(data.Data, &handedOff); err != nil {
+			return err
+		}
+		if handedOff == nil {
+			handedOff = map[string]interface{}{}
+		}
+		// the serializer was negotiated by the new connection
+		delete(handedOff, constants.SessionSerializerKey)
+		for k, v := range s.GetData() {
+			handedOff[k] = v
+		}
+		if err := s.SetData(handedOff); err != nil {
+			return err
+		}
+	}
+	if data.UID == "" || uid != "" {
+		return nil
+	}
+	return s.Bind(ctx, data.UID)
+}
+
+// Redirect tells the client to reconnect to the frontend at address, resuming
+// its state with the given token, and closes the session
+func (s *Session) Redirect(ctx context.Context, address, resumeToken string) error {
+	err := s.entity.Redirect(ctx, address, resumeToken)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	s.redirected = true
+	s.Unlock()
+
+	return s.entity.Close()
+}
+
+// Redirected returns whether the session was handed off to another frontend,
+// close callbacks can use it to keep state that now belongs to the new frontend
+func (s *Session) Redirected() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.redirected
+}
@@ -0,0 +1,178 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/session/mocks"
+)
+
+func TestGetHandoffData(t *testing.T) {
+	t.Parallel()
+	ss := New(nil, true)
+	defer sessionsByID.Delete(ss.ID())
+	uid := uuid.New().String()
+	ss.uid = uid
+	err := ss.SetData(map[string]interface{}{"hello": "test"})
+	assert.NoError(t, err)
+	hd := &HandshakeData{Sys: HandshakeClientData{Platform: "mac"}}
+	ss.SetHandshakeData(hd)
+
+	data := ss.GetHandoffData("token")
+	assert.Equal(t, uid, data.UID)
+	assert.Equal(t, ss.GetDataEncoded(), data.Data)
+	assert.Equal(t, hd, data.HandshakeData)
+	assert.Equal(t, "token", data.ResumeToken)
+}
+
+func TestResume(t *testing.T) {
+	tables := []struct {
+		name string
+		uid  string
+	}{
+		{"unbound_session", ""},
+		{"bound_session", uuid.New().String()},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			token := uuid.New().String()
+			hd := &HandshakeData{Sys: HandshakeClientData{Platform: "android"}}
+			AddHandoff(&HandoffData{
+				UID:           table.uid,
+				Data:          []byte(`{"hello":"test"}`),
+				HandshakeData: hd,
+				ResumeToken:   token,
+			})
+
+			ss := New(nil, true)
+			defer sessionsByID.Delete(ss.ID())
+			defer sessionsByUID.Delete(table.uid)
+			err := ss.Resume(context.Background(), token)
+			assert.NoError(t, err)
+			assert.Equal(t, table.uid, ss.UID())
+			assert.Equal(t, "test", ss.Get("hello"))
+			assert.Equal(t, hd, ss.GetHandshakeData())
+			if table.uid != "" {
+				assert.Equal(t, ss, GetSessionByUID(table.uid))
+			}
+
+			other := New(nil, true)
+			defer sessionsByID.Delete(other.ID())
+			err = other.Resume(context.Background(), token)
+			assert.Equal(t, constants.ErrHandoffNotFound, err)
+		})
+	}
+}
+
+func TestResumeValidatedSession(t *testing.T) {
+	uid := uuid.New().String()
+	tables := []struct {
+		name string
+		uid  string
+		err  error
+	}{
+		{"same_uid", uid, nil},
+		{"other_uid", uuid.New().String(), constants.ErrHandoffUIDMismatch},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			token := uuid.New().String()
+			AddHandoff(&HandoffData{
+				UID:           uid,
+				Data:          []byte(fmt.Sprintf(`{"hello":"test","auth":"old","%s":"msgpack"}`, constants.SessionSerializerKey)),
+				HandshakeData: &HandshakeData{Sys: HandshakeClientData{Platform: "android"}},
+				ResumeToken:   token,
+			})
+
+			// the handshake validators already bound the session and set its data
+			ss := New(nil, true)
+			defer sessionsByID.Delete(ss.ID())
+			ss.uid = table.uid
+			hd := &HandshakeData{Sys: HandshakeClientData{Platform: "mac"}}
+			ss.SetHandshakeData(hd)
+			err := ss.Set("auth", "new")
+			assert.NoError(t, err)
+
+			err = ss.Resume(context.Background(), token)
+			assert.Equal(t, table.err, err)
+			assert.Equal(t, table.uid, ss.UID())
+			assert.Equal(t, hd, ss.GetHandshakeData())
+			assert.Equal(t, "new", ss.Get("auth"))
+			assert.False(t, ss.HasKey(constants.SessionSerializerKey))
+			if table.err == nil {
+				assert.Equal(t, "test", ss.Get("hello"))
+			}
+		})
+	}
+}
+
+func TestResumeShouldFailIfExpired(t *testing.T) {
+	SetHandoffTimeout(time.Millisecond)
+	defer SetHandoffTimeout(time.Minute)
+
+	token := uuid.New().String()
+	AddHandoff(&HandoffData{ResumeToken: token})
+	time.Sleep(5 * time.Millisecond)
+
+	ss := New(nil, true)
+	defer sessionsByID.Delete(ss.ID())
+	err := ss.Resume(context.Background(), token)
+	assert.Equal(t, constants.ErrHandoffNotFound, err)
+}
+
+func TestRedirect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	entity := mocks.NewMockNetworkEntity(ctrl)
+	ss := New(entity, true)
+	defer sessionsByID.Delete(ss.ID())
+	c := context.Background()
+	entity.EXPECT().Redirect(c, "localhost:3250", "token")
+	entity.EXPECT().Close()
+	assert.False(t, ss.Redirected())
+	err := ss.Redirect(c, "localhost:3250", "token")
+	assert.NoError(t, err)
+	assert.True(t, ss.Redirected())
+}
+
+func TestRedirectShouldNotCloseOnError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	entity := mocks.NewMockNetworkEntity(ctrl)
+	ss := New(entity, true)
+	defer sessionsByID.Delete(ss.ID())
+	c := context.Background()
+	expected := errors.New("some error")
+	entity.EXPECT().Redirect(c, "localhost:3250", "token").Return(expected)
+	err := ss.Redirect(c, "localhost:3250", "token")
+	assert.Equal(t, expected, err)
+	assert.False(t, ss.Redirected())
+}
@@ -0,0 +1,62 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package session
+
+import (
+	"context"
+	"net"
+	"reflect"
+)
+
+// HandshakeValidator validates the handshake data sent by a client before
+// the handshake response. It can bind an UID to the session, e.g. after
+// verifying a token sent in the handshake, or reject the connection by
+// returning an error, preferably an *errors.Error whose code and message
+// are sent to the client in the handshake response
+type HandshakeValidator func(ctx context.Context, s *Session, data *HandshakeData, remoteAddr net.Addr) error
+
+var handshakeValidators = make([]HandshakeValidator, 0)
+
+// OnHandshake adds a validator to be called when a client sends the
+// handshake, validators are called in the order they were added and the
+// first error rejects the connection. Same function cannot be added twice!
+func OnHandshake(f HandshakeValidator) {
+	sf1 := reflect.ValueOf(f)
+	for _, fun := range handshakeValidators {
+		sf2 := reflect.ValueOf(fun)
+		if sf1.Pointer() == sf2.Pointer() {
+			return
+		}
+	}
+	handshakeValidators = append(handshakeValidators, f)
+}
+
+// ValidateHandshake calls the handshake validators with the handshake data
+// of the session
+func (s *Session) ValidateHandshake(ctx context.Context, remoteAddr net.Addr) error {
+	data := s.GetHandshakeData()
+	for _, validate := range handshakeValidators {
+		if err := validate(ctx, s, data, remoteAddr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
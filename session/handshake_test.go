@@ -0,0 +1,79 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package session
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateHandshake(t *testing.T) {
+	defer func() { handshakeValidators = make([]HandshakeValidator, 0) }()
+
+	rejectErr := errors.New("invalid token")
+	remoteAddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 3250}
+	bindUser := func(ctx context.Context, s *Session, data *HandshakeData, addr net.Addr) error {
+		assert.Equal(t, remoteAddr, addr)
+		token, _ := data.User["token"].(string)
+		if token == "" {
+			return rejectErr
+		}
+		s.uid = token
+		return nil
+	}
+	calls := 0
+	count := func(ctx context.Context, s *Session, data *HandshakeData, addr net.Addr) error {
+		calls++
+		return nil
+	}
+	OnHandshake(bindUser)
+	OnHandshake(count)
+	OnHandshake(bindUser)
+	assert.Len(t, handshakeValidators, 2)
+
+	tables := []struct {
+		name  string
+		data  *HandshakeData
+		err   error
+		uid   string
+		calls int
+	}{
+		{"accepted", &HandshakeData{User: map[string]interface{}{"token": "uid"}}, nil, "uid", 1},
+		{"rejected", &HandshakeData{}, rejectErr, "", 0},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			calls = 0
+			ss := New(nil, false)
+			ss.SetHandshakeData(table.data)
+
+			err := ss.ValidateHandshake(context.Background(), remoteAddr)
+			assert.Equal(t, table.err, err)
+			assert.Equal(t, table.uid, ss.UID())
+			assert.Equal(t, table.calls, calls)
+		})
+	}
+}
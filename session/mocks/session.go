@@ -88,6 +88,18 @@ func (mr *MockNetworkEntityMockRecorder) Kick(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Kick", reflect.TypeOf((*MockNetworkEntity)(nil).Kick), ctx)
 }
 
+// Redirect mocks base method
+func (m *MockNetworkEntity) Redirect(ctx context.Context, address, resumeToken string) error {
+	ret := m.ctrl.Call(m, "Redirect", ctx, address, resumeToken)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Redirect indicates an expected call of Redirect
+func (mr *MockNetworkEntityMockRecorder) Redirect(ctx, address, resumeToken interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redirect", reflect.TypeOf((*MockNetworkEntity)(nil).Redirect), ctx, address, resumeToken)
+}
+
 // RemoteAddr mocks base method
 func (m *MockNetworkEntity) RemoteAddr() net.Addr {
 	ret := m.ctrl.Call(m, "RemoteAddr")
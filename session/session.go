@@ -23,6 +23,7 @@ package session
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net"
 	"reflect"
 	"sync"
@@ -34,6 +35,7 @@ import (
 	"github.com/hnlxhzw/pitaya/constants"
 	"github.com/hnlxhzw/pitaya/logger"
 	"github.com/hnlxhzw/pitaya/protos"
+	"github.com/hnlxhzw/pitaya/serialize"
 )
 
 // NetworkEntity represent low-level network instance
@@ -42,6 +44,7 @@ type NetworkEntity interface {
 	ResponseMID(ctx context.Context, mid uint, v interface{}, isError ...bool) error
 	Close() error
 	Kick(ctx context.Context) error
+	Redirect(ctx context.Context, address, resumeToken string) error
 	RemoteAddr() net.Addr
 	SendRequest(ctx context.Context, serverID, route string, v interface{}) (*protos.Response, error)
 }
@@ -54,6 +57,9 @@ var (
 	sessionsByUID         sync.Map
 	sessionsByID          sync.Map
 	sessionIDSvc          = newSessionIDService()
+	pushHooksMu           sync.RWMutex
+	pushValidator         func(route string, v interface{}) error
+	pushDecoder           func(route string, data []byte) (interface{}, error)
 	// SessionCount keeps the current number of sessions
 	SessionCount int64
 )
@@ -64,6 +70,9 @@ type HandshakeClientData struct {
 	LibVersion  string `json:"libVersion"`
 	BuildNumber string `json:"clientBuildNumber"`
 	Version     string `json:"clientVersion"`
+	ResumeToken string `json:"resumeToken,omitempty"`
+	DictVersion string `json:"dictVersion,omitempty"`
+	Serializer  string `json:"serializer,omitempty"`
 }
 
 // HandshakeData represents information about the handshake sent by the client.
@@ -92,6 +101,8 @@ type Session struct {
 	frontendID        string                 // the id of the frontend that owns the session
 	frontendSessionID int64                  // the id of the session on the frontend server
 	Subscriptions     []*nats.Subscription   // subscription created on bind when using nats rpc server
+	redirected        bool                   // if session was handed off to another frontend
+	groups            []string               // groups this session is indexed by
 }
 
 type sessionIDService struct {
@@ -188,6 +199,23 @@ func OnSessionClose(f func(s *Session)) {
 	SessionCloseCallbacks = append(SessionCloseCallbacks, f)
 }
 
+// SetPushValidator sets a function that checks the route and payload of
+// every push sent through Push, a nil validator disables the check
+func SetPushValidator(f func(route string, v interface{}) error) {
+	pushHooksMu.Lock()
+	defer pushHooksMu.Unlock()
+	pushValidator = f
+}
+
+// SetPushDecoder sets the function that decodes the pushes forwarded by other
+// servers, serialized with the server serializer, to push them to sessions
+// that negotiated another serializer
+func SetPushDecoder(f func(route string, data []byte) (interface{}, error)) {
+	pushHooksMu.Lock()
+	defer pushHooksMu.Unlock()
+	pushDecoder = f
+}
+
 // CloseAll calls Close on all sessions
 func CloseAll() {
 	logger.Log.Debugf("closing all sessions, %d sessions", SessionCount)
@@ -199,6 +227,14 @@ func CloseAll() {
 	logger.Log.Debug("finished closing sessions")
 }
 
+// Range calls f sequentially for every session on this server,
+// stopping the iteration if f returns false
+func Range(f func(s *Session) bool) {
+	sessionsByID.Range(func(_, value interface{}) bool {
+		return f(value.(*Session))
+	})
+}
+
 func (s *Session) updateEncodedData() error {
 	var b []byte
 	b, err := json.Marshal(s.data)
@@ -206,11 +242,20 @@ func (s *Session) updateEncodedData() error {
 		return err
 	}
 	s.encodedData = b
+	s.updateGroupIndex(false)
 	return nil
 }
 
 // Push message to client
 func (s *Session) Push(route string, v interface{}) error {
+	pushHooksMu.RLock()
+	validate := pushValidator
+	pushHooksMu.RUnlock()
+	if validate != nil {
+		if err := validate(route, v); err != nil {
+			return err
+		}
+	}
 	return s.entity.Push(route, v)
 }
 
@@ -238,11 +283,20 @@ func (s *Session) GetData() map[string]interface{} {
 	return s.data
 }
 
-// SetData sets the whole session data
+// SetData sets the whole session data, keeping the serializer negotiated in
+// the handshake unless data sets it
 func (s *Session) SetData(data map[string]interface{}) error {
 	s.Lock()
 	defer s.Unlock()
 
+	serializer, negotiated := s.data[constants.SessionSerializerKey]
+	if _, ok := data[constants.SessionSerializerKey]; negotiated && !ok {
+		// the serializer negotiated in the handshake belongs to the connection
+		if data == nil {
+			data = map[string]interface{}{}
+		}
+		data[constants.SessionSerializerKey] = serializer
+	}
 	s.data = data
 	return s.updateEncodedData()
 }
@@ -339,6 +393,9 @@ func (s *Session) Close() {
 	atomic.AddInt64(&SessionCount, -1)
 	sessionsByID.Delete(s.ID())
 	sessionsByUID.Delete(s.UID())
+	s.Lock()
+	s.updateGroupIndex(true)
+	s.Unlock()
 	// TODO: this logic should be moved to nats rpc server
 	if s.IsFrontend && s.Subscriptions != nil && len(s.Subscriptions) > 0 {
 		// if the user is bound to an userid and nats rpc server is being used we need to unsubscribe
@@ -619,6 +676,33 @@ func (s *Session) String(key string) string {
 	return value
 }
 
+// Serializer returns the serializer negotiated by the client in the
+// handshake, or nil if the client uses the one of the server
+func (s *Session) Serializer() serialize.Serializer {
+	return serialize.Get(s.String(constants.SessionSerializerKey))
+}
+
+// PushData returns what to push to the session for a push forwarded by
+// another server, data, serialized with the server serializer, or, if the
+// session negotiated another serializer, data decoded for its agent to
+// serialize it again
+func (s *Session) PushData(route string, data []byte) (interface{}, error) {
+	if s.Serializer() == nil {
+		return data, nil
+	}
+	pushHooksMu.RLock()
+	decode := pushDecoder
+	pushHooksMu.RUnlock()
+	if decode == nil {
+		return nil, fmt.Errorf("%w: %s", constants.ErrPushNotDecoded, route)
+	}
+	v, err := decode(route, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", constants.ErrPushNotDecoded, route, err.Error())
+	}
+	return v, nil
+}
+
 // Value returns the value associated with the key as a interface{}.
 func (s *Session) Value(key string) interface{} {
 	s.RLock()
@@ -639,13 +723,18 @@ func (s *Session) PushToFront(ctx context.Context) error {
 	return s.sendRequestToFront(ctx, constants.SessionPushRoute, true)
 }
 
-// Clear releases all data related to current session
+// Clear releases all data related to current session, except the serializer
+// negotiated in the handshake
 func (s *Session) Clear() {
 	s.Lock()
 	defer s.Unlock()
 
 	s.uid = ""
-	s.data = map[string]interface{}{}
+	data := map[string]interface{}{}
+	if serializer, ok := s.data[constants.SessionSerializerKey]; ok {
+		data[constants.SessionSerializerKey] = serializer
+	}
+	s.data = data
 	s.updateEncodedData()
 }
 
@@ -39,6 +39,8 @@ import (
 	"github.com/hnlxhzw/pitaya/constants"
 	"github.com/hnlxhzw/pitaya/helpers"
 	"github.com/hnlxhzw/pitaya/protos"
+	"github.com/hnlxhzw/pitaya/serialize"
+	"github.com/hnlxhzw/pitaya/serialize/msgpack"
 	"github.com/hnlxhzw/pitaya/session/mocks"
 )
 
@@ -349,6 +351,53 @@ func TestSessionSetData(t *testing.T) {
 	}
 }
 
+func TestSessionSetDataKeepsSerializer(t *testing.T) {
+	t.Parallel()
+
+	ss := New(nil, false)
+	assert.NoError(t, ss.Set(constants.SessionSerializerKey, "protobuf"))
+
+	assert.NoError(t, ss.SetData(map[string]interface{}{"int": 1}))
+	assert.Equal(t, map[string]interface{}{"int": 1, constants.SessionSerializerKey: "protobuf"}, ss.data)
+
+	assert.NoError(t, ss.SetData(nil))
+	assert.Equal(t, "protobuf", ss.String(constants.SessionSerializerKey))
+
+	assert.NoError(t, ss.SetData(map[string]interface{}{constants.SessionSerializerKey: "json"}))
+	assert.Equal(t, "json", ss.String(constants.SessionSerializerKey))
+
+	ss.Clear()
+	assert.Equal(t, map[string]interface{}{constants.SessionSerializerKey: "json"}, ss.data)
+}
+
+func TestSessionPushData(t *testing.T) {
+	defer SetPushDecoder(nil)
+	serialize.Register(msgpack.NewSerializer())
+	defer serialize.Unregister("msgpack")
+
+	ss := New(nil, false)
+	v, err := ss.PushData("onSome", []byte("data"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data"), v)
+
+	assert.NoError(t, ss.Set(constants.SessionSerializerKey, "msgpack"))
+	_, err = ss.PushData("onSome", []byte("data"))
+	assert.True(t, errors.Is(err, constants.ErrPushNotDecoded))
+
+	SetPushDecoder(func(route string, data []byte) (interface{}, error) {
+		if route != "onSome" {
+			return nil, errors.New("undeclared")
+		}
+		return map[string]interface{}{"data": string(data)}, nil
+	})
+	v, err = ss.PushData("onSome", []byte("data"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"data": "data"}, v)
+
+	_, err = ss.PushData("onOther", []byte("data"))
+	assert.True(t, errors.Is(err, constants.ErrPushNotDecoded))
+}
+
 func TestSessionGetEncodedData(t *testing.T) {
 	t.Parallel()
 	tables := []struct {
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2018 TFG Co <backend@tfgco.com>
+ * Author: TFG Co <backend@tfgco.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of
+ * this software and associated documentation files (the "Software"), to deal in
+ * the Software without restriction, including without limitation the rights to
+ * use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ * the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package opentelemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Options holds configuration options for OpenTelemetry
+type Options struct {
+	Disabled    bool
+	Probability float64
+	ServiceName string
+	// SpanExporter exports the spans, e.g. an OTLP exporter. The spans are
+	// sampled but not exported if it is nil
+	SpanExporter sdktrace.SpanExporter
+	// MetricReader reads the metrics reported with an OTel metrics reporter,
+	// e.g. a periodic reader with an OTLP exporter. The global meter provider
+	// is not set if it is nil
+	MetricReader sdkmetric.Reader
+}
+
+// Configure configures the global OpenTelemetry tracer and meter providers
+// and the W3C trace context propagator. It returns a function that flushes
+// and shuts down the providers
+func Configure(options Options) (func(context.Context) error, error) {
+	if options.Disabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res := resource.NewSchemaless(attribute.String("service.name", options.ServiceName))
+
+	traceOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.Probability))),
+	}
+	if options.SpanExporter != nil {
+		traceOpts = append(traceOpts, sdktrace.WithBatcher(options.SpanExporter))
+	}
+	tracerProvider := sdktrace.NewTracerProvider(traceOpts...)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	shutdowns := []func(context.Context) error{tracerProvider.Shutdown}
+	if options.MetricReader != nil {
+		meterProvider := sdkmetric.NewMeterProvider(
+			sdkmetric.WithResource(res),
+			sdkmetric.WithReader(options.MetricReader),
+		)
+		otel.SetMeterProvider(meterProvider)
+		shutdowns = append(shutdowns, meterProvider.Shutdown)
+	}
+
+	return func(ctx context.Context) error {
+		var err error
+		for _, shutdown := range shutdowns {
+			if e := shutdown(ctx); e != nil && err == nil {
+				err = e
+			}
+		}
+		return err
+	}, nil
+}
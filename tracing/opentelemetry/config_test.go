@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2018 TFG Co <backend@tfgco.com>
+ * Author: TFG Co <backend@tfgco.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of
+ * this software and associated documentation files (the "Software"), to deal in
+ * the Software without restriction, including without limitation the rights to
+ * use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ * the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package opentelemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestConfigure(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown, err := Configure(Options{
+		Probability:  1,
+		ServiceName:  "test-svc",
+		SpanExporter: exporter,
+		MetricReader: sdkmetric.NewManualReader(),
+	})
+	assert.NoError(t, err)
+	defer func() {
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	}()
+
+	assert.IsType(t, &sdktrace.TracerProvider{}, otel.GetTracerProvider())
+	assert.IsType(t, &sdkmetric.MeterProvider{}, otel.GetMeterProvider())
+	assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+
+	_, span := otel.Tracer("test").Start(context.Background(), "span")
+	span.End()
+	assert.NoError(t, otel.GetTracerProvider().(*sdktrace.TracerProvider).ForceFlush(context.Background()))
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "span", spans[0].Name)
+
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestConfigureDisabled(t *testing.T) {
+	tracerProvider := otel.GetTracerProvider()
+	shutdown, err := Configure(Options{Disabled: true})
+	assert.NoError(t, err)
+	assert.Equal(t, tracerProvider, otel.GetTracerProvider())
+	assert.NoError(t, shutdown(context.Background()))
+}
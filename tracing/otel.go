@@ -0,0 +1,162 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/hnlxhzw/pitaya/constants"
+	pcontext "github.com/hnlxhzw/pitaya/context"
+	"github.com/hnlxhzw/pitaya/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// InstrumentationName is the name of the OpenTelemetry tracer and meter used by pitaya
+const InstrumentationName = "github.com/hnlxhzw/pitaya"
+
+// startOTelSpan starts an OpenTelemetry span with the same name and tags of
+// an opentracing span. Its parent is the span in the context or, if there
+// is none, the span in the W3C trace context propagated by an RPC call.
+// The spans are not recorded unless a global tracer provider is configured
+func startOTelSpan(ctx context.Context, opName string, tags opentracing.Tags) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = extractTraceContext(ctx)
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(tags))
+	for k, v := range tags {
+		if v == nil {
+			continue
+		}
+		attrs = append(attrs, attribute.String(k, fmt.Sprint(v)))
+	}
+
+	ctx, _ = otel.Tracer(InstrumentationName).Start(ctx, opName,
+		trace.WithSpanKind(spanKind(tags["span.kind"])),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx
+}
+
+func finishOTelSpan(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func spanKind(kind interface{}) trace.SpanKind {
+	switch kind {
+	case "server":
+		return trace.SpanKindServer
+	case "client":
+		return trace.SpanKindClient
+	default:
+		return trace.SpanKindInternal
+	}
+}
+
+// injectTraceContext adds the W3C trace context of the OpenTelemetry span in
+// the context to the propagatable context content
+func injectTraceContext(ctx context.Context) context.Context {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return pcontext.AddToPropagateCtx(ctx, constants.TraceContextPropagateCtxKey, map[string]string(carrier))
+}
+
+func extractTraceContext(ctx context.Context) context.Context {
+	val := pcontext.GetFromPropagateCtx(ctx, constants.TraceContextPropagateCtxKey)
+	if val == nil {
+		return ctx
+	}
+
+	carrier := propagation.MapCarrier{}
+	switch v := val.(type) {
+	case map[string]string:
+		carrier = propagation.MapCarrier(v)
+	case map[string]interface{}:
+		for k, value := range v {
+			if s, ok := value.(string); ok {
+				carrier[k] = s
+			} else {
+				logger.Log.Warnf("value from trace context carrier cannot be cast to string: %+v", value)
+			}
+		}
+	default:
+		logger.Log.Warnf("invalid trace context carrier: %+v", val)
+		return ctx
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// InjectGRPCMetadata adds the W3C trace context of the OpenTelemetry span in
+// the context to the outgoing gRPC metadata, for calls made directly to gRPC
+// services that don't receive the pitaya propagatable context
+func InjectGRPCMetadata(ctx context.Context) context.Context {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	for k, v := range carrier {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+	}
+	return ctx
+}
+
+// ExtractGRPCMetadata adds the W3C trace context in the incoming gRPC metadata
+// to the context, so the spans started by gRPC services called with
+// InjectGRPCMetadata continue the trace of the caller
+func ExtractGRPCMetadata(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	carrier := propagation.MapCarrier{}
+	for _, k := range otel.GetTextMapPropagator().Fields() {
+		if values := md.Get(k); len(values) > 0 {
+			carrier[k] = values[0]
+		}
+	}
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// UnaryServerInterceptor is a gRPC interceptor that continues the trace of the
+// calls with the W3C trace context in their metadata, like the ones made by
+// GRPCClient.Call2
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(ExtractGRPCMetadata(ctx), req)
+}
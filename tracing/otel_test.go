@@ -0,0 +1,155 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/constants"
+	pcontext "github.com/hnlxhzw/pitaya/context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func setupOTel(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(propagator)
+	})
+	return exporter
+}
+
+func TestOTelSpanPropagation(t *testing.T) {
+	exporter := setupOTel(t)
+
+	ctx := pcontext.AddToPropagateCtx(context.Background(), constants.PeerIDKey, "frontend")
+	ctx = StartSpan(ctx, "RPC Call", opentracing.Tags{"span.kind": "client", "peer.id": "backend"})
+	ctx, err := InjectSpan(ctx)
+	assert.NoError(t, err)
+
+	// the propagatable context is sent in the rpc request
+	encoded, err := pcontext.Encode(ctx)
+	assert.NoError(t, err)
+	remoteCtx, err := pcontext.Decode(encoded)
+	assert.NoError(t, err)
+
+	remoteCtx = StartSpan(remoteCtx, "connector.handler.route", opentracing.Tags{"span.kind": "server"})
+	expectedErr := errors.New("failed")
+	FinishSpan(remoteCtx, expectedErr)
+	FinishSpan(ctx, nil)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	server, client := spans[0], spans[1]
+
+	assert.Equal(t, "RPC Call", client.Name)
+	assert.Equal(t, trace.SpanKindClient, client.SpanKind)
+	assert.Contains(t, client.Attributes, attribute.String("peer.id", "backend"))
+
+	assert.Equal(t, "connector.handler.route", server.Name)
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.True(t, server.Parent.IsRemote())
+	assert.Equal(t, client.SpanContext.TraceID(), server.SpanContext.TraceID())
+	assert.Equal(t, client.SpanContext.SpanID(), server.Parent.SpanID())
+	assert.Equal(t, codes.Error, server.Status.Code)
+	assert.Equal(t, expectedErr.Error(), server.Status.Description)
+}
+
+func TestOTelSpanWithoutPropagatedContext(t *testing.T) {
+	exporter := setupOTel(t)
+
+	ctx := StartSpan(context.Background(), "connector.handler.route", opentracing.Tags{})
+	FinishSpan(ctx, nil)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent.IsValid())
+	assert.Equal(t, trace.SpanKindInternal, spans[0].SpanKind)
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+}
+
+func TestInjectGRPCMetadata(t *testing.T) {
+	setupOTel(t)
+
+	ctx := StartSpan(context.Background(), "RPC Call", opentracing.Tags{"span.kind": "client"})
+	ctx = InjectGRPCMetadata(ctx)
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	assert.True(t, ok)
+	assert.Len(t, md.Get("traceparent"), 1)
+	assert.Contains(t, md.Get("traceparent")[0], trace.SpanContextFromContext(ctx).TraceID().String())
+}
+
+func TestExtractGRPCMetadata(t *testing.T) {
+	exporter := setupOTel(t)
+
+	ctx := StartSpan(context.Background(), "RPC Call", opentracing.Tags{"span.kind": "client"})
+	md, _ := metadata.FromOutgoingContext(InjectGRPCMetadata(ctx))
+	FinishSpan(ctx, nil)
+
+	serverCtx := ExtractGRPCMetadata(metadata.NewIncomingContext(context.Background(), md))
+	serverCtx = StartSpan(serverCtx, "RPC Handle", opentracing.Tags{"span.kind": "server"})
+	FinishSpan(serverCtx, nil)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, spans[0].SpanContext.TraceID(), spans[1].SpanContext.TraceID())
+	assert.Equal(t, spans[0].SpanContext.SpanID(), spans[1].Parent.SpanID())
+	assert.True(t, spans[1].Parent.IsRemote())
+}
+
+func TestExtractGRPCMetadataWithoutTraceContext(t *testing.T) {
+	setupOTel(t)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("key", "value"))
+	assert.Equal(t, ctx, ExtractGRPCMetadata(ctx))
+	assert.Equal(t, context.Background(), ExtractGRPCMetadata(context.Background()))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	setupOTel(t)
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", traceparent))
+	var spanContext trace.SpanContext
+	_, err := UnaryServerInterceptor(ctx, nil, nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+		spanContext = trace.SpanContextFromContext(ctx)
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, spanContext.IsRemote())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spanContext.SpanID().String())
+}
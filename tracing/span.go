@@ -77,8 +77,10 @@ func ExtractSpan(ctx context.Context) (opentracing.SpanContext, error) {
 }
 
 // InjectSpan retrieves an opentrancing span from the current context and creates a new context
-// with it encoded in binary format inside the propagatable context content
+// with it encoded in binary format inside the propagatable context content, along with the
+// W3C trace context of the OpenTelemetry span
 func InjectSpan(ctx context.Context) (context.Context, error) {
+	ctx = injectTraceContext(ctx)
 	span := opentracing.SpanFromContext(ctx)
 	if span == nil {
 		return ctx, nil
@@ -93,7 +95,8 @@ func InjectSpan(ctx context.Context) (context.Context, error) {
 }
 
 // StartSpan starts a new span with a given parent context, operation name, tags and
-// optional parent span. It returns a context with the created span.
+// optional parent span. It returns a context with the created span and with an
+// OpenTelemetry span with the same name and tags.
 func StartSpan(
 	parentCtx context.Context,
 	opName string,
@@ -105,14 +108,16 @@ func StartSpan(
 		ref = reference[0]
 	}
 	span := opentracing.StartSpan(opName, opentracing.ChildOf(ref), tags)
-	return opentracing.ContextWithSpan(parentCtx, span)
+	ctx := startOTelSpan(parentCtx, opName, tags)
+	return opentracing.ContextWithSpan(ctx, span)
 }
 
-// FinishSpan finishes a span retrieved from the given context and logs the error if it exists
+// FinishSpan finishes the spans retrieved from the given context and logs the error if it exists
 func FinishSpan(ctx context.Context, err error) {
 	if ctx == nil {
 		return
 	}
+	finishOTelSpan(ctx, err)
 	span := opentracing.SpanFromContext(ctx)
 	if span == nil {
 		return
@@ -32,6 +32,8 @@ import (
 	"github.com/hnlxhzw/pitaya/protos"
 	"github.com/hnlxhzw/pitaya/serialize"
 	"github.com/hnlxhzw/pitaya/serialize/json"
+	"github.com/hnlxhzw/pitaya/serialize/jsonpb"
+	"github.com/hnlxhzw/pitaya/serialize/msgpack"
 	"github.com/hnlxhzw/pitaya/serialize/protobuf"
 	"github.com/hnlxhzw/pitaya/tracing"
 	"os"
@@ -124,7 +126,7 @@ func FileExists(filename string) bool {
 func GetErrorFromPayload(serializer serialize.Serializer, payload []byte) error {
 	err := &e.Error{Code: e.ErrUnknownCode.Desc, ErrorCode: e.ErrUnknownCode.ErrorCode}
 	switch serializer.(type) {
-	case *json.Serializer:
+	case *json.Serializer, *jsonpb.Serializer, *msgpack.Serializer:
 		_ = serializer.Unmarshal(payload, err)
 	case *protobuf.Serializer:
 		pErr := &protos.Error{Code: e.ErrUnknownCode.Desc, ErrorCode: e.ErrUnknownCode.ErrorCode}
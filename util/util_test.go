@@ -32,8 +32,13 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/hnlxhzw/pitaya/conn/message"
 	"github.com/hnlxhzw/pitaya/constants"
+	e "github.com/hnlxhzw/pitaya/errors"
 	"github.com/hnlxhzw/pitaya/protos"
+	"github.com/hnlxhzw/pitaya/serialize"
+	"github.com/hnlxhzw/pitaya/serialize/json"
+	"github.com/hnlxhzw/pitaya/serialize/jsonpb"
 	"github.com/hnlxhzw/pitaya/serialize/mocks"
+	"github.com/hnlxhzw/pitaya/serialize/msgpack"
 )
 
 var update = flag.Bool("update", false, "update .golden files")
@@ -196,6 +201,27 @@ func TestGetErrorPayload(t *testing.T) {
 	}
 }
 
+func TestGetErrorFromPayload(t *testing.T) {
+	t.Parallel()
+	tables := []struct {
+		name       string
+		serializer serialize.Serializer
+	}{
+		{"json", json.NewSerializer()},
+		{"jsonpb", jsonpb.NewSerializer()},
+		{"msgpack", msgpack.NewSerializer()},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			payload, err := GetErrorPayload(table.serializer, e.NewError(errors.New("not found"), "PIT-404", 404))
+			assert.NoError(t, err)
+
+			pErr := GetErrorFromPayload(table.serializer, payload)
+			assert.Equal(t, int32(404), pErr.(*e.Error).ErrorCode)
+		})
+	}
+}
+
 func TestConvertProtoToMessageType(t *testing.T) {
 	t.Parallel()
 	tables := []struct {
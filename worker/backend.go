@@ -0,0 +1,222 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"time"
+)
+
+// Backend is the job queue used by the worker to store and fetch jobs
+type Backend interface {
+	// Enqueue adds the job to its queue, or schedules it if it must run
+	// at a future time, and returns its ID. If the job has an idempotency
+	// key used by another job within the idempotency window, the job is not
+	// enqueued and the ID of the other job is returned
+	Enqueue(job *Job) (jid string, err error)
+
+	// Cancel removes a scheduled job that was not enqueued yet
+	Cancel(jid string) error
+
+	// Fetch blocks until a job of the queue is available, moving it to the
+	// in progress jobs, or until the context is done
+	Fetch(ctx context.Context, queue string) (*Job, error)
+
+	// Ack removes an in progress job, counting it as processed
+	Ack(job *Job) error
+
+	// Retry removes an in progress job that failed, counting it as processed
+	// and failed, and schedules it to be enqueued again at the given time
+	Retry(job *Job, at time.Time) error
+
+	// Kill removes an in progress job that failed and will not be retried,
+	// counting it as processed and failed, and keeps it as a dead job
+	Kill(job *Job) error
+
+	// DeadJobs returns the dead jobs, from the oldest to the newest
+	DeadJobs() ([]*Job, error)
+
+	// DeadJob returns a dead job by its ID
+	DeadJob(jid string) (*Job, error)
+
+	// Replay enqueues a dead job again, resetting its retries
+	Replay(jid string) error
+
+	// Purge removes a dead job
+	Purge(jid string) error
+
+	// Stats returns the jobs and queues statistics
+	Stats() (*Stats, error)
+}
+
+// Stats has the statistics of a backend
+type Stats struct {
+	Processed int
+	Failed    int
+	Retries   int64
+	Enqueued  map[string]int64
+	Scheduled map[string]int64
+}
+
+// Job is a job stored in a backend, it is encoded the same way as the
+// jobs of github.com/topfreegames/go-workers
+type Job struct {
+	ID           string          `json:"jid"`
+	Queue        string          `json:"queue,omitempty"`
+	Class        string          `json:"class"`
+	Args         json.RawMessage `json:"args"`
+	EnqueuedAt   float64         `json:"enqueued_at"`
+	At           float64         `json:"at,omitempty"`
+	Retry        bool            `json:"retry,omitempty"`
+	RetryMax     int             `json:"retry_max,omitempty"`
+	RetryCount   int             `json:"retry_count,omitempty"`
+	RetryOptions RetryOptions    `json:"retry_options"`
+	ErrorMessage string          `json:"error_message,omitempty"`
+	DiedAt       float64         `json:"died_at,omitempty"`
+
+	IdempotencyKey    string        `json:"idempotency_key,omitempty"`
+	IdempotencyWindow time.Duration `json:"-"`
+
+	raw string
+}
+
+// RetryOptions has the exponential backoff options of a job, in seconds
+type RetryOptions struct {
+	Exp      int `json:"exp"`
+	MinDelay int `json:"min_delay"`
+	MaxDelay int `json:"max_delay"`
+	MaxRand  int `json:"max_rand"`
+}
+
+func newJob(queue string, args interface{}, opts *EnqueueOpts) (*Job, error) {
+	bts, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := generateJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var at float64
+	if !opts.RunAt.IsZero() {
+		at = timeToSeconds(opts.RunAt)
+	} else if opts.RunAfter > 0 {
+		at = timeToSeconds(now.Add(opts.RunAfter))
+	}
+
+	return &Job{
+		ID:                id,
+		Queue:             queue,
+		Class:             class,
+		Args:              bts,
+		EnqueuedAt:        timeToSeconds(now),
+		At:                at,
+		Retry:             opts.RetryEnabled,
+		IdempotencyKey:    opts.IdempotencyKey,
+		IdempotencyWindow: opts.IdempotencyWindow,
+		RetryMax:          opts.MaxRetries,
+		RetryOptions: RetryOptions{
+			Exp:      opts.ExponentialFactor,
+			MinDelay: opts.MinDelayToRetry,
+			MaxDelay: opts.MaxDelayToRetry,
+			MaxRand:  opts.MaxRandom,
+		},
+	}, nil
+}
+
+func decodeJob(raw string) (*Job, error) {
+	job := &Job{}
+	if err := json.Unmarshal([]byte(raw), job); err != nil {
+		return nil, err
+	}
+	job.raw = raw
+	return job, nil
+}
+
+func (j *Job) encode() (string, error) {
+	bts, err := json.Marshal(j)
+	if err != nil {
+		return "", err
+	}
+	return string(bts), nil
+}
+
+// isScheduled returns true if the job must run after now
+func (j *Job) isScheduled(now time.Time) bool {
+	return j.At > timeToSeconds(now)
+}
+
+func (j *Job) runAt() time.Time {
+	return secondsToTime(j.At)
+}
+
+// resetForReplay clears the retries and failure of a dead job
+func (j *Job) resetForReplay() {
+	j.RetryCount = 0
+	j.ErrorMessage = ""
+	j.DiedAt = 0
+}
+
+func (j *Job) shouldRetry() bool {
+	return j.Retry && j.RetryCount < j.RetryMax
+}
+
+// retryDelay returns the backoff before retrying the job, that is
+// retryCount^exp + minDelay + rand(maxRand) * (retryCount + 1) seconds,
+// limited to maxDelay seconds
+func (j *Job) retryDelay() time.Duration {
+	opts := j.RetryOptions
+	power := math.Pow(float64(j.RetryCount), float64(opts.Exp))
+	randN := 0
+	if opts.MaxRand > 0 {
+		randN = mathrand.Intn(opts.MaxRand)
+	}
+	seconds := math.Min(
+		power+float64(opts.MinDelay)+float64(randN*(j.RetryCount+1)),
+		float64(opts.MaxDelay),
+	)
+	return time.Duration(seconds) * time.Second
+}
+
+func generateJobID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", b), nil
+}
+
+func timeToSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+func secondsToTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
@@ -0,0 +1,299 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+func newTestJob(t *testing.T, queue string) *Job {
+	job, err := newJob(queue, &rpcRoute{Route: "server.svc.method"}, &EnqueueOpts{
+		RetryEnabled: true,
+		MaxRetries:   2,
+	})
+	assert.NoError(t, err)
+	return job
+}
+
+func testEnqueueFetchAck(t *testing.T, b Backend) {
+	job := newTestJob(t, "enqueuefetch")
+	_, err := b.Enqueue(job)
+	assert.NoError(t, err)
+
+	stats, err := b.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Enqueued["enqueuefetch"])
+
+	fetched, err := b.Fetch(context.Background(), "enqueuefetch")
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, fetched.ID)
+	assert.JSONEq(t, string(job.Args), string(fetched.Args))
+	assert.Equal(t, job.RetryMax, fetched.RetryMax)
+
+	err = b.Ack(fetched)
+	assert.NoError(t, err)
+
+	stats, err = b.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Processed)
+	assert.Equal(t, 0, stats.Failed)
+	assert.Equal(t, int64(0), stats.Enqueued["enqueuefetch"])
+}
+
+func testFetchKeepsOrder(t *testing.T, b Backend) {
+	first := newTestJob(t, "order")
+	second := newTestJob(t, "order")
+	_, err := b.Enqueue(first)
+	assert.NoError(t, err)
+	_, err = b.Enqueue(second)
+	assert.NoError(t, err)
+
+	fetched, err := b.Fetch(context.Background(), "order")
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, fetched.ID)
+	fetched, err = b.Fetch(context.Background(), "order")
+	assert.NoError(t, err)
+	assert.Equal(t, second.ID, fetched.ID)
+}
+
+func testFetchStopsWhenContextIsDone(t *testing.T, b Backend) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := b.Fetch(ctx, "empty")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func testRetry(t *testing.T, b Backend) {
+	_, err := b.Enqueue(newTestJob(t, "retry"))
+	assert.NoError(t, err)
+	job, err := b.Fetch(context.Background(), "retry")
+	assert.NoError(t, err)
+
+	job.RetryCount++
+	job.ErrorMessage = "failed"
+	err = b.Retry(job, time.Now().Add(100*time.Millisecond))
+	assert.NoError(t, err)
+
+	stats, err := b.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Retries)
+	assert.Equal(t, 1, stats.Failed)
+
+	retried, err := b.Fetch(context.Background(), "retry")
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, retried.ID)
+	assert.Equal(t, 1, retried.RetryCount)
+	assert.Equal(t, "failed", retried.ErrorMessage)
+
+	stats, err = b.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), stats.Retries)
+}
+
+func testSchedule(t *testing.T, b Backend) {
+	job, err := newJob("schedule", &rpcRoute{Route: "server.svc.method"}, &EnqueueOpts{
+		RunAfter: 100 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	_, err = b.Enqueue(job)
+	assert.NoError(t, err)
+
+	stats, err := b.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Scheduled["schedule"])
+	assert.Equal(t, int64(0), stats.Enqueued["schedule"])
+
+	fetched, err := b.Fetch(context.Background(), "schedule")
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, fetched.ID)
+	assert.False(t, time.Now().Before(job.runAt()))
+
+	stats, err = b.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), stats.Scheduled["schedule"])
+}
+
+func testCancel(t *testing.T, b Backend) {
+	job, err := newJob("cancel", &rpcRoute{Route: "server.svc.method"}, &EnqueueOpts{
+		RunAt: time.Now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+	_, err = b.Enqueue(job)
+	assert.NoError(t, err)
+
+	err = b.Cancel(job.ID)
+	assert.NoError(t, err)
+	err = b.Cancel(job.ID)
+	assert.Equal(t, constants.ErrJobNotFound, err)
+
+	stats, err := b.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), stats.Scheduled["cancel"])
+
+	enqueued := newTestJob(t, "cancel")
+	_, err = b.Enqueue(enqueued)
+	assert.NoError(t, err)
+	err = b.Cancel(enqueued.ID)
+	assert.Equal(t, constants.ErrJobNotFound, err)
+}
+
+func testIdempotencyKey(t *testing.T, b Backend, expire func(time.Duration)) {
+	opts := &EnqueueOpts{IdempotencyKey: "grant-1", IdempotencyWindow: 100 * time.Millisecond}
+	first, err := newJob("idempotency", nil, opts)
+	assert.NoError(t, err)
+	jid, err := b.Enqueue(first)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, jid)
+
+	duplicated, err := newJob("idempotency", nil, opts)
+	assert.NoError(t, err)
+	jid, err = b.Enqueue(duplicated)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, jid)
+
+	stats, err := b.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Enqueued["idempotency"])
+
+	expire(150 * time.Millisecond)
+	jid, err = b.Enqueue(duplicated)
+	assert.NoError(t, err)
+	assert.Equal(t, duplicated.ID, jid)
+
+	opts = &EnqueueOpts{IdempotencyKey: "grant-2"}
+	first, err = newJob("idempotency", nil, opts)
+	assert.NoError(t, err)
+	_, err = b.Enqueue(first)
+	assert.NoError(t, err)
+	expire(150 * time.Millisecond)
+	duplicated, err = newJob("idempotency", nil, opts)
+	assert.NoError(t, err)
+	jid, err = b.Enqueue(duplicated)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, jid)
+}
+
+func testDeadJobs(t *testing.T, b Backend) {
+	_, err := b.Enqueue(newTestJob(t, "dead"))
+	assert.NoError(t, err)
+	job, err := b.Fetch(context.Background(), "dead")
+	assert.NoError(t, err)
+
+	job.RetryCount = 2
+	job.ErrorMessage = "failed"
+	job.DiedAt = timeToSeconds(time.Now())
+	err = b.Kill(job)
+	assert.NoError(t, err)
+
+	stats, err := b.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Processed)
+	assert.Equal(t, 1, stats.Failed)
+
+	deadJobs, err := b.DeadJobs()
+	assert.NoError(t, err)
+	assert.Len(t, deadJobs, 1)
+	assert.Equal(t, job.ID, deadJobs[0].ID)
+	deadJob, err := b.DeadJob(job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "failed", deadJob.ErrorMessage)
+
+	err = b.Replay(job.ID)
+	assert.NoError(t, err)
+	_, err = b.DeadJob(job.ID)
+	assert.Equal(t, constants.ErrJobNotFound, err)
+	replayed, err := b.Fetch(context.Background(), "dead")
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, replayed.ID)
+	assert.Equal(t, 0, replayed.RetryCount)
+	assert.Empty(t, replayed.ErrorMessage)
+
+	err = b.Kill(replayed)
+	assert.NoError(t, err)
+	err = b.Purge(job.ID)
+	assert.NoError(t, err)
+	err = b.Purge(job.ID)
+	assert.Equal(t, constants.ErrJobNotFound, err)
+	err = b.Replay(job.ID)
+	assert.Equal(t, constants.ErrJobNotFound, err)
+	deadJobs, err = b.DeadJobs()
+	assert.NoError(t, err)
+	assert.Empty(t, deadJobs)
+}
+
+func TestNewJobSchedule(t *testing.T) {
+	t.Parallel()
+	runAt := time.Now().Add(time.Hour)
+	tables := []struct {
+		name      string
+		opts      *EnqueueOpts
+		scheduled bool
+	}{
+		{"run_now", &EnqueueOpts{}, false},
+		{"run_at", &EnqueueOpts{RunAt: runAt}, true},
+		{"run_after", &EnqueueOpts{RunAfter: time.Hour}, true},
+		{"run_at_over_run_after", &EnqueueOpts{RunAt: runAt, RunAfter: time.Minute}, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			job, err := newJob("queue", nil, table.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, table.scheduled, job.isScheduled(time.Now()))
+			if !table.opts.RunAt.IsZero() {
+				assert.WithinDuration(t, runAt, job.runAt(), time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestJobRetryDelay(t *testing.T) {
+	t.Parallel()
+	tables := []struct {
+		name       string
+		retryCount int
+		opts       RetryOptions
+		delay      time.Duration
+	}{
+		{"first_retry", 0, RetryOptions{Exp: 2, MinDelay: 1, MaxDelay: 10}, 1 * time.Second},
+		{"exponential", 3, RetryOptions{Exp: 2, MinDelay: 1, MaxDelay: 100}, 10 * time.Second},
+		{"max_delay", 5, RetryOptions{Exp: 2, MinDelay: 1, MaxDelay: 10}, 10 * time.Second},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			job := &Job{RetryCount: table.retryCount, RetryOptions: table.opts}
+			assert.Equal(t, table.delay, job.retryDelay())
+		})
+	}
+}
+
+func TestJobShouldRetry(t *testing.T) {
+	t.Parallel()
+	assert.False(t, (&Job{Retry: false, RetryMax: 2}).shouldRetry())
+	assert.True(t, (&Job{Retry: true, RetryMax: 2, RetryCount: 1}).shouldRetry())
+	assert.False(t, (&Job{Retry: true, RetryMax: 2, RetryCount: 2}).shouldRetry())
+}
@@ -0,0 +1,133 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package worker
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DeadRPCJob is a RPC job that failed and will not be retried
+type DeadRPCJob struct {
+	ID           string                 `json:"jid"`
+	Route        string                 `json:"route"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	Arg          json.RawMessage        `json:"arg,omitempty"`
+	RetryCount   int                    `json:"retryCount"`
+	ErrorMessage string                 `json:"errorMessage"`
+	EnqueuedAt   time.Time              `json:"enqueuedAt"`
+	DiedAt       time.Time              `json:"diedAt"`
+}
+
+type deadRPCInfo struct {
+	Route    string
+	Metadata map[string]interface{}
+	Arg      json.RawMessage
+}
+
+func newDeadRPCJob(job *Job) (*DeadRPCJob, error) {
+	info := &deadRPCInfo{}
+	if err := json.Unmarshal(job.Args, info); err != nil {
+		return nil, err
+	}
+
+	return &DeadRPCJob{
+		ID:           job.ID,
+		Route:        info.Route,
+		Metadata:     info.Metadata,
+		Arg:          info.Arg,
+		RetryCount:   job.RetryCount,
+		ErrorMessage: job.ErrorMessage,
+		EnqueuedAt:   secondsToTime(job.EnqueuedAt),
+		DiedAt:       secondsToTime(job.DiedAt),
+	}, nil
+}
+
+// DeadRPCJobs returns the dead RPC jobs of a route, or of all routes if
+// route is empty, from the oldest to the newest
+func (w *Worker) DeadRPCJobs(route string) ([]*DeadRPCJob, error) {
+	jobs, err := w.backend.DeadJobs()
+	if err != nil {
+		return nil, err
+	}
+
+	deadJobs := make([]*DeadRPCJob, 0, len(jobs))
+	for _, job := range jobs {
+		if job.Queue != rpcQueue {
+			continue
+		}
+		deadJob, err := newDeadRPCJob(job)
+		if err != nil {
+			return nil, err
+		}
+		if route == "" || deadJob.Route == route {
+			deadJobs = append(deadJobs, deadJob)
+		}
+	}
+	return deadJobs, nil
+}
+
+// DeadRPCJob returns a dead RPC job by its ID
+func (w *Worker) DeadRPCJob(jid string) (*DeadRPCJob, error) {
+	job, err := w.backend.DeadJob(jid)
+	if err != nil {
+		return nil, err
+	}
+	return newDeadRPCJob(job)
+}
+
+// ReplayDeadRPCJob enqueues a dead RPC job again, resetting its retries
+func (w *Worker) ReplayDeadRPCJob(jid string) error {
+	return w.backend.Replay(jid)
+}
+
+// ReplayDeadRPCJobs enqueues again the dead RPC jobs of a route, or of all
+// routes if route is empty, and returns their IDs
+func (w *Worker) ReplayDeadRPCJobs(route string) ([]string, error) {
+	return w.forEachDeadRPCJob(route, w.backend.Replay)
+}
+
+// PurgeDeadRPCJob removes a dead RPC job
+func (w *Worker) PurgeDeadRPCJob(jid string) error {
+	return w.backend.Purge(jid)
+}
+
+// PurgeDeadRPCJobs removes the dead RPC jobs of a route, or of all routes
+// if route is empty, and returns their IDs
+func (w *Worker) PurgeDeadRPCJobs(route string) ([]string, error) {
+	return w.forEachDeadRPCJob(route, w.backend.Purge)
+}
+
+func (w *Worker) forEachDeadRPCJob(route string, f func(jid string) error) ([]string, error) {
+	deadJobs, err := w.DeadRPCJobs(route)
+	if err != nil {
+		return nil, err
+	}
+
+	jids := make([]string, 0, len(deadJobs))
+	for _, deadJob := range deadJobs {
+		if err := f(deadJob.ID); err != nil {
+			return jids, err
+		}
+		jids = append(jids, deadJob.ID)
+	}
+	return jids, nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/config"
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/helpers"
+	"github.com/hnlxhzw/pitaya/worker/mocks"
+)
+
+func TestWorkerDeadRPCJobs(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := viper.New()
+	cfg.Set("pitaya.worker.retry.max", 0)
+	worker := NewWorkerWithBackend(config.NewConfig(cfg), NewMemoryBackend())
+	defer worker.Stop()
+
+	route := "server.svc.method"
+	executed := make(chan bool, 1)
+	mockRPCJob := mocks.NewMockRPCJob(ctrl)
+	mockRPCJob.EXPECT().GetArgReply(route).Return(&fakeProtoMessage{}, &fakeProtoMessage{}, nil).Times(2)
+	mockRPCJob.EXPECT().ServerDiscovery(route, map[string]interface{}{"stack": "a"}).Return("", nil).Times(2)
+	gomock.InOrder(
+		mockRPCJob.EXPECT().RPC(gomock.Any(), "", route, gomock.Any(), gomock.Any()).Return(errors.New("error")),
+		mockRPCJob.EXPECT().RPC(gomock.Any(), "", route, gomock.Any(), gomock.Any()).Do(
+			func(context.Context, string, string, proto.Message, proto.Message) { executed <- true }),
+	)
+	err := worker.RegisterRPCJob(mockRPCJob)
+	assert.NoError(t, err)
+	worker.Start()
+
+	jid, err := worker.EnqueueRPC(route, map[string]interface{}{"stack": "a"},
+		&fakeProtoMessage{}, &fakeProtoMessage{Field: "arg"})
+	assert.NoError(t, err)
+
+	helpers.ShouldEventuallyReturn(t, func() int {
+		deadJobs, err := worker.DeadRPCJobs(route)
+		assert.NoError(t, err)
+		return len(deadJobs)
+	}, 1)
+
+	deadJob, err := worker.DeadRPCJob(jid)
+	assert.NoError(t, err)
+	assert.Equal(t, route, deadJob.Route)
+	assert.Equal(t, map[string]interface{}{"stack": "a"}, deadJob.Metadata)
+	assert.JSONEq(t, `{"Field": "arg"}`, string(deadJob.Arg))
+	assert.Equal(t, "error", deadJob.ErrorMessage)
+	assert.False(t, deadJob.DiedAt.Before(deadJob.EnqueuedAt))
+
+	deadJobs, err := worker.DeadRPCJobs("other.svc.method")
+	assert.NoError(t, err)
+	assert.Empty(t, deadJobs)
+
+	jids, err := worker.ReplayDeadRPCJobs(route)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{jid}, jids)
+	helpers.ShouldEventuallyReceive(t, executed)
+
+	_, err = worker.DeadRPCJob(jid)
+	assert.Equal(t, constants.ErrJobNotFound, err)
+	jids, err = worker.PurgeDeadRPCJobs("")
+	assert.NoError(t, err)
+	assert.Empty(t, jids)
+}
+
+func TestWorkerEnqueueRPCWithIdempotencyKey(t *testing.T) {
+	t.Parallel()
+	worker := NewWorkerWithBackend(config.NewConfig(), NewMemoryBackend())
+
+	opts := NewEnqueueOpts(config.NewConfig())
+	opts.IdempotencyKey = "grant-1"
+	jid, err := worker.EnqueueRPCWithOptions("server.svc.method", nil, &fakeProtoMessage{}, &fakeProtoMessage{}, opts)
+	assert.NoError(t, err)
+	duplicated, err := worker.EnqueueRPCWithOptions("server.svc.method", nil, &fakeProtoMessage{}, &fakeProtoMessage{}, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, jid, duplicated)
+}
+
+func TestWorkerEnqueueRPCWithIdempotencyWindow(t *testing.T) {
+	t.Parallel()
+	cfg := viper.New()
+	cfg.Set("pitaya.worker.idempotency.window", 10*time.Millisecond)
+	worker := NewWorkerWithBackend(config.NewConfig(cfg), NewMemoryBackend())
+
+	tables := []struct {
+		name       string
+		window     time.Duration
+		duplicated bool
+	}{
+		{"configured_window", 0, false},
+		{"never_expires", NoIdempotencyExpiration, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			opts := &EnqueueOpts{IdempotencyKey: table.name, IdempotencyWindow: table.window}
+			jid, err := worker.EnqueueRPCWithOptions("server.svc.method", nil, &fakeProtoMessage{}, &fakeProtoMessage{}, opts)
+			assert.NoError(t, err)
+			time.Sleep(20 * time.Millisecond)
+			other, err := worker.EnqueueRPCWithOptions("server.svc.method", nil, &fakeProtoMessage{}, &fakeProtoMessage{}, opts)
+			assert.NoError(t, err)
+			assert.Equal(t, table.duplicated, jid == other)
+		})
+	}
+}
@@ -20,9 +20,17 @@
 
 package worker
 
-import "github.com/hnlxhzw/pitaya/config"
+import (
+	"time"
 
-// EnqueueOpts has retry options for worker
+	"github.com/hnlxhzw/pitaya/config"
+)
+
+// NoIdempotencyExpiration is an IdempotencyWindow that keeps the
+// idempotency key forever, whatever the configured window is
+const NoIdempotencyExpiration time.Duration = -1
+
+// EnqueueOpts has retry and scheduling options for worker
 type EnqueueOpts struct {
 	RetryEnabled      bool
 	MaxRetries        int
@@ -30,6 +38,16 @@ type EnqueueOpts struct {
 	MinDelayToRetry   int
 	MaxDelayToRetry   int
 	MaxRandom         int
+	// RunAt schedules the job to run at the given time
+	RunAt time.Time
+	// RunAfter schedules the job to run after the given delay, it is
+	// ignored if RunAt is set
+	RunAfter time.Duration
+	// IdempotencyKey deduplicates the jobs enqueued with the same key
+	// within the IdempotencyWindow, a window of 0 uses the configured one
+	// and NoIdempotencyExpiration never expires
+	IdempotencyKey    string
+	IdempotencyWindow time.Duration
 }
 
 // NewEnqueueOpts reads from config to build *EnqueueOpts
@@ -41,5 +59,6 @@ func NewEnqueueOpts(config *config.Config) *EnqueueOpts {
 		MinDelayToRetry:   config.GetInt("pitaya.worker.retry.minDelay"),
 		MaxDelayToRetry:   config.GetInt("pitaya.worker.retry.maxDelay"),
 		MaxRandom:         config.GetInt("pitaya.worker.retry.maxRandom"),
+		IdempotencyWindow: config.GetDuration("pitaya.worker.idempotency.window"),
 	}
 }
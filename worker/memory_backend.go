@@ -0,0 +1,344 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package worker
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hnlxhzw/pitaya/constants"
+)
+
+// defaultMaxDeadJobs is the number of dead jobs kept by a memory backend
+// unless changed with SetMaxDeadJobs
+const defaultMaxDeadJobs = 10000
+
+type memoryIdempotencyKey struct {
+	jid string
+	// expiresAt is zero if the key never expires
+	expiresAt time.Time
+}
+
+func (k *memoryIdempotencyKey) expired(now time.Time) bool {
+	return !k.expiresAt.IsZero() && !now.Before(k.expiresAt)
+}
+
+type memoryDelayedJob struct {
+	job *Job
+	at  time.Time
+}
+
+// memoryDelayedJobs is a list of jobs sorted by the time they must be enqueued
+type memoryDelayedJobs []*memoryDelayedJob
+
+func (d memoryDelayedJobs) insert(job *Job, at time.Time) memoryDelayedJobs {
+	i := sort.Search(len(d), func(i int) bool {
+		return d[i].at.After(at)
+	})
+	d = append(d, nil)
+	copy(d[i+1:], d[i:])
+	d[i] = &memoryDelayedJob{job: job, at: at}
+	return d
+}
+
+// due returns the jobs that must be enqueued at now and the remaining ones
+func (d memoryDelayedJobs) due(now time.Time) ([]*Job, memoryDelayedJobs) {
+	var jobs []*Job
+	for len(d) > 0 && !d[0].at.After(now) {
+		jobs = append(jobs, d[0].job)
+		d = d[1:]
+	}
+	return jobs, d
+}
+
+// MemoryBackend is a backend that keeps the jobs in memory, it is meant for
+// tests and single node deployments, since the jobs are lost when the
+// process exits
+type MemoryBackend struct {
+	mutex      sync.Mutex
+	queues     map[string][]*Job
+	inProgress map[string]*Job
+	retries    memoryDelayedJobs
+	scheduled  memoryDelayedJobs
+	dead       []*Job
+	maxDead    int
+	keys       map[string]*memoryIdempotencyKey
+	processed  int
+	failed     int
+	wakeup     chan struct{}
+}
+
+// NewMemoryBackend returns a new in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		queues:     make(map[string][]*Job),
+		inProgress: make(map[string]*Job),
+		keys:       make(map[string]*memoryIdempotencyKey),
+		maxDead:    defaultMaxDeadJobs,
+		wakeup:     make(chan struct{}),
+	}
+}
+
+// SetMaxDeadJobs sets the number of dead jobs kept, the oldest ones are
+// removed when a job dies and there are more, 0 keeps all of them
+func (m *MemoryBackend) SetMaxDeadJobs(max int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.maxDead = max
+	m.trimDead()
+}
+
+// trimDead removes the oldest dead jobs above maxDead, it must be called with
+// the mutex locked
+func (m *MemoryBackend) trimDead() {
+	if m.maxDead > 0 && len(m.dead) > m.maxDead {
+		m.dead = append([]*Job(nil), m.dead[len(m.dead)-m.maxDead:]...)
+	}
+}
+
+// notify wakes up the fetchers, it must be called with the mutex locked
+func (m *MemoryBackend) notify() {
+	close(m.wakeup)
+	m.wakeup = make(chan struct{})
+}
+
+// Enqueue adds the job to its queue, or schedules it if it must run later
+func (m *MemoryBackend) Enqueue(job *Job) (string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	if job.IdempotencyKey != "" {
+		for name, key := range m.keys {
+			if key.expired(now) {
+				delete(m.keys, name)
+			}
+		}
+		if key, ok := m.keys[job.IdempotencyKey]; ok {
+			return key.jid, nil
+		}
+		key := &memoryIdempotencyKey{jid: job.ID}
+		if job.IdempotencyWindow > 0 {
+			key.expiresAt = now.Add(job.IdempotencyWindow)
+		}
+		m.keys[job.IdempotencyKey] = key
+	}
+
+	m.enqueue(job, now)
+	return job.ID, nil
+}
+
+// enqueue must be called with the mutex locked
+func (m *MemoryBackend) enqueue(job *Job, now time.Time) {
+	if job.isScheduled(now) {
+		m.scheduled = m.scheduled.insert(job, job.runAt())
+	} else {
+		m.queues[job.Queue] = append(m.queues[job.Queue], job)
+	}
+	m.notify()
+}
+
+// Cancel removes a scheduled job that was not enqueued yet
+func (m *MemoryBackend) Cancel(jid string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, delayed := range m.scheduled {
+		if delayed.job.ID == jid {
+			m.scheduled = append(m.scheduled[:i], m.scheduled[i+1:]...)
+			return nil
+		}
+	}
+	return constants.ErrJobNotFound
+}
+
+// Fetch blocks until a job of the queue is available or the context is done
+func (m *MemoryBackend) Fetch(ctx context.Context, queue string) (*Job, error) {
+	for {
+		m.mutex.Lock()
+		m.enqueueDue(time.Now())
+		if jobs := m.queues[queue]; len(jobs) > 0 {
+			job := jobs[0]
+			m.queues[queue] = jobs[1:]
+			m.inProgress[job.ID] = job
+			m.mutex.Unlock()
+			return job, nil
+		}
+
+		wakeup := m.wakeup
+		dueTimer := time.NewTimer(m.nextDue())
+		m.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			dueTimer.Stop()
+			return nil, ctx.Err()
+		case <-wakeup:
+		case <-dueTimer.C:
+		}
+		dueTimer.Stop()
+	}
+}
+
+// enqueueDue enqueues the retries and scheduled jobs that must run at now,
+// it must be called with the mutex locked
+func (m *MemoryBackend) enqueueDue(now time.Time) {
+	var retries, scheduled []*Job
+	retries, m.retries = m.retries.due(now)
+	scheduled, m.scheduled = m.scheduled.due(now)
+	for _, job := range append(retries, scheduled...) {
+		m.queues[job.Queue] = append(m.queues[job.Queue], job)
+	}
+}
+
+// nextDue returns how long until the next retry or scheduled job must be
+// enqueued, it must be called with the mutex locked
+func (m *MemoryBackend) nextDue() time.Duration {
+	next := time.Hour
+	for _, delayed := range []memoryDelayedJobs{m.retries, m.scheduled} {
+		if len(delayed) > 0 && time.Until(delayed[0].at) < next {
+			next = time.Until(delayed[0].at)
+		}
+	}
+	return next
+}
+
+// Ack removes an in progress job
+func (m *MemoryBackend) Ack(job *Job) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.inProgress, job.ID)
+	m.processed++
+	return nil
+}
+
+// Retry schedules an in progress job to be enqueued again at the given time
+func (m *MemoryBackend) Retry(job *Job, at time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.inProgress, job.ID)
+	m.processed++
+	m.failed++
+
+	m.retries = m.retries.insert(job, at)
+	m.notify()
+	return nil
+}
+
+// Kill removes an in progress job and keeps it as a dead job
+func (m *MemoryBackend) Kill(job *Job) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.inProgress, job.ID)
+	m.processed++
+	m.failed++
+	m.dead = append(m.dead, job)
+	m.trimDead()
+	return nil
+}
+
+// DeadJobs returns the dead jobs, from the oldest to the newest
+func (m *MemoryBackend) DeadJobs() ([]*Job, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	jobs := make([]*Job, len(m.dead))
+	copy(jobs, m.dead)
+	return jobs, nil
+}
+
+// DeadJob returns a dead job by its ID
+func (m *MemoryBackend) DeadJob(jid string) (*Job, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if i := m.deadJobIndex(jid); i >= 0 {
+		return m.dead[i], nil
+	}
+	return nil, constants.ErrJobNotFound
+}
+
+// Replay enqueues a dead job again
+func (m *MemoryBackend) Replay(jid string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	i := m.deadJobIndex(jid)
+	if i < 0 {
+		return constants.ErrJobNotFound
+	}
+	job := m.dead[i]
+	m.dead = append(m.dead[:i], m.dead[i+1:]...)
+	job.resetForReplay()
+	m.enqueue(job, time.Now())
+	return nil
+}
+
+// Purge removes a dead job
+func (m *MemoryBackend) Purge(jid string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	i := m.deadJobIndex(jid)
+	if i < 0 {
+		return constants.ErrJobNotFound
+	}
+	m.dead = append(m.dead[:i], m.dead[i+1:]...)
+	return nil
+}
+
+// deadJobIndex must be called with the mutex locked
+func (m *MemoryBackend) deadJobIndex(jid string) int {
+	for i, job := range m.dead {
+		if job.ID == jid {
+			return i
+		}
+	}
+	return -1
+}
+
+// Stats returns the jobs and queues statistics
+func (m *MemoryBackend) Stats() (*Stats, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	enqueued := make(map[string]int64, len(m.queues))
+	for queue, jobs := range m.queues {
+		enqueued[queue] = int64(len(jobs))
+	}
+	scheduled := make(map[string]int64)
+	for _, delayed := range m.scheduled {
+		scheduled[delayed.job.Queue]++
+	}
+	return &Stats{
+		Processed: m.processed,
+		Failed:    m.failed,
+		Retries:   int64(len(m.retries)),
+		Enqueued:  enqueued,
+		Scheduled: scheduled,
+	}, nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBackendEnqueueFetchAck(t *testing.T) {
+	t.Parallel()
+	testEnqueueFetchAck(t, NewMemoryBackend())
+}
+
+func TestMemoryBackendFetchKeepsOrder(t *testing.T) {
+	t.Parallel()
+	testFetchKeepsOrder(t, NewMemoryBackend())
+}
+
+func TestMemoryBackendFetchStopsWhenContextIsDone(t *testing.T) {
+	t.Parallel()
+	testFetchStopsWhenContextIsDone(t, NewMemoryBackend())
+}
+
+func TestMemoryBackendRetry(t *testing.T) {
+	t.Parallel()
+	testRetry(t, NewMemoryBackend())
+}
+
+func TestMemoryBackendSchedule(t *testing.T) {
+	t.Parallel()
+	testSchedule(t, NewMemoryBackend())
+}
+
+func TestMemoryBackendCancel(t *testing.T) {
+	t.Parallel()
+	testCancel(t, NewMemoryBackend())
+}
+
+func TestMemoryBackendIdempotencyKey(t *testing.T) {
+	t.Parallel()
+	testIdempotencyKey(t, NewMemoryBackend(), time.Sleep)
+}
+
+func TestMemoryBackendDeadJobs(t *testing.T) {
+	t.Parallel()
+	testDeadJobs(t, NewMemoryBackend())
+}
+
+func TestMemoryBackendMaxDeadJobs(t *testing.T) {
+	t.Parallel()
+	b := NewMemoryBackend()
+	b.SetMaxDeadJobs(2)
+
+	var jids []string
+	for i := 0; i < 3; i++ {
+		jid, err := b.Enqueue(newTestJob(t, "dead"))
+		assert.NoError(t, err)
+		job, err := b.Fetch(context.Background(), "dead")
+		assert.NoError(t, err)
+		assert.NoError(t, b.Kill(job))
+		jids = append(jids, jid)
+	}
+
+	jobs, err := b.DeadJobs()
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, jids[1], jobs[0].ID)
+	assert.Equal(t, jids[2], jobs[1].ID)
+
+	b.SetMaxDeadJobs(1)
+	jobs, err = b.DeadJobs()
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, jids[2], jobs[0].ID)
+}
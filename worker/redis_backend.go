@@ -0,0 +1,500 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/hnlxhzw/pitaya/config"
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/logger"
+)
+
+// the scheduled jobs of a queue and the dead jobs are stored by ID in sorted
+// sets, by the time they must run or the time they died, and the encoded jobs
+// are stored in hashes
+var (
+	redisEnqueueIndexedScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('LPUSH', KEYS[3], ARGV[2])
+return 1`)
+
+	redisRemoveIndexedScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+return 1`)
+)
+
+// RedisBackend is a backend that stores the jobs in Redis. It re-implements
+// github.com/topfreegames/go-workers instead of wrapping it, and only its
+// queue and goretry keys are wire-compatible with go-workers. Scheduled jobs
+// are kept in the scheduled keys, while go-workers uses schedule, and dead
+// jobs have no go-workers counterpart
+type RedisBackend struct {
+	client       *redis.Client
+	namespace    string
+	processID    string
+	fetchTimeout time.Duration
+	recovered    map[string]bool
+	recoverMutex sync.Mutex
+}
+
+// NewRedisBackend returns a new Redis backend, if no client is given one is
+// created using the pitaya.worker.redis configurations
+func NewRedisBackend(config *config.Config, clientOrNil *redis.Client) (*RedisBackend, error) {
+	client := clientOrNil
+	if client == nil {
+		client = redis.NewClient(&redis.Options{
+			Addr:     config.GetString("pitaya.worker.redis.url"),
+			Password: config.GetString("pitaya.worker.redis.password"),
+			PoolSize: config.GetInt("pitaya.worker.redis.pool"),
+		})
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := config.GetString("pitaya.worker.namespace")
+	if namespace != "" {
+		namespace = namespace + ":"
+	}
+
+	return &RedisBackend{
+		client:       client,
+		namespace:    namespace,
+		processID:    hostname,
+		fetchTimeout: time.Second,
+		recovered:    make(map[string]bool),
+	}, nil
+}
+
+func (r *RedisBackend) queuesKey() string {
+	return r.namespace + "queues"
+}
+
+func (r *RedisBackend) queueKey(queue string) string {
+	return fmt.Sprintf("%squeue:%s", r.namespace, queue)
+}
+
+func (r *RedisBackend) inProgressKey(queue string) string {
+	return fmt.Sprintf("%s:%s:inprogress", r.queueKey(queue), r.processID)
+}
+
+func (r *RedisBackend) scheduledKey(queue string) string {
+	return fmt.Sprintf("%sscheduled:%s", r.namespace, queue)
+}
+
+func (r *RedisBackend) scheduledJobsKey() string {
+	return r.namespace + "scheduled:jobs"
+}
+
+func (r *RedisBackend) deadKey() string {
+	return r.namespace + "dead"
+}
+
+func (r *RedisBackend) deadJobsKey() string {
+	return r.namespace + "dead:jobs"
+}
+
+func (r *RedisBackend) idempotencyKey(key string) string {
+	return fmt.Sprintf("%sidempotency:%s", r.namespace, key)
+}
+
+func (r *RedisBackend) retryKey() string {
+	return r.namespace + "goretry"
+}
+
+func (r *RedisBackend) processedKey() string {
+	return r.namespace + "stat:processed"
+}
+
+func (r *RedisBackend) failedKey() string {
+	return r.namespace + "stat:failed"
+}
+
+// Enqueue adds the job to its queue, or schedules it if it must run later
+func (r *RedisBackend) Enqueue(job *Job) (string, error) {
+	if job.IdempotencyKey != "" {
+		key := r.idempotencyKey(job.IdempotencyKey)
+		set, err := r.client.SetNX(key, job.ID, job.IdempotencyWindow).Result()
+		if err != nil {
+			return "", err
+		}
+		if !set {
+			jid, err := r.client.Get(key).Result()
+			if err != redis.Nil {
+				return jid, err
+			}
+			// the key expired in the meantime
+			return r.Enqueue(job)
+		}
+		if err := r.enqueue(job); err != nil {
+			r.client.Del(key)
+			return "", err
+		}
+		return job.ID, nil
+	}
+
+	if err := r.enqueue(job); err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+func (r *RedisBackend) enqueue(job *Job) error {
+	raw, err := job.encode()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.SAdd(r.queuesKey(), job.Queue)
+		if job.isScheduled(time.Now()) {
+			pipe.HSet(r.scheduledJobsKey(), job.ID, raw)
+			pipe.ZAdd(r.scheduledKey(job.Queue), redis.Z{Score: job.At, Member: job.ID})
+		} else {
+			pipe.LPush(r.queueKey(job.Queue), raw)
+		}
+		return nil
+	})
+	return err
+}
+
+// Cancel removes a scheduled job that was not enqueued yet
+func (r *RedisBackend) Cancel(jid string) error {
+	raw, err := r.client.HGet(r.scheduledJobsKey(), jid).Result()
+	if err == redis.Nil {
+		return constants.ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	job, err := decodeJob(raw)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{r.scheduledKey(job.Queue), r.scheduledJobsKey()}
+	canceled, err := redisRemoveIndexedScript.Run(r.client, keys, jid).Int()
+	if err != nil {
+		return err
+	}
+	if canceled == 0 {
+		return constants.ErrJobNotFound
+	}
+	return nil
+}
+
+// Fetch blocks until a job of the queue is available or the context is done
+func (r *RedisBackend) Fetch(ctx context.Context, queue string) (*Job, error) {
+	if err := r.recoverInProgress(queue); err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		if err := r.enqueueRetries(); err != nil {
+			return nil, err
+		}
+		if err := r.enqueueScheduled(queue); err != nil {
+			return nil, err
+		}
+
+		raw, err := r.client.BRPopLPush(r.queueKey(queue), r.inProgressKey(queue), r.fetchTimeout).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		job, err := decodeJob(raw)
+		if err != nil {
+			r.client.LRem(r.inProgressKey(queue), -1, raw)
+			return nil, err
+		}
+		return job, nil
+	}
+}
+
+// recoverInProgress enqueues again the jobs that were in progress when this
+// process last exited
+func (r *RedisBackend) recoverInProgress(queue string) error {
+	r.recoverMutex.Lock()
+	defer r.recoverMutex.Unlock()
+
+	if r.recovered[queue] {
+		return nil
+	}
+
+	for {
+		err := r.client.RPopLPush(r.inProgressKey(queue), r.queueKey(queue)).Err()
+		if err == redis.Nil {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	r.recovered[queue] = true
+	return nil
+}
+
+func (r *RedisBackend) enqueueRetries() error {
+	now := fmt.Sprintf("%f", timeToSeconds(time.Now()))
+	raws, err := r.client.ZRangeByScore(r.retryKey(), redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, raw := range raws {
+		// the job is only enqueued by the fetcher that removed it
+		removed, err := r.client.ZRem(r.retryKey(), raw).Result()
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			continue
+		}
+
+		job, err := decodeJob(raw)
+		if err != nil {
+			logger.Log.Errorf("failed to decode job to retry: %q", err)
+			continue
+		}
+		if err := r.client.LPush(r.queueKey(job.Queue), raw).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RedisBackend) enqueueScheduled(queue string) error {
+	now := fmt.Sprintf("%f", timeToSeconds(time.Now()))
+	jids, err := r.client.ZRangeByScore(r.scheduledKey(queue), redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, jid := range jids {
+		raw, err := r.client.HGet(r.scheduledJobsKey(), jid).Result()
+		if err == redis.Nil {
+			// the job was enqueued or canceled by another fetcher
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		keys := []string{r.scheduledKey(queue), r.scheduledJobsKey(), r.queueKey(queue)}
+		if err := redisEnqueueIndexedScript.Run(r.client, keys, jid, raw).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ack removes an in progress job
+func (r *RedisBackend) Ack(job *Job) error {
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.LRem(r.inProgressKey(job.Queue), -1, job.raw)
+		pipe.Incr(r.processedKey())
+		return nil
+	})
+	return err
+}
+
+// Retry schedules an in progress job to be enqueued again at the given time
+func (r *RedisBackend) Retry(job *Job, at time.Time) error {
+	raw, err := job.encode()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.LRem(r.inProgressKey(job.Queue), -1, job.raw)
+		pipe.ZAdd(r.retryKey(), redis.Z{Score: timeToSeconds(at), Member: raw})
+		pipe.Incr(r.processedKey())
+		pipe.Incr(r.failedKey())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	job.raw = raw
+	return nil
+}
+
+// Kill removes an in progress job and keeps it as a dead job
+func (r *RedisBackend) Kill(job *Job) error {
+	raw, err := job.encode()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.LRem(r.inProgressKey(job.Queue), -1, job.raw)
+		pipe.HSet(r.deadJobsKey(), job.ID, raw)
+		pipe.ZAdd(r.deadKey(), redis.Z{Score: job.DiedAt, Member: job.ID})
+		pipe.Incr(r.processedKey())
+		pipe.Incr(r.failedKey())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	job.raw = raw
+	return nil
+}
+
+// DeadJobs returns the dead jobs, from the oldest to the newest
+func (r *RedisBackend) DeadJobs() ([]*Job, error) {
+	jids, err := r.client.ZRange(r.deadKey(), 0, -1).Result()
+	if err != nil || len(jids) == 0 {
+		return nil, err
+	}
+
+	raws, err := r.client.HMGet(r.deadJobsKey(), jids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(raws))
+	for _, raw := range raws {
+		// the job was replayed or purged in the meantime
+		rawStr, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		job, err := decodeJob(rawStr)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// DeadJob returns a dead job by its ID
+func (r *RedisBackend) DeadJob(jid string) (*Job, error) {
+	raw, err := r.client.HGet(r.deadJobsKey(), jid).Result()
+	if err == redis.Nil {
+		return nil, constants.ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeJob(raw)
+}
+
+// Replay enqueues a dead job again
+func (r *RedisBackend) Replay(jid string) error {
+	job, err := r.DeadJob(jid)
+	if err != nil {
+		return err
+	}
+
+	job.resetForReplay()
+	raw, err := job.encode()
+	if err != nil {
+		return err
+	}
+
+	keys := []string{r.deadKey(), r.deadJobsKey(), r.queueKey(job.Queue)}
+	replayed, err := redisEnqueueIndexedScript.Run(r.client, keys, jid, raw).Int()
+	if err != nil {
+		return err
+	}
+	if replayed == 0 {
+		return constants.ErrJobNotFound
+	}
+	return r.client.SAdd(r.queuesKey(), job.Queue).Err()
+}
+
+// Purge removes a dead job
+func (r *RedisBackend) Purge(jid string) error {
+	keys := []string{r.deadKey(), r.deadJobsKey()}
+	purged, err := redisRemoveIndexedScript.Run(r.client, keys, jid).Int()
+	if err != nil {
+		return err
+	}
+	if purged == 0 {
+		return constants.ErrJobNotFound
+	}
+	return nil
+}
+
+// Stats returns the jobs and queues statistics
+func (r *RedisBackend) Stats() (*Stats, error) {
+	queues, err := r.client.SMembers(r.queuesKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var processed, failed *redis.StringCmd
+	var retries *redis.IntCmd
+	sizes := make(map[string]*redis.IntCmd, len(queues))
+	scheduledSizes := make(map[string]*redis.IntCmd, len(queues))
+	_, err = r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		processed = pipe.Get(r.processedKey())
+		failed = pipe.Get(r.failedKey())
+		retries = pipe.ZCard(r.retryKey())
+		for _, queue := range queues {
+			sizes[queue] = pipe.LLen(r.queueKey(queue))
+			scheduledSizes[queue] = pipe.ZCard(r.scheduledKey(queue))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	stats := &Stats{
+		Retries:   retries.Val(),
+		Enqueued:  make(map[string]int64, len(queues)),
+		Scheduled: make(map[string]int64, len(queues)),
+	}
+	stats.Processed, _ = processed.Int()
+	stats.Failed, _ = failed.Int()
+	for queue, size := range sizes {
+		stats.Enqueued[queue] = size.Val()
+		stats.Scheduled[queue] = scheduledSizes[queue].Val()
+	}
+	return stats, nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) TFG Co. All Rights Reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/hnlxhzw/pitaya/config"
+)
+
+func newTestRedisBackend(t *testing.T) (*RedisBackend, *miniredis.Miniredis) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	b, err := NewRedisBackend(config.NewConfig(), client)
+	assert.NoError(t, err)
+	return b, redisServer
+}
+
+func TestRedisBackendEnqueueFetchAck(t *testing.T) {
+	t.Parallel()
+	b, redisServer := newTestRedisBackend(t)
+	defer redisServer.Close()
+	testEnqueueFetchAck(t, b)
+}
+
+func TestRedisBackendFetchKeepsOrder(t *testing.T) {
+	t.Parallel()
+	b, redisServer := newTestRedisBackend(t)
+	defer redisServer.Close()
+	testFetchKeepsOrder(t, b)
+}
+
+func TestRedisBackendFetchStopsWhenContextIsDone(t *testing.T) {
+	t.Parallel()
+	b, redisServer := newTestRedisBackend(t)
+	defer redisServer.Close()
+	testFetchStopsWhenContextIsDone(t, b)
+}
+
+func TestRedisBackendRetry(t *testing.T) {
+	t.Parallel()
+	b, redisServer := newTestRedisBackend(t)
+	defer redisServer.Close()
+	testRetry(t, b)
+}
+
+func TestRedisBackendRecoversInProgressJobs(t *testing.T) {
+	t.Parallel()
+	b, redisServer := newTestRedisBackend(t)
+	defer redisServer.Close()
+
+	job := newTestJob(t, "recover")
+	raw, err := job.encode()
+	assert.NoError(t, err)
+	_, err = redisServer.Lpush(b.inProgressKey("recover"), raw)
+	assert.NoError(t, err)
+
+	fetched, err := b.Fetch(context.Background(), "recover")
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, fetched.ID)
+}
+
+func TestRedisBackendSchedule(t *testing.T) {
+	t.Parallel()
+	b, redisServer := newTestRedisBackend(t)
+	defer redisServer.Close()
+	testSchedule(t, b)
+}
+
+func TestRedisBackendCancel(t *testing.T) {
+	t.Parallel()
+	b, redisServer := newTestRedisBackend(t)
+	defer redisServer.Close()
+	testCancel(t, b)
+}
+
+func TestRedisBackendKeepsScheduledJobsOnRestart(t *testing.T) {
+	t.Parallel()
+	b, redisServer := newTestRedisBackend(t)
+	defer redisServer.Close()
+
+	job, err := newJob("restart", nil, &EnqueueOpts{RunAfter: 100 * time.Millisecond})
+	assert.NoError(t, err)
+	_, err = b.Enqueue(job)
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	restarted, err := NewRedisBackend(config.NewConfig(), client)
+	assert.NoError(t, err)
+	fetched, err := restarted.Fetch(context.Background(), "restart")
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, fetched.ID)
+}
+
+func TestRedisBackendIdempotencyKey(t *testing.T) {
+	t.Parallel()
+	b, redisServer := newTestRedisBackend(t)
+	defer redisServer.Close()
+	testIdempotencyKey(t, b, redisServer.FastForward)
+}
+
+func TestRedisBackendDeadJobs(t *testing.T) {
+	t.Parallel()
+	b, redisServer := newTestRedisBackend(t)
+	defer redisServer.Close()
+	testDeadJobs(t, b)
+}
@@ -1,45 +1,57 @@
 package worker
 
 import (
-	"strconv"
 	"time"
 
 	"github.com/hnlxhzw/pitaya/logger"
 	"github.com/hnlxhzw/pitaya/metrics"
-
-	workers "github.com/topfreegames/go-workers"
 )
 
-// Report sends periodic of worker reports
+// Report sends periodic reports of the last started worker, it is kept for
+// compatibility, (*Worker).Report must be used instead
 func Report(reporters []metrics.Reporter, period time.Duration) {
 	for {
 		time.Sleep(period)
 
-		workerStats := workers.GetStats()
-		for _, r := range reporters {
-			reportJobsRetry(r, workerStats.Retries)
-			reportQueueSizes(r, workerStats.Enqueued)
-			reportJobsTotal(r, workerStats.Failed, workerStats.Processed)
+		if w := lastStarted(); w != nil {
+			w.report(reporters)
 		}
 	}
 }
 
+// Report sends periodic of worker reports
+func (w *Worker) Report(reporters []metrics.Reporter, period time.Duration) {
+	for {
+		time.Sleep(period)
+
+		w.report(reporters)
+	}
+}
+
+func (w *Worker) report(reporters []metrics.Reporter) {
+	workerStats, err := w.backend.Stats()
+	if err != nil {
+		w.log().Errorf("failed to get worker stats: %q", err)
+		return
+	}
+	for _, r := range reporters {
+		reportJobsRetry(r, workerStats.Retries)
+		reportQueueSizes(r, metrics.WorkerQueueSize, workerStats.Enqueued)
+		reportQueueSizes(r, metrics.WorkerScheduledJobs, workerStats.Scheduled)
+		reportJobsTotal(r, workerStats.Failed, workerStats.Processed)
+	}
+}
+
 func reportJobsRetry(r metrics.Reporter, retries int64) {
 	err := r.ReportGauge(metrics.WorkerJobsRetry, map[string]string{}, float64(retries))
 	checkReportErr(metrics.WorkerJobsRetry, err)
 }
 
-func reportQueueSizes(r metrics.Reporter, queues map[string]string) {
+func reportQueueSizes(r metrics.Reporter, metric string, queues map[string]int64) {
 	for queue, size := range queues {
 		tags := map[string]string{"queue": queue}
-		sizeFlt, err := strconv.ParseFloat(size, 64)
-		if err != nil {
-			logger.Log.Errorf("queue size is not int: queue=%s size=%s", queue, size)
-			continue
-		}
-
-		err = r.ReportGauge(metrics.WorkerQueueSize, tags, sizeFlt)
-		checkReportErr(metrics.WorkerQueueSize, err)
+		err := r.ReportGauge(metric, tags, float64(size))
+		checkReportErr(metric, err)
 	}
 }
 
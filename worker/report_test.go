@@ -60,9 +60,9 @@ func TestReportQueueSizes(t *testing.T) {
 		map[string]string{"queue": queue2},
 		float64(20))
 
-	reportQueueSizes(mockReporter, map[string]string{
-		queue1: "10",
-		queue2: "20",
+	reportQueueSizes(mockReporter, metrics.WorkerQueueSize, map[string]int64{
+		queue1: 10,
+		queue2: 20,
 	})
 }
 
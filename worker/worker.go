@@ -23,56 +23,100 @@ package worker
 import (
 	"context"
 	"encoding/json"
-	"os"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hnlxhzw/pitaya/config"
 	"github.com/hnlxhzw/pitaya/constants"
 	"github.com/hnlxhzw/pitaya/logger"
-
-	workers "github.com/topfreegames/go-workers"
 )
 
 // Worker executes RPCs with retry and backoff time
 type Worker struct {
+	backend     Backend
 	concurrency int
-	registered  bool
 	opts        *EnqueueOpts
 	config      *config.Config
+	rpcJob      func(*Job) error
 	started     bool
+	ctx         context.Context
+	cancel      context.CancelFunc
+	mutex       sync.Mutex
+	logger      logger.Logger
+}
+
+var (
+	lastStartedWorker *Worker
+	lastStartedMutex  sync.Mutex
+)
+
+func lastStarted() *Worker {
+	lastStartedMutex.Lock()
+	defer lastStartedMutex.Unlock()
+	return lastStartedWorker
 }
 
-// NewWorker configures and returns a *Worker
+// NewWorker configures and returns a *Worker, using the backend
+// set in pitaya.worker.backend
 func NewWorker(config *config.Config) (*Worker, error) {
-	hostname, err := os.Hostname()
-	if err != nil {
-		return nil, err
+	var backend Backend
+	switch config.GetString("pitaya.worker.backend") {
+	case "redis":
+		redisBackend, err := NewRedisBackend(config, nil)
+		if err != nil {
+			return nil, err
+		}
+		backend = redisBackend
+	case "memory":
+		memoryBackend := NewMemoryBackend()
+		memoryBackend.SetMaxDeadJobs(config.GetInt("pitaya.worker.memory.maxDeadJobs"))
+		backend = memoryBackend
+	default:
+		return nil, constants.ErrInvalidWorkerBackend
 	}
 
-	workers.Configure(map[string]string{
-		"server":    config.GetString("pitaya.worker.redis.url"),
-		"pool":      config.GetString("pitaya.worker.redis.pool"),
-		"password":  config.GetString("pitaya.worker.redis.password"),
-		"namespace": config.GetString("pitaya.worker.namespace"),
-		"process":   hostname,
-	})
+	return NewWorkerWithBackend(config, backend), nil
+}
 
+// NewWorkerWithBackend returns a *Worker that stores its jobs in the given backend
+func NewWorkerWithBackend(config *config.Config, backend Backend) *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Worker{
+		backend:     backend,
 		concurrency: config.GetInt("pitaya.worker.concurrency"),
 		opts:        NewEnqueueOpts(config),
 		config:      config,
-	}, nil
+		ctx:         ctx,
+		cancel:      cancel,
+	}
 }
 
 // SetLogger overwrites worker logger
 func (w *Worker) SetLogger(logger logger.Logger) {
-	workers.Logger = logger
+	w.logger = logger
 }
 
-// Start starts worker in another gorotine
+func (w *Worker) log() logger.Logger {
+	if w.logger == nil {
+		return logger.Log
+	}
+	return w.logger
+}
+
+// Start starts processing the registered jobs
 func (w *Worker) Start() {
-	go workers.Start()
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
 	w.started = true
+	lastStartedMutex.Lock()
+	lastStartedWorker = w
+	lastStartedMutex.Unlock()
+	if w.rpcJob != nil {
+		w.process(rpcQueue, w.rpcJob)
+	}
 }
 
 // Started returns true if worker was started
@@ -80,19 +124,18 @@ func (w *Worker) Started() bool {
 	return w != nil && w.started
 }
 
+// Stop stops fetching jobs, the jobs being executed are not interrupted
+func (w *Worker) Stop() {
+	w.cancel()
+}
+
 // EnqueueRPC enqueues rpc job to worker
 func (w *Worker) EnqueueRPC(
 	routeStr string,
 	metadata map[string]interface{},
 	reply, arg proto.Message,
 ) (jid string, err error) {
-	opts := w.enqueueOptions(w.opts)
-	return workers.EnqueueWithOptions(rpcQueue, class, &rpcInfo{
-		Route:    routeStr,
-		Metadata: metadata,
-		Arg:      arg,
-		Reply:    reply,
-	}, opts)
+	return w.EnqueueRPCWithOptions(routeStr, metadata, reply, arg, w.opts)
 }
 
 // EnqueueRPCWithOptions enqueues rpc job to worker
@@ -102,101 +145,145 @@ func (w *Worker) EnqueueRPCWithOptions(
 	reply, arg proto.Message,
 	opts *EnqueueOpts,
 ) (jid string, err error) {
-	return workers.EnqueueWithOptions(rpcQueue, class, &rpcInfo{
+	job, err := newJob(rpcQueue, &rpcInfo{
 		Route:    routeStr,
 		Metadata: metadata,
 		Arg:      arg,
 		Reply:    reply,
-	}, w.enqueueOptions(opts))
+	}, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if job.IdempotencyWindow == 0 {
+		job.IdempotencyWindow = w.opts.IdempotencyWindow
+	}
+	if job.IdempotencyWindow < 0 {
+		// the backends keep keys without a window forever
+		job.IdempotencyWindow = 0
+	}
+	return w.backend.Enqueue(job)
+}
+
+// Cancel cancels a scheduled job that did not run yet
+func (w *Worker) Cancel(jid string) error {
+	return w.backend.Cancel(jid)
 }
 
 // RegisterRPCJob registers a RPC job
 func (w *Worker) RegisterRPCJob(rpcJob RPCJob) error {
-	if w.registered {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.rpcJob != nil {
 		return constants.ErrRPCJobAlreadyRegistered
 	}
 
-	job := w.parsedRPCJob(rpcJob)
-	workers.Process(rpcQueue, job, w.concurrency)
-	w.registered = true
+	w.rpcJob = w.parsedRPCJob(rpcJob)
+	if w.started {
+		w.process(rpcQueue, w.rpcJob)
+	}
 	return nil
 }
 
-func (w *Worker) parsedRPCJob(rpcJob RPCJob) func(*workers.Msg) {
-	return func(jobArg *workers.Msg) {
-		logger.Log.Debug("executing rpc job")
-		bts, rpcRoute, err := w.unmarshalRouteMetadata(jobArg)
+func (w *Worker) process(queue string, execute func(*Job) error) {
+	for i := 0; i < w.concurrency; i++ {
+		go func() {
+			for {
+				job, err := w.backend.Fetch(w.ctx, queue)
+				if err != nil {
+					if w.ctx.Err() != nil {
+						return
+					}
+					w.log().Errorf("failed to fetch job: %q", err)
+					time.Sleep(time.Second)
+					continue
+				}
+
+				w.finish(job, safeExecute(execute, job))
+			}
+		}()
+	}
+}
+
+// safeExecute executes the job, returning a panic as an error
+func safeExecute(execute func(*Job) error, job *Job) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("job panicked: %v", rec)
+		}
+	}()
+	return execute(job)
+}
+
+// finish acknowledges the job, schedules a retry with exponential backoff
+// or, if the job exhausted its retries, keeps it as a dead job
+func (w *Worker) finish(job *Job, jobErr error) {
+	var err error
+	switch {
+	case jobErr == nil:
+		err = w.backend.Ack(job)
+	case job.shouldRetry():
+		at := time.Now().Add(job.retryDelay())
+		job.RetryCount++
+		job.ErrorMessage = jobErr.Error()
+		err = w.backend.Retry(job, at)
+	default:
+		job.ErrorMessage = jobErr.Error()
+		job.DiedAt = timeToSeconds(time.Now())
+		err = w.backend.Kill(job)
+	}
+
+	if err != nil {
+		w.log().Errorf("failed to finish job %s: %q", job.ID, err)
+	}
+}
+
+func (w *Worker) parsedRPCJob(rpcJob RPCJob) func(*Job) error {
+	return func(job *Job) error {
+		w.log().Debug("executing rpc job")
+		rpcRoute := new(rpcRoute)
+		err := json.Unmarshal(job.Args, rpcRoute)
 		if err != nil {
-			logger.Log.Errorf("failed to get job arg: %q", err)
-			panic(err)
+			w.log().Errorf("failed to get job arg: %q", err)
+			return err
 		}
 
-		logger.Log.Debug("getting route arg and reply")
+		w.log().Debug("getting route arg and reply")
 		arg, reply, err := rpcJob.GetArgReply(rpcRoute.Route)
 		if err != nil {
-			logger.Log.Errorf("failed to get methods arg and reply: %q", err)
-			panic(err)
+			w.log().Errorf("failed to get methods arg and reply: %q", err)
+			return err
 		}
 		rpcInfo := &rpcInfo{
 			Arg:   arg,
 			Reply: reply,
 		}
 
-		logger.Log.Debug("unmarshalling rpc info")
-		err = json.Unmarshal(bts, rpcInfo)
+		w.log().Debug("unmarshalling rpc info")
+		err = json.Unmarshal(job.Args, rpcInfo)
 		if err != nil {
-			logger.Log.Errorf("failed to unmarshal rpc info: %q", err)
-			panic(err)
+			w.log().Errorf("failed to unmarshal rpc info: %q", err)
+			return err
 		}
 
-		logger.Log.Debug("choosing server to make rpc")
+		w.log().Debug("choosing server to make rpc")
 		serverID, err := rpcJob.ServerDiscovery(rpcInfo.Route, rpcInfo.Metadata)
 		if err != nil {
-			logger.Log.Errorf("failed get server: %q", err)
-			panic(err)
+			w.log().Errorf("failed get server: %q", err)
+			return err
 		}
 
 		ctx := context.Background()
 
-		logger.Log.Debugf("executing rpc func to %s", rpcInfo.Route)
+		w.log().Debugf("executing rpc func to %s", rpcInfo.Route)
 		err = rpcJob.RPC(ctx, serverID, rpcInfo.Route, reply, arg)
 		if err != nil {
-			logger.Log.Errorf("failed make rpc: %q", err)
-			panic(err)
+			w.log().Errorf("failed make rpc: %q", err)
+			return err
 		}
 
-		logger.Log.Debug("finished executing rpc job")
-	}
-}
-
-func (w *Worker) enqueueOptions(
-	opts *EnqueueOpts,
-) workers.EnqueueOptions {
-	return workers.EnqueueOptions{
-		Retry:    opts.RetryEnabled,
-		RetryMax: opts.MaxRetries,
-		RetryOptions: workers.RetryOptions{
-			Exp:      opts.ExponentialFactor,
-			MinDelay: opts.MinDelayToRetry,
-			MaxDelay: opts.MaxDelayToRetry,
-			MaxRand:  opts.MaxRandom,
-		},
-	}
-}
-
-func (w *Worker) unmarshalRouteMetadata(
-	jobArg *workers.Msg,
-) ([]byte, *rpcRoute, error) {
-	bts, err := jobArg.Args().MarshalJSON()
-	if err != nil {
-		return nil, nil, err
+		w.log().Debug("finished executing rpc job")
+		return nil
 	}
-
-	rpcRoute := new(rpcRoute)
-	err = json.Unmarshal(bts, rpcRoute)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return bts, rpcRoute, nil
 }
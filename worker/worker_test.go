@@ -6,8 +6,12 @@ import (
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
-	workers "github.com/topfreegames/go-workers"
+	"github.com/hnlxhzw/pitaya/config"
+	"github.com/hnlxhzw/pitaya/constants"
+	"github.com/hnlxhzw/pitaya/helpers"
 	"github.com/hnlxhzw/pitaya/worker/mocks"
 )
 
@@ -33,70 +37,48 @@ func TestParsedRPCJob(t *testing.T) {
 	)
 
 	tables := map[string]struct {
-		arg    *workers.Msg
+		arg    *Job
 		mocks  func()
-		assert func(func(*workers.Msg), *workers.Msg)
+		assert func(func(*Job) error, *Job)
 	}{
 		"test_error_when_route_not_string": {
-			arg: func() *workers.Msg {
-				msg, err := workers.NewMsg(`{"args": {"route": 10}}`)
-				assert.NoError(t, err)
-				return msg
-			}(),
+			arg: &Job{Args: []byte(`{"route": 10}`)},
 			mocks: func() {},
-			assert: func(f func(*workers.Msg), m *workers.Msg) {
-				assert.Panics(t, func() { f(m) })
+			assert: func(f func(*Job) error, j *Job) {
+				assert.Error(t, f(j))
 			},
 		},
 		"test_error_on_get_arg_reply": {
-			arg: func() *workers.Msg {
-				msg, err := workers.NewMsg(`{
-					"args": { "route": "server.svc.method" }
-				}`)
-				assert.NoError(t, err)
-				return msg
-			}(),
+			arg: &Job{Args: []byte(`{ "route": "server.svc.method" }`)},
 			mocks: func() {
 				mockRPCJob.EXPECT().
 					GetArgReply(route).
 					Return(nil, nil, testErr)
 			},
-			assert: func(f func(*workers.Msg), m *workers.Msg) {
-				assert.Panics(t, func() { f(m) })
+			assert: func(f func(*Job) error, j *Job) {
+				assert.Error(t, f(j))
 			},
 		},
 		"test_error_on_unmarshal_rpc_info": {
-			arg: func() *workers.Msg {
-				msg, err := workers.NewMsg(`{
-					"args": { 
-						"route": "server.svc.method",
-						"arg": { "field": 10 }
-					}
-				}`)
-				assert.NoError(t, err)
-				return msg
-			}(),
+			arg: &Job{Args: []byte(`{
+				"route": "server.svc.method",
+				"arg": { "field": 10 }
+			}`)},
 			mocks: func() {
 				mockRPCJob.EXPECT().
 					GetArgReply(route).
 					Return(&fakeProtoMessage{}, &fakeProtoMessage{}, nil)
 			},
-			assert: func(f func(*workers.Msg), m *workers.Msg) {
-				assert.Panics(t, func() { f(m) })
+			assert: func(f func(*Job) error, j *Job) {
+				assert.Error(t, f(j))
 			},
 		},
 		"test_error_on_server_discovery": {
-			arg: func() *workers.Msg {
-				msg, err := workers.NewMsg(`{
-					"args": { 
-						"route": "server.svc.method",
-						"arg": { "field": "string" },
-						"metadata": { "stack": "a" }
-					}
-				}`)
-				assert.NoError(t, err)
-				return msg
-			}(),
+			arg: &Job{Args: []byte(`{
+				"route": "server.svc.method",
+				"arg": { "field": "string" },
+				"metadata": { "stack": "a" }
+			}`)},
 			mocks: func() {
 				mockRPCJob.EXPECT().
 					GetArgReply(route).
@@ -105,22 +87,16 @@ func TestParsedRPCJob(t *testing.T) {
 					ServerDiscovery(route, map[string]interface{}{"stack": "a"}).
 					Return("", testErr)
 			},
-			assert: func(f func(*workers.Msg), m *workers.Msg) {
-				assert.Panics(t, func() { f(m) })
+			assert: func(f func(*Job) error, j *Job) {
+				assert.Error(t, f(j))
 			},
 		},
 		"test_error_on_rpc": {
-			arg: func() *workers.Msg {
-				msg, err := workers.NewMsg(`{
-					"args": { 
-						"route": "server.svc.method",
-						"arg": { "field": "string" },
-						"metadata": { "stack": "a" }
-					}
-				}`)
-				assert.NoError(t, err)
-				return msg
-			}(),
+			arg: &Job{Args: []byte(`{
+				"route": "server.svc.method",
+				"arg": { "field": "string" },
+				"metadata": { "stack": "a" }
+			}`)},
 			mocks: func() {
 				mockRPCJob.EXPECT().
 					GetArgReply(route).
@@ -132,22 +108,16 @@ func TestParsedRPCJob(t *testing.T) {
 					RPC(ctx, serverID, route, &fakeProtoMessage{}, &fakeProtoMessage{Field: "string"}).
 					Return(testErr)
 			},
-			assert: func(f func(*workers.Msg), m *workers.Msg) {
-				assert.Panics(t, func() { f(m) })
+			assert: func(f func(*Job) error, j *Job) {
+				assert.Error(t, f(j))
 			},
 		},
 		"test_execute_rpc": {
-			arg: func() *workers.Msg {
-				msg, err := workers.NewMsg(`{
-					"args": { 
-						"route": "server.svc.method",
-						"arg": { "field": "string" },
-						"metadata": { "stack": "a" }
-					}
-				}`)
-				assert.NoError(t, err)
-				return msg
-			}(),
+			arg: &Job{Args: []byte(`{
+				"route": "server.svc.method",
+				"arg": { "field": "string" },
+				"metadata": { "stack": "a" }
+			}`)},
 			mocks: func() {
 				mockRPCJob.EXPECT().
 					GetArgReply(route).
@@ -158,8 +128,8 @@ func TestParsedRPCJob(t *testing.T) {
 				mockRPCJob.EXPECT().
 					RPC(ctx, serverID, route, &fakeProtoMessage{}, &fakeProtoMessage{Field: "string"})
 			},
-			assert: func(f func(*workers.Msg), m *workers.Msg) {
-				assert.NotPanics(t, func() { f(m) })
+			assert: func(f func(*Job) error, j *Job) {
+				assert.NoError(t, f(j))
 			},
 		},
 	}
@@ -177,3 +147,56 @@ func TestParsedRPCJob(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkerRetriesFailedRPCJobs(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := viper.New()
+	cfg.Set("pitaya.worker.retry.maxDelay", 0)
+	backend := NewMemoryBackend()
+	worker := NewWorkerWithBackend(config.NewConfig(cfg), backend)
+	defer worker.Stop()
+
+	route := "server.svc.method"
+	done := make(chan bool, 1)
+	mockRPCJob := mocks.NewMockRPCJob(ctrl)
+	mockRPCJob.EXPECT().GetArgReply(route).Return(&fakeProtoMessage{}, &fakeProtoMessage{}, nil).Times(2)
+	mockRPCJob.EXPECT().ServerDiscovery(route, nil).Return("", nil).Times(2)
+	gomock.InOrder(
+		mockRPCJob.EXPECT().RPC(gomock.Any(), "", route, gomock.Any(), gomock.Any()).Return(errors.New("error")),
+		mockRPCJob.EXPECT().RPC(gomock.Any(), "", route, gomock.Any(), gomock.Any()).Do(
+			func(context.Context, string, string, proto.Message, proto.Message) { done <- true }),
+	)
+
+	err := worker.RegisterRPCJob(mockRPCJob)
+	assert.NoError(t, err)
+	err = worker.RegisterRPCJob(mockRPCJob)
+	assert.Equal(t, constants.ErrRPCJobAlreadyRegistered, err)
+	worker.Start()
+
+	jid, err := worker.EnqueueRPC(route, nil, &fakeProtoMessage{}, &fakeProtoMessage{Field: "arg"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, jid)
+
+	helpers.ShouldEventuallyReceive(t, done)
+	helpers.ShouldEventuallyReturn(t, func() int {
+		stats, err := backend.Stats()
+		assert.NoError(t, err)
+		return stats.Processed
+	}, 2)
+
+	stats, err := backend.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Failed)
+	assert.Equal(t, int64(0), stats.Retries)
+}
+
+func TestWorkerStartSetsLastStarted(t *testing.T) {
+	worker := NewWorkerWithBackend(config.NewConfig(viper.New()), NewMemoryBackend())
+	defer worker.Stop()
+
+	worker.Start()
+	assert.Equal(t, worker, lastStarted())
+}